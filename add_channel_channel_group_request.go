@@ -181,10 +181,18 @@ func (o *addChannelOpts) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *addChannelOpts) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *addChannelOpts) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
 
+func (o *addChannelOpts) tokenResources() (channels, groups, uuids []string) {
+	return nil, []string{o.ChannelGroup}, nil
+}
+
 // AddChannelToChannelGroupResponse is the struct returned when the Execute function of AddChannelToChannelGroup is called.
 type AddChannelToChannelGroupResponse struct {
 }
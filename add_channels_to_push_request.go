@@ -209,6 +209,10 @@ func (o *addChannelsToPushOpts) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *addChannelsToPushOpts) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *addChannelsToPushOpts) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
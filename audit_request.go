@@ -0,0 +1,309 @@
+package webpubsub
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const auditPath = "/v2/auth/audit/sub-key/%s"
+
+var emptyAuditResponse *AuditResponse
+
+type auditBuilder struct {
+	opts *auditOpts
+}
+
+func newAuditBuilder(webpubsub *WebPubSub) *auditBuilder {
+	builder := auditBuilder{
+		opts: &auditOpts{
+			webpubsub: webpubsub,
+		},
+	}
+
+	return &builder
+}
+
+func newAuditBuilderWithContext(webpubsub *WebPubSub, context Context) *auditBuilder {
+	builder := auditBuilder{
+		opts: &auditOpts{
+			webpubsub: webpubsub,
+			ctx:       context,
+		},
+	}
+
+	return &builder
+}
+
+// AuthKeys sets the AuthKeys for the Audit request.
+func (b *auditBuilder) AuthKeys(authKeys []string) *auditBuilder {
+	b.opts.AuthKeys = authKeys
+
+	return b
+}
+
+// Channels sets the Channels for the Audit request.
+func (b *auditBuilder) Channels(channels []string) *auditBuilder {
+	b.opts.Channels = channels
+
+	return b
+}
+
+// ChannelGroups sets the ChannelGroups for the Audit request.
+func (b *auditBuilder) ChannelGroups(groups []string) *auditBuilder {
+	b.opts.ChannelGroups = groups
+
+	return b
+}
+
+// UUIDs sets the UUIDs for the Audit request.
+func (b *auditBuilder) UUIDs(targetUUIDs []string) *auditBuilder {
+	b.opts.UUIDs = targetUUIDs
+
+	return b
+}
+
+// QueryParam accepts a map, the keys and values of the map are passed as the query string parameters of the URL called by the API.
+func (b *auditBuilder) QueryParam(queryParam map[string]string) *auditBuilder {
+	b.opts.QueryParam = queryParam
+
+	return b
+}
+
+// Execute runs the Audit request.
+func (b *auditBuilder) Execute() (*AuditResponse, StatusResponse, error) {
+	rawJSON, status, err := executeRequest(b.opts)
+	if err != nil {
+		return emptyAuditResponse, status, err
+	}
+
+	return newAuditResponse(rawJSON, status)
+}
+
+type auditOpts struct {
+	webpubsub *WebPubSub
+	ctx       Context
+
+	AuthKeys      []string
+	Channels      []string
+	ChannelGroups []string
+	UUIDs         []string
+	QueryParam    map[string]string
+}
+
+func (o *auditOpts) config() Config {
+	return *o.webpubsub.Config
+}
+
+func (o *auditOpts) client() *http.Client {
+	return o.webpubsub.GetClient()
+}
+
+func (o *auditOpts) context() Context {
+	return o.ctx
+}
+
+func (o *auditOpts) validate() error {
+	if o.config().PublishKey == "" {
+		return newValidationError(o, StrMissingPubKey)
+	}
+
+	if o.config().SubscribeKey == "" {
+		return newValidationError(o, StrMissingSubKey)
+	}
+
+	if o.config().SecretKey == "" {
+		return newValidationError(o, StrMissingSecretKey)
+	}
+
+	return nil
+}
+
+func (o *auditOpts) buildPath() (string, error) {
+	return fmt.Sprintf(auditPath, o.webpubsub.Config.SubscribeKey), nil
+}
+
+func (o *auditOpts) buildQuery() (*url.Values, error) {
+	q := defaultQuery(o.webpubsub.Config.UUID, o.webpubsub.telemetryManager)
+
+	if len(o.AuthKeys) > 0 {
+		q.Set("auth", strings.Join(o.AuthKeys, ","))
+	}
+
+	if len(o.Channels) > 0 {
+		q.Set("channel", strings.Join(o.Channels, ","))
+	}
+
+	if len(o.ChannelGroups) > 0 {
+		q.Set("channel-group", strings.Join(o.ChannelGroups, ","))
+	}
+
+	if len(o.UUIDs) > 0 {
+		q.Set("target-uuid", strings.Join(o.UUIDs, ","))
+	}
+
+	timestamp := time.Now().Unix()
+	q.Set("timestamp", strconv.Itoa(int(timestamp)))
+	SetQueryParam(q, o.QueryParam)
+
+	return q, nil
+}
+
+func (o *auditOpts) jobQueue() chan *JobQItem {
+	return o.webpubsub.jobQueue
+}
+
+func (o *auditOpts) buildBody() ([]byte, error) {
+	return []byte{}, nil
+}
+
+func (o *auditOpts) buildBodyMultipartFileUpload() (bytes.Buffer, *multipart.Writer, int64, error) {
+	return bytes.Buffer{}, nil, 0, errors.New("Not required")
+}
+
+func (o *auditOpts) httpMethod() string {
+	return "GET"
+}
+
+func (o *auditOpts) isAuthRequired() bool {
+	return true
+}
+
+func (o *auditOpts) requestTimeout() int {
+	return o.webpubsub.Config.NonSubscribeRequestTimeout
+}
+
+func (o *auditOpts) connectTimeout() int {
+	return o.webpubsub.Config.ConnectTimeout
+}
+
+func (o *auditOpts) operationType() OperationType {
+	return WPSAccessManagerAudit
+}
+
+func (o *auditOpts) telemetryManager() *TelemetryManager {
+	return o.webpubsub.telemetryManager
+}
+
+func (o *auditOpts) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
+func (o *auditOpts) tokenManager() *TokenManager {
+	return o.webpubsub.tokenManager
+}
+
+// AuditResponse is the struct returned when the Execute function of Audit is
+// called, the permissions are in the same shape as in GrantResponse.
+type AuditResponse struct {
+	Level        string
+	SubscribeKey string
+
+	TTL int
+
+	Channels      map[string]*WPSPAMEntityData
+	ChannelGroups map[string]*WPSPAMEntityData
+	UUIDs         map[string]*WPSPAMEntityData
+}
+
+func newAuditResponse(jsonBytes []byte, status StatusResponse) (
+	*AuditResponse, StatusResponse, error) {
+	grant, status, err := newGrantResponse(jsonBytes, status)
+	if err != nil {
+		return emptyAuditResponse, status, err
+	}
+
+	return &AuditResponse{
+		Level:         grant.Level,
+		SubscribeKey:  grant.SubscribeKey,
+		TTL:           grant.TTL,
+		Channels:      grant.Channels,
+		ChannelGroups: grant.ChannelGroups,
+		UUIDs:         grant.UUIDs,
+	}, status, nil
+}
+
+// Permissions returns the permissions granted to authKey, or the ones granted
+// at the channel, channel group and UUID level when authKey is empty. Token
+// holds authKey, Timestamp isn't returned by the audit.
+func (r *AuditResponse) Permissions(authKey string) *GrantResourcesWithPermissions {
+	resources := &GrantResourcesWithPermissions{
+		Channels: make(map[string]ChannelPermissionsWithToken),
+		Groups:   make(map[string]GroupPermissionsWithToken),
+		UUIDs:    make(map[string]UUIDPermissionsWithToken),
+	}
+
+	for name, entity := range r.Channels {
+		if mask, ttl, ok := auditedPermissions(entity, authKey); ok {
+			resources.Channels[name] = ChannelPermissionsWithToken{
+				Permissions:  parseGrantPerms(mask, WPSChannels).(ChannelPermissions),
+				BitMaskPerms: mask,
+				Token:        authKey,
+				TTL:          ttl,
+			}
+		}
+	}
+	for name, entity := range r.ChannelGroups {
+		if mask, ttl, ok := auditedPermissions(entity, authKey); ok {
+			resources.Groups[name] = GroupPermissionsWithToken{
+				Permissions:  parseGrantPerms(mask, WPSGroups).(GroupPermissions),
+				BitMaskPerms: mask,
+				Token:        authKey,
+				TTL:          ttl,
+			}
+		}
+	}
+	for name, entity := range r.UUIDs {
+		if mask, ttl, ok := auditedPermissions(entity, authKey); ok {
+			resources.UUIDs[name] = UUIDPermissionsWithToken{
+				Permissions:  parseGrantPerms(mask, WPSUUIDs).(UUIDPermissions),
+				BitMaskPerms: mask,
+				Token:        authKey,
+				TTL:          ttl,
+			}
+		}
+	}
+
+	return resources
+}
+
+// auditedPermissions returns the bitmask and the TTL of the permissions of
+// authKey on entity, false when authKey has no permissions on it.
+func auditedPermissions(entity *WPSPAMEntityData, authKey string) (int64, int, bool) {
+	data := &WPSAccessManagerKeyData{
+		ReadEnabled:   entity.ReadEnabled,
+		WriteEnabled:  entity.WriteEnabled,
+		ManageEnabled: entity.ManageEnabled,
+		DeleteEnabled: entity.DeleteEnabled,
+		GetEnabled:    entity.GetEnabled,
+		UpdateEnabled: entity.UpdateEnabled,
+		JoinEnabled:   entity.JoinEnabled,
+	}
+	if authKey != "" {
+		var ok bool
+		if data, ok = entity.AuthKeys[authKey]; !ok {
+			return 0, 0, false
+		}
+	}
+
+	ttl := data.TTL
+	if ttl == 0 {
+		ttl = entity.TTL
+	}
+	mask := channelPermissionsMask(ChannelPermissions{
+		Read:   data.ReadEnabled,
+		Write:  data.WriteEnabled,
+		Manage: data.ManageEnabled,
+		Delete: data.DeleteEnabled,
+		Get:    data.GetEnabled,
+		Update: data.UpdateEnabled,
+		Join:   data.JoinEnabled,
+	})
+	return int64(mask), ttl, true
+}
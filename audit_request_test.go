@@ -0,0 +1,98 @@
+package webpubsub
+
+import (
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	h "github.com/webpubsub/sdk-go/v7/tests/helpers"
+)
+
+func TestAuditRequestBasic(t *testing.T) {
+	assert := assert.New(t)
+
+	o := newAuditBuilder(webpubsub)
+	o.AuthKeys([]string{"key-1", "key-2"})
+	o.Channels([]string{"ch1", "ch2"})
+	o.ChannelGroups([]string{"cg"})
+	o.UUIDs([]string{"uuid"})
+	o.QueryParam(map[string]string{"q1": "v1"})
+
+	path, err := o.opts.buildPath()
+	assert.Nil(err)
+	u := &url.URL{
+		Path: path,
+	}
+
+	h.AssertPathsEqual(t,
+		fmt.Sprintf("/v2/auth/audit/sub-key/%s", o.opts.webpubsub.Config.SubscribeKey),
+		u.EscapedPath(), []int{})
+
+	query, err := o.opts.buildQuery()
+	assert.Nil(err)
+
+	expected := &url.Values{}
+	expected.Set("auth", "key-1,key-2")
+	expected.Set("channel", "ch1,ch2")
+	expected.Set("channel-group", "cg")
+	expected.Set("target-uuid", "uuid")
+	expected.Set("q1", "v1")
+	h.AssertQueriesEqual(t, expected, query, []string{"pnsdk", "uuid", "timestamp"}, []string{})
+
+	assert.Equal(WPSAccessManagerAudit, o.opts.operationType())
+	assert.Equal("Audit", o.opts.operationType().String())
+}
+
+func TestAuditRequestValidation(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := NewWebPubSub(NewDemoConfig())
+	pn.Config.SecretKey = ""
+
+	_, _, err := pn.Audit().Channels([]string{"ch"}).Execute()
+	assert.Contains(err.Error(), "Missing Secret Key")
+}
+
+func TestNewAuditResponse(t *testing.T) {
+	assert := assert.New(t)
+	jsonBytes := []byte(`{"message":"Success","payload":{"level":"channel-group+auth","subscribe_key":"sub-c","ttl":1440,"channels":{"ch1":{"r":1,"w":0,"auths":{"key-1":{"r":1,"w":1,"m":0,"d":1,"ttl":60},"key-2":{"r":1,"w":0,"m":0,"d":0}}}},"channel-groups":{"cg1":{"auths":{"key-1":{"r":1,"m":1,"ttl":30}}}},"uuids":{"alice":{"auths":{"key-1":{"g":1,"u":1,"d":0}}}}},"service":"Access Manager","status":200}`)
+
+	resp, _, err := newAuditResponse(jsonBytes, StatusResponse{})
+	assert.Nil(err)
+	assert.Equal("channel-group+auth", resp.Level)
+	assert.Equal("sub-c", resp.SubscribeKey)
+	assert.Equal(1440, resp.TTL)
+	assert.True(resp.Channels["ch1"].AuthKeys["key-1"].WriteEnabled)
+	assert.Equal(60, resp.Channels["ch1"].AuthKeys["key-1"].TTL)
+
+	permissions := resp.Permissions("key-1")
+	ch1 := permissions.Channels["ch1"]
+	assert.Equal(ChannelPermissions{Read: true, Write: true, Delete: true}, ch1.Permissions)
+	assert.Equal(int64(WPSRead|WPSWrite|WPSDelete), ch1.BitMaskPerms)
+	assert.Equal("key-1", ch1.Token)
+	assert.Equal(60, ch1.TTL)
+	cg1 := permissions.Groups["cg1"]
+	assert.Equal(GroupPermissions{Read: true, Manage: true}, cg1.Permissions)
+	assert.Equal(30, cg1.TTL)
+	alice := permissions.UUIDs["alice"]
+	assert.Equal(UUIDPermissions{Get: true, Update: true}, alice.Permissions)
+	assert.Equal(1440, alice.TTL)
+
+	permissions = resp.Permissions("key-2")
+	assert.Equal(ChannelPermissions{Read: true}, permissions.Channels["ch1"].Permissions)
+	assert.Equal(1440, permissions.Channels["ch1"].TTL)
+	assert.Empty(permissions.Groups)
+	assert.Empty(permissions.UUIDs)
+
+	permissions = resp.Permissions("")
+	assert.Equal(ChannelPermissions{Read: true}, permissions.Channels["ch1"].Permissions)
+	assert.Len(permissions.Groups, 1)
+}
+
+func TestNewAuditResponseErrorUnmarshalling(t *testing.T) {
+	assert := assert.New(t)
+
+	_, _, err := newAuditResponse([]byte("{not json"), StatusResponse{})
+	assert.Contains(err.Error(), "webpubsub/parsing")
+}
@@ -0,0 +1,157 @@
+package webpubsub
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// catchUpMessage is a message missed while the network was down.
+type catchUpMessage struct {
+	channel   string
+	timetoken int64
+	item      FetchResponseItem
+}
+
+// catchUp fetches the messages published on the subscribed channels after the
+// last received timetoken and delivers them in timetoken order. It runs on
+// reconnection before the subscribe loop restarts, so the caught up messages
+// are delivered before the live ones.
+func (m *SubscriptionManager) catchUp() {
+	m.RLock()
+	since := m.timetoken
+	m.RUnlock()
+	if since <= 0 {
+		return
+	}
+
+	missed := m.fetchMissed(since, 0)
+
+	m.catchUpMutex.Lock()
+	m.catchUpTimetokens = make(map[string]bool, len(missed))
+	m.catchUpUntil = 0
+	for _, msg := range missed {
+		m.catchUpTimetokens[catchUpKey(msg.channel, msg.timetoken)] = true
+		m.catchUpUntil = msg.timetoken
+	}
+	m.catchUpMutex.Unlock()
+
+	m.webpubsub.Config.logger(WPSSubscribeSubsystem).Info("catch up", "messages", len(missed), "since", since)
+	for _, msg := range missed {
+		m.announceCaughtUp(msg.channel, msg.toWPSMessage(m.webpubsub.Config))
+	}
+}
+
+// recoverGap fetches the messages the server skipped when a subscribe
+// response exceeded MessageQueueOverflowCount: the ones published after
+// since, the timetoken of the request, and before the first message of the
+// response. They are queued ahead of the messages of the response, so the
+// listeners receive them in order.
+func (m *SubscriptionManager) recoverGap(since int64, messages []subscribeMessage) {
+	var until int64
+	for _, message := range messages {
+		timetoken, err := strconv.ParseInt(message.PublishMetaData.PublishTimetoken, 10, 64)
+		if err == nil && (until == 0 || timetoken < until) {
+			until = timetoken
+		}
+	}
+	if since <= 0 || until <= since {
+		return
+	}
+
+	missed := m.fetchMissed(since, until)
+	m.webpubsub.Config.logger(WPSSubscribeSubsystem).Info("gap recovery", "messages", len(missed), "since", since, "until", until)
+	for _, msg := range missed {
+		m.enqueueMessage(subscribeMessage{
+			Channel:   msg.channel,
+			recovered: msg.toWPSMessage(m.webpubsub.Config),
+		})
+	}
+}
+
+// fetchMissed fetches the messages of the subscribed channels published after
+// since and before until, 0 for no upper bound, in timetoken order.
+func (m *SubscriptionManager) fetchMissed(since, until int64) []catchUpMessage {
+	var missed []catchUpMessage
+	for _, channel := range m.stateManager.prepareChannelList(false) {
+		if strings.HasSuffix(channel, ".*") {
+			continue
+		}
+		builder := m.webpubsub.Fetch().Channels([]string{channel}).End(since)
+		if until > 0 {
+			builder.Start(until)
+		}
+		err := builder.FetchAll(func(ch string, item FetchResponseItem) bool {
+			timetoken, err := strconv.ParseInt(item.Timetoken, 10, 64)
+			if err != nil || timetoken <= since || (until > 0 && timetoken >= until) {
+				return true
+			}
+			if item.MessageType != 0 {
+				// only the messages are caught up, file events need the
+				// file URL which is built by the subscribe flow.
+				return true
+			}
+			missed = append(missed, catchUpMessage{
+				channel:   ch,
+				timetoken: timetoken,
+				item:      item,
+			})
+			return true
+		})
+		if err != nil {
+			pnStatus := &WPSStatus{
+				Category:         WPSUnknownCategory,
+				Operation:        WPSFetchMessagesOperation,
+				ErrorData:        fmt.Errorf("catch up error: %s", err),
+				Error:            true,
+				AffectedChannels: []string{channel},
+			}
+			m.webpubsub.Config.logger(WPSSubscribeSubsystem).Warn("catch up failed", "channel", channel, "error", err)
+			m.listenerManager.announceStatus(pnStatus)
+		}
+	}
+
+	sort.SliceStable(missed, func(i, j int) bool {
+		return missed[i].timetoken < missed[j].timetoken
+	})
+	return missed
+}
+
+func (msg catchUpMessage) toWPSMessage(config *Config) *WPSMessage {
+	pnMessageResult := createWPSMessageResult(msg.item.Message, "", msg.channel, msg.channel, "", msg.item.UUID, msg.item.Meta, msg.timetoken)
+	pnMessageResult.DecryptKeyIndex = msg.item.DecryptKeyIndex
+	pnMessageResult.rawMessage = msg.item.rawMessage
+	if config.PropagateTraceContext {
+		pnMessageResult.TraceParent = traceParentFromMeta(msg.item.Meta)
+	}
+	return pnMessageResult
+}
+
+func (m *SubscriptionManager) announceCaughtUp(channel string, pnMessageResult *WPSMessage) {
+	m.listenerManager.announceMessage(pnMessageResult)
+	for _, l := range m.subscriptionListeners(channel, "", false) {
+		l.announceMessage(pnMessageResult)
+	}
+}
+
+// caughtUp returns true when the live message was already delivered by the
+// catch up. The delivered timetokens are forgotten once the live stream goes
+// past them.
+func (m *SubscriptionManager) caughtUp(channel string, timetoken int64) bool {
+	m.catchUpMutex.Lock()
+	defer m.catchUpMutex.Unlock()
+
+	if len(m.catchUpTimetokens) == 0 {
+		return false
+	}
+	if timetoken > m.catchUpUntil {
+		m.catchUpTimetokens = nil
+		return false
+	}
+	return m.catchUpTimetokens[catchUpKey(channel, timetoken)]
+}
+
+func catchUpKey(channel string, timetoken int64) string {
+	return channel + "/" + strconv.FormatInt(timetoken, 10)
+}
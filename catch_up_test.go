@@ -0,0 +1,156 @@
+package webpubsub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/webpubsub/sdk-go/v7/tests/stubs"
+)
+
+func catchUpStub(channel, body string) *stubs.Stub {
+	return &stubs.Stub{
+		Method:             "GET",
+		Path:               "/v3/history/sub-key/demo/channel/" + channel,
+		Query:              "end=100&max=100&reverse=false",
+		ResponseBody:       `{"status": 200, "error": false, "error_message": "", "channels": {"` + channel + `":` + body + `}}`,
+		IgnoreQueryKeys:    fetchAllIgnoreQueryKeys,
+		ResponseStatusCode: 200,
+	}
+}
+
+func nextMessage(t *testing.T, listener *recordingEventListener) *WPSMessage {
+	select {
+	case message := <-listener.messages:
+		return message
+	case <-time.After(5 * time.Second):
+		t.Fatal("message not received")
+		return nil
+	}
+}
+
+// subscribeForCatchUp subscribes pn to a and b from the timetoken 100 with
+// RestoreOnReconnect and returns the listener of the events.
+func subscribeForCatchUp(pn *WebPubSub) *recordingEventListener {
+	pn.Config.RestoreOnReconnect = true
+	listener := newRecordingEventListener()
+	pn.AddListener(listener)
+
+	m := pn.subscriptionManager
+	m.stateManager.adaptSubscribeOperation(&SubscribeOperation{
+		Channels: []string{"a", "b"},
+	})
+	m.Lock()
+	m.timetoken = 100
+	m.Unlock()
+	return listener
+}
+
+func TestCatchUpDeliversInTimetokenOrder(t *testing.T) {
+	assert := assert.New(t)
+
+	interceptor := stubs.NewInterceptor()
+	interceptor.AddStub(catchUpStub("a", `[{"message":"a2","timetoken":"130","uuid":"u1"},{"message":"a1","timetoken":"110","uuid":"u1"}]`))
+	interceptor.AddStub(catchUpStub("b", `[{"message":"b1","timetoken":"120","uuid":"u2"},{"message":"old","timetoken":"100"}]`))
+	pn := newTestWebPubSub(interceptor.Transport)
+	listener := subscribeForCatchUp(pn)
+
+	pn.subscriptionManager.catchUp()
+
+	var messages []interface{}
+	var channels []string
+	for i := 0; i < 3; i++ {
+		message := nextMessage(t, listener)
+		messages = append(messages, message.Message)
+		channels = append(channels, message.Channel)
+	}
+	assert.Equal([]interface{}{"a1", "b1", "a2"}, messages)
+	assert.Equal([]string{"a", "b", "a"}, channels)
+	assert.Empty(listener.messages)
+}
+
+func TestCatchUpSkipsLiveDuplicates(t *testing.T) {
+	assert := assert.New(t)
+
+	interceptor := stubs.NewInterceptor()
+	interceptor.AddStub(catchUpStub("a", `[{"message":"a1","timetoken":"110"}]`))
+	interceptor.AddStub(catchUpStub("b", `[]`))
+	pn := newTestWebPubSub(interceptor.Transport)
+	listener := subscribeForCatchUp(pn)
+	m := pn.subscriptionManager
+
+	m.catchUp()
+	assert.Equal("a1", nextMessage(t, listener).Message)
+
+	assert.True(m.caughtUp("a", 110))
+	assert.False(m.caughtUp("b", 110))
+
+	processNonPresencePayload(m, subscribeMessage{Channel: "a", Payload: "a1"}, "a", "", publishMetadata{PublishTimetoken: "110"})
+	assert.Empty(listener.messages)
+
+	processNonPresencePayload(m, subscribeMessage{Channel: "a", Payload: "live"}, "a", "", publishMetadata{PublishTimetoken: "140"})
+	assert.Equal("live", nextMessage(t, listener).Message)
+	assert.False(m.caughtUp("a", 110))
+}
+
+func TestCatchUpWithoutTimetoken(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := newTestWebPubSub(stubs.NewInterceptor().Transport)
+	listener := subscribeForCatchUp(pn)
+	m := pn.subscriptionManager
+	m.Lock()
+	m.timetoken = 0
+	m.Unlock()
+
+	m.catchUp()
+	assert.Empty(listener.messages)
+	assert.Empty(listener.statuses)
+}
+
+func gapStub(channel, body string) *stubs.Stub {
+	stub := catchUpStub(channel, body)
+	stub.Query = "start=150&end=100&max=100&reverse=false"
+	return stub
+}
+
+func TestRecoverGapQueuesSkippedMessagesInOrder(t *testing.T) {
+	assert := assert.New(t)
+
+	interceptor := stubs.NewInterceptor()
+	interceptor.AddStub(gapStub("a", `[{"message":"a2","timetoken":"140"},{"message":"a1","timetoken":"110"}]`))
+	interceptor.AddStub(gapStub("b", `[{"message":"b1","timetoken":"120"},{"message":"old","timetoken":"100"}]`))
+	pn := newTestWebPubSub(interceptor.Transport)
+	listener := subscribeForCatchUp(pn)
+	m := pn.subscriptionManager
+
+	live := []subscribeMessage{
+		{Channel: "a", Payload: "live2", PublishMetaData: publishMetadata{PublishTimetoken: "160"}},
+		{Channel: "b", Payload: "live1", PublishMetaData: publishMetadata{PublishTimetoken: "150"}},
+	}
+	m.recoverGap(100, live)
+	assert.Equal(3, len(m.messages))
+	for _, message := range live {
+		m.enqueueMessage(message)
+	}
+
+	go subscribeMessageWorker(m)
+	defer pn.Destroy()
+
+	var messages []interface{}
+	for i := 0; i < 5; i++ {
+		messages = append(messages, nextMessage(t, listener).Message)
+	}
+	assert.Equal([]interface{}{"a1", "b1", "a2", "live2", "live1"}, messages)
+}
+
+func TestRecoverGapWithoutPreviousTimetoken(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := newTestWebPubSub(stubs.NewInterceptor().Transport)
+	subscribeForCatchUp(pn)
+	m := pn.subscriptionManager
+
+	m.recoverGap(0, []subscribeMessage{{Channel: "a", PublishMetaData: publishMetadata{PublishTimetoken: "160"}}})
+	assert.Equal(0, len(m.messages))
+}
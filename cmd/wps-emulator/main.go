@@ -0,0 +1,37 @@
+// Command wps-emulator serves the WebPubSub REST API from memory for offline
+// development and CI. Point Config.Origin at its address and set
+// Config.Secure to false.
+//
+//	wps-emulator -addr localhost:8090
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/webpubsub/sdk-go/v7/emulator"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:8090", "address to listen on")
+	longPoll := flag.Duration("long-poll-timeout", emulator.DefaultLongPollTimeout, "time a subscribe request waits for messages")
+	verbose := flag.Bool("v", false, "log every request")
+	flag.Parse()
+
+	e := emulator.New()
+	e.LongPollTimeout = *longPoll
+
+	var handler http.Handler = e
+	if *verbose {
+		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			e.ServeHTTP(w, r)
+			log.Printf("%s %s %s", r.Method, r.URL.Path, time.Since(start))
+		})
+	}
+
+	log.Printf("wps-emulator listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, handler))
+}
@@ -0,0 +1,91 @@
+// Command wps-policy compares an access policy file with the grants in place
+// and applies it. plan prints the tokens to grant and the changes of the
+// PAM v2 grants, apply issues them and prints the tokens granted.
+//
+//	wps-policy plan -f access.yaml
+//	wps-policy apply -f access.yaml -chunk 100
+//
+// The keys are read from the WPS_PUBLISH_KEY, WPS_SUBSCRIBE_KEY and
+// WPS_SECRET_KEY environment variables unless set with flags.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	webpubsub "github.com/webpubsub/sdk-go/v7"
+	"github.com/webpubsub/sdk-go/v7/policy"
+)
+
+func usage() int {
+	fmt.Fprintf(os.Stderr, "usage: wps-policy plan|apply -f policy.yaml [flags]\n")
+	return 2
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+// run runs the command of args and returns the exit code.
+func run(args []string) int {
+	if len(args) < 1 || (args[0] != "plan" && args[0] != "apply") {
+		return usage()
+	}
+	command := args[0]
+
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	file := flags.String("f", "", "policy file, YAML or JSON")
+	publishKey := flags.String("publish-key", os.Getenv("WPS_PUBLISH_KEY"), "publish key")
+	subscribeKey := flags.String("subscribe-key", os.Getenv("WPS_SUBSCRIBE_KEY"), "subscribe key")
+	secretKey := flags.String("secret-key", os.Getenv("WPS_SECRET_KEY"), "secret key")
+	origin := flags.String("origin", "", "custom origin")
+	insecure := flags.Bool("insecure", false, "don't use TLS")
+	chunkSize := flags.Int("chunk", policy.DefaultChunkSize, "resources and auth keys per request")
+	flags.Parse(args[1:])
+	if *file == "" {
+		return usage()
+	}
+
+	p, err := policy.Load(*file)
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+
+	config := webpubsub.NewConfig("wps-policy")
+	config.PublishKey = *publishKey
+	config.SubscribeKey = *subscribeKey
+	config.SecretKey = *secretKey
+	if *origin != "" {
+		config.Origin = *origin
+	}
+	config.Secure = !*insecure
+	pn := webpubsub.NewWebPubSub(config)
+	defer pn.Destroy()
+
+	opts := policy.Options{ChunkSize: *chunkSize}
+	plan, err := p.Plan(pn, opts)
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+	fmt.Print(plan)
+	if command == "plan" {
+		return 0
+	}
+
+	result := plan.Apply(pn, opts)
+	for _, t := range plan.Tokens {
+		if token, ok := result.Tokens[t.Name]; ok {
+			fmt.Printf("token %s: %s\n", t.Name, token)
+		}
+	}
+	fmt.Printf("Applied: %d requests succeeded, %d failed.\n", result.Succeeded, result.Failed)
+	if err := result.Err(); err != nil {
+		log.Print(err)
+		return 1
+	}
+	return 0
+}
@@ -33,7 +33,9 @@ type Config struct {
 	MaximumLatencyDataAge         int                // Max time to store the latency data for telemetry
 	FilterExpression              string             // Feature to subscribe with a custom filter expression.
 	WPSReconnectionPolicy         ReconnectionPolicy // Reconnection policy selection
-	Log                           *log.Logger        // Logger instance
+	Log                           *log.Logger        // Logger instance, used at debug level when Logger is nil.
+	Logger                        Logger             // Leveled logger receiving the redacted log entries of the SDK.
+	SubsystemLoggers              SubsystemLoggers   // Loggers of the subsystems, overriding Logger.
 	SuppressLeaveEvents           bool               // When true the SDK doesn't send out the leave requests.
 	DisableWPSOtherProcessing     bool               // WPSOther processing looks for pn_other in the JSON on the recevied message
 	UseHTTP2                      bool               // HTTP2 Flag
@@ -44,6 +46,24 @@ type Config struct {
 	StoreTokensOnGrant            bool               // Will store grant v3 tokens in token manager for further use.
 	FileMessagePublishRetryLimit  int                // The number of tries made in case of Publish File Message failure.
 	UseRandomInitializationVector bool               // When true the IV will be random for all requests and not just file upload. When false the IV will be hardcoded for all requests except File Upload
+	ListenerQueueSize             int                // Number of events buffered for each EventListener, and of pending deliveries to the channel based Listeners, before the OverflowPolicy applies.
+	CryptoModule                  CryptoModule       // CryptoModule used to encrypt and decrypt the messages and files. When nil a legacy module is created from the CipherKey.
+	DecryptCipherKeys             []string           // Previous cipher keys, tried in order when the decryption with the current key fails.
+	PublishQueueStore             PublishQueueStore  // Store of the offline publish queue, read when the client is built. When nil the queue is saved in PublishQueueFile.
+	PublishQueueFile              string             // File used to persist the offline publish queue when PublishQueueStore is nil, the queue is kept in memory only if it is empty. The messages are saved unencrypted.
+	PublishQueueMaxAttempts       int                // Number of failed deliveries after which a queued publish is abandoned, 0 retries until delivered.
+	RetryPolicy                   *RetryPolicy       // Retry policy of the non-subscribe requests, nil disables the retries. The publishes are retried only with RetryPolicy.RetryPublish.
+	Tracer                        Tracer             // Creates a span for each request.
+	PropagateTraceContext         bool               // Inject the traceparent of the request span in the meta of the published messages (signals have no meta and carry none) and extract it in WPSMessage.TraceParent.
+	Metrics                       Metrics            // Receives the measurements of the requests, the subscribe loop and the queues.
+	RestoreOnReconnect            bool               // On reconnection fetch the messages missed since the last received timetoken and deliver them before the live messages.
+	Reconnector                   Reconnector        // Delays of the reconnection attempts and of the heartbeat retries. When nil it is derived from WPSReconnectionPolicy and MaximumReconnectionRetries.
+	ReconnectHook                 ReconnectHook      // Called before each reconnection attempt to change its delay or veto it.
+	MessageQueueSize              int                // Number of received messages buffered before their processing, before the OverflowPolicy applies.
+	OverflowPolicy                OverflowPolicy     // What happens when the message queue or a listener queue is full, WPSOverflowBlock by default. The status events are never dropped.
+	RecoverMessageCountExceeded   bool               // When a subscribe response exceeds MessageQueueOverflowCount, fetch the messages skipped by the server and deliver them before the ones of the response.
+	TokenProvider                 TokenProvider      // Called to replace a PAMv3 token before it expires or when a request is rejected with a 403, the request is retried with the new token.
+	PermissionPreflight           bool               // Check the requests against the grants of their PAMv3 token before sending them, the denied ones fail with a pnerr.PermissionError instead of a 403.
 }
 
 // NewDemoConfig initiates the config with demo keys, for tests only.
@@ -80,6 +100,8 @@ func NewConfig(uuid string) *Config {
 		StoreTokensOnGrant:            true,
 		FileMessagePublishRetryLimit:  5,
 		UseRandomInitializationVector: true,
+		ListenerQueueSize:             defaultListenerQueueSize,
+		MessageQueueSize:              defaultMessageQueueSize,
 	}
 
 	return &c
@@ -87,9 +109,7 @@ func NewConfig(uuid string) *Config {
 
 func (c *Config) checkMinTimeout(timeout int) int {
 	if timeout < minTimeout {
-		if c.Log != nil {
-			c.Log.Println(fmt.Sprintf("PresenceTimeout value less than the min recommended value of %[1]d, setting value to %[1]d %d", minTimeout, timeout))
-		}
+		c.logger(WPSGeneralSubsystem).Warn(fmt.Sprintf("PresenceTimeout value less than the min recommended value of %[1]d, setting value to %[1]d", minTimeout), "presenceTimeout", timeout)
 		timeout = minTimeout
 	}
 	return timeout
@@ -116,3 +136,37 @@ func (c *Config) SetPresenceTimeout(timeout int) *Config {
 	timeout = c.checkMinTimeout(timeout)
 	return c.SetPresenceTimeoutWithCustomInterval(timeout, (timeout/2)-1)
 }
+
+// cryptoModule returns the CryptoModule to use, nil when the communications
+// are not encrypted.
+func (c *Config) cryptoModule() CryptoModule {
+	if c.CryptoModule != nil {
+		return c.CryptoModule
+	}
+	if c.CipherKey != "" {
+		return NewLegacyCryptoModule(c.CipherKey, c.UseRandomInitializationVector)
+	}
+	return nil
+}
+
+// decryptKeyring returns the modules to try in order to decrypt the data:
+// the current one followed by the ones of the DecryptCipherKeys.
+func (c *Config) decryptKeyring() []decryptionKey {
+	var keyring []decryptionKey
+	if module := c.cryptoModule(); module != nil {
+		keyring = append(keyring, decryptionKey{
+			index:  0,
+			module: module,
+		})
+	}
+	for i, cipherKey := range c.DecryptCipherKeys {
+		if cipherKey == "" {
+			continue
+		}
+		keyring = append(keyring, decryptionKey{
+			index:  i + 1,
+			module: NewLegacyCryptoModule(cipherKey, c.UseRandomInitializationVector),
+		})
+	}
+	return keyring
+}
@@ -0,0 +1,110 @@
+package webpubsub
+
+import (
+	"time"
+)
+
+// ConnectionStateChange describes a transition of the connection state of the
+// subscribe loop. It is attached to the single status event announcing the
+// transition, in WPSStatus.ConnectionStateChange.
+type ConnectionStateChange struct {
+	Previous ConnectionState
+	Current  ConnectionState
+	Category StatusCategory // Category of the status event announcing the transition.
+	Cause    error          // Error which caused the transition, nil for the transitions requested by the app.
+	At       time.Time      // Time of the transition.
+	Duration time.Duration  // Time spent in the previous state.
+}
+
+// connectionStateListener calls handler for the status events announcing a
+// transition of the connection state.
+type connectionStateListener struct {
+	BaseEventListener
+	handler func(change *ConnectionStateChange)
+}
+
+func (l *connectionStateListener) OnStatus(status *WPSStatus) {
+	if status.ConnectionStateChange != nil {
+		l.handler(status.ConnectionStateChange)
+	}
+}
+
+func (m *SubscriptionManager) getConnectionState() ConnectionState {
+	m.connectionStateMutex.RLock()
+	defer m.connectionStateMutex.RUnlock()
+
+	return m.connectionState
+}
+
+// transition moves the connection to the state to and announces status, which
+// carries the category of the transition. Nothing happens when the connection
+// is already in the state to, or when from is given and the current state
+// isn't one of them, so every transition is announced by exactly one status.
+func (m *SubscriptionManager) transition(to ConnectionState, status *WPSStatus, from ...ConnectionState) bool {
+	m.transitionMutex.Lock()
+
+	previous := m.getConnectionState()
+	if previous == to || (len(from) > 0 && !containsConnectionState(from, previous)) {
+		m.transitionMutex.Unlock()
+		return false
+	}
+	if to == WPSConnectedState && previous == WPSReconnectingState {
+		status.Category = WPSReconnectedCategory
+	}
+
+	now := time.Now()
+	status.ConnectionStateChange = &ConnectionStateChange{
+		Previous: previous,
+		Current:  to,
+		Category: status.Category,
+		Cause:    status.ErrorData,
+		At:       now,
+		Duration: now.Sub(m.connectionStateSince),
+	}
+	m.connectionStateMutex.Lock()
+	m.connectionState = to
+	m.connectionStateSince = now
+	m.connectionStateMutex.Unlock()
+
+	m.webpubsub.Config.logger(WPSSubscribeSubsystem).Info("connection state changed",
+		"previous", previous.String(), "current", to.String(), "category", status.Category.String())
+
+	// The transitions are announced in order by the caller which finds no
+	// announcement running, so a slow listener doesn't block the transitions.
+	m.pendingTransitions = append(m.pendingTransitions, status)
+	announce := !m.announcingTransitions
+	m.announcingTransitions = true
+	m.transitionMutex.Unlock()
+
+	if announce {
+		m.announceTransitions()
+	}
+	return true
+}
+
+// announceTransitions announces the pending transitions until there is none
+// left.
+func (m *SubscriptionManager) announceTransitions() {
+	for {
+		m.transitionMutex.Lock()
+		if len(m.pendingTransitions) == 0 {
+			m.announcingTransitions = false
+			m.transitionMutex.Unlock()
+			return
+		}
+		status := m.pendingTransitions[0]
+		m.pendingTransitions = m.pendingTransitions[1:]
+		m.transitionMutex.Unlock()
+
+		m.listenerManager.announceStatus(status)
+	}
+}
+
+func containsConnectionState(states []ConnectionState, state ConnectionState) bool {
+	for _, s := range states {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,218 @@
+package webpubsub
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// longPollTransport answers the first subscribe request with status and body
+// and keeps the following ones open until they are cancelled.
+type longPollTransport struct {
+	sync.Mutex
+	calls  int
+	status int
+	body   string
+}
+
+func (t *longPollTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.Lock()
+	t.calls++
+	first := t.calls == 1
+	t.Unlock()
+	if !first {
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	}
+	return &http.Response{
+		StatusCode: t.status,
+		Body:       ioutil.NopCloser(bytes.NewBufferString(t.body)),
+		Request:    req,
+	}, nil
+}
+
+// nextStateChange skips the statuses which don't report a transition.
+func nextStateChange(t *testing.T, listener *recordingEventListener) *WPSStatus {
+	for {
+		status := nextStatus(t, listener)
+		if status == nil || status.ConnectionStateChange != nil {
+			return status
+		}
+	}
+}
+
+func TestConnectionStateTransition(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := newTestWebPubSub(&capturingTransport{status: 200, body: `{"status":200}`})
+	pn.Config.SuppressLeaveEvents = true
+	pn.SetSubscribeClient(&http.Client{Transport: &longPollTransport{}})
+	listener := newRecordingEventListener()
+	pn.AddListener(listener)
+	defer pn.Destroy()
+	m := pn.subscriptionManager
+	assert.Equal(WPSDisconnectedState, pn.ConnectionState())
+
+	assert.True(m.transition(WPSConnectingState, &WPSStatus{Category: WPSConnectingCategory}))
+	assert.False(m.transition(WPSConnectingState, &WPSStatus{Category: WPSConnectingCategory}))
+	assert.False(m.transition(WPSDisconnectedState, &WPSStatus{Category: WPSDisconnectedCategory}, WPSConnectedState))
+	assert.Equal(WPSConnectingState, pn.ConnectionState())
+
+	cause := errors.New("network is unreachable")
+	assert.True(m.transition(WPSReconnectingState, &WPSStatus{Category: WPSDisconnectedCategory, ErrorData: cause}))
+	assert.True(m.transition(WPSConnectedState, &WPSStatus{Category: WPSConnectedCategory}))
+
+	status := nextStatus(t, listener)
+	assert.Equal(WPSConnectingCategory, status.Category)
+	assert.Equal(WPSDisconnectedState, status.ConnectionStateChange.Previous)
+	assert.Equal(WPSConnectingState, status.ConnectionStateChange.Current)
+
+	status = nextStatus(t, listener)
+	assert.Equal(WPSDisconnectedCategory, status.Category)
+	assert.Equal(cause, status.ConnectionStateChange.Cause)
+
+	status = nextStatus(t, listener)
+	change := status.ConnectionStateChange
+	assert.Equal(WPSReconnectedCategory, status.Category)
+	assert.Equal(WPSReconnectedCategory, change.Category)
+	assert.Equal(WPSReconnectingState, change.Previous)
+	assert.Equal(WPSConnectedState, change.Current)
+	assert.False(change.At.IsZero())
+	assert.True(change.Duration >= 0)
+
+	select {
+	case status := <-listener.statuses:
+		t.Fatalf("unexpected status %s", status.Category)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// blockingStatusListener holds the status events until release is closed.
+type blockingStatusListener struct {
+	BaseEventListener
+	release  chan struct{}
+	statuses chan *WPSStatus
+}
+
+func (l *blockingStatusListener) OnStatus(status *WPSStatus) {
+	<-l.release
+	l.statuses <- status
+}
+
+func TestConnectionStateSlowListenerDoesNotBlockTransitions(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := NewWebPubSub(NewDemoConfig())
+	defer pn.Destroy()
+	pn.Config.OverflowPolicy = WPSOverflowBlock
+	pn.Config.ListenerQueueSize = 1
+	listener := &blockingStatusListener{release: make(chan struct{}), statuses: make(chan *WPSStatus, 10)}
+	pn.AddListener(listener)
+	m := pn.subscriptionManager
+
+	// The third announcement blocks: the listener holds the first one and the
+	// second one fills its queue.
+	go func() {
+		m.transition(WPSConnectingState, &WPSStatus{Category: WPSConnectingCategory})
+		m.transition(WPSReconnectingState, &WPSStatus{Category: WPSDisconnectedCategory})
+		m.transition(WPSConnectedState, &WPSStatus{Category: WPSConnectedCategory})
+	}()
+	assert.Eventually(func() bool {
+		return pn.ConnectionState() == WPSConnectedState
+	}, 5*time.Second, 10*time.Millisecond)
+
+	done := make(chan bool)
+	go func() {
+		done <- m.transition(WPSDisconnectedState, &WPSStatus{Category: WPSDisconnectedCategory})
+	}()
+	select {
+	case ok := <-done:
+		assert.True(ok)
+	case <-time.After(5 * time.Second):
+		t.Fatal("transition blocked by the listener")
+	}
+	assert.Equal(WPSDisconnectedState, pn.ConnectionState())
+
+	close(listener.release)
+	for _, expected := range []ConnectionState{WPSConnectingState, WPSReconnectingState, WPSConnectedState, WPSDisconnectedState} {
+		select {
+		case status := <-listener.statuses:
+			assert.Equal(expected, status.ConnectionStateChange.Current)
+		case <-time.After(5 * time.Second):
+			t.Fatal("state change not received")
+		}
+	}
+}
+
+func TestConnectionStateSubscribeAndUnsubscribe(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := newTestWebPubSub(&capturingTransport{status: 200, body: `{"status":200}`})
+	pn.Config.SuppressLeaveEvents = true
+	pn.SetSubscribeClient(&http.Client{Transport: &longPollTransport{status: 200, body: `{"t":{"t":"15","r":1},"m":[]}`}})
+	listener := newRecordingEventListener()
+	pn.AddListener(listener)
+	defer pn.Destroy()
+
+	changes := make(chan *ConnectionStateChange, 10)
+	pn.AddConnectionStateListener(func(change *ConnectionStateChange) {
+		changes <- change
+	})
+
+	pn.Subscribe().Channels([]string{"ch"}).Execute()
+
+	status := nextStateChange(t, listener)
+	assert.Equal(WPSConnectingCategory, status.Category)
+	assert.Equal([]string{"ch"}, status.AffectedChannels)
+	status = nextStateChange(t, listener)
+	assert.Equal(WPSConnectedCategory, status.Category)
+	assert.Equal(WPSConnectedState, pn.ConnectionState())
+
+	pn.UnsubscribeAll()
+
+	status = nextStateChange(t, listener)
+	assert.Equal(WPSDisconnectedCategory, status.Category)
+	assert.Equal(WPSUnsubscribeOperation, status.Operation)
+	assert.Equal(WPSDisconnectedState, pn.ConnectionState())
+
+	for _, expected := range []ConnectionState{WPSConnectingState, WPSConnectedState, WPSDisconnectedState} {
+		select {
+		case change := <-changes:
+			assert.Equal(expected, change.Current)
+		case <-time.After(5 * time.Second):
+			t.Fatal("state change not received")
+		}
+	}
+}
+
+func TestConnectionStateAccessDenied(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := newTestWebPubSub(&capturingTransport{status: 200, body: `{"status":200}`})
+	pn.Config.SuppressLeaveEvents = true
+	pn.SetSubscribeClient(&http.Client{Transport: &longPollTransport{status: 403, body: `{"status":403,"error":true,"message":"Forbidden"}`}})
+	listener := newRecordingEventListener()
+	pn.AddListener(listener)
+	defer pn.Destroy()
+
+	pn.Subscribe().Channels([]string{"ch"}).Execute()
+
+	status := nextStateChange(t, listener)
+	assert.Equal(WPSConnectingCategory, status.Category)
+	status = nextStateChange(t, listener)
+	assert.Equal(WPSAccessDeniedCategory, status.Category)
+	assert.True(status.Error)
+	assert.Equal(WPSConnectingState, status.ConnectionStateChange.Previous)
+	assert.Equal(WPSDisconnectedWithErrorState, status.ConnectionStateChange.Current)
+	assert.NotNil(status.ConnectionStateChange.Cause)
+
+	// The error state is kept when the channels are released.
+	assert.Empty(pn.GetSubscribedChannels())
+	assert.Equal(WPSDisconnectedWithErrorState, pn.ConnectionState())
+}
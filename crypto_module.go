@@ -0,0 +1,381 @@
+package webpubsub
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/webpubsub/sdk-go/v7/pnerr"
+	"github.com/webpubsub/sdk-go/v7/utils"
+)
+
+const (
+	cryptoHeaderSentinel   = "WPED"
+	cryptoHeaderVersion    = 1
+	cryptoHeaderIDLength   = 4
+	cryptoHeaderMaxVersion = cryptoHeaderVersion
+
+	// LegacyCryptorID is the id of the AES-CBC cryptor used before the
+	// versioned header was introduced. Its data is written without header.
+	LegacyCryptorID = "0000"
+	// AesGcmCryptorID is the id of the AES-256-GCM cryptor.
+	AesGcmCryptorID = "AGCM"
+)
+
+// EncryptedData is the result of a Cryptor encryption: the metadata is
+// stored in the header and is needed by the cryptor to decrypt the data.
+type EncryptedData struct {
+	Metadata []byte
+	Data     []byte
+}
+
+// Cryptor implements a single encryption algorithm.
+type Cryptor interface {
+	// ID returns the 4 bytes identifier written in the header.
+	ID() string
+	Encrypt(data []byte) (*EncryptedData, error)
+	Decrypt(data *EncryptedData) ([]byte, error)
+}
+
+// CryptoModule encrypts and decrypts the messages and the files.
+type CryptoModule interface {
+	Encrypt(data []byte) ([]byte, error)
+	Decrypt(data []byte) ([]byte, error)
+	EncryptStream(stream io.Reader) (io.Reader, error)
+	DecryptStream(stream io.Reader) (io.Reader, error)
+}
+
+// NewCryptoModule creates a CryptoModule which encrypts with the encryptor
+// and decrypts the data of the encryptor and the decryptors. The cryptor used
+// to decrypt is chosen from the header of the data.
+func NewCryptoModule(encryptor Cryptor, decryptors []Cryptor) CryptoModule {
+	m := &cryptoModule{
+		encryptor:  encryptor,
+		decryptors: make(map[string]Cryptor),
+	}
+	for _, c := range append([]Cryptor{encryptor}, decryptors...) {
+		if _, ok := m.decryptors[c.ID()]; !ok {
+			m.decryptors[c.ID()] = c
+		}
+	}
+	return m
+}
+
+// NewLegacyCryptoModule creates a CryptoModule which encrypts with AES-CBC
+// as the previous versions did, and decrypts AES-GCM data too.
+func NewLegacyCryptoModule(cipherKey string, useRandomInitializationVector bool) CryptoModule {
+	return NewCryptoModule(NewLegacyCryptor(cipherKey, useRandomInitializationVector), []Cryptor{NewAesGcmCryptor(cipherKey)})
+}
+
+// NewAesGcmCryptoModule creates a CryptoModule which encrypts with AES-GCM
+// and decrypts the legacy AES-CBC data too.
+func NewAesGcmCryptoModule(cipherKey string, useRandomInitializationVector bool) CryptoModule {
+	return NewCryptoModule(NewAesGcmCryptor(cipherKey), []Cryptor{NewLegacyCryptor(cipherKey, useRandomInitializationVector)})
+}
+
+type cryptoModule struct {
+	encryptor  Cryptor
+	decryptors map[string]Cryptor
+}
+
+func (m *cryptoModule) Encrypt(data []byte) ([]byte, error) {
+	return encryptWithCryptor(m.encryptor, data)
+}
+
+func (m *cryptoModule) Decrypt(data []byte) ([]byte, error) {
+	return m.decrypt(data, false)
+}
+
+// EncryptStream reads the whole stream and returns the encrypted data.
+// Files always use a random IV, even with the legacy cryptor.
+func (m *cryptoModule) EncryptStream(stream io.Reader) (io.Reader, error) {
+	data, err := ioutil.ReadAll(stream)
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := encryptWithCryptor(fileCryptor(m.encryptor), data)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(encrypted), nil
+}
+
+// DecryptStream reads the whole stream and returns the decrypted data.
+func (m *cryptoModule) DecryptStream(stream io.Reader) (io.Reader, error) {
+	data, err := ioutil.ReadAll(stream)
+	if err != nil {
+		return nil, err
+	}
+	decrypted, err := m.decrypt(data, true)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(decrypted), nil
+}
+
+func (m *cryptoModule) decrypt(data []byte, file bool) ([]byte, error) {
+	id, metadata, payload, err := parseCryptoHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	cryptor, ok := m.decryptors[id]
+	if !ok {
+		return nil, fmt.Errorf("decrypt error: unknown cryptor %q", id)
+	}
+	if file {
+		cryptor = fileCryptor(cryptor)
+	}
+	return cryptor.Decrypt(&EncryptedData{
+		Metadata: metadata,
+		Data:     payload,
+	})
+}
+
+func encryptWithCryptor(cryptor Cryptor, data []byte) ([]byte, error) {
+	encrypted, err := cryptor.Encrypt(data)
+	if err != nil {
+		return nil, err
+	}
+	if cryptor.ID() == LegacyCryptorID {
+		return encrypted.Data, nil
+	}
+	header, err := buildCryptoHeader(cryptor.ID(), encrypted.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	return append(header, encrypted.Data...), nil
+}
+
+// buildCryptoHeader returns the header:
+// sentinel (4 bytes) | version (1 byte) | cryptor id (4 bytes) |
+// metadata length (1 byte, or 255 followed by 2 bytes big endian) | metadata
+func buildCryptoHeader(id string, metadata []byte) ([]byte, error) {
+	if len(id) != cryptoHeaderIDLength {
+		return nil, fmt.Errorf("encrypt error: invalid cryptor id %q", id)
+	}
+	if len(metadata) > 0xFFFF {
+		return nil, fmt.Errorf("encrypt error: metadata too long %d", len(metadata))
+	}
+	header := make([]byte, 0, len(cryptoHeaderSentinel)+1+cryptoHeaderIDLength+3+len(metadata))
+	header = append(header, cryptoHeaderSentinel...)
+	header = append(header, cryptoHeaderVersion)
+	header = append(header, id...)
+	if len(metadata) < 0xFF {
+		header = append(header, byte(len(metadata)))
+	} else {
+		header = append(header, 0xFF, 0, 0)
+		binary.BigEndian.PutUint16(header[len(header)-2:], uint16(len(metadata)))
+	}
+	return append(header, metadata...), nil
+}
+
+// parseCryptoHeader returns the cryptor id, the metadata and the encrypted
+// payload. Data without header belongs to the legacy cryptor.
+func parseCryptoHeader(data []byte) (string, []byte, []byte, error) {
+	if !bytes.HasPrefix(data, []byte(cryptoHeaderSentinel)) {
+		return LegacyCryptorID, nil, data, nil
+	}
+	rest := data[len(cryptoHeaderSentinel):]
+	if len(rest) < 1+cryptoHeaderIDLength+1 {
+		return "", nil, nil, errors.New("decrypt error: truncated header")
+	}
+	if version := rest[0]; version == 0 || version > cryptoHeaderMaxVersion {
+		return "", nil, nil, fmt.Errorf("decrypt error: unknown header version %d", version)
+	}
+	id := string(rest[1 : 1+cryptoHeaderIDLength])
+	rest = rest[1+cryptoHeaderIDLength:]
+	size := int(rest[0])
+	rest = rest[1:]
+	if size == 0xFF {
+		if len(rest) < 2 {
+			return "", nil, nil, errors.New("decrypt error: truncated header")
+		}
+		size = int(binary.BigEndian.Uint16(rest))
+		rest = rest[2:]
+	}
+	if len(rest) < size {
+		return "", nil, nil, errors.New("decrypt error: truncated header")
+	}
+	return id, rest[:size], rest[size:], nil
+}
+
+// fileCryptor returns the cryptor to use for files: the legacy cryptor
+// always uses a random IV for files.
+func fileCryptor(cryptor Cryptor) Cryptor {
+	if legacy, ok := cryptor.(*legacyCryptor); ok && !legacy.useRandomInitializationVector {
+		return &legacyCryptor{
+			cipherKey:                     legacy.cipherKey,
+			useRandomInitializationVector: true,
+		}
+	}
+	return cryptor
+}
+
+// NewLegacyCryptor creates the AES-CBC cryptor.
+func NewLegacyCryptor(cipherKey string, useRandomInitializationVector bool) Cryptor {
+	return &legacyCryptor{
+		cipherKey:                     cipherKey,
+		useRandomInitializationVector: useRandomInitializationVector,
+	}
+}
+
+type legacyCryptor struct {
+	cipherKey                     string
+	useRandomInitializationVector bool
+}
+
+func (c *legacyCryptor) ID() string {
+	return LegacyCryptorID
+}
+
+func (c *legacyCryptor) Encrypt(data []byte) (*EncryptedData, error) {
+	encrypted, err := utils.EncryptBytes(c.cipherKey, data, c.useRandomInitializationVector)
+	if err != nil {
+		return nil, err
+	}
+	return &EncryptedData{Data: encrypted}, nil
+}
+
+func (c *legacyCryptor) Decrypt(data *EncryptedData) ([]byte, error) {
+	return utils.DecryptBytes(c.cipherKey, data.Data, c.useRandomInitializationVector)
+}
+
+// NewAesGcmCryptor creates the AES-256-GCM cryptor, the nonce is stored in
+// the header metadata.
+func NewAesGcmCryptor(cipherKey string) Cryptor {
+	return &aesGcmCryptor{
+		cipherKey: cipherKey,
+	}
+}
+
+type aesGcmCryptor struct {
+	cipherKey string
+}
+
+func (c *aesGcmCryptor) ID() string {
+	return AesGcmCryptorID
+}
+
+func (c *aesGcmCryptor) Encrypt(data []byte) (*EncryptedData, error) {
+	nonce, encrypted, err := utils.EncryptBytesGCM(c.cipherKey, data)
+	if err != nil {
+		return nil, err
+	}
+	return &EncryptedData{
+		Metadata: nonce,
+		Data:     encrypted,
+	}, nil
+}
+
+func (c *aesGcmCryptor) Decrypt(data *EncryptedData) ([]byte, error) {
+	return utils.DecryptBytesGCM(c.cipherKey, data.Metadata, data.Data)
+}
+
+// encryptString encrypts the message and returns it base64 encoded. The
+// legacy cryptor encodes the non-ASCII chars first, like utils.EncryptString.
+func encryptString(module CryptoModule, message string) (string, error) {
+	if m, ok := module.(*cryptoModule); ok && m.encryptor.ID() == LegacyCryptorID {
+		message = utils.EncodeNonASCIIChars(message)
+	}
+	encrypted, err := module.Encrypt([]byte(message))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(encrypted), nil
+}
+
+// decryptString decrypts the base64 encoded message.
+func decryptString(module CryptoModule, message string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(message)
+	if err != nil {
+		return "", fmt.Errorf("decrypt error on decode: %s", err)
+	}
+	decrypted, err := module.Decrypt(data)
+	if err != nil {
+		return "", err
+	}
+	return string(decrypted), nil
+}
+
+// decryptionKey is an entry of the keyring used to decrypt. index is reported
+// in the results instead of the key: 0 for the current key, i for
+// DecryptCipherKeys[i-1].
+type decryptionKey struct {
+	index  int
+	module CryptoModule
+}
+
+// notDecrypted is the key index of the data which was not encrypted.
+const notDecrypted = -1
+
+// decryptStringWithKeyring tries the keys in order and returns the decrypted
+// message and the index of the key that succeeded. The error of the first key
+// is returned when no key succeeds.
+func decryptStringWithKeyring(keyring []decryptionKey, message string) (string, int, error) {
+	var firstErr error
+	for _, k := range keyring {
+		decrypted, err := decryptString(k.module, message)
+		if err == nil {
+			return decrypted, k.index, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return "", notDecrypted, firstErr
+}
+
+// decryptStreamWithKeyring works like decryptStringWithKeyring for files.
+// The whole stream is read in memory to try the keys in turn, the cryptors
+// need it anyway: AES-GCM authenticates the whole data before decrypting.
+func decryptStreamWithKeyring(keyring []decryptionKey, stream io.Reader) (io.Reader, int, error) {
+	data, err := ioutil.ReadAll(stream)
+	if err != nil {
+		return nil, notDecrypted, err
+	}
+	var firstErr error
+	for _, k := range keyring {
+		decrypted, err := k.module.DecryptStream(bytes.NewReader(data))
+		if err == nil {
+			return decrypted, k.index, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return nil, notDecrypted, firstErr
+}
+
+// serializeAndEncrypt serializes the message if needed and encrypts it.
+func serializeAndEncrypt(module CryptoModule, msg interface{}, serialize bool) (string, error) {
+	if serialize {
+		jsonSerialized, errJSONMarshal := json.Marshal(msg)
+		if errJSONMarshal != nil {
+			return "", errJSONMarshal
+		}
+		return encryptString(module, string(jsonSerialized))
+	}
+	if serializedMsg, ok := msg.(string); ok {
+		return encryptString(module, serializedMsg)
+	}
+	return "", pnerr.NewBuildRequestError("Message is not JSON serialized.")
+}
+
+// serializeEncryptAndSerialize works like serializeAndEncrypt and
+// serializes the encrypted string.
+func serializeEncryptAndSerialize(module CryptoModule, msg interface{}, serialize bool) (string, error) {
+	encrypted, err := serializeAndEncrypt(module, msg, serialize)
+	if err != nil {
+		return "", err
+	}
+	jsonSerialized, errJSONMarshal := json.Marshal(encrypted)
+	if errJSONMarshal != nil {
+		return "", errJSONMarshal
+	}
+	return string(jsonSerialized), nil
+}
@@ -0,0 +1,187 @@
+package webpubsub
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/webpubsub/sdk-go/v7/utils"
+)
+
+func TestLegacyCryptoModuleCompatible(t *testing.T) {
+	assert := assert.New(t)
+
+	module := NewLegacyCryptoModule("enigma", false)
+	enc, err := encryptString(module, "\"yay!\"")
+	assert.Nil(err)
+	assert.Equal(utils.EncryptString("enigma", "\"yay!\"", false), enc)
+
+	enc, err = encryptString(module, "\"héllo\"")
+	assert.Nil(err)
+	assert.Equal(utils.EncryptString("enigma", "\"héllo\"", false), enc)
+
+	dec, err := decryptString(NewLegacyCryptoModule("enigma", true), utils.EncryptString("enigma", "\"yay!\"", true))
+	assert.Nil(err)
+	assert.Equal("\"yay!\"", dec)
+}
+
+func TestAesGcmCryptoModuleRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+
+	module := NewAesGcmCryptoModule("enigma", true)
+	enc, err := module.Encrypt([]byte("hello"))
+	assert.Nil(err)
+	assert.True(bytes.HasPrefix(enc, []byte("WPED\x01AGCM\x0c")))
+
+	dec, err := module.Decrypt(enc)
+	assert.Nil(err)
+	assert.Equal("hello", string(dec))
+
+	_, err = NewAesGcmCryptoModule("other", true).Decrypt(enc)
+	assert.NotNil(err)
+}
+
+func TestCryptoModuleCrossDecrypt(t *testing.T) {
+	assert := assert.New(t)
+
+	legacy := NewLegacyCryptoModule("enigma", true)
+	gcm := NewAesGcmCryptoModule("enigma", true)
+
+	enc, _ := gcm.Encrypt([]byte("from gcm"))
+	dec, err := legacy.Decrypt(enc)
+	assert.Nil(err)
+	assert.Equal("from gcm", string(dec))
+
+	enc, _ = legacy.Encrypt([]byte("from legacy"))
+	dec, err = gcm.Decrypt(enc)
+	assert.Nil(err)
+	assert.Equal("from legacy", string(dec))
+}
+
+func TestCryptoModuleUnknownCryptor(t *testing.T) {
+	assert := assert.New(t)
+
+	header, err := buildCryptoHeader("ABCD", []byte{1, 2})
+	assert.Nil(err)
+	_, err = NewAesGcmCryptoModule("enigma", true).Decrypt(append(header, 3, 4))
+	assert.Contains(err.Error(), "unknown cryptor")
+
+	_, err = NewAesGcmCryptoModule("enigma", true).Decrypt([]byte("WPED\x02AGCM\x00"))
+	assert.Contains(err.Error(), "unknown header version")
+
+	_, err = NewAesGcmCryptoModule("enigma", true).Decrypt([]byte("WPED\x01AG"))
+	assert.Contains(err.Error(), "truncated header")
+}
+
+func TestCryptoHeaderLongMetadata(t *testing.T) {
+	assert := assert.New(t)
+
+	metadata := bytes.Repeat([]byte{7}, 300)
+	header, err := buildCryptoHeader(AesGcmCryptorID, metadata)
+	assert.Nil(err)
+	assert.Equal(byte(0xFF), header[9])
+
+	id, parsed, payload, err := parseCryptoHeader(append(header, 1, 2, 3))
+	assert.Nil(err)
+	assert.Equal(AesGcmCryptorID, id)
+	assert.Equal(metadata, parsed)
+	assert.Equal([]byte{1, 2, 3}, payload)
+}
+
+func TestCryptoModuleStreamLegacyRandomIV(t *testing.T) {
+	assert := assert.New(t)
+
+	module := NewLegacyCryptoModule("enigma", false)
+	r, err := module.EncryptStream(bytes.NewReader([]byte("file content")))
+	assert.Nil(err)
+	enc, _ := ioutil.ReadAll(r)
+
+	dec, err := utils.DecryptBytes("enigma", enc, true)
+	assert.Nil(err)
+	assert.Equal("file content", string(dec))
+
+	r, err = module.DecryptStream(bytes.NewReader(enc))
+	assert.Nil(err)
+	dec, _ = ioutil.ReadAll(r)
+	assert.Equal("file content", string(dec))
+}
+
+func TestParseCipherInterfaceCryptoModule(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := NewWebPubSub(NewDemoConfig())
+	pn.Config.CryptoModule = NewAesGcmCryptoModule("enigma", true)
+
+	enc, err := pn.Config.CryptoModule.Encrypt([]byte("{\"text\":\"hi\"}"))
+	assert.Nil(err)
+
+	intf, raw, _, err := parseCipherInterfaceWithRaw(base64.StdEncoding.EncodeToString(enc), nil, pn.Config)
+	assert.Nil(err)
+	assert.Equal(map[string]interface{}{"text": "hi"}, intf)
+	assert.Equal("{\"text\":\"hi\"}", string(raw))
+}
+
+func TestPublishCryptoModule(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := NewWebPubSub(NewDemoConfig())
+	pn.Config.CryptoModule = NewAesGcmCryptoModule("enigma", true)
+
+	o := newPublishBuilder(pn)
+	o.Channel("ch")
+	o.Message("hi")
+	o.UsePost(true)
+
+	body, err := o.opts.buildBody()
+	assert.Nil(err)
+
+	intf, err := parseCipherInterface(string(body[1:len(body)-1]), pn.Config)
+	assert.Nil(err)
+	assert.Equal("hi", intf)
+}
+
+func TestDecryptStringWithKeyring(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := NewWebPubSub(NewDemoConfig())
+	pn.Config.CipherKey = "current"
+	pn.Config.DecryptCipherKeys = []string{"previous"}
+
+	enc, _ := encryptString(NewLegacyCryptoModule("previous", true), "\"old\"")
+	intf, _, keyIndex, err := parseCipherInterfaceWithRaw(enc, nil, pn.Config)
+	assert.Nil(err)
+	assert.Equal("old", intf)
+	assert.Equal(1, keyIndex)
+
+	enc, _ = encryptString(pn.Config.cryptoModule(), "\"new\"")
+	intf, _, keyIndex, err = parseCipherInterfaceWithRaw(enc, nil, pn.Config)
+	assert.Nil(err)
+	assert.Equal("new", intf)
+	assert.Equal(0, keyIndex)
+
+	enc, _ = encryptString(NewLegacyCryptoModule("unknown", true), "\"lost\"")
+	_, _, keyIndex, err = parseCipherInterfaceWithRaw(enc, nil, pn.Config)
+	assert.NotNil(err)
+	assert.Equal(-1, keyIndex)
+
+	_, _, keyIndex, err = parseCipherInterfaceWithRaw("plain", nil, NewDemoConfig())
+	assert.Nil(err)
+	assert.Equal(-1, keyIndex)
+}
+
+func TestDecryptStreamWithKeyring(t *testing.T) {
+	assert := assert.New(t)
+
+	r, _ := NewAesGcmCryptoModule("previous", true).EncryptStream(bytes.NewReader([]byte("file content")))
+	keyring := []decryptionKey{
+		{index: 0, module: NewAesGcmCryptoModule("current", true)},
+		{index: 2, module: NewLegacyCryptoModule("previous", true)},
+	}
+	dec, keyIndex, err := decryptStreamWithKeyring(keyring, r)
+	assert.Nil(err)
+	assert.Equal(2, keyIndex)
+	content, _ := ioutil.ReadAll(dec)
+	assert.Equal("file content", string(content))
+}
@@ -150,6 +150,14 @@ func (o *deleteChannelGroupOpts) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *deleteChannelGroupOpts) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *deleteChannelGroupOpts) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
+
+func (o *deleteChannelGroupOpts) tokenResources() (channels, groups, uuids []string) {
+	return nil, []string{o.ChannelGroup}, nil
+}
@@ -0,0 +1,69 @@
+package emulator
+
+import (
+	"net/http"
+	"sort"
+)
+
+func channelGroupOK(w http.ResponseWriter) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":  200,
+		"message": "OK",
+		"service": "channel-registry",
+		"error":   false,
+	})
+}
+
+// handleChannelGroup adds channels to a group, removes channels from it or
+// lists its channels depending on the query.
+func (e *Emulator) handleChannelGroup(w http.ResponseWriter, r *http.Request, p params) {
+	q := r.URL.Query()
+	group := p["group"]
+
+	e.Lock()
+	defer e.Unlock()
+	ks := e.keyset(p["sub"])
+
+	if add := splitList(q.Get("add")); len(add) > 0 {
+		channels, ok := ks.groups[group]
+		if !ok {
+			channels = make(map[string]bool)
+			ks.groups[group] = channels
+		}
+		for _, ch := range add {
+			channels[ch] = true
+		}
+		channelGroupOK(w)
+		return
+	}
+
+	if remove := splitList(q.Get("remove")); len(remove) > 0 {
+		for _, ch := range remove {
+			delete(ks.groups[group], ch)
+		}
+		channelGroupOK(w)
+		return
+	}
+
+	channels := []string{}
+	for ch := range ks.groups[group] {
+		channels = append(channels, ch)
+	}
+	sort.Strings(channels)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":  200,
+		"service": "channel-registry",
+		"error":   false,
+		"payload": map[string]interface{}{
+			"group":    group,
+			"channels": channels,
+		},
+	})
+}
+
+func (e *Emulator) handleDeleteChannelGroup(w http.ResponseWriter, r *http.Request, p params) {
+	e.Lock()
+	delete(e.keyset(p["sub"]).groups, p["group"])
+	e.Unlock()
+	channelGroupOK(w)
+}
@@ -0,0 +1,28 @@
+package emulator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmulatorChannelGroups(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := newTestWebPubSub(t, New(), "uuid")
+	_, _, err := pn.AddChannelToChannelGroup().ChannelGroup("cg").Channels([]string{"b", "a", "c"}).Execute()
+	assert.Nil(err)
+	_, _, err = pn.RemoveChannelFromChannelGroup().ChannelGroup("cg").Channels([]string{"c"}).Execute()
+	assert.Nil(err)
+
+	res, _, err := pn.ListChannelsInChannelGroup().ChannelGroup("cg").Execute()
+	assert.Nil(err)
+	assert.Equal("cg", res.ChannelGroup)
+	assert.Equal([]string{"a", "b"}, res.Channels)
+
+	_, _, err = pn.DeleteChannelGroup().ChannelGroup("cg").Execute()
+	assert.Nil(err)
+	res, _, err = pn.ListChannelsInChannelGroup().ChannelGroup("cg").Execute()
+	assert.Nil(err)
+	assert.Empty(res.Channels)
+}
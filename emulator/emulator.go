@@ -0,0 +1,281 @@
+// Package emulator is an in-memory implementation of the WebPubSub REST API
+// for offline development and tests. It serves publish, subscribe, history,
+// presence, state, channel groups, objects, message actions and files.
+//
+// The emulator doesn't enforce access control nor verify signatures, and it
+// ignores subscribe filter expressions.
+//
+//	srv := httptest.NewServer(emulator.New())
+//	defer srv.Close()
+//
+//	config := webpubsub.NewConfig("my-uuid")
+//	emulator.Configure(config, srv.URL)
+package emulator
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	webpubsub "github.com/webpubsub/sdk-go/v7"
+)
+
+// DefaultLongPollTimeout is the time a subscribe request waits for messages
+// before answering with an empty response.
+const DefaultLongPollTimeout = 280 * time.Second
+
+// defaultPresenceTimeout is used when a request doesn't set its heartbeat.
+const defaultPresenceTimeout = 300
+
+// Emulator is an http.Handler serving the WebPubSub REST API from memory.
+type Emulator struct {
+	// LongPollTimeout is the time a subscribe request waits for messages,
+	// DefaultLongPollTimeout when zero.
+	LongPollTimeout time.Duration
+
+	sync.Mutex
+	keysets   map[string]*keyset
+	last      int64
+	published chan struct{}
+	routes    []route
+}
+
+// New creates an empty Emulator.
+func New() *Emulator {
+	e := &Emulator{
+		keysets:   make(map[string]*keyset),
+		published: make(chan struct{}),
+	}
+	e.routes = e.buildRoutes()
+	return e
+}
+
+// Configure points config at the emulator served at serverURL.
+func Configure(config *webpubsub.Config, serverURL string) error {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return err
+	}
+	config.Origin = u.Host
+	config.Secure = u.Scheme == "https"
+	if config.PublishKey == "" {
+		config.PublishKey = "demo"
+	}
+	if config.SubscribeKey == "" {
+		config.SubscribeKey = "demo"
+	}
+	return nil
+}
+
+// Reset drops the data of every keyset.
+func (e *Emulator) Reset() {
+	e.Lock()
+	e.keysets = make(map[string]*keyset)
+	e.Unlock()
+}
+
+// keyset holds the data of a subscribe key.
+type keyset struct {
+	messages    []*message
+	groups      map[string]map[string]bool
+	presence    map[string]map[string]time.Time
+	states      map[string]map[string]json.RawMessage
+	uuids       map[string]*webpubsub.WPSUUID
+	channels    map[string]*webpubsub.WPSChannel
+	memberships map[membershipKey]*membership
+	actions     []*messageAction
+	files       map[string][]*storedFile
+}
+
+func (e *Emulator) keyset(subKey string) *keyset {
+	ks, ok := e.keysets[subKey]
+	if !ok {
+		ks = &keyset{
+			groups:      make(map[string]map[string]bool),
+			presence:    make(map[string]map[string]time.Time),
+			states:      make(map[string]map[string]json.RawMessage),
+			uuids:       make(map[string]*webpubsub.WPSUUID),
+			channels:    make(map[string]*webpubsub.WPSChannel),
+			memberships: make(map[membershipKey]*membership),
+			files:       make(map[string][]*storedFile),
+		}
+		e.keysets[subKey] = ks
+	}
+	return ks
+}
+
+// nextTimetoken returns a timetoken greater than all the previous ones.
+func (e *Emulator) nextTimetoken() int64 {
+	tt := time.Now().UnixNano() / 100
+	if tt <= e.last {
+		tt = e.last + 1
+	}
+	e.last = tt
+	return tt
+}
+
+// notify wakes up the waiting subscribe requests.
+func (e *Emulator) notify() {
+	close(e.published)
+	e.published = make(chan struct{})
+}
+
+// route is an endpoint of the emulator. The segments of pattern starting
+// with ':' capture the path segment, a trailing '*' matches the rest of the
+// path.
+type route struct {
+	method  string
+	pattern []string
+	handle  func(w http.ResponseWriter, r *http.Request, p params)
+}
+
+type params map[string]string
+
+func (e *Emulator) buildRoutes() []route {
+	add := func(method, pattern string, handle func(http.ResponseWriter, *http.Request, params)) route {
+		return route{method, strings.Split(pattern, "/"), handle}
+	}
+	return []route{
+		add("GET", "time/0", e.handleTime),
+
+		add("", "publish/:pub/:sub/0/:channel/0/*", e.handlePublish),
+		add("", "signal/:pub/:sub/0/:channel/0/*", e.handleSignal),
+		add("", "v1/files/publish-file/:pub/:sub/0/:channel/0/*", e.handlePublishFileMessage),
+		add("GET", "v2/subscribe/:sub/:channels/0", e.handleSubscribe),
+
+		add("GET", "v2/history/sub-key/:sub/channel/:channel", e.handleHistory),
+		add("GET", "v3/history/sub-key/:sub/channel/:channels", e.handleFetch),
+		add("DELETE", "v3/history/sub-key/:sub/channel/:channel", e.handleDeleteMessages),
+		add("GET", "v3/history-with-actions/sub-key/:sub/channel/:channels", e.handleFetch),
+
+		add("GET", "v2/presence/sub-key/:sub/channel/:channels/heartbeat", e.handleHeartbeat),
+		add("GET", "v2/presence/sub-key/:sub/channel/:channels/leave", e.handleLeave),
+		add("GET", "v2/presence/sub-key/:sub/channel/:channels/uuid/:uuid", e.handleGetState),
+		add("GET", "v2/presence/sub-key/:sub/channel/:channels/uuid/:uuid/data", e.handleSetState),
+		add("GET", "v2/presence/sub_key/:sub/channel/:channels", e.handleHereNow),
+		add("GET", "v2/presence/sub_key/:sub", e.handleHereNow),
+		add("GET", "v2/presence/sub-key/:sub/uuid/:uuid", e.handleWhereNow),
+
+		add("GET", "v1/channel-registration/sub-key/:sub/channel-group/:group", e.handleChannelGroup),
+		add("GET", "v1/channel-registration/sub-key/:sub/channel-group/:group/remove", e.handleDeleteChannelGroup),
+
+		add("GET", "v2/objects/:sub/uuids", e.handleGetAllUUIDMetadata),
+		add("GET", "v2/objects/:sub/uuids/:uuid", e.handleGetUUIDMetadata),
+		add("PATCH", "v2/objects/:sub/uuids/:uuid", e.handleSetUUIDMetadata),
+		add("DELETE", "v2/objects/:sub/uuids/:uuid", e.handleRemoveUUIDMetadata),
+		add("GET", "v2/objects/:sub/uuids/:uuid/channels", e.handleGetMemberships),
+		add("PATCH", "v2/objects/:sub/uuids/:uuid/channels", e.handleManageMemberships),
+		add("GET", "v2/objects/:sub/channels", e.handleGetAllChannelMetadata),
+		add("GET", "v2/objects/:sub/channels/:channel", e.handleGetChannelMetadata),
+		add("PATCH", "v2/objects/:sub/channels/:channel", e.handleSetChannelMetadata),
+		add("DELETE", "v2/objects/:sub/channels/:channel", e.handleRemoveChannelMetadata),
+		add("GET", "v2/objects/:sub/channels/:channel/uuids", e.handleGetChannelMembers),
+		add("PATCH", "v2/objects/:sub/channels/:channel/uuids", e.handleManageChannelMembers),
+
+		add("GET", "v1/message-actions/:sub/channel/:channel", e.handleGetMessageActions),
+		add("POST", "v1/message-actions/:sub/channel/:channel/message/:timetoken", e.handleAddMessageAction),
+		add("DELETE", "v1/message-actions/:sub/channel/:channel/message/:timetoken/action/:action", e.handleRemoveMessageAction),
+
+		add("POST", "v1/files/:sub/channels/:channel/generate-upload-url", e.handleGenerateUploadURL),
+		add("GET", "v1/files/:sub/channels/:channel/files", e.handleListFiles),
+		add("GET", "v1/files/:sub/channels/:channel/files/:id/:name", e.handleDownloadFile),
+		add("DELETE", "v1/files/:sub/channels/:channel/files/:id/:name", e.handleDeleteFile),
+		add("POST", uploadPath, e.handleUpload),
+	}
+}
+
+// ServeHTTP serves a request of the REST API.
+func (e *Emulator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	segments := strings.Split(strings.Trim(r.URL.EscapedPath(), "/"), "/")
+	for i, s := range segments {
+		if unescaped, err := url.PathUnescape(s); err == nil {
+			segments[i] = unescaped
+		}
+	}
+
+	pathMatched := false
+	for _, rt := range e.routes {
+		p, ok := rt.match(segments)
+		if !ok {
+			continue
+		}
+		pathMatched = true
+		if rt.method != "" && rt.method != r.Method {
+			continue
+		}
+		rt.handle(w, r, p)
+		return
+	}
+
+	if pathMatched {
+		writeError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+		return
+	}
+	writeError(w, http.StatusNotFound, "Not Found")
+}
+
+func (rt route) match(segments []string) (params, bool) {
+	p := params{}
+	for i, s := range rt.pattern {
+		if s == "*" {
+			p["*"] = strings.Join(segments[i:], "/")
+			return p, true
+		}
+		if i >= len(segments) {
+			return nil, false
+		}
+		if strings.HasPrefix(s, ":") {
+			p[s[1:]] = segments[i]
+		} else if s != segments[i] {
+			return nil, false
+		}
+	}
+	return p, len(segments) == len(rt.pattern)
+}
+
+func (e *Emulator) handleTime(w http.ResponseWriter, r *http.Request, p params) {
+	e.Lock()
+	tt := e.nextTimetoken()
+	e.Unlock()
+	writeJSON(w, http.StatusOK, []int64{tt})
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, statusCode int, message string) {
+	writeJSON(w, statusCode, map[string]interface{}{
+		"status":  statusCode,
+		"error":   true,
+		"message": message,
+		"service": "Emulator",
+	})
+}
+
+// splitList splits a comma separated path segment or query parameter, the
+// SDK sends "," for an empty channel list.
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	h := hex.EncodeToString(b)
+	return fmt.Sprintf("%s-%s-%s-%s-%s", h[0:8], h[8:12], h[12:16], h[16:20], h[20:])
+}
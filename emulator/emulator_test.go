@@ -0,0 +1,114 @@
+package emulator
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	webpubsub "github.com/webpubsub/sdk-go/v7"
+)
+
+func newTestWebPubSub(t *testing.T, e *Emulator, uuid string) *webpubsub.WebPubSub {
+	srv := httptest.NewServer(e)
+	t.Cleanup(srv.Close)
+
+	config := webpubsub.NewConfig(uuid)
+	assert.Nil(t, Configure(config, srv.URL))
+	pn := webpubsub.NewWebPubSub(config)
+	t.Cleanup(pn.Destroy)
+	return pn
+}
+
+func newBufferedListener() *webpubsub.Listener {
+	return &webpubsub.Listener{
+		Status:              make(chan *webpubsub.WPSStatus, 100),
+		Message:             make(chan *webpubsub.WPSMessage, 100),
+		Presence:            make(chan *webpubsub.WPSPresence, 100),
+		Signal:              make(chan *webpubsub.WPSMessage, 100),
+		UUIDEvent:           make(chan *webpubsub.WPSUUIDEvent, 100),
+		ChannelEvent:        make(chan *webpubsub.WPSChannelEvent, 100),
+		MembershipEvent:     make(chan *webpubsub.WPSMembershipEvent, 100),
+		MessageActionsEvent: make(chan *webpubsub.WPSMessageActionsEvent, 100),
+		File:                make(chan *webpubsub.WPSFilesEvent, 100),
+	}
+}
+
+// subscribe subscribes pn to the channels and waits for the connection.
+func subscribe(t *testing.T, pn *webpubsub.WebPubSub, withPresence bool, channels ...string) *webpubsub.Listener {
+	l := newBufferedListener()
+	pn.AddListener(l)
+	pn.Subscribe().Channels(channels).WithPresence(withPresence).Execute()
+
+	for {
+		select {
+		case status := <-l.Status:
+			if status.Category == webpubsub.WPSConnectedCategory {
+				return l
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("subscribe not connected")
+		}
+	}
+}
+
+func nextMessage(t *testing.T, l *webpubsub.Listener) *webpubsub.WPSMessage {
+	select {
+	case m := <-l.Message:
+		return m
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+	}
+	return nil
+}
+
+func TestEmulatorNotFound(t *testing.T) {
+	assert := assert.New(t)
+
+	srv := httptest.NewServer(New())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/v9/unknown")
+	assert.Nil(err)
+	assert.Equal(http.StatusNotFound, resp.StatusCode)
+
+	resp, err = http.Post(srv.URL+"/time/0", "application/json", nil)
+	assert.Nil(err)
+	assert.Equal(http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestEmulatorTime(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := newTestWebPubSub(t, New(), "uuid")
+	first, _, err := pn.Time().Execute()
+	assert.Nil(err)
+	second, _, err := pn.Time().Execute()
+	assert.Nil(err)
+	assert.True(second.Timetoken > first.Timetoken)
+}
+
+func TestEmulatorConfigure(t *testing.T) {
+	assert := assert.New(t)
+
+	config := webpubsub.NewConfig("uuid")
+	assert.Nil(Configure(config, "https://localhost:8443"))
+	assert.Equal("localhost:8443", config.Origin)
+	assert.True(config.Secure)
+	assert.Equal("demo", config.SubscribeKey)
+}
+
+func TestEmulatorReset(t *testing.T) {
+	assert := assert.New(t)
+
+	e := New()
+	pn := newTestWebPubSub(t, e, "uuid")
+	_, _, err := pn.Publish().Channel("ch").Message("hi").Execute()
+	assert.Nil(err)
+
+	e.Reset()
+	res, _, err := pn.History().Channel("ch").Execute()
+	assert.Nil(err)
+	assert.Empty(res.Messages)
+}
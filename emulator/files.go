@@ -0,0 +1,160 @@
+package emulator
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	webpubsub "github.com/webpubsub/sdk-go/v7"
+)
+
+// uploadPath is the path of the emulated storage upload, the upload URL
+// returned by generate-upload-url points at it.
+const uploadPath = "_upload"
+
+// maxUploadSize is the maximum size of an uploaded file.
+const maxUploadSize = 5 << 20
+
+type storedFile struct {
+	ID      string
+	Name    string
+	Data    []byte
+	Created string
+}
+
+func (f *storedFile) info() webpubsub.WPSFileInfo {
+	return webpubsub.WPSFileInfo{
+		Name:    f.Name,
+		ID:      f.ID,
+		Size:    len(f.Data),
+		Created: f.Created,
+	}
+}
+
+func findFile(ks *keyset, channel, id, name string) (int, *storedFile) {
+	for i, f := range ks.files[channel] {
+		if f.ID == id && f.Name == name {
+			return i, f
+		}
+	}
+	return -1, nil
+}
+
+func (e *Emulator) handleGenerateUploadURL(w http.ResponseWriter, r *http.Request, p params) {
+	var body webpubsub.WPSSendFileBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Name == "" {
+		writeError(w, http.StatusBadRequest, "Invalid file name")
+		return
+	}
+
+	f := &storedFile{ID: newID(), Name: body.Name}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status": 200,
+		"data":   webpubsub.WPSFileData{ID: f.ID},
+		"file_upload_request": webpubsub.WPSFileUploadRequest{
+			URL:    fmt.Sprintf("%s://%s/%s", scheme, r.Host, uploadPath),
+			Method: "POST",
+			FormFields: []webpubsub.WPSFormField{
+				{Key: "key", Value: strings.Join([]string{p["sub"], p["channel"], f.ID, f.Name}, "/")},
+				{Key: "Content-Type", Value: "application/octet-stream"},
+			},
+		},
+	})
+}
+
+// handleUpload stores the file of an upload form, the form key identifies the
+// keyset, channel, id and name of the file.
+func (e *Emulator) handleUpload(w http.ResponseWriter, r *http.Request, p params) {
+	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	key := strings.SplitN(r.FormValue("key"), "/", 4)
+	if len(key) != 4 {
+		http.Error(w, "invalid key", http.StatusBadRequest)
+		return
+	}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	e.Lock()
+	ks := e.keyset(key[0])
+	ks.files[key[1]] = append(ks.files[key[1]], &storedFile{
+		ID:      key[2],
+		Name:    key[3],
+		Data:    data,
+		Created: time.Now().UTC().Format(time.RFC3339),
+	})
+	e.Unlock()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (e *Emulator) handleListFiles(w http.ResponseWriter, r *http.Request, p params) {
+	limit := queryCount(r.URL.Query(), "limit", 100, 100)
+
+	e.Lock()
+	files := e.keyset(p["sub"]).files[p["channel"]]
+	data := make([]webpubsub.WPSFileInfo, 0, len(files))
+	for _, f := range files {
+		if len(data) == limit {
+			break
+		}
+		data = append(data, f.info())
+	}
+	e.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status": 200,
+		"data":   data,
+		"count":  len(data),
+		"next":   "",
+	})
+}
+
+func (e *Emulator) handleDownloadFile(w http.ResponseWriter, r *http.Request, p params) {
+	e.Lock()
+	_, f := findFile(e.keyset(p["sub"]), p["channel"], p["id"], p["name"])
+	e.Unlock()
+	if f == nil {
+		writeError(w, http.StatusNotFound, "File not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Write(f.Data)
+}
+
+func (e *Emulator) handleDeleteFile(w http.ResponseWriter, r *http.Request, p params) {
+	e.Lock()
+	ks := e.keyset(p["sub"])
+	i, f := findFile(ks, p["channel"], p["id"], p["name"])
+	if f != nil {
+		files := ks.files[p["channel"]]
+		ks.files[p["channel"]] = append(files[:i], files[i+1:]...)
+	}
+	e.Unlock()
+	if f == nil {
+		writeError(w, http.StatusNotFound, "File not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"status": 200})
+}
@@ -0,0 +1,54 @@
+package emulator
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmulatorFiles(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := newTestWebPubSub(t, New(), "uploader")
+	l := subscribe(t, pn, false, "ch")
+
+	path := filepath.Join(t.TempDir(), "hello.txt")
+	assert.Nil(ioutil.WriteFile(path, []byte("hello emulator"), 0600))
+	file, err := os.Open(path)
+	assert.Nil(err)
+	defer file.Close()
+
+	sent, _, err := pn.SendFile().Channel("ch").Name("hello.txt").Message("a file").File(file).Execute()
+	assert.Nil(err)
+	assert.NotEmpty(sent.Data.ID)
+
+	select {
+	case event := <-l.File:
+		assert.Equal(sent.Data.ID, event.File.WPSFile.ID)
+		assert.Equal("hello.txt", event.File.WPSFile.Name)
+		assert.Equal("a file", event.File.WPSMessage.Text)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no file event received")
+	}
+
+	list, _, err := pn.ListFiles().Channel("ch").Execute()
+	assert.Nil(err)
+	assert.Equal(1, len(list.Data))
+	assert.Equal(len("hello emulator"), list.Data[0].Size)
+
+	download, _, err := pn.DownloadFile().Channel("ch").ID(sent.Data.ID).Name("hello.txt").Execute()
+	assert.Nil(err)
+	content, err := ioutil.ReadAll(download.File)
+	assert.Nil(err)
+	assert.Equal("hello emulator", string(content))
+
+	_, _, err = pn.DeleteFile().Channel("ch").ID(sent.Data.ID).Name("hello.txt").Execute()
+	assert.Nil(err)
+	list, _, err = pn.ListFiles().Channel("ch").Execute()
+	assert.Nil(err)
+	assert.Empty(list.Data)
+}
@@ -0,0 +1,150 @@
+package emulator
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	webpubsub "github.com/webpubsub/sdk-go/v7"
+)
+
+type messageAction struct {
+	Channel          string
+	Type             string
+	Value            string
+	UUID             string
+	ActionTimetoken  int64
+	MessageTimetoken int64
+}
+
+func (a *messageAction) response() webpubsub.WPSMessageActionsResponse {
+	return webpubsub.WPSMessageActionsResponse{
+		ActionType:       a.Type,
+		ActionValue:      a.Value,
+		ActionTimetoken:  strconv.FormatInt(a.ActionTimetoken, 10),
+		MessageTimetoken: strconv.FormatInt(a.MessageTimetoken, 10),
+		UUID:             a.UUID,
+	}
+}
+
+// announceMessageAction publishes a message actions event on the channel of
+// the action, the caller holds the lock.
+func (e *Emulator) announceMessageAction(ks *keyset, a *messageAction, event string) {
+	payload, _ := json.Marshal(map[string]interface{}{
+		"source":  "actions",
+		"version": "1.0",
+		"event":   event,
+		"data":    a.response(),
+	})
+	e.publish(ks, &message{
+		Channel:     a.Channel,
+		Payload:     payload,
+		Publisher:   a.UUID,
+		MessageType: webpubsub.WPSMessageTypeMessageActions,
+		Replicated:  true,
+	})
+}
+
+// historyActions returns the actions of a message in the format of the
+// history with actions response.
+func historyActions(ks *keyset, channel string, messageTimetoken int64) map[string]map[string][]map[string]string {
+	actions := make(map[string]map[string][]map[string]string)
+	for _, a := range ks.actions {
+		if a.Channel != channel || a.MessageTimetoken != messageTimetoken {
+			continue
+		}
+		if actions[a.Type] == nil {
+			actions[a.Type] = make(map[string][]map[string]string)
+		}
+		actions[a.Type][a.Value] = append(actions[a.Type][a.Value], map[string]string{
+			"uuid":            a.UUID,
+			"actionTimetoken": strconv.FormatInt(a.ActionTimetoken, 10),
+		})
+	}
+	return actions
+}
+
+func (e *Emulator) handleAddMessageAction(w http.ResponseWriter, r *http.Request, p params) {
+	messageTimetoken, err := strconv.ParseInt(p["timetoken"], 10, 64)
+	var body struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	}
+	if err != nil || json.NewDecoder(r.Body).Decode(&body) != nil || body.Type == "" || body.Value == "" {
+		writeError(w, http.StatusBadRequest, "Invalid message action")
+		return
+	}
+
+	e.Lock()
+	defer e.Unlock()
+	ks := e.keyset(p["sub"])
+	a := &messageAction{
+		Channel:          p["channel"],
+		Type:             body.Type,
+		Value:            body.Value,
+		UUID:             r.URL.Query().Get("uuid"),
+		ActionTimetoken:  e.nextTimetoken(),
+		MessageTimetoken: messageTimetoken,
+	}
+	ks.actions = append(ks.actions, a)
+	e.announceMessageAction(ks, a, "added")
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"status": 200, "data": a.response()})
+}
+
+func (e *Emulator) handleRemoveMessageAction(w http.ResponseWriter, r *http.Request, p params) {
+	e.Lock()
+	defer e.Unlock()
+	ks := e.keyset(p["sub"])
+	for i, a := range ks.actions {
+		if a.Channel == p["channel"] &&
+			strconv.FormatInt(a.MessageTimetoken, 10) == p["timetoken"] &&
+			strconv.FormatInt(a.ActionTimetoken, 10) == p["action"] {
+			ks.actions = append(ks.actions[:i], ks.actions[i+1:]...)
+			e.announceMessageAction(ks, a, "removed")
+			break
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"status": 200, "data": map[string]interface{}{}})
+}
+
+// handleGetMessageActions returns the newest actions of the channel added
+// before start (exclusive) and from end (inclusive) in ascending order.
+func (e *Emulator) handleGetMessageActions(w http.ResponseWriter, r *http.Request, p params) {
+	q := r.URL.Query()
+	start, end := queryInt64(q, "start"), queryInt64(q, "end")
+	limit := queryCount(q, "limit", 100, 100)
+
+	e.Lock()
+	var actions []*messageAction
+	for _, a := range e.keyset(p["sub"]).actions {
+		if a.Channel != p["channel"] {
+			continue
+		}
+		if start != 0 && a.ActionTimetoken >= start || end != 0 && a.ActionTimetoken < end {
+			continue
+		}
+		actions = append(actions, a)
+	}
+	e.Unlock()
+
+	resp := map[string]interface{}{"status": 200}
+	if len(actions) > limit {
+		actions = actions[len(actions)-limit:]
+		more := webpubsub.WPSGetMessageActionsMore{
+			Start: strconv.FormatInt(actions[0].ActionTimetoken, 10),
+			Limit: limit,
+		}
+		if end != 0 {
+			more.End = strconv.FormatInt(end, 10)
+		}
+		more.URL = r.URL.Path + "?start=" + more.Start + "&limit=" + strconv.Itoa(limit)
+		resp["more"] = more
+	}
+	data := make([]webpubsub.WPSMessageActionsResponse, 0, len(actions))
+	for _, a := range actions {
+		data = append(data, a.response())
+	}
+	resp["data"] = data
+	writeJSON(w, http.StatusOK, resp)
+}
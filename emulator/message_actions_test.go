@@ -0,0 +1,61 @@
+package emulator
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	webpubsub "github.com/webpubsub/sdk-go/v7"
+)
+
+func TestEmulatorMessageActions(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := newTestWebPubSub(t, New(), "reactor")
+	l := subscribe(t, pn, false, "ch")
+
+	pub, _, err := pn.Publish().Channel("ch").Message("hi").Execute()
+	assert.Nil(err)
+	nextMessage(t, l)
+	messageTimetoken := strconv.FormatInt(pub.Timestamp, 10)
+
+	var added []*webpubsub.WPSAddMessageActionsResponse
+	for _, value := range []string{"smile", "wave"} {
+		res, _, err := pn.AddMessageAction().Channel("ch").MessageTimetoken(messageTimetoken).
+			Action(webpubsub.MessageAction{ActionType: "reaction", ActionValue: value}).Execute()
+		assert.Nil(err)
+		assert.Equal("reactor", res.Data.UUID)
+		added = append(added, res)
+	}
+
+	select {
+	case event := <-l.MessageActionsEvent:
+		assert.Equal(webpubsub.WPSMessageActionsEventType("added"), event.Event)
+		assert.Equal("smile", event.Data.ActionValue)
+		assert.Equal(messageTimetoken, event.Data.MessageTimetoken)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message actions event received")
+	}
+
+	res, _, err := pn.GetMessageActions().Channel("ch").Limit(1).Execute()
+	assert.Nil(err)
+	assert.Equal(1, len(res.Data))
+	assert.Equal("wave", res.Data[0].ActionValue)
+	assert.Equal(added[1].Data.ActionTimetoken, res.More.Start)
+
+	fetch, _, err := pn.Fetch().Channels([]string{"ch"}).IncludeMessageActions(true).Execute()
+	assert.Nil(err)
+	actions := fetch.Messages["ch"][0].MessageActions["reaction"].ActionsTypeValues
+	assert.Equal(2, len(actions))
+	assert.Equal("reactor", actions["smile"][0].UUID)
+
+	_, _, err = pn.RemoveMessageAction().Channel("ch").MessageTimetoken(messageTimetoken).
+		ActionTimetoken(added[0].Data.ActionTimetoken).Execute()
+	assert.Nil(err)
+
+	res, _, err = pn.GetMessageActions().Channel("ch").Execute()
+	assert.Nil(err)
+	assert.Equal(1, len(res.Data))
+	assert.Equal("wave", res.Data[0].ActionValue)
+}
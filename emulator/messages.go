@@ -0,0 +1,434 @@
+package emulator
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	webpubsub "github.com/webpubsub/sdk-go/v7"
+)
+
+// maxSubscribeMessages is the maximum number of messages of a subscribe response.
+const maxSubscribeMessages = 100
+
+const presenceSuffix = "-pnpres"
+
+// message is a message published on a channel, presence events included.
+type message struct {
+	Channel     string
+	Timetoken   int64
+	Payload     json.RawMessage
+	Meta        json.RawMessage
+	Publisher   string
+	MessageType webpubsub.WPSMessageType
+	Sequence    int
+	Stored      bool
+	Replicated  bool
+}
+
+// subscribeEnvelope is the body of a subscribe response.
+type subscribeEnvelope struct {
+	Timetoken subscribeTimetoken `json:"t"`
+	Messages  []subscribeMessage `json:"m"`
+}
+
+type subscribeTimetoken struct {
+	Timetoken string `json:"t"`
+	Region    int    `json:"r"`
+}
+
+type subscribeMessage struct {
+	Shard             string                   `json:"a"`
+	SubscriptionMatch string                   `json:"b,omitempty"`
+	Channel           string                   `json:"c"`
+	IssuingClientID   string                   `json:"i,omitempty"`
+	SubscribeKey      string                   `json:"k"`
+	Flags             int                      `json:"f"`
+	Payload           json.RawMessage          `json:"d"`
+	UserMetadata      json.RawMessage          `json:"u,omitempty"`
+	MessageType       webpubsub.WPSMessageType `json:"e"`
+	SequenceNumber    int                      `json:"s"`
+	PublishMetaData   subscribeTimetoken       `json:"p"`
+}
+
+// publish stores a message and wakes up the subscribe requests, the caller
+// holds the lock.
+func (e *Emulator) publish(ks *keyset, m *message) int64 {
+	m.Timetoken = e.nextTimetoken()
+	ks.messages = append(ks.messages, m)
+	e.notify()
+	return m.Timetoken
+}
+
+// publishedMessage reads the message of a publish request from the path or
+// from the body of a POST.
+func publishedMessage(r *http.Request, p params) (json.RawMessage, bool) {
+	payload := []byte(p["*"])
+	if r.Method == "POST" {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return nil, false
+		}
+		payload = body
+	}
+	if !json.Valid(payload) {
+		return nil, false
+	}
+	return json.RawMessage(payload), true
+}
+
+func (e *Emulator) handlePublish(w http.ResponseWriter, r *http.Request, p params) {
+	e.publishMessage(w, r, p, webpubsub.WPSMessageType(0))
+}
+
+func (e *Emulator) handleSignal(w http.ResponseWriter, r *http.Request, p params) {
+	e.publishMessage(w, r, p, webpubsub.WPSMessageTypeSignal)
+}
+
+func (e *Emulator) handlePublishFileMessage(w http.ResponseWriter, r *http.Request, p params) {
+	e.publishMessage(w, r, p, webpubsub.WPSMessageTypeFile)
+}
+
+func (e *Emulator) publishMessage(w http.ResponseWriter, r *http.Request, p params, messageType webpubsub.WPSMessageType) {
+	payload, ok := publishedMessage(r, p)
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, []interface{}{0, "Invalid JSON", "0"})
+		return
+	}
+
+	q := r.URL.Query()
+	m := &message{
+		Channel:     p["channel"],
+		Payload:     payload,
+		Publisher:   q.Get("uuid"),
+		MessageType: messageType,
+		Stored:      messageType != webpubsub.WPSMessageTypeSignal && q.Get("store") != "0",
+		Replicated:  q.Get("norep") != "true",
+	}
+	if meta := q.Get("meta"); meta != "" && json.Valid([]byte(meta)) {
+		m.Meta = json.RawMessage(meta)
+	}
+	m.Sequence, _ = strconv.Atoi(q.Get("seqn"))
+
+	e.Lock()
+	tt := e.publish(e.keyset(p["sub"]), m)
+	e.Unlock()
+
+	writeJSON(w, http.StatusOK, []interface{}{1, "Sent", strconv.FormatInt(tt, 10)})
+}
+
+// subscription matches the channels of a subscribe request.
+type subscription struct {
+	channels  map[string]bool
+	wildcards []string
+	groups    []string
+}
+
+func newSubscription(channels, groups []string) *subscription {
+	s := &subscription{channels: make(map[string]bool), groups: groups}
+	for _, ch := range channels {
+		if strings.HasSuffix(ch, ".*") {
+			s.wildcards = append(s.wildcards, ch)
+		} else {
+			s.channels[ch] = true
+		}
+	}
+	return s
+}
+
+// match returns whether the message of channel is delivered and the
+// subscription which matched it, empty for a channel subscription.
+func (s *subscription) match(ks *keyset, channel string) (string, bool) {
+	if s.channels[channel] {
+		return "", true
+	}
+	for _, w := range s.wildcards {
+		if strings.HasPrefix(channel, strings.TrimSuffix(w, "*")) {
+			return w, true
+		}
+	}
+	for _, g := range s.groups {
+		group, ch := g, channel
+		if strings.HasSuffix(g, presenceSuffix) {
+			if !strings.HasSuffix(channel, presenceSuffix) {
+				continue
+			}
+			group, ch = strings.TrimSuffix(g, presenceSuffix), strings.TrimSuffix(channel, presenceSuffix)
+		}
+		if ks.groups[group][ch] {
+			return g, true
+		}
+	}
+	return "", false
+}
+
+// presenceChannels returns the channels in which the subscriber is present.
+func (s *subscription) presenceChannels(ks *keyset) []string {
+	var channels []string
+	for ch := range s.channels {
+		if !strings.HasSuffix(ch, presenceSuffix) {
+			channels = append(channels, ch)
+		}
+	}
+	return append(channels, groupChannels(ks, s.groups)...)
+}
+
+func (e *Emulator) handleSubscribe(w http.ResponseWriter, r *http.Request, p params) {
+	q := r.URL.Query()
+	subKey := p["sub"]
+	sub := newSubscription(splitList(p["channels"]), splitList(q.Get("channel-group")))
+	cursor, _ := strconv.ParseInt(q.Get("tt"), 10, 64)
+	uuid := q.Get("uuid")
+
+	e.Lock()
+	ks := e.keyset(subKey)
+	// The handshake timetoken precedes the join events of the subscriber so
+	// the next request receives them.
+	var handshake int64
+	if cursor == 0 {
+		handshake = e.nextTimetoken()
+	}
+	e.sweepPresence(ks)
+	heartbeat := presenceTimeout(q.Get("heartbeat"))
+	for _, ch := range sub.presenceChannels(ks) {
+		e.join(ks, ch, uuid, heartbeat)
+	}
+	if state := q.Get("state"); state != "" {
+		e.setStates(ks, uuid, sub.presenceChannels(ks), state)
+	}
+
+	if cursor == 0 {
+		e.Unlock()
+		writeJSON(w, http.StatusOK, subscribeEnvelope{
+			Timetoken: subscribeTimetoken{strconv.FormatInt(handshake, 10), 1},
+			Messages:  []subscribeMessage{},
+		})
+		return
+	}
+
+	timeout := e.LongPollTimeout
+	if timeout == 0 {
+		timeout = DefaultLongPollTimeout
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		envelope := e.collect(ks, subKey, sub, cursor)
+		if len(envelope.Messages) > 0 {
+			e.Unlock()
+			writeJSON(w, http.StatusOK, envelope)
+			return
+		}
+		published := e.published
+		e.Unlock()
+
+		select {
+		case <-published:
+		case <-timer.C:
+			writeJSON(w, http.StatusOK, envelope)
+			return
+		case <-r.Context().Done():
+			return
+		}
+
+		e.Lock()
+		ks = e.keyset(subKey)
+	}
+}
+
+// collect builds the subscribe response with the messages published after
+// cursor, the caller holds the lock.
+func (e *Emulator) collect(ks *keyset, subKey string, sub *subscription, cursor int64) subscribeEnvelope {
+	envelope := subscribeEnvelope{
+		Timetoken: subscribeTimetoken{strconv.FormatInt(cursor, 10), 1},
+		Messages:  []subscribeMessage{},
+	}
+	if e.last > cursor {
+		envelope.Timetoken.Timetoken = strconv.FormatInt(e.last, 10)
+	}
+
+	for _, m := range ks.messages {
+		if m.Timetoken <= cursor || !m.Replicated {
+			continue
+		}
+		match, ok := sub.match(ks, m.Channel)
+		if !ok {
+			continue
+		}
+		tt := subscribeTimetoken{strconv.FormatInt(m.Timetoken, 10), 1}
+		envelope.Messages = append(envelope.Messages, subscribeMessage{
+			Shard:             "1",
+			SubscriptionMatch: match,
+			Channel:           m.Channel,
+			IssuingClientID:   m.Publisher,
+			SubscribeKey:      subKey,
+			Payload:           m.Payload,
+			UserMetadata:      m.Meta,
+			MessageType:       m.MessageType,
+			SequenceNumber:    m.Sequence,
+			PublishMetaData:   tt,
+		})
+		if len(envelope.Messages) == maxSubscribeMessages {
+			envelope.Timetoken = tt
+			break
+		}
+	}
+	return envelope
+}
+
+// storedMessages returns the stored messages of channel published after start
+// (exclusive) and up to end (inclusive) in ascending order, 0 leaves the
+// bound open.
+func storedMessages(ks *keyset, channel string, start, end int64) []*message {
+	var messages []*message
+	for _, m := range ks.messages {
+		if !m.Stored || m.Channel != channel {
+			continue
+		}
+		if start != 0 && m.Timetoken >= start || end != 0 && m.Timetoken < end {
+			continue
+		}
+		messages = append(messages, m)
+	}
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].Timetoken < messages[j].Timetoken
+	})
+	return messages
+}
+
+// page keeps count messages, the oldest ones when reverse is set and the
+// newest ones otherwise.
+func page(messages []*message, count int, reverse bool) []*message {
+	if len(messages) <= count {
+		return messages
+	}
+	if reverse {
+		return messages[:count]
+	}
+	return messages[len(messages)-count:]
+}
+
+func queryInt64(q map[string][]string, key string) int64 {
+	if v, ok := q[key]; ok && len(v) > 0 {
+		i, _ := strconv.ParseInt(v[0], 10, 64)
+		return i
+	}
+	return 0
+}
+
+func queryCount(q map[string][]string, key string, def, max int) int {
+	count := int(queryInt64(q, key))
+	if count <= 0 || count > max {
+		return def
+	}
+	return count
+}
+
+func (e *Emulator) handleHistory(w http.ResponseWriter, r *http.Request, p params) {
+	q := r.URL.Query()
+	count := queryCount(q, "count", 100, 100)
+	includeToken := q.Get("include_token") == "true"
+	includeMeta := q.Get("include_meta") == "true"
+
+	e.Lock()
+	messages := page(storedMessages(e.keyset(p["sub"]), p["channel"],
+		queryInt64(q, "start"), queryInt64(q, "end")), count, q.Get("reverse") == "true")
+
+	items := make([]interface{}, 0, len(messages))
+	for _, m := range messages {
+		if !includeToken && !includeMeta {
+			items = append(items, m.Payload)
+			continue
+		}
+		item := map[string]interface{}{"message": m.Payload}
+		if includeToken {
+			item["timetoken"] = m.Timetoken
+		}
+		if includeMeta && m.Meta != nil {
+			item["meta"] = m.Meta
+		}
+		items = append(items, item)
+	}
+	e.Unlock()
+
+	var start, end int64
+	if len(messages) > 0 {
+		start, end = messages[0].Timetoken, messages[len(messages)-1].Timetoken
+	}
+	writeJSON(w, http.StatusOK, []interface{}{items, start, end})
+}
+
+func (e *Emulator) handleFetch(w http.ResponseWriter, r *http.Request, p params) {
+	q := r.URL.Query()
+	channels := splitList(p["channels"])
+	withActions := strings.HasPrefix(r.URL.Path, "/v3/history-with-actions/")
+	max := 25
+	if len(channels) == 1 {
+		max = 100
+	}
+	count := queryCount(q, "max", max, max)
+
+	e.Lock()
+	ks := e.keyset(p["sub"])
+	result := make(map[string][]map[string]interface{})
+	for _, ch := range channels {
+		messages := page(storedMessages(ks, ch, queryInt64(q, "start"), queryInt64(q, "end")),
+			count, q.Get("reverse") == "true")
+		for _, m := range messages {
+			item := map[string]interface{}{
+				"message":   m.Payload,
+				"timetoken": strconv.FormatInt(m.Timetoken, 10),
+			}
+			if q.Get("include_meta") == "true" && m.Meta != nil {
+				item["meta"] = m.Meta
+			}
+			if q.Get("include_uuid") == "true" {
+				item["uuid"] = m.Publisher
+			}
+			if q.Get("include_message_type") == "true" {
+				item["message_type"] = m.MessageType
+			}
+			if withActions {
+				item["actions"] = historyActions(ks, ch, m.Timetoken)
+			}
+			result[ch] = append(result[ch], item)
+		}
+	}
+	e.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":        200,
+		"error":         false,
+		"error_message": "",
+		"channels":      result,
+	})
+}
+
+func (e *Emulator) handleDeleteMessages(w http.ResponseWriter, r *http.Request, p params) {
+	q := r.URL.Query()
+	start, end := queryInt64(q, "start"), queryInt64(q, "end")
+
+	e.Lock()
+	ks := e.keyset(p["sub"])
+	for _, m := range ks.messages {
+		if !m.Stored || m.Channel != p["channel"] {
+			continue
+		}
+		if start != 0 && m.Timetoken <= start || end != 0 && m.Timetoken > end {
+			continue
+		}
+		m.Stored = false
+	}
+	e.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":        200,
+		"error":         false,
+		"error_message": "",
+	})
+}
@@ -0,0 +1,184 @@
+package emulator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	webpubsub "github.com/webpubsub/sdk-go/v7"
+)
+
+func TestEmulatorPublishSubscribe(t *testing.T) {
+	assert := assert.New(t)
+
+	e := New()
+	subscriber := newTestWebPubSub(t, e, "subscriber")
+	publisher := newTestWebPubSub(t, e, "publisher")
+	l := subscribe(t, subscriber, false, "ch", "news.*")
+
+	res, _, err := publisher.Publish().Channel("ch").Message(map[string]interface{}{"text": "hi/there"}).
+		Meta(map[string]interface{}{"lang": "en"}).Execute()
+	assert.Nil(err)
+
+	m := nextMessage(t, l)
+	assert.Equal("ch", m.Channel)
+	assert.Equal(map[string]interface{}{"text": "hi/there"}, m.Message)
+	assert.Equal(map[string]interface{}{"lang": "en"}, m.UserMetadata)
+	assert.Equal("publisher", m.Publisher)
+	assert.Equal(res.Timestamp, m.Timetoken)
+
+	_, _, err = publisher.Publish().Channel("news.sport").Message("goal").UsePost(true).Execute()
+	assert.Nil(err)
+	m = nextMessage(t, l)
+	assert.Equal("news.sport", m.Channel)
+	assert.Equal("news.*", m.Subscription)
+	assert.Equal("goal", m.Message)
+
+	_, _, err = publisher.Signal().Channel("ch").Message("typing").Execute()
+	assert.Nil(err)
+	select {
+	case s := <-l.Signal:
+		assert.Equal("typing", s.Message)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no signal received")
+	}
+
+	_, _, err = publisher.Fire().Channel("ch").Message("not delivered").Execute()
+	assert.Nil(err)
+	_, _, err = publisher.Publish().Channel("other").Message("not subscribed").Execute()
+	assert.Nil(err)
+	_, _, err = publisher.Publish().Channel("ch").Message("last").Execute()
+	assert.Nil(err)
+	assert.Equal("last", nextMessage(t, l).Message)
+}
+
+func TestEmulatorSubscribeChannelGroup(t *testing.T) {
+	assert := assert.New(t)
+
+	e := New()
+	pn := newTestWebPubSub(t, e, "uuid")
+	_, _, err := pn.AddChannelToChannelGroup().ChannelGroup("cg").Channels([]string{"a", "b"}).Execute()
+	assert.Nil(err)
+
+	l := newBufferedListener()
+	pn.AddListener(l)
+	pn.Subscribe().ChannelGroups([]string{"cg"}).Execute()
+	for status := range l.Status {
+		if status.Category == webpubsub.WPSConnectedCategory {
+			break
+		}
+	}
+
+	_, _, err = pn.Publish().Channel("b").Message("hello").Execute()
+	assert.Nil(err)
+	m := nextMessage(t, l)
+	assert.Equal("b", m.Channel)
+	assert.Equal("cg", m.Subscription)
+}
+
+func TestEmulatorHistory(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := newTestWebPubSub(t, New(), "uuid")
+	var timetokens []int64
+	for _, text := range []string{"one", "two", "three"} {
+		res, _, err := pn.Publish().Channel("ch").Message(text).Meta(map[string]interface{}{"n": text}).Execute()
+		assert.Nil(err)
+		timetokens = append(timetokens, res.Timestamp)
+	}
+	_, _, err := pn.Publish().Channel("ch").Message("unstored").ShouldStore(false).Execute()
+	assert.Nil(err)
+
+	res, _, err := pn.History().Channel("ch").Count(2).IncludeTimetoken(true).Execute()
+	assert.Nil(err)
+	assert.Equal(2, len(res.Messages))
+	assert.Equal("two", res.Messages[0].Message)
+	assert.Equal(timetokens[2], res.Messages[1].Timetoken)
+	assert.Equal(timetokens[1], res.StartTimetoken)
+	assert.Equal(timetokens[2], res.EndTimetoken)
+
+	res, _, err = pn.History().Channel("ch").Count(2).Reverse(true).Execute()
+	assert.Nil(err)
+	assert.Equal("one", res.Messages[0].Message)
+	assert.Equal("two", res.Messages[1].Message)
+
+	res, _, err = pn.History().Channel("ch").Start(timetokens[2]).Execute()
+	assert.Nil(err)
+	assert.Equal(2, len(res.Messages))
+}
+
+func TestEmulatorFetch(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := newTestWebPubSub(t, New(), "fetcher")
+	first, _, err := pn.Publish().Channel("a").Message("a1").Meta(map[string]interface{}{"k": "v"}).Execute()
+	assert.Nil(err)
+	_, _, err = pn.Publish().Channel("b").Message("b1").Execute()
+	assert.Nil(err)
+	_, _, err = pn.Publish().Channel("a").Message("a2").Execute()
+	assert.Nil(err)
+
+	res, _, err := pn.Fetch().Channels([]string{"a", "b"}).IncludeMeta(true).IncludeUUID(true).Execute()
+	assert.Nil(err)
+	assert.Equal(2, len(res.Messages["a"]))
+	assert.Equal("a1", res.Messages["a"][0].Message)
+	assert.Equal(map[string]interface{}{"k": "v"}, res.Messages["a"][0].Meta)
+	assert.Equal("fetcher", res.Messages["a"][0].UUID)
+	assert.Equal(1, len(res.Messages["b"]))
+
+	res, _, err = pn.Fetch().Channels([]string{"a"}).End(first.Timestamp + 1).Execute()
+	assert.Nil(err)
+	assert.Equal(1, len(res.Messages["a"]))
+	assert.Equal("a2", res.Messages["a"][0].Message)
+}
+
+func TestEmulatorFetchAll(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := newTestWebPubSub(t, New(), "fetcher")
+	var timetokens []int64
+	for _, text := range []string{"m1", "m2", "m3", "m4", "m5"} {
+		res, _, err := pn.Publish().Channel("ch").Message(text).Execute()
+		assert.Nil(err)
+		timetokens = append(timetokens, res.Timestamp)
+	}
+
+	fetchAll := func(b interface {
+		FetchAll(func(string, webpubsub.FetchResponseItem) bool) error
+	}) []interface{} {
+		var messages []interface{}
+		assert.Nil(b.FetchAll(func(channel string, item webpubsub.FetchResponseItem) bool {
+			messages = append(messages, item.Message)
+			return true
+		}))
+		return messages
+	}
+
+	assert.Equal([]interface{}{"m5", "m4", "m3", "m2", "m1"},
+		fetchAll(pn.Fetch().Channels([]string{"ch"}).Count(2)))
+	assert.Equal([]interface{}{"m1", "m2", "m3", "m4", "m5"},
+		fetchAll(pn.Fetch().Channels([]string{"ch"}).Count(2).Reverse(true)))
+	assert.Equal([]interface{}{"m2", "m3", "m4"},
+		fetchAll(pn.Fetch().Channels([]string{"ch"}).Count(2).Reverse(true).Start(timetokens[4]).End(timetokens[1])))
+	assert.Equal([]interface{}{"m1", "m2", "m3", "m4", "m5"},
+		fetchAll(pn.Fetch().Channels([]string{"ch"}).Count(1).Reverse(true)))
+}
+
+func TestEmulatorDeleteMessages(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := newTestWebPubSub(t, New(), "uuid")
+	// The SDK requires a secret key to delete messages, the emulator doesn't
+	// verify the signature.
+	pn.Config.SecretKey = "secret"
+	first, _, _ := pn.Publish().Channel("ch").Message("one").Execute()
+	_, _, _ = pn.Publish().Channel("ch").Message("two").Execute()
+
+	_, _, err := pn.DeleteMessages().Channel("ch").Start(first.Timestamp - 1).End(first.Timestamp).Execute()
+	assert.Nil(err)
+
+	res, _, err := pn.History().Channel("ch").Execute()
+	assert.Nil(err)
+	assert.Equal(1, len(res.Messages))
+	assert.Equal("two", res.Messages[0].Message)
+}
@@ -0,0 +1,423 @@
+package emulator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	webpubsub "github.com/webpubsub/sdk-go/v7"
+)
+
+// membershipKey identifies the membership of a uuid in a channel, it is a
+// membership of the uuid and a member of the channel.
+type membershipKey struct {
+	UUID    string
+	Channel string
+}
+
+type membership struct {
+	Custom  map[string]interface{}
+	Created string
+	Updated string
+	ETag    string
+}
+
+// objectsEvent is the payload of an objects event.
+type objectsEvent struct {
+	Source  string      `json:"source"`
+	Version string      `json:"version"`
+	Event   string      `json:"event"`
+	Type    string      `json:"type"`
+	Data    interface{} `json:"data"`
+}
+
+func updated() (string, string) {
+	now := time.Now().UTC()
+	return now.Format(time.RFC3339Nano), fmt.Sprintf("%x", now.UnixNano())
+}
+
+// announceObjects publishes an objects event on channel, the caller holds the
+// lock.
+func (e *Emulator) announceObjects(ks *keyset, channel, event, eventType string, data map[string]interface{}) {
+	for k, v := range data {
+		if v == nil || v == "" {
+			delete(data, k)
+		}
+	}
+	payload, _ := json.Marshal(objectsEvent{"objects", "2.0", event, eventType, data})
+	e.publish(ks, &message{
+		Channel:     channel,
+		Payload:     payload,
+		MessageType: webpubsub.WPSMessageTypeObjects,
+		Replicated:  true,
+	})
+}
+
+func uuidEventData(u *webpubsub.WPSUUID) map[string]interface{} {
+	data := map[string]interface{}{
+		"id":         u.ID,
+		"name":       u.Name,
+		"externalId": u.ExternalID,
+		"profileUrl": u.ProfileURL,
+		"email":      u.Email,
+		"updated":    u.Updated,
+		"eTag":       u.ETag,
+	}
+	if u.Custom != nil {
+		data["custom"] = u.Custom
+	}
+	return data
+}
+
+func channelEventData(c *webpubsub.WPSChannel) map[string]interface{} {
+	data := map[string]interface{}{
+		"id":          c.ID,
+		"name":        c.Name,
+		"description": c.Description,
+		"updated":     c.Updated,
+		"eTag":        c.ETag,
+	}
+	if c.Custom != nil {
+		data["custom"] = c.Custom
+	}
+	return data
+}
+
+// objectsPage paginates the sorted ids with the limit, start and end query
+// parameters. The cursors are the offsets of the pages.
+func objectsPage(r *http.Request, ids []string) ([]string, map[string]interface{}) {
+	q := r.URL.Query()
+	limit := queryCount(q, "limit", 100, 100)
+	offset := 0
+	if start := q.Get("start"); start != "" {
+		offset, _ = strconv.Atoi(start)
+	} else if end := q.Get("end"); end != "" {
+		offset, _ = strconv.Atoi(end)
+	}
+	if offset < 0 || offset > len(ids) {
+		offset = len(ids)
+	}
+
+	resp := map[string]interface{}{"status": 200}
+	if q.Get("count") == "1" {
+		resp["totalCount"] = len(ids)
+	}
+	last := offset + limit
+	if last < len(ids) {
+		resp["next"] = strconv.Itoa(last)
+	} else {
+		last = len(ids)
+	}
+	if offset > 0 {
+		prev := offset - limit
+		if prev < 0 {
+			prev = 0
+		}
+		resp["prev"] = strconv.Itoa(prev)
+	}
+	return ids[offset:last], resp
+}
+
+func (e *Emulator) handleGetAllUUIDMetadata(w http.ResponseWriter, r *http.Request, p params) {
+	e.Lock()
+	defer e.Unlock()
+	ks := e.keyset(p["sub"])
+
+	ids := make([]string, 0, len(ks.uuids))
+	for id := range ks.uuids {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	ids, resp := objectsPage(r, ids)
+	data := make([]*webpubsub.WPSUUID, 0, len(ids))
+	for _, id := range ids {
+		data = append(data, ks.uuids[id])
+	}
+	resp["data"] = data
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (e *Emulator) handleGetUUIDMetadata(w http.ResponseWriter, r *http.Request, p params) {
+	e.Lock()
+	defer e.Unlock()
+	u, ok := e.keyset(p["sub"]).uuids[p["uuid"]]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Requested object was not found.")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"status": 200, "data": u})
+}
+
+func (e *Emulator) handleSetUUIDMetadata(w http.ResponseWriter, r *http.Request, p params) {
+	var body webpubsub.SetUUIDMetadataBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	e.Lock()
+	defer e.Unlock()
+	ks := e.keyset(p["sub"])
+	u, ok := ks.uuids[p["uuid"]]
+	if !ok {
+		u = &webpubsub.WPSUUID{ID: p["uuid"]}
+		ks.uuids[u.ID] = u
+	}
+	if body.Name != "" {
+		u.Name = body.Name
+	}
+	if body.ExternalID != "" {
+		u.ExternalID = body.ExternalID
+	}
+	if body.ProfileURL != "" {
+		u.ProfileURL = body.ProfileURL
+	}
+	if body.Email != "" {
+		u.Email = body.Email
+	}
+	if body.Custom != nil {
+		u.Custom = body.Custom
+	}
+	u.Updated, u.ETag = updated()
+
+	e.announceObjects(ks, u.ID, "set", "uuid", uuidEventData(u))
+	writeJSON(w, http.StatusOK, map[string]interface{}{"status": 200, "data": u})
+}
+
+func (e *Emulator) handleRemoveUUIDMetadata(w http.ResponseWriter, r *http.Request, p params) {
+	e.Lock()
+	defer e.Unlock()
+	ks := e.keyset(p["sub"])
+	if _, ok := ks.uuids[p["uuid"]]; ok {
+		delete(ks.uuids, p["uuid"])
+		e.announceObjects(ks, p["uuid"], "delete", "uuid", map[string]interface{}{"id": p["uuid"]})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"status": 200, "data": nil})
+}
+
+func (e *Emulator) handleGetAllChannelMetadata(w http.ResponseWriter, r *http.Request, p params) {
+	e.Lock()
+	defer e.Unlock()
+	ks := e.keyset(p["sub"])
+
+	ids := make([]string, 0, len(ks.channels))
+	for id := range ks.channels {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	ids, resp := objectsPage(r, ids)
+	data := make([]*webpubsub.WPSChannel, 0, len(ids))
+	for _, id := range ids {
+		data = append(data, ks.channels[id])
+	}
+	resp["data"] = data
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (e *Emulator) handleGetChannelMetadata(w http.ResponseWriter, r *http.Request, p params) {
+	e.Lock()
+	defer e.Unlock()
+	c, ok := e.keyset(p["sub"]).channels[p["channel"]]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Requested object was not found.")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"status": 200, "data": c})
+}
+
+func (e *Emulator) handleSetChannelMetadata(w http.ResponseWriter, r *http.Request, p params) {
+	var body webpubsub.SetChannelMetadataBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	e.Lock()
+	defer e.Unlock()
+	ks := e.keyset(p["sub"])
+	c, ok := ks.channels[p["channel"]]
+	if !ok {
+		c = &webpubsub.WPSChannel{ID: p["channel"]}
+		ks.channels[c.ID] = c
+	}
+	if body.Name != "" {
+		c.Name = body.Name
+	}
+	if body.Description != "" {
+		c.Description = body.Description
+	}
+	if body.Custom != nil {
+		c.Custom = body.Custom
+	}
+	c.Updated, c.ETag = updated()
+
+	e.announceObjects(ks, c.ID, "set", "channel", channelEventData(c))
+	writeJSON(w, http.StatusOK, map[string]interface{}{"status": 200, "data": c})
+}
+
+func (e *Emulator) handleRemoveChannelMetadata(w http.ResponseWriter, r *http.Request, p params) {
+	e.Lock()
+	defer e.Unlock()
+	ks := e.keyset(p["sub"])
+	if _, ok := ks.channels[p["channel"]]; ok {
+		delete(ks.channels, p["channel"])
+		e.announceObjects(ks, p["channel"], "delete", "channel", map[string]interface{}{"id": p["channel"]})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"status": 200, "data": nil})
+}
+
+// setMembership creates or updates a membership, the caller holds the lock.
+func (e *Emulator) setMembership(ks *keyset, key membershipKey, custom map[string]interface{}) {
+	m, ok := ks.memberships[key]
+	if !ok {
+		m = &membership{}
+		m.Created, _ = updated()
+		ks.memberships[key] = m
+	}
+	m.Custom = custom
+	m.Updated, m.ETag = updated()
+	e.announceMembership(ks, key, m, "set")
+}
+
+func (e *Emulator) removeMembership(ks *keyset, key membershipKey) {
+	m, ok := ks.memberships[key]
+	if !ok {
+		return
+	}
+	delete(ks.memberships, key)
+	e.announceMembership(ks, key, m, "delete")
+}
+
+func (e *Emulator) announceMembership(ks *keyset, key membershipKey, m *membership, event string) {
+	for _, ch := range []string{key.Channel, key.UUID} {
+		data := map[string]interface{}{
+			"uuid":    map[string]interface{}{"id": key.UUID},
+			"channel": map[string]interface{}{"id": key.Channel},
+			"updated": m.Updated,
+			"eTag":    m.ETag,
+		}
+		if m.Custom != nil {
+			data["custom"] = m.Custom
+		}
+		e.announceObjects(ks, ch, event, "membership", data)
+	}
+}
+
+// memberships returns the sorted memberships matching keep.
+func memberships(ks *keyset, keep func(membershipKey) bool, id func(membershipKey) string) ([]string, map[string]membershipKey) {
+	keys := make(map[string]membershipKey)
+	var ids []string
+	for key := range ks.memberships {
+		if keep(key) {
+			ids = append(ids, id(key))
+			keys[id(key)] = key
+		}
+	}
+	sort.Strings(ids)
+	return ids, keys
+}
+
+func (e *Emulator) writeMemberships(w http.ResponseWriter, r *http.Request, ks *keyset, uuid string) {
+	ids, keys := memberships(ks,
+		func(k membershipKey) bool { return k.UUID == uuid },
+		func(k membershipKey) string { return k.Channel })
+	ids, resp := objectsPage(r, ids)
+
+	data := make([]webpubsub.WPSMemberships, 0, len(ids))
+	for _, id := range ids {
+		m := ks.memberships[keys[id]]
+		channel := webpubsub.WPSChannel{ID: id}
+		if c, ok := ks.channels[id]; ok {
+			channel = *c
+		}
+		data = append(data, webpubsub.WPSMemberships{
+			ID:      id,
+			Channel: channel,
+			Created: m.Created,
+			Updated: m.Updated,
+			ETag:    m.ETag,
+			Custom:  m.Custom,
+		})
+	}
+	resp["data"] = data
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (e *Emulator) writeChannelMembers(w http.ResponseWriter, r *http.Request, ks *keyset, channel string) {
+	ids, keys := memberships(ks,
+		func(k membershipKey) bool { return k.Channel == channel },
+		func(k membershipKey) string { return k.UUID })
+	ids, resp := objectsPage(r, ids)
+
+	data := make([]webpubsub.WPSChannelMembers, 0, len(ids))
+	for _, id := range ids {
+		m := ks.memberships[keys[id]]
+		uuid := webpubsub.WPSUUID{ID: id}
+		if u, ok := ks.uuids[id]; ok {
+			uuid = *u
+		}
+		data = append(data, webpubsub.WPSChannelMembers{
+			ID:      id,
+			UUID:    uuid,
+			Created: m.Created,
+			Updated: m.Updated,
+			ETag:    m.ETag,
+			Custom:  m.Custom,
+		})
+	}
+	resp["data"] = data
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (e *Emulator) handleGetMemberships(w http.ResponseWriter, r *http.Request, p params) {
+	e.Lock()
+	defer e.Unlock()
+	e.writeMemberships(w, r, e.keyset(p["sub"]), p["uuid"])
+}
+
+func (e *Emulator) handleManageMemberships(w http.ResponseWriter, r *http.Request, p params) {
+	var body webpubsub.WPSManageMembershipsBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	e.Lock()
+	defer e.Unlock()
+	ks := e.keyset(p["sub"])
+	for _, s := range body.Set {
+		e.setMembership(ks, membershipKey{p["uuid"], s.Channel.ID}, s.Custom)
+	}
+	for _, d := range body.Remove {
+		e.removeMembership(ks, membershipKey{p["uuid"], d.Channel.ID})
+	}
+	e.writeMemberships(w, r, ks, p["uuid"])
+}
+
+func (e *Emulator) handleGetChannelMembers(w http.ResponseWriter, r *http.Request, p params) {
+	e.Lock()
+	defer e.Unlock()
+	e.writeChannelMembers(w, r, e.keyset(p["sub"]), p["channel"])
+}
+
+func (e *Emulator) handleManageChannelMembers(w http.ResponseWriter, r *http.Request, p params) {
+	var body webpubsub.WPSManageChannelMembersBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	e.Lock()
+	defer e.Unlock()
+	ks := e.keyset(p["sub"])
+	for _, s := range body.Set {
+		e.setMembership(ks, membershipKey{s.UUID.ID, p["channel"]}, s.Custom)
+	}
+	for _, d := range body.Remove {
+		e.removeMembership(ks, membershipKey{d.UUID.ID, p["channel"]})
+	}
+	e.writeChannelMembers(w, r, ks, p["channel"])
+}
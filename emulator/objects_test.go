@@ -0,0 +1,100 @@
+package emulator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	webpubsub "github.com/webpubsub/sdk-go/v7"
+)
+
+func TestEmulatorUUIDMetadata(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := newTestWebPubSub(t, New(), "uuid")
+	for _, id := range []string{"c", "a", "b"} {
+		_, _, err := pn.SetUUIDMetadata().UUID(id).Name("user " + id).
+			Custom(map[string]interface{}{"n": id}).Execute()
+		assert.Nil(err)
+	}
+	res, _, err := pn.SetUUIDMetadata().UUID("a").Email("a@example.com").Execute()
+	assert.Nil(err)
+	assert.Equal("user a", res.Data.Name)
+	assert.Equal("a@example.com", res.Data.Email)
+	assert.NotEmpty(res.Data.ETag)
+
+	get, _, err := pn.GetUUIDMetadata().UUID("b").Execute()
+	assert.Nil(err)
+	assert.Equal(map[string]interface{}{"n": "b"}, get.Data.Custom)
+
+	var ids []string
+	it := pn.GetAllUUIDMetadata().Limit(2).Iterate(nil)
+	for it.Next() {
+		ids = append(ids, it.Value().ID)
+	}
+	assert.Nil(it.Err())
+	assert.Equal([]string{"a", "b", "c"}, ids)
+
+	all, _, err := pn.GetAllUUIDMetadata().Limit(2).Count(true).Execute()
+	assert.Nil(err)
+	assert.Equal(3, all.TotalCount)
+	assert.Equal("2", all.Next)
+
+	_, _, err = pn.RemoveUUIDMetadata().UUID("b").Execute()
+	assert.Nil(err)
+	_, status, err := pn.GetUUIDMetadata().UUID("b").Execute()
+	assert.NotNil(err)
+	assert.Equal(404, status.StatusCode)
+}
+
+func TestEmulatorMemberships(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := newTestWebPubSub(t, New(), "uuid")
+	_, _, err := pn.SetChannelMetadata().Channel("room").Name("The room").Execute()
+	assert.Nil(err)
+
+	res, _, err := pn.SetMemberships().UUID("alice").Set([]webpubsub.WPSMembershipsSet{
+		{Channel: webpubsub.WPSMembershipsChannel{ID: "room"}, Custom: map[string]interface{}{"role": "admin"}},
+		{Channel: webpubsub.WPSMembershipsChannel{ID: "lobby"}},
+	}).Execute()
+	assert.Nil(err)
+	assert.Equal(2, len(res.Data))
+	assert.Equal("lobby", res.Data[0].Channel.ID)
+	assert.Equal("The room", res.Data[1].Channel.Name)
+	assert.Equal(map[string]interface{}{"role": "admin"}, res.Data[1].Custom)
+
+	members, _, err := pn.GetChannelMembers().Channel("room").Execute()
+	assert.Nil(err)
+	assert.Equal(1, len(members.Data))
+	assert.Equal("alice", members.Data[0].UUID.ID)
+
+	_, _, err = pn.RemoveMemberships().UUID("alice").Remove([]webpubsub.WPSMembershipsRemove{
+		{Channel: webpubsub.WPSMembershipsChannel{ID: "room"}},
+	}).Execute()
+	assert.Nil(err)
+
+	memberships, _, err := pn.GetMemberships().UUID("alice").Execute()
+	assert.Nil(err)
+	assert.Equal(1, len(memberships.Data))
+	assert.Equal("lobby", memberships.Data[0].Channel.ID)
+}
+
+func TestEmulatorObjectsEvents(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := newTestWebPubSub(t, New(), "uuid")
+	l := subscribe(t, pn, false, "room")
+
+	_, _, err := pn.SetChannelMetadata().Channel("room").Description("chat").Execute()
+	assert.Nil(err)
+
+	select {
+	case event := <-l.ChannelEvent:
+		assert.Equal(webpubsub.WPSObjectsEvent("set"), event.Event)
+		assert.Equal("room", event.ChannelID)
+		assert.Equal("chat", event.Description)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no channel event received")
+	}
+}
@@ -0,0 +1,313 @@
+package emulator
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// presenceEvent is the payload of a message on a presence channel.
+type presenceEvent struct {
+	Action    string          `json:"action"`
+	UUID      string          `json:"uuid"`
+	Timestamp int64           `json:"timestamp"`
+	Occupancy int             `json:"occupancy"`
+	Data      json.RawMessage `json:"data,omitempty"`
+}
+
+func presenceTimeout(heartbeat string) time.Duration {
+	seconds, err := strconv.Atoi(heartbeat)
+	if err != nil || seconds <= 0 {
+		seconds = defaultPresenceTimeout
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// announcePresence publishes a presence event on the presence channel of
+// channel, the caller holds the lock.
+func (e *Emulator) announcePresence(ks *keyset, channel, action, uuid string, data json.RawMessage) {
+	payload, _ := json.Marshal(presenceEvent{
+		Action:    action,
+		UUID:      uuid,
+		Timestamp: time.Now().Unix(),
+		Occupancy: len(ks.presence[channel]),
+		Data:      data,
+	})
+	e.publish(ks, &message{
+		Channel:    channel + presenceSuffix,
+		Payload:    payload,
+		Replicated: true,
+	})
+}
+
+// join marks uuid present in channel until the timeout expires.
+func (e *Emulator) join(ks *keyset, channel, uuid string, timeout time.Duration) {
+	if uuid == "" {
+		return
+	}
+	occupants, ok := ks.presence[channel]
+	if !ok {
+		occupants = make(map[string]time.Time)
+		ks.presence[channel] = occupants
+	}
+	_, present := occupants[uuid]
+	occupants[uuid] = time.Now().Add(timeout)
+	if !present {
+		e.announcePresence(ks, channel, "join", uuid, nil)
+	}
+}
+
+func (e *Emulator) leave(ks *keyset, channel, uuid, action string) {
+	if _, ok := ks.presence[channel][uuid]; !ok {
+		return
+	}
+	delete(ks.presence[channel], uuid)
+	if len(ks.presence[channel]) == 0 {
+		delete(ks.presence, channel)
+	}
+	delete(ks.states[channel], uuid)
+	e.announcePresence(ks, channel, action, uuid, nil)
+}
+
+// sweepPresence times out the occupants which stopped sending heartbeats.
+func (e *Emulator) sweepPresence(ks *keyset) {
+	now := time.Now()
+	for channel, occupants := range ks.presence {
+		for uuid, expires := range occupants {
+			if now.After(expires) {
+				e.leave(ks, channel, uuid, "timeout")
+			}
+		}
+	}
+}
+
+// groupChannels returns the channels of the channel groups.
+func groupChannels(ks *keyset, groups []string) []string {
+	var channels []string
+	for _, g := range groups {
+		for ch := range ks.groups[g] {
+			channels = append(channels, ch)
+		}
+	}
+	sort.Strings(channels)
+	return channels
+}
+
+// requestChannels returns the channels of the path and of the channel-group
+// query parameter.
+func requestChannels(ks *keyset, r *http.Request, p params) []string {
+	channels := splitList(p["channels"])
+	return append(channels, groupChannels(ks, splitList(r.URL.Query().Get("channel-group")))...)
+}
+
+func (e *Emulator) handleHeartbeat(w http.ResponseWriter, r *http.Request, p params) {
+	q := r.URL.Query()
+	uuid := q.Get("uuid")
+
+	e.Lock()
+	ks := e.keyset(p["sub"])
+	e.sweepPresence(ks)
+	channels := requestChannels(ks, r, p)
+	for _, ch := range channels {
+		e.join(ks, ch, uuid, presenceTimeout(q.Get("heartbeat")))
+	}
+	if state := q.Get("state"); state != "" {
+		e.setStates(ks, uuid, channels, state)
+	}
+	e.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":  200,
+		"message": "OK",
+		"service": "Presence",
+	})
+}
+
+func (e *Emulator) handleLeave(w http.ResponseWriter, r *http.Request, p params) {
+	e.Lock()
+	ks := e.keyset(p["sub"])
+	for _, ch := range requestChannels(ks, r, p) {
+		e.leave(ks, ch, r.URL.Query().Get("uuid"), "leave")
+	}
+	e.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":  200,
+		"message": "OK",
+		"action":  "leave",
+		"service": "Presence",
+	})
+}
+
+// occupants returns the here now entry of channel.
+func occupants(ks *keyset, channel string, withUUIDs, withState bool) map[string]interface{} {
+	var uuids []string
+	for uuid := range ks.presence[channel] {
+		uuids = append(uuids, uuid)
+	}
+	sort.Strings(uuids)
+
+	list := []interface{}{}
+	if withUUIDs {
+		for _, uuid := range uuids {
+			if !withState {
+				list = append(list, uuid)
+				continue
+			}
+			occupant := map[string]interface{}{"uuid": uuid}
+			if state, ok := ks.states[channel][uuid]; ok {
+				occupant["state"] = state
+			}
+			list = append(list, occupant)
+		}
+	}
+	return map[string]interface{}{
+		"occupancy": len(uuids),
+		"uuids":     list,
+	}
+}
+
+func (e *Emulator) handleHereNow(w http.ResponseWriter, r *http.Request, p params) {
+	q := r.URL.Query()
+	withUUIDs := q.Get("disable-uuids") != "1"
+	withState := q.Get("state") == "1"
+
+	e.Lock()
+	defer e.Unlock()
+	ks := e.keyset(p["sub"])
+	e.sweepPresence(ks)
+
+	_, global := p["channels"]
+	global = !global
+	channels := requestChannels(ks, r, p)
+	if global {
+		channels = nil
+		for ch := range ks.presence {
+			channels = append(channels, ch)
+		}
+	}
+
+	if !global && len(channels) == 1 && q.Get("channel-group") == "" {
+		resp := occupants(ks, channels[0], withUUIDs, withState)
+		resp["status"] = 200
+		resp["message"] = "OK"
+		resp["service"] = "Presence"
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+
+	result := make(map[string]interface{})
+	total := 0
+	for _, ch := range channels {
+		if len(ks.presence[ch]) == 0 {
+			continue
+		}
+		result[ch] = occupants(ks, ch, withUUIDs, withState)
+		total += len(ks.presence[ch])
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":  200,
+		"message": "OK",
+		"service": "Presence",
+		"payload": map[string]interface{}{
+			"channels":        result,
+			"total_channels":  len(result),
+			"total_occupancy": total,
+		},
+	})
+}
+
+func (e *Emulator) handleWhereNow(w http.ResponseWriter, r *http.Request, p params) {
+	e.Lock()
+	ks := e.keyset(p["sub"])
+	e.sweepPresence(ks)
+	channels := []string{}
+	for ch, occupants := range ks.presence {
+		if _, ok := occupants[p["uuid"]]; ok {
+			channels = append(channels, ch)
+		}
+	}
+	e.Unlock()
+	sort.Strings(channels)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":  200,
+		"message": "OK",
+		"service": "Presence",
+		"payload": map[string]interface{}{"channels": channels},
+	})
+}
+
+// setStates sets the state of uuid in the channels, the occupied channels
+// announce the change.
+func (e *Emulator) setStates(ks *keyset, uuid string, channels []string, state string) {
+	if !json.Valid([]byte(state)) {
+		return
+	}
+	for _, ch := range channels {
+		states, ok := ks.states[ch]
+		if !ok {
+			states = make(map[string]json.RawMessage)
+			ks.states[ch] = states
+		}
+		states[uuid] = json.RawMessage(state)
+		if _, present := ks.presence[ch][uuid]; present {
+			e.announcePresence(ks, ch, "state-change", uuid, json.RawMessage(state))
+		}
+	}
+}
+
+func (e *Emulator) handleSetState(w http.ResponseWriter, r *http.Request, p params) {
+	state := r.URL.Query().Get("state")
+	if !json.Valid([]byte(state)) {
+		writeError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	e.Lock()
+	ks := e.keyset(p["sub"])
+	e.setStates(ks, p["uuid"], requestChannels(ks, r, p), state)
+	e.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":  200,
+		"message": "OK",
+		"service": "Presence",
+		"payload": json.RawMessage(state),
+	})
+}
+
+func (e *Emulator) handleGetState(w http.ResponseWriter, r *http.Request, p params) {
+	uuid := p["uuid"]
+
+	e.Lock()
+	defer e.Unlock()
+	ks := e.keyset(p["sub"])
+	channels := requestChannels(ks, r, p)
+	state := func(ch string) json.RawMessage {
+		if s, ok := ks.states[ch][uuid]; ok {
+			return s
+		}
+		return json.RawMessage("{}")
+	}
+
+	resp := map[string]interface{}{
+		"status":  200,
+		"message": "OK",
+		"service": "Presence",
+		"uuid":    uuid,
+	}
+	if len(channels) == 1 && r.URL.Query().Get("channel-group") == "" {
+		resp["channel"] = channels[0]
+		resp["payload"] = state(channels[0])
+	} else {
+		states := make(map[string]json.RawMessage)
+		for _, ch := range channels {
+			states[ch] = state(ch)
+		}
+		resp["payload"] = map[string]interface{}{"channels": states}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
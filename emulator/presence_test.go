@@ -0,0 +1,110 @@
+package emulator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	webpubsub "github.com/webpubsub/sdk-go/v7"
+)
+
+func nextPresence(t *testing.T, l *webpubsub.Listener) *webpubsub.WPSPresence {
+	select {
+	case p := <-l.Presence:
+		return p
+	case <-time.After(5 * time.Second):
+		t.Fatal("no presence event received")
+	}
+	return nil
+}
+
+func TestEmulatorPresenceEvents(t *testing.T) {
+	assert := assert.New(t)
+
+	e := New()
+	watcher := newTestWebPubSub(t, e, "watcher")
+	l := subscribe(t, watcher, true, "room")
+
+	join := nextPresence(t, l)
+	assert.Equal("join", join.Event)
+	assert.Equal("watcher", join.UUID)
+
+	other := newTestWebPubSub(t, e, "other")
+	_, _, err := other.Heartbeat().Channels([]string{"room"}).Execute()
+	assert.Nil(err)
+	join = nextPresence(t, l)
+	assert.Equal("join", join.Event)
+	assert.Equal("other", join.UUID)
+	assert.Equal(2, join.Occupancy)
+
+	_, _, err = other.SetState().Channels([]string{"room"}).State(map[string]interface{}{"mood": "happy"}).Execute()
+	assert.Nil(err)
+	change := nextPresence(t, l)
+	assert.Equal("state-change", change.Event)
+	assert.Equal(map[string]interface{}{"mood": "happy"}, change.State)
+
+	_, err = other.Leave().Channels([]string{"room"}).Execute()
+	assert.Nil(err)
+	leave := nextPresence(t, l)
+	assert.Equal("leave", leave.Event)
+	assert.Equal(1, leave.Occupancy)
+}
+
+func TestEmulatorHereNowWhereNow(t *testing.T) {
+	assert := assert.New(t)
+
+	e := New()
+	alice := newTestWebPubSub(t, e, "alice")
+	bob := newTestWebPubSub(t, e, "bob")
+	_, _, err := alice.Heartbeat().Channels([]string{"a", "b"}).Execute()
+	assert.Nil(err)
+	_, _, err = bob.Heartbeat().Channels([]string{"a"}).Execute()
+	assert.Nil(err)
+	_, _, err = bob.SetState().Channels([]string{"a"}).State(map[string]interface{}{"k": "v"}).Execute()
+	assert.Nil(err)
+
+	res, _, err := alice.HereNow().Channels([]string{"a"}).IncludeState(true).Execute()
+	assert.Nil(err)
+	assert.Equal(1, res.TotalChannels)
+	assert.Equal(2, res.TotalOccupancy)
+	assert.Equal(2, len(res.Channels[0].Occupants))
+	assert.Equal("bob", res.Channels[0].Occupants[1].UUID)
+	assert.Equal(map[string]interface{}{"k": "v"}, res.Channels[0].Occupants[1].State)
+
+	res, _, err = alice.HereNow().Channels([]string{"a", "b"}).Execute()
+	assert.Nil(err)
+	assert.Equal(2, res.TotalChannels)
+	assert.Equal(3, res.TotalOccupancy)
+
+	res, _, err = alice.HereNow().Execute()
+	assert.Nil(err)
+	assert.Equal(2, res.TotalChannels)
+
+	where, _, err := alice.WhereNow().Execute()
+	assert.Nil(err)
+	assert.Equal([]string{"a", "b"}, where.Channels)
+
+	state, _, err := alice.GetState().UUID("bob").Channels([]string{"a"}).Execute()
+	assert.Nil(err)
+	assert.Equal(map[string]interface{}{"a": map[string]interface{}{"k": "v"}}, state.State)
+}
+
+func TestEmulatorPresenceTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	e := New()
+	pn := newTestWebPubSub(t, e, "uuid")
+	pn.Config.PresenceTimeout = 1
+	_, _, err := pn.Heartbeat().Channels([]string{"ch"}).Execute()
+	assert.Nil(err)
+
+	e.Lock()
+	for uuid := range e.keyset("demo").presence["ch"] {
+		e.keyset("demo").presence["ch"][uuid] = time.Now().Add(-time.Second)
+	}
+	e.Unlock()
+
+	res, _, err := pn.HereNow().Channels([]string{"ch"}).Execute()
+	assert.Nil(err)
+	assert.Equal(0, res.TotalOccupancy)
+}
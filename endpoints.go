@@ -3,7 +3,6 @@ package webpubsub
 import (
 	"bytes"
 	"fmt"
-	"log"
 	"mime/multipart"
 	"net/http"
 	"net/url"
@@ -28,6 +27,7 @@ type endpointOpts interface {
 	httpMethod() string
 	operationType() OperationType
 	telemetryManager() *TelemetryManager
+	middlewares() []Middleware
 	tokenManager() *TokenManager
 }
 
@@ -96,7 +96,7 @@ func buildURL(o endpointOpts) (*url.URL, error) {
 		return &url.URL{}, err
 	}
 
-	if v := o.tokenManager().GetToken(); v != "" && query.Get("auth") == "" {
+	if v := requestToken(o); v != "" && query.Get("auth") == "" {
 		query.Set("auth", v)
 	} else if v := o.config().AuthKey; v != "" && query.Get("auth") == "" {
 		query.Set("auth", v)
@@ -112,7 +112,6 @@ func buildURL(o endpointOpts) (*url.URL, error) {
 			signedInput += fmt.Sprintf("%s\n", path)
 
 			signedInput += utils.PreparePamParams(query)
-			o.config().Log.Println("signedInput:", signedInput)
 
 			signature = utils.GetHmacSha256(o.config().SecretKey, signedInput)
 		} else {
@@ -205,7 +204,7 @@ func createSignatureV2(o endpointOpts, path string, query *url.Values) string {
 	if err == nil {
 		bodyString = string(b)
 	} else {
-		o.config().Log.Println("buildBody error", err.Error())
+		endpointLogger(o).Error("buildBody error", "error", err)
 	}
 
 	sig := createSignatureV2FromStrings(
@@ -215,21 +214,20 @@ func createSignatureV2(o endpointOpts, path string, query *url.Values) string {
 		fmt.Sprintf("%s", path),
 		utils.PreparePamParams(query),
 		bodyString,
-		o.config().Log,
+		endpointLogger(o),
 	)
 
-	o.config().Log.Println("signaturev2:", sig)
 	return sig
 }
 
-func createSignatureV2FromStrings(httpMethod, pubKey, secKey, path, query, body string, l *log.Logger) string {
+func createSignatureV2FromStrings(httpMethod, pubKey, secKey, path, query, body string, l *sdkLogger) string {
 	signedInputV2 := httpMethod + "\n"
 	signedInputV2 += pubKey + "\n"
 	signedInputV2 += path + "\n"
 	signedInputV2 += query + "\n"
 	signedInputV2 += body
 	if l != nil {
-		l.Println("signedInputV2:", signedInputV2)
+		l.Debug("signedInputV2", "input", signedInputV2)
 	}
 
 	encoded := utils.GetHmacSha256(secKey, signedInputV2)
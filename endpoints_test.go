@@ -69,6 +69,10 @@ func (o *fakeEndpointOpts) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *fakeEndpointOpts) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *fakeEndpointOpts) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
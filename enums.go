@@ -16,6 +16,12 @@ type OperationType int
 // ReconnectionPolicy is used as an enum to catgorize the reconnection policies
 type ReconnectionPolicy int
 
+// ConnectionState is used as an enum to catgorize the states of the subscribe connection
+type ConnectionState int
+
+// OverflowPolicy is used as an enum to catgorize what happens when a queue of received events is full
+type OverflowPolicy int
+
 // WPSPushType is used as an enum to catgorize the available Push Types
 type WPSPushType int
 
@@ -168,15 +174,54 @@ const (
 	// WPSNoStubMatchedCategory as the StatusCategory means an unknown status category event occurred.
 	WPSNoStubMatchedCategory
 	// WPSReconnectedCategory as the StatusCategory means that the network was reconnected (after a disconnection).
-	// Applicable on for WPSLinearPolicy and WPSExponentialPolicycy.
+	// Applicable on for WPSLinearPolicy, WPSExponentialPolicycy and a Config.Reconnector.
 	WPSReconnectedCategory
 	// WPSReconnectionAttemptsExhausted as the StatusCategory means that the reconnection attempts
 	// to reconnect to the network were exhausted. All channels would be unsubscribed at this point.
-	// Applicable on for WPSLinearPolicy and WPSExponentialPolicycy.
-	// Reconnection attempts are set in the config: MaximumReconnectionRetries.
+	// Applicable on for WPSLinearPolicy, WPSExponentialPolicycy and a Config.Reconnector.
+	// Reconnection attempts are set in the config: MaximumReconnectionRetries, or the MaxAttempts of the Reconnector.
 	WPSReconnectionAttemptsExhausted
 	// WPSRequestMessageCountExceededCategory is fired when the MessageQueueOverflowCount limit is exceeded by the number of messages received in a single subscribe request
+	// With Config.RecoverMessageCountExceeded the messages skipped by the server are fetched and delivered first.
 	WPSRequestMessageCountExceededCategory
+	// WPSPublishQueueDeliveredCategory is fired when an item of the offline publish queue is delivered.
+	WPSPublishQueueDeliveredCategory
+	// WPSPublishQueueAbandonedCategory is fired when an item of the offline publish queue is dropped
+	// because of a non retryable error or after PublishQueueMaxAttempts failed deliveries.
+	WPSPublishQueueAbandonedCategory
+	// WPSConnectingCategory as the StatusCategory means the subscribe loop is (re)started for a new channel mix
+	// and waits for its first response.
+	WPSConnectingCategory
+	// WPSQueueOverflowCategory is fired when the OverflowPolicy dropped received messages or events,
+	// DroppedMessages and DroppedEvents hold the counts since the previous one.
+	WPSQueueOverflowCategory
+)
+
+const (
+	// WPSOverflowBlock as the OverflowPolicy waits for room in the queue, a slow listener slows down
+	// the subscribe loop.
+	WPSOverflowBlock OverflowPolicy = iota
+	// WPSOverflowDropOldest as the OverflowPolicy drops the oldest queued event to make room.
+	WPSOverflowDropOldest
+	// WPSOverflowDropNewest as the OverflowPolicy drops the event which doesn't fit in the queue.
+	WPSOverflowDropNewest
+)
+
+const (
+	// WPSDisconnectedState as the ConnectionState means nothing is subscribed.
+	WPSDisconnectedState ConnectionState = iota
+	// WPSConnectingState as the ConnectionState means the subscribe loop waits for its first response.
+	WPSConnectingState
+	// WPSConnectedState as the ConnectionState means the subscribe loop receives the messages.
+	WPSConnectedState
+	// WPSReconnectingState as the ConnectionState means the network was lost and the reconnection manager
+	// tries to reach the server.
+	WPSReconnectingState
+	// WPSDisconnectedWithErrorState as the ConnectionState means the subscribe loop was stopped by an error
+	// like WPSAccessDeniedCategory.
+	WPSDisconnectedWithErrorState
+	// WPSFailedState as the ConnectionState means the reconnection attempts were exhausted.
+	WPSFailedState
 )
 
 const (
@@ -310,6 +355,8 @@ const (
 	WPSPublishFileMessageOperation
 	// WPSAccessManagerRevokeToken is the enum used for Grant Token remove requests.
 	WPSAccessManagerRevokeToken
+	// WPSAccessManagerAudit is the enum used for the Access Manager Audit operation.
+	WPSAccessManagerAudit
 )
 
 const (
@@ -447,12 +494,67 @@ func (c StatusCategory) String() string {
 	case WPSNoStubMatchedCategory:
 		return "No Stub Matched"
 
+	case WPSPublishQueueDeliveredCategory:
+		return "Publish Queue Delivered"
+
+	case WPSPublishQueueAbandonedCategory:
+		return "Publish Queue Abandoned"
+
+	case WPSConnectingCategory:
+		return "Connecting"
+
+	case WPSQueueOverflowCategory:
+		return "Queue Overflow"
+
 	default:
 		return "No Stub Matched"
 
 	}
 }
 
+func (p OverflowPolicy) String() string {
+	switch p {
+	case WPSOverflowBlock:
+		return "Block"
+
+	case WPSOverflowDropOldest:
+		return "Drop Oldest"
+
+	case WPSOverflowDropNewest:
+		return "Drop Newest"
+
+	default:
+		return "Unknown"
+
+	}
+}
+
+func (s ConnectionState) String() string {
+	switch s {
+	case WPSDisconnectedState:
+		return "Disconnected"
+
+	case WPSConnectingState:
+		return "Connecting"
+
+	case WPSConnectedState:
+		return "Connected"
+
+	case WPSReconnectingState:
+		return "Reconnecting"
+
+	case WPSDisconnectedWithErrorState:
+		return "Disconnected With Error"
+
+	case WPSFailedState:
+		return "Failed"
+
+	default:
+		return "Unknown"
+
+	}
+}
+
 func (t OperationType) String() string {
 	switch t {
 	case WPSSubscribeOperation:
@@ -521,6 +623,9 @@ func (t OperationType) String() string {
 	case WPSAccessManagerRevoke:
 		return "Revoke"
 
+	case WPSAccessManagerAudit:
+		return "Audit"
+
 	case WPSDeleteMessagesOperation:
 		return "Delete messages"
 
@@ -0,0 +1,142 @@
+package webpubsub
+
+import (
+	"strconv"
+)
+
+// FetchAllResult is a message streamed by StreamAll, the last result carries
+// the error which stopped the walk, if any.
+type FetchAllResult struct {
+	Channel string
+	Item    FetchResponseItem
+	Error   error
+}
+
+// FetchAll walks the history of each channel from Start to End page by page
+// and calls the callback for every message. The messages are passed newest
+// first, or oldest first when Reverse is set. Count sets the page size.
+// Returning false from the callback stops the walk.
+func (b *fetchBuilder) FetchAll(callback func(channel string, item FetchResponseItem) bool) error {
+	for _, channel := range b.opts.Channels {
+		stopped, err := b.fetchAllChannel(channel, callback)
+		if err != nil || stopped {
+			return err
+		}
+	}
+	return nil
+}
+
+// StreamAll works like FetchAll and streams the messages through the returned
+// channel, which is closed at the end of the walk. Cancelling the context of
+// the builder stops the walk.
+func (b *fetchBuilder) StreamAll(bufferSize int) <-chan FetchAllResult {
+	results := make(chan FetchAllResult, bufferSize)
+	ctx := b.opts.ctx
+
+	go func() {
+		defer close(results)
+		err := b.FetchAll(func(channel string, item FetchResponseItem) bool {
+			result := FetchAllResult{
+				Channel: channel,
+				Item:    item,
+			}
+			if ctx == nil {
+				results <- result
+				return true
+			}
+			select {
+			case results <- result:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+		if err == nil {
+			return
+		}
+		result := FetchAllResult{
+			Error: err,
+		}
+		if ctx == nil {
+			results <- result
+			return
+		}
+		select {
+		case results <- result:
+		case <-ctx.Done():
+		}
+	}()
+
+	return results
+}
+
+func (b *fetchBuilder) fetchAllChannel(channel string, callback func(channel string, item FetchResponseItem) bool) (bool, error) {
+	opts := *b.opts
+	opts.Channels = []string{channel}
+	if maxCount := opts.maxCount(); opts.Count <= 0 || opts.Count > maxCount {
+		opts.Count = maxCount
+	}
+
+	var seen map[string]bool
+	for {
+		if opts.ctx != nil {
+			select {
+			case <-opts.ctx.Done():
+				return true, opts.ctx.Err()
+			default:
+			}
+		}
+
+		resp, _, err := (&fetchBuilder{opts: &opts}).Execute()
+		if err != nil {
+			return false, err
+		}
+		items := resp.Messages[channel]
+		if len(items) == 0 {
+			return false, nil
+		}
+
+		// the pages are in chronological order, the walk goes backwards
+		// unless reversed.
+		page := make(map[string]bool, len(items))
+		var boundary int64
+		for i := range items {
+			item := items[i]
+			if !opts.Reverse {
+				item = items[len(items)-1-i]
+			}
+			page[item.Timetoken] = true
+			if timetoken, err := strconv.ParseInt(item.Timetoken, 10, 64); err == nil {
+				if boundary == 0 || (opts.Reverse && timetoken > boundary) || (!opts.Reverse && timetoken < boundary) {
+					boundary = timetoken
+				}
+			}
+			if seen[item.Timetoken] {
+				continue
+			}
+			if !callback(channel, item) {
+				return true, nil
+			}
+		}
+
+		if len(items) < opts.Count || boundary == 0 {
+			return false, nil
+		}
+		if opts.Reverse {
+			// End is inclusive, the next page starts with the boundary message,
+			// which is skipped as seen. Start still bounds the walk.
+			if opts.setEnd && boundary <= opts.End {
+				boundary = opts.End + 1
+			}
+			opts.End = boundary
+			opts.setEnd = true
+		} else {
+			if opts.setStart && boundary == opts.Start {
+				return false, nil
+			}
+			opts.Start = boundary
+			opts.setStart = true
+		}
+		seen = page
+	}
+}
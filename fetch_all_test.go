@@ -0,0 +1,114 @@
+package webpubsub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/webpubsub/sdk-go/v7/tests/stubs"
+)
+
+var fetchAllIgnoreQueryKeys = []string{"uuid", "pnsdk", "signature", "timestamp", "l_hist", "include_meta", "include_message_type", "include_uuid"}
+
+func fetchAllStub(query, body string) *stubs.Stub {
+	return &stubs.Stub{
+		Method:             "GET",
+		Path:               "/v3/history/sub-key/demo/channel/ch",
+		Query:              query,
+		ResponseBody:       `{"status": 200, "error": false, "error_message": "", "channels": {"ch":` + body + `}}`,
+		IgnoreQueryKeys:    fetchAllIgnoreQueryKeys,
+		ResponseStatusCode: 200,
+	}
+}
+
+func TestFetchAllBackwardsDeduplicates(t *testing.T) {
+	assert := assert.New(t)
+
+	interceptor := stubs.NewInterceptor()
+	interceptor.AddStub(fetchAllStub("max=2&reverse=false", `[{"message":"m3","timetoken":"30"},{"message":"m4","timetoken":"40"}]`))
+	interceptor.AddStub(fetchAllStub("max=2&reverse=false&start=30", `[{"message":"m2","timetoken":"20"},{"message":"m3","timetoken":"30"}]`))
+	interceptor.AddStub(fetchAllStub("max=2&reverse=false&start=20", `[{"message":"m1","timetoken":"10"}]`))
+	pn := newTestWebPubSub(interceptor.Transport)
+
+	var messages []interface{}
+	err := pn.Fetch().Channels([]string{"ch"}).Count(2).FetchAll(func(channel string, item FetchResponseItem) bool {
+		assert.Equal("ch", channel)
+		messages = append(messages, item.Message)
+		return true
+	})
+	assert.Nil(err)
+	assert.Equal([]interface{}{"m4", "m3", "m2", "m1"}, messages)
+}
+
+func TestFetchAllReverse(t *testing.T) {
+	assert := assert.New(t)
+
+	interceptor := stubs.NewInterceptor()
+	interceptor.AddStub(fetchAllStub("max=2&reverse=true", `[{"message":"m1","timetoken":"10"},{"message":"m2","timetoken":"20"}]`))
+	interceptor.AddStub(fetchAllStub("max=2&reverse=true&end=20", `[{"message":"m2","timetoken":"20"},{"message":"m3","timetoken":"30"}]`))
+	interceptor.AddStub(fetchAllStub("max=2&reverse=true&end=30", `[{"message":"m3","timetoken":"30"}]`))
+	pn := newTestWebPubSub(interceptor.Transport)
+
+	var messages []interface{}
+	err := pn.Fetch().Channels([]string{"ch"}).Count(2).Reverse(true).FetchAll(func(channel string, item FetchResponseItem) bool {
+		messages = append(messages, item.Message)
+		return true
+	})
+	assert.Nil(err)
+	assert.Equal([]interface{}{"m1", "m2", "m3"}, messages)
+}
+
+func TestFetchAllEarlyStop(t *testing.T) {
+	assert := assert.New(t)
+
+	interceptor := stubs.NewInterceptor()
+	interceptor.AddStub(fetchAllStub("max=2&reverse=false", `[{"message":"m3","timetoken":"30"},{"message":"m4","timetoken":"40"}]`))
+	pn := newTestWebPubSub(interceptor.Transport)
+
+	count := 0
+	err := pn.Fetch().Channels([]string{"ch"}).Count(2).FetchAll(func(channel string, item FetchResponseItem) bool {
+		count++
+		return false
+	})
+	assert.Nil(err)
+	assert.Equal(1, count)
+}
+
+func TestStreamAllError(t *testing.T) {
+	assert := assert.New(t)
+
+	interceptor := stubs.NewInterceptor()
+	interceptor.AddStub(fetchAllStub("max=2&reverse=false", `[{"message":"m3","timetoken":"30"},{"message":"m4","timetoken":"40"}]`))
+	pn := newTestWebPubSub(interceptor.Transport)
+
+	var messages []interface{}
+	var lastErr error
+	for result := range pn.Fetch().Channels([]string{"ch"}).Count(2).StreamAll(0) {
+		if result.Error != nil {
+			lastErr = result.Error
+			continue
+		}
+		messages = append(messages, result.Item.Message)
+	}
+	assert.Equal([]interface{}{"m4", "m3"}, messages)
+	assert.NotNil(lastErr)
+}
+
+func TestStreamAllCancelled(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := newTestWebPubSub(stubs.NewInterceptor().Transport)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results := pn.FetchWithContext(ctx).Channels([]string{"ch"}).StreamAll(0)
+	// nobody receives the error of the cancelled walk, which ends anyway.
+	time.Sleep(100 * time.Millisecond)
+	select {
+	case _, ok := <-results:
+		assert.False(ok)
+	case <-time.After(time.Second):
+		t.Fatal("results not closed")
+	}
+}
@@ -216,15 +216,7 @@ func (o *fetchOpts) buildQuery() (*url.Values, error) {
 		q.Set("end", strconv.FormatInt(o.End, 10))
 	}
 
-	maxCount := maxCountFetch
-
-	if o.WithMessageActions {
-		maxCount = maxCountHistoryWithMessageActions
-	}
-
-	if len(o.Channels) > 1 {
-		maxCount = maxCountFetchMoreThanOneChannel
-	}
+	maxCount := o.maxCount()
 
 	if o.Count > 0 && o.Count <= maxCount {
 		q.Set("max", strconv.Itoa(o.Count))
@@ -242,6 +234,17 @@ func (o *fetchOpts) buildQuery() (*url.Values, error) {
 	return q, nil
 }
 
+// maxCount returns the max number of messages per channel the server returns.
+func (o *fetchOpts) maxCount() int {
+	if len(o.Channels) > 1 {
+		return maxCountFetchMoreThanOneChannel
+	}
+	if o.WithMessageActions {
+		return maxCountHistoryWithMessageActions
+	}
+	return maxCountFetch
+}
+
 func (o *fetchOpts) jobQueue() chan *JobQItem {
 	return o.webpubsub.jobQueue
 }
@@ -278,12 +281,20 @@ func (o *fetchOpts) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *fetchOpts) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *fetchOpts) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
 
+func (o *fetchOpts) tokenResources() (channels, groups, uuids []string) {
+	return o.Channels, nil, nil
+}
+
 func (o *fetchOpts) parseMessageActions(actions interface{}) map[string]WPSHistoryMessageActionsTypeMap {
-	o.webpubsub.Config.Log.Println(actions)
+	o.webpubsub.Config.logger(WPSRequestSubsystem).Debug("actions", "actions", actions)
 	resp := make(map[string]WPSHistoryMessageActionsTypeMap)
 
 	if actions != nil {
@@ -291,8 +302,8 @@ func (o *fetchOpts) parseMessageActions(actions interface{}) map[string]WPSHisto
 
 		for actionType, action := range actionsMap {
 
-			o.webpubsub.Config.Log.Println("action:", action)
-			o.webpubsub.Config.Log.Println("actionType:", actionType) //reaction2
+			o.webpubsub.Config.logger(WPSRequestSubsystem).Debug("action", "action", action)
+			o.webpubsub.Config.logger(WPSRequestSubsystem).Debug("action", "actionType", actionType) //reaction2
 
 			actionMap := action.(map[string]interface{})
 
@@ -300,8 +311,8 @@ func (o *fetchOpts) parseMessageActions(actions interface{}) map[string]WPSHisto
 				messageActionsTypeMap := WPSHistoryMessageActionsTypeMap{}
 				messageActionsTypeMap.ActionsTypeValues = make(map[string][]WPSHistoryMessageActionTypeVal, len(actionMap))
 				for actionVal, val := range actionMap {
-					o.webpubsub.Config.Log.Println("actionVal:", actionVal) // smiley_face
-					o.webpubsub.Config.Log.Println("val:", val)
+					o.webpubsub.Config.logger(WPSRequestSubsystem).Debug("action", "actionVal", actionVal) // smiley_face
+					o.webpubsub.Config.logger(WPSRequestSubsystem).Debug("action", "val", val)
 
 					actionValInt := val.([]interface{})
 					if actionValInt != nil {
@@ -311,8 +322,8 @@ func (o *fetchOpts) parseMessageActions(actions interface{}) map[string]WPSHisto
 
 							pv := WPSHistoryMessageActionTypeVal{}
 							for actionParamName, actionParamVal := range actionParam.(map[string]interface{}) {
-								o.webpubsub.Config.Log.Println("actionParamName", actionParamName)
-								o.webpubsub.Config.Log.Println("actionParamVal", actionParamVal)
+								o.webpubsub.Config.logger(WPSRequestSubsystem).Debug("action", "actionParamName", actionParamName)
+								o.webpubsub.Config.logger(WPSRequestSubsystem).Debug("action", "actionParamVal", actionParamVal)
 								switch actionParamName {
 								case "uuid":
 									pv.UUID = actionParamVal.(string)
@@ -336,23 +347,29 @@ func (o *fetchOpts) parseMessageActions(actions interface{}) map[string]WPSHisto
 }
 
 //{"status": 200, "error": false, "error_message": "", "channels": {"ch1":[{"message_type": "", "message": {"text": "hey"}, "timetoken": "15959610984115342", "meta": "", "uuid": "db9c5e39-7c95-40f5-8d71-125765b6f561"}]}}
-func (o *fetchOpts) fetchMessages(channels map[string]interface{}) map[string][]FetchResponseItem {
+func (o *fetchOpts) fetchMessages(channels map[string]interface{}, rawChannels map[string][]fetchRawItem) map[string][]FetchResponseItem {
 	messages := make(map[string][]FetchResponseItem, len(channels))
 
 	for channel, histResponseSliceMap := range channels {
 		if histResponseMap, ok2 := histResponseSliceMap.([]interface{}); ok2 {
-			o.webpubsub.Config.Log.Printf("Channel:%s, count:%d\n", channel, len(histResponseMap))
+			o.webpubsub.Config.logger(WPSRequestSubsystem).Debug("fetch", "channel", channel, "count", len(histResponseMap))
 			items := make([]FetchResponseItem, len(histResponseMap))
 			count := 0
 
-			for _, val := range histResponseMap {
+			for i, val := range histResponseMap {
 				if histResponse, ok3 := val.(map[string]interface{}); ok3 {
-					msg, _ := parseCipherInterface(histResponse["message"], o.webpubsub.Config)
+					var raw []byte
+					if i < len(rawChannels[channel]) {
+						raw = rawChannels[channel][i].Message
+					}
+					msg, rawMessage, keyIndex, _ := parseCipherInterfaceWithRaw(histResponse["message"], raw, o.webpubsub.Config)
 
 					histItem := FetchResponseItem{
-						Message:   msg,
-						Timetoken: histResponse["timetoken"].(string),
-						Meta:      histResponse["meta"],
+						Message:         msg,
+						Timetoken:       histResponse["timetoken"].(string),
+						Meta:            histResponse["meta"],
+						DecryptKeyIndex: keyIndex,
+						rawMessage:      rawMessage,
 					}
 					if d, ok := histResponse["message_type"]; ok {
 						switch v := d.(type) {
@@ -363,14 +380,14 @@ func (o *fetchOpts) fetchMessages(channels map[string]interface{}) map[string][]
 							if err == nil {
 								histItem.MessageType = int(t)
 							} else {
-								o.webpubsub.Config.Log.Printf("MessageType conversion error.")
+								o.webpubsub.Config.logger(WPSRequestSubsystem).Warn("MessageType conversion error")
 							}
 						default:
-							o.webpubsub.Config.Log.Printf("histResponse message_type type %vv", d)
+							o.webpubsub.Config.logger(WPSRequestSubsystem).Debug("histResponse message_type", "type", d)
 							if v != nil {
-								o.webpubsub.Config.Log.Printf("histResponse message_type type %vv", reflect.TypeOf(v).Kind())
+								o.webpubsub.Config.logger(WPSRequestSubsystem).Debug("histResponse message_type", "type", reflect.TypeOf(v).Kind())
 							} else {
-								o.webpubsub.Config.Log.Printf("histResponse message_type nil")
+								o.webpubsub.Config.logger(WPSRequestSubsystem).Debug("histResponse message_type nil")
 							}
 						}
 					}
@@ -384,21 +401,22 @@ func (o *fetchOpts) fetchMessages(channels map[string]interface{}) map[string][]
 						if f.Name != "" && f.ID != "" {
 							histItem.File = f
 							histItem.Message = m
+							histItem.rawMessage = nil
 						}
 					}
 
 					items[count] = histItem
-					o.webpubsub.Config.Log.Printf("Channel:%s, count:%d %d\n", channel, count, len(items))
+					o.webpubsub.Config.logger(WPSRequestSubsystem).Debug("fetch", "channel", channel, "count", count, "items", len(items))
 					count++
 				} else {
-					o.webpubsub.Config.Log.Printf("histResponse not a map %v\n", histResponse)
+					o.webpubsub.Config.logger(WPSRequestSubsystem).Warn("histResponse not a map", "histResponse", histResponse)
 					continue
 				}
 			}
 			messages[channel] = items
-			o.webpubsub.Config.Log.Printf("Channel:%s, count:%d\n", channel, len(messages[channel]))
+			o.webpubsub.Config.logger(WPSRequestSubsystem).Debug("fetch", "channel", channel, "count", len(messages[channel]))
 		} else {
-			o.webpubsub.Config.Log.Printf("histResponseSliceMap not an []interface %v\n", histResponseSliceMap)
+			o.webpubsub.Config.logger(WPSRequestSubsystem).Warn("histResponseSliceMap not an []interface", "histResponseSliceMap", histResponseSliceMap)
 			continue
 		}
 	}
@@ -420,22 +438,36 @@ func newFetchResponse(jsonBytes []byte, o *fetchOpts,
 		return emptyFetchResp, status, e
 	}
 
+	var raw fetchRawResponse
+	if err := json.Unmarshal(jsonBytes, &raw); err != nil {
+		o.webpubsub.Config.logger(WPSRequestSubsystem).Warn("raw messages unmarshal error", "error", err)
+	}
+
 	if result, ok := value.(map[string]interface{}); ok {
-		o.webpubsub.Config.Log.Println(result["channels"])
+		o.webpubsub.Config.logger(WPSRequestSubsystem).Debug("fetch", "channels", result["channels"])
 		if channels, ok1 := result["channels"].(map[string]interface{}); ok1 {
 			if channels != nil {
-				resp.Messages = o.fetchMessages(channels)
+				resp.Messages = o.fetchMessages(channels, raw.Channels)
 			} else {
-				o.webpubsub.Config.Log.Printf("type assertion to map failed %v\n", result)
+				o.webpubsub.Config.logger(WPSRequestSubsystem).Warn("type assertion to map failed", "result", result)
 			}
 		}
 	} else {
-		o.webpubsub.Config.Log.Printf("type assertion to map failed %v\n", value)
+		o.webpubsub.Config.logger(WPSRequestSubsystem).Warn("type assertion to map failed", "value", value)
 	}
 
 	return resp, status, nil
 }
 
+// fetchRawResponse retains the raw message bytes of a Fetch response.
+type fetchRawResponse struct {
+	Channels map[string][]fetchRawItem `json:"channels"`
+}
+
+type fetchRawItem struct {
+	Message json.RawMessage `json:"message"`
+}
+
 // FetchResponse is the response to Fetch request. It contains a map of type FetchResponseItem
 type FetchResponse struct {
 	Messages map[string][]FetchResponseItem
@@ -450,6 +482,12 @@ type FetchResponseItem struct {
 	Timetoken      string                                     `json:"timetoken"`
 	UUID           string                                     `json:"uuid"`
 	MessageType    int                                        `json:"message_type"`
+	// DecryptKeyIndex is the keyring index of the key which decrypted the
+	// message: 0 for the current key, i for DecryptCipherKeys[i-1], -1 when
+	// it was not encrypted.
+	DecryptKeyIndex int `json:"-"`
+
+	rawMessage []byte
 }
 
 // WPSHistoryMessageActionsTypeMap is the struct used in the Fetch request that includes Message Actions
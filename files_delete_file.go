@@ -179,10 +179,18 @@ func (o *deleteFileOpts) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *deleteFileOpts) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *deleteFileOpts) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
 
+func (o *deleteFileOpts) tokenResources() (channels, groups, uuids []string) {
+	return []string{o.Channel}, nil, nil
+}
+
 // WPSDeleteFileResponse is the File Upload API Response for Delete file operation
 type WPSDeleteFileResponse struct {
 	status int `json:"status"`
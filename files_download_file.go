@@ -8,9 +8,6 @@ import (
 	"mime/multipart"
 	"net/http"
 	"net/url"
-	"strconv"
-
-	"github.com/webpubsub/sdk-go/v7/utils"
 )
 
 var emptyDownloadFileResponse *WPSDownloadFileResponse
@@ -94,40 +91,40 @@ func (b *downloadFileBuilder) Execute() (*WPSDownloadFileResponse, StatusRespons
 		Origin:           b.opts.config().Origin,
 		UUID:             b.opts.config().UUID,
 	}
-	b.opts.webpubsub.Config.Log.Printf("u.RequestURI(): %s", u.RequestURI())
+	b.opts.webpubsub.Config.logger(WPSRequestSubsystem).Debug("download file", "uri", u.RequestURI())
 	resp, err := b.opts.client().Get(u.RequestURI())
 	if err != nil {
-		b.opts.webpubsub.Config.Log.Printf("err %s", err)
+		b.opts.webpubsub.Config.logger(WPSRequestSubsystem).Error("download file error", "error", err)
 		return nil, stat, err
 	}
 	if resp.StatusCode != 200 {
 		stat.StatusCode = resp.StatusCode
 		return nil, stat, err
 	}
-	contentLenEnc, err := strconv.ParseInt(string(resp.Header.Get("Content-Length")), 10, 64)
-	if err != nil {
-		b.opts.webpubsub.Config.Log.Printf("err in parsing content length %s", err)
-		return nil, stat, err
+	keyring := b.opts.webpubsub.Config.decryptKeyring()
+	if b.opts.CipherKey != "" {
+		keyring = append([]decryptionKey{{
+			index:  0,
+			module: NewLegacyCryptoModule(b.opts.CipherKey, true),
+		}}, keyring...)
 	}
 
 	var respDL *WPSDownloadFileResponse
-	if b.opts.CipherKey != "" {
-		r, w := io.Pipe()
-		utils.DecryptFile(b.opts.CipherKey, contentLenEnc, resp.Body, w)
-		respDL = &WPSDownloadFileResponse{
-			File: r,
+	if len(keyring) > 0 {
+		defer resp.Body.Close()
+		r, keyIndex, err := decryptStreamWithKeyring(keyring, resp.Body)
+		if err != nil {
+			b.opts.webpubsub.Config.logger(WPSCryptoSubsystem).Warn("err in decrypting file", "error", err)
+			return nil, stat, err
 		}
-
-	} else if b.opts.webpubsub.Config.CipherKey != "" {
-		r, w := io.Pipe()
-		utils.DecryptFile(b.opts.webpubsub.Config.CipherKey, contentLenEnc, resp.Body, w)
 		respDL = &WPSDownloadFileResponse{
-			File: r,
+			File:            r,
+			DecryptKeyIndex: keyIndex,
 		}
-
 	} else {
 		respDL = &WPSDownloadFileResponse{
-			File: resp.Body,
+			File:            resp.Body,
+			DecryptKeyIndex: notDecrypted,
 		}
 	}
 	return respDL, stat, nil
@@ -228,14 +225,26 @@ func (o *downloadFileOpts) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *downloadFileOpts) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *downloadFileOpts) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
 
+func (o *downloadFileOpts) tokenResources() (channels, groups, uuids []string) {
+	return []string{o.Channel}, nil, nil
+}
+
 // WPSDownloadFileResponse is the File Upload API Response for Get Spaces
 type WPSDownloadFileResponse struct {
 	status int       `json:"status"`
 	File   io.Reader `json:"data"`
+	// DecryptKeyIndex is the keyring index of the key which decrypted the
+	// file: 0 for the current key, the CipherKey of the request or else of the
+	// Config, i for DecryptCipherKeys[i-1], -1 when it was not encrypted.
+	DecryptKeyIndex int `json:"-"`
 }
 
 func newWPSDownloadFileResponse(jsonBytes []byte, o *downloadFileOpts,
@@ -184,10 +184,18 @@ func (o *getFileURLOpts) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *getFileURLOpts) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *getFileURLOpts) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
 
+func (o *getFileURLOpts) tokenResources() (channels, groups, uuids []string) {
+	return []string{o.Channel}, nil, nil
+}
+
 // WPSGetFileURLResponse is the File Upload API Response for Get Spaces
 type WPSGetFileURLResponse struct {
 	URL string `json:"location"`
@@ -88,6 +88,26 @@ func (b *listFilesBuilder) Execute() (*WPSListFilesResponse, StatusResponse, err
 	return newWPSListFilesResponse(rawJSON, b.opts, status)
 }
 
+// Iterate returns an iterator over the files of all the pages, the page
+// size is set with Limit. The requests are made with ctx when it is not nil.
+func (b *listFilesBuilder) Iterate(ctx Context) *ListFilesIterator {
+	opts := *b.opts
+	if ctx != nil {
+		opts.ctx = ctx
+	}
+	iterator := &ListFilesIterator{}
+	iterator.pageIterator = newPageIterator(opts.ctx, opts.Next, func(cursor string) (int, string, error) {
+		opts.Next = cursor
+		resp, _, err := (&listFilesBuilder{opts: &opts}).Execute()
+		if err != nil {
+			return 0, "", err
+		}
+		iterator.page = resp.Data
+		return len(resp.Data), resp.Next, nil
+	})
+	return iterator
+}
+
 type listFilesOpts struct {
 	webpubsub *WebPubSub
 
@@ -177,10 +197,18 @@ func (o *listFilesOpts) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *listFilesOpts) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *listFilesOpts) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
 
+func (o *listFilesOpts) tokenResources() (channels, groups, uuids []string) {
+	return []string{o.Channel}, nil, nil
+}
+
 // WPSListFilesResponse is the File Upload API Response for Get Spaces
 type WPSListFilesResponse struct {
 	status int           `json:"status"`
@@ -204,3 +232,25 @@ func newWPSListFilesResponse(jsonBytes []byte, o *listFilesOpts,
 
 	return resp, status, nil
 }
+
+// ListFilesIterator iterates over the results of ListFiles across the pages.
+type ListFilesIterator struct {
+	*pageIterator
+	page []WPSFileInfo
+}
+
+// Next advances to the next item, requesting the next page when needed.
+// It returns false when all the items are consumed or on error.
+func (it *ListFilesIterator) Next() bool {
+	return it.next()
+}
+
+// Value returns the current item.
+func (it *ListFilesIterator) Value() WPSFileInfo {
+	return it.page[it.index]
+}
+
+// Err returns the error which stopped the iteration, if any.
+func (it *ListFilesIterator) Err() error {
+	return it.err
+}
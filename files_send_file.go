@@ -192,7 +192,7 @@ func (o *sendFileOpts) buildBody() ([]byte, error) {
 	jsonEncBytes, errEnc := json.Marshal(b)
 
 	if errEnc != nil {
-		o.webpubsub.Config.Log.Printf("ERROR: Serialization error: %s\n", errEnc.Error())
+		o.webpubsub.Config.logger(WPSRequestSubsystem).Error("serialization error", "error", errEnc)
 		return []byte{}, errEnc
 	}
 	return jsonEncBytes, nil
@@ -226,10 +226,18 @@ func (o *sendFileOpts) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *sendFileOpts) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *sendFileOpts) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
 
+func (o *sendFileOpts) tokenResources() (channels, groups, uuids []string) {
+	return []string{o.Channel}, nil, nil
+}
+
 // WPSSendFileResponseForS3 is the File Upload API Response for SendFile.
 type WPSSendFileResponseForS3 struct {
 	status            int                  `json:"status"`
@@ -264,7 +272,7 @@ func newWPSSendFileResponse(jsonBytes []byte, o *sendFileOpts,
 	}
 	_, s3ResponseStatus, errS3Response := s.File(o.File).CipherKey(o.CipherKey).FileUploadRequestData(respForS3.FileUploadRequest).Execute()
 	if s3ResponseStatus.StatusCode != 204 {
-		o.webpubsub.Config.Log.Printf("s3ResponseStatus: %d", s3ResponseStatus.StatusCode)
+		o.webpubsub.Config.logger(WPSRequestSubsystem).Debug("send file to S3", "statusCode", s3ResponseStatus.StatusCode)
 		return emptySendFileResponse, s3ResponseStatus, errS3Response
 	}
 
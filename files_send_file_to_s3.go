@@ -11,7 +11,6 @@ import (
 	"os"
 
 	"github.com/webpubsub/sdk-go/v7/pnerr"
-	"github.com/webpubsub/sdk-go/v7/utils"
 )
 
 var emptySendFileToS3Response *WPSSendFileToS3Response
@@ -147,7 +146,7 @@ func (o *sendFileToS3Opts) buildBodyMultipartFileUpload() (bytes.Buffer, *multip
 	writer := multipart.NewWriter(&fileBody)
 
 	for _, v := range o.FileUploadRequestData.FormFields {
-		o.webpubsub.Config.Log.Printf("FormFields: Key: %s Value: %s\n", v.Key, v.Value)
+		o.webpubsub.Config.logger(WPSRequestSubsystem).Debug("FormFields", "key", v.Key, "value", v.Value)
 		if v.Key == "Content-Type" {
 			v.Value = contentType
 		}
@@ -157,26 +156,35 @@ func (o *sendFileToS3Opts) buildBodyMultipartFileUpload() (bytes.Buffer, *multip
 	filePart, errFilePart := writer.CreateFormFile("file", fileInfo.Name())
 
 	if errFilePart != nil {
-		o.webpubsub.Config.Log.Printf("ERROR: writer CreateFormFile: %s\n", errFilePart.Error())
+		o.webpubsub.Config.logger(WPSRequestSubsystem).Error("writer CreateFormFile", "error", errFilePart)
 		return bytes.Buffer{}, writer, s, errFilePart
 	}
 
+	module := o.webpubsub.Config.cryptoModule()
 	if o.CipherKey != "" {
-		utils.EncryptFile(o.CipherKey, []byte{}, filePart, o.File)
-	} else if o.webpubsub.Config.CipherKey != "" {
-		utils.EncryptFile(o.webpubsub.Config.CipherKey, []byte{}, filePart, o.File)
-	} else {
-		_, errIOCopy := io.Copy(filePart, o.File)
-
-		if errIOCopy != nil {
-			o.webpubsub.Config.Log.Printf("ERROR: io Copy error: %s\n", errIOCopy.Error())
-			return bytes.Buffer{}, writer, s, errIOCopy
+		module = NewLegacyCryptoModule(o.CipherKey, true)
+	}
+
+	var fileReader io.Reader = o.File
+	if module != nil {
+		encrypted, errEncrypt := module.EncryptStream(o.File)
+		if errEncrypt != nil {
+			o.webpubsub.Config.logger(WPSCryptoSubsystem).Error("file encryption error", "error", errEncrypt)
+			return bytes.Buffer{}, writer, s, errEncrypt
 		}
+		fileReader = encrypted
+	}
+
+	_, errIOCopy := io.Copy(filePart, fileReader)
+
+	if errIOCopy != nil {
+		o.webpubsub.Config.logger(WPSRequestSubsystem).Error("io Copy error", "error", errIOCopy)
+		return bytes.Buffer{}, writer, s, errIOCopy
 	}
 
 	errWriterClose := writer.Close()
 	if errWriterClose != nil {
-		o.webpubsub.Config.Log.Printf("ERROR: Writer close: %s\n", errWriterClose.Error())
+		o.webpubsub.Config.logger(WPSRequestSubsystem).Error("writer close", "error", errWriterClose)
 		return bytes.Buffer{}, writer, s, errWriterClose
 	}
 
@@ -208,6 +216,10 @@ func (o *sendFileToS3Opts) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *sendFileToS3Opts) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *sendFileToS3Opts) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
@@ -228,7 +240,7 @@ func newWPSSendFileToS3Response(jsonBytes []byte, o *sendFileToS3Opts,
 
 		return emptySendFileToS3Response, status, e
 	}
-	o.webpubsub.Config.Log.Printf("newWPSSendFileToS3Response status.StatusCode==> %d", status.StatusCode)
+	o.webpubsub.Config.logger(WPSRequestSubsystem).Debug("newWPSSendFileToS3Response", "statusCode", status.StatusCode)
 
 	return resp, status, nil
 }
@@ -172,8 +172,11 @@ func (o *fireOpts) buildPath() (string, error) {
 	var message []byte
 	var err error
 
-	if cipherKey := o.webpubsub.Config.CipherKey; cipherKey != "" {
-		msg := utils.EncryptString(cipherKey, string(message), o.webpubsub.Config.UseRandomInitializationVector)
+	if module := o.webpubsub.Config.cryptoModule(); module != nil {
+		msg, errEncrypt := encryptString(module, string(message))
+		if errEncrypt != nil {
+			return "", errEncrypt
+		}
 
 		o.Message = []byte(msg)
 	}
@@ -241,8 +244,11 @@ func (o *fireOpts) buildBody() ([]byte, error) {
 			}
 		}
 
-		if cipherKey := o.webpubsub.Config.CipherKey; cipherKey != "" {
-			enc := utils.EncryptString(cipherKey, string(msg), o.webpubsub.Config.UseRandomInitializationVector)
+		if module := o.webpubsub.Config.cryptoModule(); module != nil {
+			enc, err := encryptString(module, string(msg))
+			if err != nil {
+				return []byte{}, err
+			}
 			msg, err := utils.ValueAsString(enc)
 			if err != nil {
 				return []byte{}, err
@@ -281,10 +287,22 @@ func (o *fireOpts) operationType() OperationType {
 	return WPSFireOperation
 }
 
+func (o *fireOpts) setTraceParent(traceParent string) {
+	o.Meta = withTraceParent(o.Meta, traceParent)
+}
+
 func (o *fireOpts) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *fireOpts) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *fireOpts) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
+
+func (o *fireOpts) tokenResources() (channels, groups, uuids []string) {
+	return []string{o.Channel}, nil, nil
+}
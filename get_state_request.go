@@ -198,10 +198,22 @@ func (o *getStateOpts) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *getStateOpts) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *getStateOpts) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
 
+func (o *getStateOpts) tokenResources() (channels, groups, uuids []string) {
+	uuid := o.UUID
+	if uuid == "" {
+		uuid = o.webpubsub.Config.UUID
+	}
+	return o.Channels, o.ChannelGroups, []string{uuid}
+}
+
 // GetStateResponse is the struct returned when the Execute function of GetState is called.
 type GetStateResponse struct {
 	State map[string]interface{}
@@ -216,9 +216,21 @@ func ParseGrantResources(res GrantResources, token string, timetoken int64, ttl
 		}
 	}
 
+	uuids := make(map[string]UUIDPermissionsWithToken, len(res.UUIDs))
+	for k, v := range res.UUIDs {
+		uuids[k] = UUIDPermissionsWithToken{
+			Permissions:  parseGrantPerms(v, WPSUUIDs).(UUIDPermissions),
+			BitMaskPerms: v,
+			Token:        token,
+			Timestamp:    timetoken,
+			TTL:          ttl,
+		}
+	}
+
 	g := GrantResourcesWithPermissions{
 		Channels: channels,
 		Groups:   groups,
+		UUIDs:    uuids,
 	}
 	return &g
 }
@@ -275,12 +287,22 @@ type GroupPermissionsWithToken struct {
 	TTL          int
 }
 
+// UUIDPermissionsWithToken is used for uuids resource type permissions
+type UUIDPermissionsWithToken struct {
+	Permissions  UUIDPermissions
+	BitMaskPerms int64
+	Token        string
+	Timestamp    int64
+	TTL          int
+}
+
 // GrantResourcesWithPermissions is used as a common struct to store all resource type permissions
 type GrantResourcesWithPermissions struct {
 	Channels        map[string]ChannelPermissionsWithToken
 	Groups          map[string]GroupPermissionsWithToken
 	ChannelsPattern map[string]ChannelPermissionsWithToken
 	GroupsPattern   map[string]GroupPermissionsWithToken
+	UUIDs           map[string]UUIDPermissionsWithToken
 }
 
 // PermissionsBody is the struct used to decode the server response
@@ -336,6 +336,10 @@ func (o *grantOpts) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *grantOpts) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *grantOpts) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
@@ -604,8 +608,11 @@ func createWPSAccessManagerKeyData(value interface{}, entityData *WPSPAMEntityDa
 	}
 
 	if val, ok := valueMap["ttl"]; ok {
-		parsedVal, _ := val.(int)
-		entityData.TTL = parsedVal
+		parsedVal, _ := val.(float64)
+		keyData.TTL = int(parsedVal)
+		if writeToEntityData {
+			entityData.TTL = int(parsedVal)
+		}
 	}
 	return keyData
 }
@@ -196,7 +196,7 @@ func (o *grantTokenOpts) setBitmask(value bool, bitmask WPSGrantBitMask, bm int6
 	if value {
 		bm |= int64(bitmask)
 	}
-	o.webpubsub.Config.Log.Println(fmt.Sprintf("bmVal: %t %d %d", value, bitmask, bm))
+	o.webpubsub.Config.logger(WPSRequestSubsystem).Debug(fmt.Sprintf("bmVal: %t %d %d", value, bitmask, bm))
 	return bm
 }
 
@@ -217,7 +217,7 @@ func (o *grantTokenOpts) parseResourcePermissions(resource interface{}, resource
 				bmVal = o.setBitmask(v.Update, WPSUpdate, bmVal)
 				bmVal = o.setBitmask(v.Manage, WPSManage, bmVal)
 				bmVal = o.setBitmask(v.Get, WPSGet, bmVal)
-				o.webpubsub.Config.Log.Println("bmVal ChannelPermissions:", bmVal)
+				o.webpubsub.Config.logger(WPSRequestSubsystem).Debug("bmVal ChannelPermissions", "bmVal", bmVal)
 				r[k] = bmVal
 			}
 			return r
@@ -233,7 +233,7 @@ func (o *grantTokenOpts) parseResourcePermissions(resource interface{}, resource
 				bmVal = int64(0)
 				bmVal = o.setBitmask(v.Read, WPSRead, bmVal)
 				bmVal = o.setBitmask(v.Manage, WPSManage, bmVal)
-				o.webpubsub.Config.Log.Println("bmVal GroupPermissions:", bmVal)
+				o.webpubsub.Config.logger(WPSRequestSubsystem).Debug("bmVal GroupPermissions", "bmVal", bmVal)
 				r[k] = bmVal
 			}
 			return r
@@ -250,7 +250,7 @@ func (o *grantTokenOpts) parseResourcePermissions(resource interface{}, resource
 				bmVal = o.setBitmask(v.Get, WPSGet, bmVal)
 				bmVal = o.setBitmask(v.Update, WPSUpdate, bmVal)
 				bmVal = o.setBitmask(v.Delete, WPSDelete, bmVal)
-				o.webpubsub.Config.Log.Println("bmVal UUIDPermissions:", bmVal)
+				o.webpubsub.Config.logger(WPSRequestSubsystem).Debug("bmVal UUIDPermissions", "bmVal", bmVal)
 				r[k] = bmVal
 			}
 			return r
@@ -301,7 +301,7 @@ func (o *grantTokenOpts) buildBody() ([]byte, error) {
 		AuthorizedUUID: o.AuthorizedUUID,
 	}
 
-	o.webpubsub.Config.Log.Println("permissions: ", permissions)
+	o.webpubsub.Config.logger(WPSRequestSubsystem).Debug("permissions", "permissions", permissions)
 
 	ttl := -1
 	if o.setTTL {
@@ -318,7 +318,7 @@ func (o *grantTokenOpts) buildBody() ([]byte, error) {
 	jsonEncBytes, errEnc := json.Marshal(b)
 
 	if errEnc != nil {
-		o.webpubsub.Config.Log.Printf("ERROR: Serialization error: %s\n", errEnc.Error())
+		o.webpubsub.Config.logger(WPSRequestSubsystem).Error("serialization error", "error", errEnc)
 		return []byte{}, errEnc
 	}
 	return jsonEncBytes, nil
@@ -352,6 +352,10 @@ func (o *grantTokenOpts) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *grantTokenOpts) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *grantTokenOpts) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
@@ -380,7 +384,7 @@ func newGrantTokenResponse(b *grantTokenBuilder, jsonBytes []byte, status Status
 		return emptyWPSGrantTokenResponse, status, e
 	}
 
-	b.opts.webpubsub.tokenManager.StoreToken(resp.Data.Token)
+	b.opts.webpubsub.tokenManager.storeIssuedToken(resp.Data.Token)
 
 	return resp, status, nil
 }
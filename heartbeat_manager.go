@@ -22,6 +22,8 @@ type HeartbeatManager struct {
 	hbRunning                 bool
 	queryParam                map[string]string
 	state                     map[string]interface{}
+	failedHeartbeats          int
+	retryDelay                time.Duration
 }
 
 func newHeartbeatManager(pn *WebPubSub, context Context) *HeartbeatManager {
@@ -47,7 +49,7 @@ func (m *HeartbeatManager) nonIndependentHeartbeatLoop() {
 
 	if reqSentAt > 0 {
 		timediff := int64(m.webpubsub.Config.HeartbeatInterval) - (timeNow - reqSentAt)
-		m.webpubsub.Config.Log.Println(fmt.Sprintf("heartbeat timediff: %d", timediff))
+		m.webpubsub.Config.logger(WPSHeartbeatSubsystem).Debug(fmt.Sprintf("heartbeat timediff: %d", timediff))
 		m.webpubsub.subscriptionManager.hbDataMutex.Lock()
 		m.webpubsub.subscriptionManager.requestSentAt = 0
 		m.webpubsub.subscriptionManager.hbDataMutex.Unlock()
@@ -56,7 +58,7 @@ func (m *HeartbeatManager) nonIndependentHeartbeatLoop() {
 			m.hbTimer.Stop()
 			m.Unlock()
 
-			m.webpubsub.Config.Log.Println(fmt.Sprintf("heartbeat sleeping timediff: %d", timediff))
+			m.webpubsub.Config.logger(WPSHeartbeatSubsystem).Debug(fmt.Sprintf("heartbeat sleeping timediff: %d", timediff))
 			waitTimer := time.NewTicker(time.Duration(timediff) * time.Second)
 
 			var wg sync.WaitGroup
@@ -67,17 +69,17 @@ func (m *HeartbeatManager) nonIndependentHeartbeatLoop() {
 					select {
 					case <-m.hbDone:
 						wg.Done()
-						m.webpubsub.Config.Log.Println("nonIndependentHeartbeatLoop: breaking out to HeartbeatLabel")
+						m.webpubsub.Config.logger(WPSHeartbeatSubsystem).Debug("nonIndependentHeartbeatLoop: breaking out to HeartbeatLabel")
 						return
 					case <-waitTimerCh:
-						m.webpubsub.Config.Log.Println("nonIndependentHeartbeatLoop: waitTimerCh done")
+						m.webpubsub.Config.logger(WPSHeartbeatSubsystem).Debug("nonIndependentHeartbeatLoop: waitTimerCh done")
 						wg.Done()
 						return
 					}
 				}
 			}()
 			wg.Wait()
-			m.webpubsub.Config.Log.Println("heartbeat sleep end")
+			m.webpubsub.Config.logger(WPSHeartbeatSubsystem).Debug("heartbeat sleep end")
 
 			m.Lock()
 			m.hbTimer = time.NewTicker(time.Duration(m.webpubsub.Config.HeartbeatInterval) * time.Second)
@@ -110,7 +112,7 @@ func (m *HeartbeatManager) readHeartBeatTimer(runIndependentOfSubscribe bool) {
 					m.nonIndependentHeartbeatLoop()
 				}
 			case <-m.hbDone:
-				m.webpubsub.Config.Log.Println("heartbeat: loop after stop")
+				m.webpubsub.Config.logger(WPSHeartbeatSubsystem).Debug("heartbeat: loop after stop")
 				break HeartbeatLabel
 			}
 		}
@@ -130,7 +132,7 @@ func (m *HeartbeatManager) startHeartbeatTimer(runIndependentOfSubscribe bool) {
 	m.hbRunning = true
 	m.Unlock()
 
-	m.webpubsub.Config.Log.Println("heartbeat: new timer", m.webpubsub.Config.HeartbeatInterval)
+	m.webpubsub.Config.logger(WPSHeartbeatSubsystem).Debug("heartbeat: new timer", "interval", m.webpubsub.Config.HeartbeatInterval)
 	m.webpubsub.Config.Lock()
 	presenceTimeout := m.webpubsub.Config.PresenceTimeout
 	heartbeatInterval := m.webpubsub.Config.HeartbeatInterval
@@ -163,17 +165,17 @@ func (m *HeartbeatManager) stopHeartbeat(runIndependentOfSubscribe bool, skipRun
 			return
 		}
 	}
-	m.webpubsub.Config.Log.Println("heartbeat: loop: stopping...")
+	m.webpubsub.Config.logger(WPSHeartbeatSubsystem).Debug("heartbeat: loop: stopping...")
 
 	m.Lock()
 	if m.hbTimer != nil {
 		m.hbTimer.Stop()
-		m.webpubsub.Config.Log.Println("heartbeat: loop: timer stopped")
+		m.webpubsub.Config.logger(WPSHeartbeatSubsystem).Debug("heartbeat: loop: timer stopped")
 	}
 
 	if m.hbDone != nil {
 		m.hbDone <- true
-		m.webpubsub.Config.Log.Println("heartbeat: loop: done channel stopped")
+		m.webpubsub.Config.logger(WPSHeartbeatSubsystem).Debug("heartbeat: loop: done channel stopped")
 	}
 	m.hbRunning = false
 	m.Unlock()
@@ -199,21 +201,21 @@ func (m *HeartbeatManager) performHeartbeatLoop() error {
 	presenceGroups := m.prepareList(m.heartbeatGroups)
 	stateStorage = m.state
 	queryParam := m.queryParam
-	m.webpubsub.Config.Log.Println("performHeartbeatLoop: count presenceChannels, presenceGroups", len(presenceChannels), len(presenceGroups))
+	m.webpubsub.Config.logger(WPSHeartbeatSubsystem).Debug("performHeartbeatLoop", "presenceChannels", len(presenceChannels), "presenceGroups", len(presenceGroups))
 	m.RUnlock()
 
 	if (len(presenceChannels) == 0) && (len(presenceGroups) == 0) {
-		m.webpubsub.Config.Log.Println("performHeartbeatLoop: count presenceChannels, presenceGroups nil")
+		m.webpubsub.Config.logger(WPSHeartbeatSubsystem).Debug("performHeartbeatLoop: count presenceChannels, presenceGroups nil")
 		presenceChannels = m.webpubsub.subscriptionManager.stateManager.prepareChannelList(false)
 		presenceGroups = m.webpubsub.subscriptionManager.stateManager.prepareGroupList(false)
 		stateStorage = m.webpubsub.subscriptionManager.stateManager.createStatePayload()
 		queryParam = nil
 
-		m.webpubsub.Config.Log.Println("performHeartbeatLoop: count sub presenceChannels, presenceGroups", len(presenceChannels), len(presenceGroups))
+		m.webpubsub.Config.logger(WPSHeartbeatSubsystem).Debug("performHeartbeatLoop: subscribed", "presenceChannels", len(presenceChannels), "presenceGroups", len(presenceGroups))
 	}
 
 	if len(presenceChannels) <= 0 && len(presenceGroups) <= 0 {
-		m.webpubsub.Config.Log.Println("heartbeat: no channels left")
+		m.webpubsub.Config.logger(WPSHeartbeatSubsystem).Debug("heartbeat: no channels left")
 		go m.stopHeartbeat(true, true)
 		return nil
 	}
@@ -233,12 +235,14 @@ func (m *HeartbeatManager) performHeartbeatLoop() error {
 			Error:     true,
 			ErrorData: err,
 		}
-		m.webpubsub.Config.Log.Println("performHeartbeatLoop: err", err, pnStatus)
+		m.webpubsub.Config.logger(WPSHeartbeatSubsystem).Warn("heartbeat failed", "error", err, "status", pnStatus)
 
 		m.webpubsub.subscriptionManager.listenerManager.announceStatus(pnStatus)
+		m.scheduleRetry(true)
 
 		return err
 	}
+	m.scheduleRetry(false)
 
 	pnStatus := &WPSStatus{
 		Category:   WPSUnknownCategory,
@@ -246,9 +250,42 @@ func (m *HeartbeatManager) performHeartbeatLoop() error {
 		Operation:  WPSHeartBeatOperation,
 		StatusCode: status.StatusCode,
 	}
-	m.webpubsub.Config.Log.Println("performHeartbeatLoop: err", err, pnStatus)
+	m.webpubsub.Config.logger(WPSHeartbeatSubsystem).Debug("heartbeat", "status", pnStatus)
 
 	m.webpubsub.subscriptionManager.listenerManager.announceStatus(pnStatus)
 
 	return nil
 }
+
+// scheduleRetry brings the next heartbeat forward with the delay of the
+// Reconnector after a failure, and restores HeartbeatInterval after a
+// success. The delays never exceed HeartbeatInterval.
+func (m *HeartbeatManager) scheduleRetry(failed bool) {
+	reconnector := m.webpubsub.Config.reconnector()
+	interval := time.Duration(m.webpubsub.Config.HeartbeatInterval) * time.Second
+	if reconnector == nil || interval <= 0 {
+		return
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	if !failed {
+		if m.failedHeartbeats > 0 && m.hbRunning && m.hbTimer != nil {
+			m.hbTimer.Reset(interval)
+		}
+		m.failedHeartbeats = 0
+		m.retryDelay = 0
+		return
+	}
+
+	m.failedHeartbeats++
+	delay, ok := reconnector.NextDelay(m.failedHeartbeats, m.retryDelay)
+	if !ok || delay <= 0 || delay > interval {
+		delay = interval
+	}
+	m.retryDelay = delay
+	m.webpubsub.Config.logger(WPSHeartbeatSubsystem).Debug("heartbeat retry", "attempt", m.failedHeartbeats, "delay", delay)
+	if m.hbRunning && m.hbTimer != nil {
+		m.hbTimer.Reset(delay)
+	}
+}
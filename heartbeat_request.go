@@ -191,6 +191,14 @@ func (o *heartbeatOpts) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *heartbeatOpts) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *heartbeatOpts) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
+
+func (o *heartbeatOpts) tokenResources() (channels, groups, uuids []string) {
+	return o.Channels, o.ChannelGroups, nil
+}
@@ -205,10 +205,18 @@ func (o *hereNowOpts) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *hereNowOpts) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *hereNowOpts) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
 
+func (o *hereNowOpts) tokenResources() (channels, groups, uuids []string) {
+	return o.Channels, o.ChannelGroups, nil
+}
+
 // HereNowResponse is the struct returned when the Execute function of HereNow is called.
 type HereNowResponse struct {
 	TotalChannels  int
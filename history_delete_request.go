@@ -187,10 +187,18 @@ func (o *historyDeleteOpts) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *historyDeleteOpts) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *historyDeleteOpts) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
 
+func (o *historyDeleteOpts) tokenResources() (channels, groups, uuids []string) {
+	return []string{o.Channel}, nil, nil
+}
+
 // HistoryDeleteResponse is the struct returned when Delete Messages is called.
 type HistoryDeleteResponse struct {
 }
@@ -234,10 +234,18 @@ func (o *historyOpts) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *historyOpts) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *historyOpts) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
 
+func (o *historyOpts) tokenResources() (channels, groups, uuids []string) {
+	return []string{o.Channel}, nil, nil
+}
+
 // HistoryResponse is used to store the response from the History request.
 type HistoryResponse struct {
 	Messages       []HistoryResponseItem
@@ -250,10 +258,16 @@ type HistoryResponseItem struct {
 	Message   interface{}
 	Meta      interface{}
 	Timetoken int64
+	// DecryptKeyIndex is the keyring index of the key which decrypted the
+	// message: 0 for the current key, i for DecryptCipherKeys[i-1], -1 when
+	// it was not encrypted.
+	DecryptKeyIndex int
+
+	rawMessage []byte
 }
 
 func logAndCreateNewResponseParsingError(o *historyOpts, err error, jsonBody string, message string) *pnerr.ResponseParsingError {
-	o.webpubsub.Config.Log.Println(err.Error())
+	o.webpubsub.Config.logger(WPSRequestSubsystem).Warn("history unmarshal error", "error", err)
 	e := pnerr.NewResponseParsingError(message,
 		ioutil.NopCloser(bytes.NewBufferString(jsonBody)), err)
 	return e
@@ -268,11 +282,18 @@ func getHistoryItemsWithoutTimetoken(historyResponseRaw []byte, o *historyOpts,
 		return nil, e
 	}
 
+	var rawItems []json.RawMessage
+	_ = json.Unmarshal(historyResponseRaw, &rawItems)
+
 	items := make([]HistoryResponseItem, len(historyResponseItems))
 
 	for i, v := range historyResponseItems {
-		o.webpubsub.Config.Log.Println(v)
-		items[i].Message, _ = parseCipherInterface(v, o.webpubsub.Config)
+		o.webpubsub.Config.logger(WPSRequestSubsystem).Debug("history item", "item", v)
+		var raw []byte
+		if i < len(rawItems) {
+			raw = rawItems[i]
+		}
+		items[i].Message, items[i].rawMessage, items[i].DecryptKeyIndex, _ = parseCipherInterfaceWithRaw(v, raw, o.webpubsub.Config)
 	}
 	return items, nil
 }
@@ -280,17 +301,26 @@ func getHistoryItemsWithoutTimetoken(historyResponseRaw []byte, o *historyOpts,
 func getHistoryItemsWithTimetoken(historyResponseItems []HistoryResponseItem, o *historyOpts, historyResponseRaw []byte, jsonBytes []byte) ([]HistoryResponseItem, *pnerr.ResponseParsingError) {
 	items := make([]HistoryResponseItem, len(historyResponseItems))
 
+	var rawItems []struct {
+		Message json.RawMessage `json:"message"`
+	}
+	_ = json.Unmarshal(historyResponseRaw, &rawItems)
+
 	b := false
 
 	for i, v := range historyResponseItems {
 		if v.Message != nil {
-			o.webpubsub.Config.Log.Println(v.Message)
-			items[i].Message, _ = parseCipherInterface(v.Message, o.webpubsub.Config)
+			o.webpubsub.Config.logger(WPSRequestSubsystem).Debug("history item", "message", v.Message)
+			var raw []byte
+			if i < len(rawItems) {
+				raw = rawItems[i].Message
+			}
+			items[i].Message, items[i].rawMessage, items[i].DecryptKeyIndex, _ = parseCipherInterfaceWithRaw(v.Message, raw, o.webpubsub.Config)
 
-			o.webpubsub.Config.Log.Println(v.Timetoken)
+			o.webpubsub.Config.logger(WPSRequestSubsystem).Debug("history item", "timetoken", v.Timetoken)
 			items[i].Timetoken = v.Timetoken
 
-			o.webpubsub.Config.Log.Println(v.Meta)
+			o.webpubsub.Config.logger(WPSRequestSubsystem).Debug("history item", "meta", v.Meta)
 			items[i].Meta = v.Meta
 		} else {
 			b = true
@@ -320,9 +350,9 @@ func newHistoryResponse(jsonBytes []byte, o *historyOpts,
 	}
 
 	if historyResponseRaw != nil && len(historyResponseRaw) > 2 {
-		o.webpubsub.Config.Log.Println("M", string(historyResponseRaw[0]))
-		o.webpubsub.Config.Log.Println("T1", string(historyResponseRaw[1]))
-		o.webpubsub.Config.Log.Println("T2", string(historyResponseRaw[2]))
+		o.webpubsub.Config.logger(WPSRequestSubsystem).Debug("history", "messages", string(historyResponseRaw[0]))
+		o.webpubsub.Config.logger(WPSRequestSubsystem).Debug("history", "startTimetoken", string(historyResponseRaw[1]))
+		o.webpubsub.Config.logger(WPSRequestSubsystem).Debug("history", "endTimetoken", string(historyResponseRaw[2]))
 
 		var historyResponseItems []HistoryResponseItem
 		var items []HistoryResponseItem
@@ -330,7 +360,7 @@ func newHistoryResponse(jsonBytes []byte, o *historyOpts,
 		err1 := json.Unmarshal(historyResponseRaw[0], &historyResponseItems)
 		var e *pnerr.ResponseParsingError
 		if err1 != nil {
-			o.webpubsub.Config.Log.Println(err1.Error())
+			o.webpubsub.Config.logger(WPSRequestSubsystem).Warn("history unmarshal error", "error", err1)
 
 			items, e = getHistoryItemsWithoutTimetoken(historyResponseRaw[0], o, err1, jsonBytes)
 			if e != nil {
@@ -344,9 +374,9 @@ func newHistoryResponse(jsonBytes []byte, o *historyOpts,
 		}
 		if items != nil {
 			resp.Messages = items
-			o.webpubsub.Config.Log.Printf("returning []interface, %v\n", items)
+			o.webpubsub.Config.logger(WPSRequestSubsystem).Debug("returning []interface", "items", items)
 		} else {
-			o.webpubsub.Config.Log.Println("items nil")
+			o.webpubsub.Config.logger(WPSRequestSubsystem).Debug("items nil")
 		}
 
 		startTimetoken, err := strconv.ParseInt(string(historyResponseRaw[1]), 10, 64)
@@ -420,6 +420,26 @@ func TestHistoryEncrypt(t *testing.T) {
 	pnconfig.CipherKey = ""
 }
 
+func TestHistoryEncryptRotatedKey(t *testing.T) {
+	assert := assert.New(t)
+	pnconfig.CipherKey = "newCipher"
+	pnconfig.DecryptCipherKeys = []string{"otherCipher", "testCipher"}
+	useRandomInitializationVector := pnconfig.UseRandomInitializationVector
+	pnconfig.UseRandomInitializationVector = false
+
+	jsonString := []byte(`[["MnwzPGdVgz2osQCIQJviGg=="],14991775432719844,14991868111600528]`)
+
+	resp, _, err := newHistoryResponse(jsonString, initHistoryOpts(), fakeResponseState)
+	assert.Nil(err)
+
+	messages := resp.Messages
+	assert.Equal("hey", messages[0].Message)
+	assert.Equal(2, messages[0].DecryptKeyIndex)
+	pnconfig.CipherKey = ""
+	pnconfig.DecryptCipherKeys = nil
+	pnconfig.UseRandomInitializationVector = useRandomInitializationVector
+}
+
 func TestHistoryEncryptSlice(t *testing.T) {
 	assert := assert.New(t)
 	pnconfig.CipherKey = "testCipher"
@@ -147,6 +147,14 @@ func (o *leaveOpts) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *leaveOpts) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *leaveOpts) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
+
+func (o *leaveOpts) tokenResources() (channels, groups, uuids []string) {
+	return o.Channels, o.ChannelGroups, nil
+}
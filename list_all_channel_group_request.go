@@ -151,10 +151,18 @@ func (o *allChannelGroupOpts) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *allChannelGroupOpts) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *allChannelGroupOpts) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
 
+func (o *allChannelGroupOpts) tokenResources() (channels, groups, uuids []string) {
+	return nil, []string{o.ChannelGroup}, nil
+}
+
 // AllChannelGroupResponse is the struct returned when the Execute function of List All Channel Groups is called.
 type AllChannelGroupResponse struct {
 	Channels     []string
@@ -221,6 +221,10 @@ func (o *listPushProvisionsRequestOpts) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *listPushProvisionsRequestOpts) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *listPushProvisionsRequestOpts) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
@@ -4,6 +4,10 @@ import (
 	"sync"
 )
 
+// defaultListenerQueueSize is the number of events buffered per EventListener
+// when Config.ListenerQueueSize is not set.
+const defaultListenerQueueSize = 1000
+
 // Listener type has all the `types` of response events
 type Listener struct {
 	Status              chan *WPSStatus
@@ -32,49 +36,220 @@ func NewListener() *Listener {
 	}
 }
 
+// OnStatus sends the status to the Status channel.
+func (l *Listener) OnStatus(status *WPSStatus) {
+	l.Status <- status
+}
+
+// OnMessage sends the message to the Message channel.
+func (l *Listener) OnMessage(message *WPSMessage) {
+	l.Message <- message
+}
+
+// OnPresence sends the presence event to the Presence channel.
+func (l *Listener) OnPresence(presence *WPSPresence) {
+	l.Presence <- presence
+}
+
+// OnSignal sends the signal to the Signal channel.
+func (l *Listener) OnSignal(signal *WPSMessage) {
+	l.Signal <- signal
+}
+
+// OnFile sends the file event to the File channel.
+func (l *Listener) OnFile(file *WPSFilesEvent) {
+	l.File <- file
+}
+
+// OnObjectsEvent sends the objects event to the UUIDEvent, ChannelEvent or MembershipEvent channel.
+func (l *Listener) OnObjectsEvent(event *WPSObjectsEventResult) {
+	switch event.Type {
+	case WPSObjectsUUIDEvent:
+		l.UUIDEvent <- event.UUIDEvent
+	case WPSObjectsChannelEvent:
+		l.ChannelEvent <- event.ChannelEvent
+	case WPSObjectsMembershipEvent:
+		l.MembershipEvent <- event.MembershipEvent
+	}
+}
+
+// OnMessageAction sends the message actions event to the MessageActionsEvent channel.
+func (l *Listener) OnMessageAction(event *WPSMessageActionsEvent) {
+	l.MessageActionsEvent <- event
+}
+
+// EventListener is the callback based alternative to the channel based Listener.
+// Each EventListener added via AddListener gets its own bounded queue and a
+// single goroutine, so callbacks of one listener are invoked in the order the
+// events were received and never concurrently.
+type EventListener interface {
+	OnStatus(status *WPSStatus)
+	OnMessage(message *WPSMessage)
+	OnPresence(presence *WPSPresence)
+	OnSignal(signal *WPSMessage)
+	OnFile(file *WPSFilesEvent)
+	OnObjectsEvent(event *WPSObjectsEventResult)
+	OnMessageAction(event *WPSMessageActionsEvent)
+}
+
+// BaseEventListener implements EventListener with no-op callbacks. Embed it to
+// implement only the callbacks you are interested in.
+type BaseEventListener struct{}
+
+// OnStatus is a no-op.
+func (BaseEventListener) OnStatus(status *WPSStatus) {}
+
+// OnMessage is a no-op.
+func (BaseEventListener) OnMessage(message *WPSMessage) {}
+
+// OnPresence is a no-op.
+func (BaseEventListener) OnPresence(presence *WPSPresence) {}
+
+// OnSignal is a no-op.
+func (BaseEventListener) OnSignal(signal *WPSMessage) {}
+
+// OnFile is a no-op.
+func (BaseEventListener) OnFile(file *WPSFilesEvent) {}
+
+// OnObjectsEvent is a no-op.
+func (BaseEventListener) OnObjectsEvent(event *WPSObjectsEventResult) {}
+
+// OnMessageAction is a no-op.
+func (BaseEventListener) OnMessageAction(event *WPSMessageActionsEvent) {}
+
+// eventListenerQueue delivers the events of a single EventListener in order.
+type eventListenerQueue struct {
+	listener  EventListener
+	events    chan func(EventListener)
+	done      chan struct{}
+	delivered func()
+}
+
+func newEventListenerQueue(listener EventListener, size int, delivered func()) *eventListenerQueue {
+	if size <= 0 {
+		size = defaultListenerQueueSize
+	}
+	q := &eventListenerQueue{
+		listener:  listener,
+		events:    make(chan func(EventListener), size),
+		done:      make(chan struct{}),
+		delivered: delivered,
+	}
+	go q.run()
+	return q
+}
+
+func (q *eventListenerQueue) run() {
+	for {
+		select {
+		case <-q.done:
+			return
+		case event := <-q.events:
+			event(q.listener)
+			q.delivered()
+		}
+	}
+}
+
+func (q *eventListenerQueue) close() {
+	close(q.done)
+}
+
 // ListenerManager is used in the internal handling of listeners.
 type ListenerManager struct {
 	sync.RWMutex
 	ctx                  Context
 	listeners            map[*Listener]bool
+	eventListeners       map[EventListener]*eventListenerQueue // The queues are nil while they are closed.
+	queuesClosed         bool
 	exitListener         chan bool
 	exitListenerAnnounce chan bool
 	webpubsub            *WebPubSub
+	dropped              *dropCounter // Shared by the managers of the Subscriptions and of the client.
+	deliveriesMutex      sync.Mutex
+	deliveries           int
 }
 
 func newListenerManager(ctx Context, pn *WebPubSub) *ListenerManager {
 	return &ListenerManager{
 		listeners:            make(map[*Listener]bool, 2),
+		eventListeners:       make(map[EventListener]*eventListenerQueue, 2),
 		ctx:                  ctx,
 		exitListener:         make(chan bool),
 		exitListenerAnnounce: make(chan bool),
 		webpubsub:            pn,
+		dropped:              &dropCounter{},
 	}
 }
 
-func (m *ListenerManager) addListener(listener *Listener) {
+func (m *ListenerManager) addListener(listener EventListener) {
 	m.Lock()
+	if l, ok := listener.(*Listener); ok {
+		m.listeners[l] = true
+	} else if _, ok := m.eventListeners[listener]; !ok {
+		m.eventListeners[listener] = nil
+		if !m.queuesClosed {
+			m.eventListeners[listener] = newEventListenerQueue(listener, m.webpubsub.Config.ListenerQueueSize, m.reportQueueDepth)
+		}
+	}
+	m.Unlock()
+}
 
-	m.listeners[listener] = true
+// closeQueues stops the queues of the EventListeners, the listeners are kept
+// and get new queues from openQueues.
+func (m *ListenerManager) closeQueues() {
+	m.Lock()
+	m.queuesClosed = true
+	for l, q := range m.eventListeners {
+		if q != nil {
+			q.close()
+			m.eventListeners[l] = nil
+		}
+	}
 	m.Unlock()
 }
 
-func (m *ListenerManager) removeListener(listener *Listener) {
-	m.webpubsub.Config.Log.Println("before removeListener")
+// openQueues starts the queues of the EventListeners stopped by closeQueues.
+func (m *ListenerManager) openQueues() {
 	m.Lock()
-	m.webpubsub.Config.Log.Println("in removeListener lock")
-	delete(m.listeners, listener)
+	m.queuesClosed = false
+	for l, q := range m.eventListeners {
+		if q == nil {
+			m.eventListeners[l] = newEventListenerQueue(l, m.webpubsub.Config.ListenerQueueSize, m.reportQueueDepth)
+		}
+	}
 	m.Unlock()
-	m.webpubsub.Config.Log.Println("after removeListener")
+}
+
+func (m *ListenerManager) removeListener(listener EventListener) {
+	m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("before removeListener")
+	m.Lock()
+	m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("in removeListener lock")
+	if l, ok := listener.(*Listener); ok {
+		delete(m.listeners, l)
+	} else if q, ok := m.eventListeners[listener]; ok {
+		if q != nil {
+			q.close()
+		}
+		delete(m.eventListeners, listener)
+	}
+	m.Unlock()
+	m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("after removeListener")
 }
 
 func (m *ListenerManager) removeAllListeners() {
-	m.webpubsub.Config.Log.Println("in removeAllListeners")
+	m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("in removeAllListeners")
 	m.Lock()
 	lis := m.listeners
 	for l := range lis {
 		delete(m.listeners, l)
 	}
+	for l, q := range m.eventListeners {
+		if q != nil {
+			q.close()
+		}
+		delete(m.eventListeners, l)
+	}
 	m.Unlock()
 }
 
@@ -88,160 +263,223 @@ func (m *ListenerManager) copyListeners() map[*Listener]bool {
 	return lis
 }
 
+func (m *ListenerManager) copyEventListeners() []EventListener {
+	m.RLock()
+	lis := make([]EventListener, 0, len(m.eventListeners))
+	for l := range m.eventListeners {
+		lis = append(lis, l)
+	}
+	m.RUnlock()
+	return lis
+}
+
+// dispatch enqueues the event on the queue of every EventListener. With
+// WPSOverflowBlock it blocks while a queue is full, until the listener is
+// removed or the manager exits, the other policies drop an event instead.
+func (m *ListenerManager) dispatch(event func(EventListener), policy OverflowPolicy) {
+	m.RLock()
+	queues := make([]*eventListenerQueue, 0, len(m.eventListeners))
+	for _, q := range m.eventListeners {
+		if q != nil {
+			queues = append(queues, q)
+		}
+	}
+	m.RUnlock()
+
+	for _, q := range queues {
+		if policy != WPSOverflowBlock {
+			m.dropped.add(q.offer(event, policy))
+			continue
+		}
+		select {
+		case q.events <- event:
+		case <-q.done:
+		case <-m.exitListener:
+			return
+		}
+	}
+	m.reportQueueDepth()
+}
+
+// reportQueueDepth reports the number of events waiting in the queues of the
+// EventListeners to the Metrics, those of the client and of the Subscriptions
+// together.
+func (m *ListenerManager) reportQueueDepth() {
+	if m.webpubsub.Config.Metrics == nil {
+		return
+	}
+	m.webpubsub.Config.Metrics.ListenerQueueDepth(m.webpubsub.subscriptionManager.listenerQueueDepth())
+}
+
+// announceStatus never drops the status, whatever the OverflowPolicy: the
+// connection state transitions and the WPSQueueOverflowCategory reports must
+// reach the listeners.
 func (m *ListenerManager) announceStatus(status *WPSStatus) {
-	go func() {
+	m.dispatch(func(l EventListener) { l.OnStatus(status) }, WPSOverflowBlock)
+	m.deliver(func() {
 		lis := m.copyListeners()
 	AnnounceStatusLabel:
 		for l := range lis {
 			select {
 			case <-m.exitListener:
-				m.webpubsub.Config.Log.Println("announceStatus exitListener")
+				m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("announceStatus exitListener")
 				break AnnounceStatusLabel
 			case l.Status <- status:
 			}
 		}
-		m.webpubsub.Config.Log.Println("announceStatus exit")
-	}()
+		m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("announceStatus exit")
+	}, WPSOverflowBlock)
 }
 
 func (m *ListenerManager) announceMessage(message *WPSMessage) {
-	go func() {
+	m.dispatch(func(l EventListener) { l.OnMessage(message) }, m.webpubsub.Config.OverflowPolicy)
+	m.deliver(func() {
 		lis := m.copyListeners()
 	AnnounceMessageLabel:
 		for l := range lis {
 			select {
 			case <-m.exitListenerAnnounce:
-				m.webpubsub.Config.Log.Println("announceMessage exitListenerAnnounce")
+				m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("announceMessage exitListenerAnnounce")
 				break AnnounceMessageLabel
 			case l.Message <- message:
 			}
 		}
 
-	}()
+	}, m.webpubsub.Config.OverflowPolicy)
 }
 
 func (m *ListenerManager) announceSignal(message *WPSMessage) {
-	go func() {
+	m.dispatch(func(l EventListener) { l.OnSignal(message) }, m.webpubsub.Config.OverflowPolicy)
+	m.deliver(func() {
 		lis := m.copyListeners()
 
 	AnnounceSignalLabel:
 		for l := range lis {
 			select {
 			case <-m.exitListener:
-				m.webpubsub.Config.Log.Println("announceSignal exitListener")
+				m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("announceSignal exitListener")
 				break AnnounceSignalLabel
 
 			case l.Signal <- message:
 			}
 		}
-	}()
+	}, m.webpubsub.Config.OverflowPolicy)
 }
 
 func (m *ListenerManager) announceUUIDEvent(message *WPSUUIDEvent) {
-	go func() {
+	event := &WPSObjectsEventResult{Type: WPSObjectsUUIDEvent, UUIDEvent: message}
+	m.dispatch(func(l EventListener) { l.OnObjectsEvent(event) }, m.webpubsub.Config.OverflowPolicy)
+	m.deliver(func() {
 		lis := m.copyListeners()
 
 	AnnounceUUIDEventLabel:
 		for l := range lis {
 			select {
 			case <-m.exitListener:
-				m.webpubsub.Config.Log.Println("announceUUIDEvent exitListener")
+				m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("announceUUIDEvent exitListener")
 				break AnnounceUUIDEventLabel
 
 			case l.UUIDEvent <- message:
-				m.webpubsub.Config.Log.Println("l.UUIDEvent", message)
+				m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("l.UUIDEvent", "event", message)
 			}
 		}
-	}()
+	}, m.webpubsub.Config.OverflowPolicy)
 }
 
 func (m *ListenerManager) announceChannelEvent(message *WPSChannelEvent) {
-	go func() {
+	event := &WPSObjectsEventResult{Type: WPSObjectsChannelEvent, ChannelEvent: message}
+	m.dispatch(func(l EventListener) { l.OnObjectsEvent(event) }, m.webpubsub.Config.OverflowPolicy)
+	m.deliver(func() {
 		lis := m.copyListeners()
 
 	AnnounceChannelEventLabel:
 		for l := range lis {
-			m.webpubsub.Config.Log.Println("l.ChannelEvent", l)
+			m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("l.ChannelEvent", "listener", l)
 			select {
 			case <-m.exitListener:
-				m.webpubsub.Config.Log.Println("announceChannelEvent exitListener")
+				m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("announceChannelEvent exitListener")
 				break AnnounceChannelEventLabel
 
 			case l.ChannelEvent <- message:
-				m.webpubsub.Config.Log.Println("l.ChannelEvent", message)
+				m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("l.ChannelEvent", "event", message)
 			}
 		}
-	}()
+	}, m.webpubsub.Config.OverflowPolicy)
 }
 
 func (m *ListenerManager) announceMembershipEvent(message *WPSMembershipEvent) {
-	go func() {
+	event := &WPSObjectsEventResult{Type: WPSObjectsMembershipEvent, MembershipEvent: message}
+	m.dispatch(func(l EventListener) { l.OnObjectsEvent(event) }, m.webpubsub.Config.OverflowPolicy)
+	m.deliver(func() {
 		lis := m.copyListeners()
 
 	AnnounceMembershipEvent:
 		for l := range lis {
 			select {
 			case <-m.exitListener:
-				m.webpubsub.Config.Log.Println("announceMembershipEvent exitListener")
+				m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("announceMembershipEvent exitListener")
 				break AnnounceMembershipEvent
 
 			case l.MembershipEvent <- message:
-				m.webpubsub.Config.Log.Println("l.MembershipEvent", message)
+				m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("l.MembershipEvent", "event", message)
 			}
 		}
-	}()
+	}, m.webpubsub.Config.OverflowPolicy)
 }
 
 func (m *ListenerManager) announceMessageActionsEvent(message *WPSMessageActionsEvent) {
-	go func() {
+	m.dispatch(func(l EventListener) { l.OnMessageAction(message) }, m.webpubsub.Config.OverflowPolicy)
+	m.deliver(func() {
 		lis := m.copyListeners()
 
 	AnnounceMessageActionsEvent:
 		for l := range lis {
 			select {
 			case <-m.exitListener:
-				m.webpubsub.Config.Log.Println("announceMessageActionsEvent exitListener")
+				m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("announceMessageActionsEvent exitListener")
 				break AnnounceMessageActionsEvent
 
 			case l.MessageActionsEvent <- message:
-				m.webpubsub.Config.Log.Println("l.MessageActionsEvent", message)
+				m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("l.MessageActionsEvent", "event", message)
 			}
 		}
-	}()
+	}, m.webpubsub.Config.OverflowPolicy)
 }
 
 func (m *ListenerManager) announcePresence(presence *WPSPresence) {
-	go func() {
+	m.dispatch(func(l EventListener) { l.OnPresence(presence) }, m.webpubsub.Config.OverflowPolicy)
+	m.deliver(func() {
 		lis := m.copyListeners()
 
 	AnnouncePresenceLabel:
 		for l := range lis {
 			select {
 			case <-m.exitListener:
-				m.webpubsub.Config.Log.Println("announcePresence exitListener")
+				m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("announcePresence exitListener")
 				break AnnouncePresenceLabel
 
 			case l.Presence <- presence:
 			}
 		}
-	}()
+	}, m.webpubsub.Config.OverflowPolicy)
 }
 
 func (m *ListenerManager) announceFile(file *WPSFilesEvent) {
-	go func() {
+	m.dispatch(func(l EventListener) { l.OnFile(file) }, m.webpubsub.Config.OverflowPolicy)
+	m.deliver(func() {
 		lis := m.copyListeners()
 
 	AnnounceFileLabel:
 		for l := range lis {
 			select {
 			case <-m.exitListener:
-				m.webpubsub.Config.Log.Println("announceFile exitListener")
+				m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("announceFile exitListener")
 				break AnnounceFileLabel
 
 			case l.File <- file:
 			}
 		}
-	}()
+	}, m.webpubsub.Config.OverflowPolicy)
 }
 
 // WPSStatus is the status struct
@@ -258,6 +496,9 @@ type WPSStatus struct {
 	ClientRequest         interface{} // Should be same for non-google environment
 	AffectedChannels      []string
 	AffectedChannelGroups []string
+	ConnectionStateChange *ConnectionStateChange // Set when the status reports a transition of the connection state.
+	DroppedMessages       int                    // Received messages dropped from the message queue, for WPSQueueOverflowCategory.
+	DroppedEvents         int                    // Events dropped from the listener queues, for WPSQueueOverflowCategory.
 }
 
 // WPSMessage is the Message Response for Subscribe
@@ -270,6 +511,10 @@ type WPSMessage struct {
 	Subscription      string
 	Publisher         string
 	Timetoken         int64
+	DecryptKeyIndex   int    // Keyring index of the key which decrypted the message: 0 for the current key, i for DecryptCipherKeys[i-1], -1 when it was not encrypted.
+	TraceParent       string // W3C traceparent of the publisher span found in UserMetadata, set when Config.PropagateTraceContext is true.
+
+	rawMessage []byte
 }
 
 // WPSPresence is the Message Response for Presence
@@ -350,6 +595,15 @@ type WPSMessageActionsEvent struct {
 	Subscription      string
 }
 
+// WPSObjectsEventResult is passed to EventListener.OnObjectsEvent, only the
+// event matching Type is set.
+type WPSObjectsEventResult struct {
+	Type            WPSObjectsEventType
+	UUIDEvent       *WPSUUIDEvent
+	ChannelEvent    *WPSChannelEvent
+	MembershipEvent *WPSMembershipEvent
+}
+
 // WPSFilesEvent is the Response for a Files Event
 type WPSFilesEvent struct {
 	File              WPSFileMessageAndDetails
@@ -0,0 +1,119 @@
+package webpubsub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingEventListener struct {
+	BaseEventListener
+	messages chan *WPSMessage
+	statuses chan *WPSStatus
+	objects  chan *WPSObjectsEventResult
+}
+
+func newRecordingEventListener() *recordingEventListener {
+	return &recordingEventListener{
+		messages: make(chan *WPSMessage, 100),
+		statuses: make(chan *WPSStatus, 100),
+		objects:  make(chan *WPSObjectsEventResult, 100),
+	}
+}
+
+func (l *recordingEventListener) OnMessage(message *WPSMessage) {
+	l.messages <- message
+}
+
+func (l *recordingEventListener) OnStatus(status *WPSStatus) {
+	l.statuses <- status
+}
+
+func (l *recordingEventListener) OnObjectsEvent(event *WPSObjectsEventResult) {
+	l.objects <- event
+}
+
+func TestEventListenerOrderedDelivery(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewWebPubSub(NewDemoConfig())
+
+	listener := newRecordingEventListener()
+	pn.AddListener(listener)
+	assert.Equal(1, len(pn.GetEventListeners()))
+	assert.Equal(0, len(pn.GetListeners()))
+
+	for i := 0; i < 50; i++ {
+		pn.subscriptionManager.listenerManager.announceMessage(&WPSMessage{Timetoken: int64(i)})
+	}
+
+	for i := 0; i < 50; i++ {
+		select {
+		case m := <-listener.messages:
+			assert.Equal(int64(i), m.Timetoken)
+		case <-time.After(2 * time.Second):
+			assert.Fail("message not delivered")
+			return
+		}
+	}
+}
+
+func TestEventListenerObjectsEvent(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewWebPubSub(NewDemoConfig())
+
+	listener := newRecordingEventListener()
+	pn.AddListener(listener)
+
+	pn.subscriptionManager.listenerManager.announceChannelEvent(&WPSChannelEvent{ChannelID: "ch"})
+
+	select {
+	case e := <-listener.objects:
+		assert.Equal(WPSObjectsEventType(WPSObjectsChannelEvent), e.Type)
+		assert.Equal("ch", e.ChannelEvent.ChannelID)
+		assert.Nil(e.UUIDEvent)
+	case <-time.After(2 * time.Second):
+		assert.Fail("objects event not delivered")
+	}
+}
+
+func TestEventListenerRemove(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewWebPubSub(NewDemoConfig())
+	pn.Config.ListenerQueueSize = 1
+
+	listener := newRecordingEventListener()
+	pn.AddListener(listener)
+	pn.RemoveListener(listener)
+	assert.Equal(0, len(pn.GetEventListeners()))
+
+	pn.subscriptionManager.listenerManager.announceStatus(&WPSStatus{Category: WPSConnectedCategory})
+
+	select {
+	case <-listener.statuses:
+		assert.Fail("removed listener received status")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestChannelListenerStillSupported(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewWebPubSub(NewDemoConfig())
+
+	listener := NewListener()
+	pn.AddListener(listener)
+	assert.Equal(1, len(pn.GetListeners()))
+	assert.Equal(0, len(pn.GetEventListeners()))
+
+	pn.subscriptionManager.listenerManager.announceSignal(&WPSMessage{Channel: "ch"})
+
+	select {
+	case s := <-listener.Signal:
+		assert.Equal("ch", s.Channel)
+	case <-time.After(2 * time.Second):
+		assert.Fail("signal not delivered")
+	}
+
+	pn.RemoveListener(listener)
+	assert.Equal(0, len(pn.GetListeners()))
+}
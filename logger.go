@@ -0,0 +1,203 @@
+package webpubsub
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"regexp"
+	"strings"
+)
+
+// LogLevel is the severity of a log entry.
+type LogLevel int
+
+const (
+	// WPSLogDebug is the level of the detailed flow of the SDK.
+	WPSLogDebug LogLevel = iota
+	// WPSLogInfo is the level of the notable events, like a reconnection.
+	WPSLogInfo
+	// WPSLogWarn is the level of the recoverable errors.
+	WPSLogWarn
+	// WPSLogError is the level of the failed operations.
+	WPSLogError
+)
+
+func (l LogLevel) String() string {
+	switch l {
+	case WPSLogDebug:
+		return "DEBUG"
+	case WPSLogInfo:
+		return "INFO"
+	case WPSLogWarn:
+		return "WARN"
+	case WPSLogError:
+		return "ERROR"
+	}
+	return fmt.Sprintf("LogLevel(%d)", int(l))
+}
+
+// LogSubsystem is the part of the SDK which emits a log entry.
+type LogSubsystem string
+
+const (
+	// WPSGeneralSubsystem logs the client lifecycle and the publish queue.
+	WPSGeneralSubsystem LogSubsystem = "general"
+	// WPSSubscribeSubsystem logs the subscribe loop, the reconnections and the listeners.
+	WPSSubscribeSubsystem LogSubsystem = "subscribe"
+	// WPSHeartbeatSubsystem logs the presence heartbeats.
+	WPSHeartbeatSubsystem LogSubsystem = "heartbeat"
+	// WPSRequestSubsystem logs the requests and the responses.
+	WPSRequestSubsystem LogSubsystem = "request"
+	// WPSCryptoSubsystem logs the encryption and the decryption.
+	WPSCryptoSubsystem LogSubsystem = "crypto"
+)
+
+// SubsystemLoggers maps the subsystems to their loggers.
+type SubsystemLoggers map[LogSubsystem]Logger
+
+// Logger receives the log entries of the SDK. fields holds alternating keys
+// and values. The secrets of the Config are redacted before the entries reach
+// the Logger.
+type Logger interface {
+	Log(level LogLevel, msg string, fields ...interface{})
+}
+
+// NewStdLogger creates a Logger writing to l, the entries below minLevel are
+// dropped.
+func NewStdLogger(l *log.Logger, minLevel LogLevel) Logger {
+	return &stdLogger{
+		logger:   l,
+		minLevel: minLevel,
+	}
+}
+
+type stdLogger struct {
+	logger   *log.Logger
+	minLevel LogLevel
+}
+
+func (l *stdLogger) Log(level LogLevel, msg string, fields ...interface{}) {
+	if level < l.minLevel {
+		return
+	}
+	var b strings.Builder
+	b.WriteString(level.String())
+	b.WriteString(" ")
+	b.WriteString(msg)
+	for i := 0; i < len(fields); i += 2 {
+		b.WriteString(" ")
+		b.WriteString(fmt.Sprint(fields[i]))
+		b.WriteString("=")
+		if i+1 < len(fields) {
+			b.WriteString(fmt.Sprint(fields[i+1]))
+		}
+	}
+	l.logger.Output(2, b.String())
+}
+
+const redacted = "[REDACTED]"
+
+var (
+	redactedQueryParams = regexp.MustCompile(`(?i)((?:^|[?&\s])(?:auth|token|signature|cipher_key|secret_key)=)[^&\s"]+`)
+	redactedJSONFields  = regexp.MustCompile(`(?i)("(?:auth|auth_key|authKey|token|signature|secret_key|secretKey|cipher_key|cipherKey)"\s*:\s*")[^"]*`)
+	redactedFieldKeys   = map[string]bool{
+		"auth":        true,
+		"authkey":     true,
+		"token":       true,
+		"signature":   true,
+		"signedinput": true,
+		"secretkey":   true,
+		"cipherkey":   true,
+	}
+)
+
+// sdkLogger is the logger of a subsystem, it redacts the auth keys, tokens,
+// signatures and cipher keys of every entry.
+type sdkLogger struct {
+	logger    Logger
+	subsystem LogSubsystem
+	secrets   []string
+}
+
+// logger returns the logger of the subsystem: the logger set for it in
+// SubsystemLoggers, Logger, or Log when both are unset.
+func (c *Config) logger(subsystem LogSubsystem) *sdkLogger {
+	logger := c.SubsystemLoggers[subsystem]
+	if logger == nil {
+		logger = c.Logger
+	}
+	if logger == nil {
+		if c.Log == nil || c.Log.Writer() == ioutil.Discard {
+			return &sdkLogger{subsystem: subsystem}
+		}
+		logger = NewStdLogger(c.Log, WPSLogDebug)
+	}
+
+	secrets := []string{c.SecretKey, c.AuthKey, c.CipherKey}
+	secrets = append(secrets, c.DecryptCipherKeys...)
+	return &sdkLogger{
+		logger:    logger,
+		subsystem: subsystem,
+		secrets:   secrets,
+	}
+}
+
+// endpointLogger returns the request logger of the configuration of o.
+func endpointLogger(o endpointOpts) *sdkLogger {
+	config := o.config()
+	return config.logger(WPSRequestSubsystem)
+}
+
+func (l *sdkLogger) Debug(msg string, fields ...interface{}) {
+	l.log(WPSLogDebug, msg, fields)
+}
+
+func (l *sdkLogger) Info(msg string, fields ...interface{}) {
+	l.log(WPSLogInfo, msg, fields)
+}
+
+func (l *sdkLogger) Warn(msg string, fields ...interface{}) {
+	l.log(WPSLogWarn, msg, fields)
+}
+
+func (l *sdkLogger) Error(msg string, fields ...interface{}) {
+	l.log(WPSLogError, msg, fields)
+}
+
+func (l *sdkLogger) log(level LogLevel, msg string, fields []interface{}) {
+	if l.logger == nil {
+		return
+	}
+	redactedFields := make([]interface{}, 0, len(fields)+2)
+	redactedFields = append(redactedFields, "subsystem", string(l.subsystem))
+	for i := 0; i < len(fields); i += 2 {
+		key := fmt.Sprint(fields[i])
+		if i+1 >= len(fields) {
+			redactedFields = append(redactedFields, l.redact(key))
+			break
+		}
+		switch value := fields[i+1].(type) {
+		case int, int64, bool:
+			redactedFields = append(redactedFields, key, value)
+		default:
+			if redactedFieldKeys[strings.ToLower(key)] {
+				redactedFields = append(redactedFields, key, redacted)
+			} else {
+				redactedFields = append(redactedFields, key, l.redact(fmt.Sprint(value)))
+			}
+		}
+	}
+	l.logger.Log(level, l.redact(msg), redactedFields...)
+}
+
+// redact replaces the configured secrets and the values of the sensitive
+// query parameters and JSON fields.
+func (l *sdkLogger) redact(s string) string {
+	for _, secret := range l.secrets {
+		if secret != "" {
+			s = strings.Replace(s, secret, redacted, -1)
+		}
+	}
+	s = redactedQueryParams.ReplaceAllString(s, "${1}"+redacted)
+	return redactedJSONFields.ReplaceAllString(s, "${1}"+redacted)
+}
@@ -0,0 +1,36 @@
+//go:build go1.21
+// +build go1.21
+
+package webpubsub
+
+import (
+	"context"
+	"log/slog"
+)
+
+// NewSlogLogger creates a Logger writing to the slog.Logger l.
+func NewSlogLogger(l *slog.Logger) Logger {
+	return &slogLogger{
+		logger: l,
+	}
+}
+
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+func (l *slogLogger) Log(level LogLevel, msg string, fields ...interface{}) {
+	l.logger.Log(context.Background(), slogLevel(level), msg, fields...)
+}
+
+func slogLevel(level LogLevel) slog.Level {
+	switch level {
+	case WPSLogDebug:
+		return slog.LevelDebug
+	case WPSLogInfo:
+		return slog.LevelInfo
+	case WPSLogWarn:
+		return slog.LevelWarn
+	}
+	return slog.LevelError
+}
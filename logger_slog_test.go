@@ -0,0 +1,34 @@
+//go:build go1.21
+// +build go1.21
+
+package webpubsub
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlogLogger(t *testing.T) {
+	assert := assert.New(t)
+
+	var buf bytes.Buffer
+	handler := slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelInfo,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	config := NewConfig("uuid")
+	config.AuthKey = "akey"
+	config.Logger = NewSlogLogger(slog.New(handler))
+
+	config.logger(WPSHeartbeatSubsystem).Debug("dropped")
+	config.logger(WPSHeartbeatSubsystem).Warn("heartbeat failed", "auth", "akey", "attempt", 2)
+	assert.Equal("level=WARN msg=\"heartbeat failed\" subsystem=heartbeat auth=[REDACTED] attempt=2\n", buf.String())
+}
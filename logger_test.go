@@ -0,0 +1,156 @@
+package webpubsub
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type logEntry struct {
+	level  LogLevel
+	msg    string
+	fields []interface{}
+}
+
+type recordingLogger struct {
+	sync.Mutex
+	entries []logEntry
+}
+
+func (l *recordingLogger) Log(level LogLevel, msg string, fields ...interface{}) {
+	l.Lock()
+	l.entries = append(l.entries, logEntry{level, msg, fields})
+	l.Unlock()
+}
+
+// logged returns the entries logged so far.
+func (l *recordingLogger) logged() []logEntry {
+	l.Lock()
+	defer l.Unlock()
+	return append([]logEntry{}, l.entries...)
+}
+
+func TestLoggerRedactsSecrets(t *testing.T) {
+	assert := assert.New(t)
+
+	config := NewConfig("uuid")
+	config.SecretKey = "sec-key"
+	config.CipherKey = "my-cipher"
+	config.DecryptCipherKeys = []string{"old-cipher"}
+	logger := &recordingLogger{}
+	config.Logger = logger
+
+	l := config.logger(WPSRequestSubsystem)
+	l.Warn("request to https://ps.pndsn.com/publish?auth=akey&uuid=u&signature=v2.abc",
+		"token", "p0F2AkF0",
+		"body", `{"token":"p0F2AkF0","text":"my-cipher old-cipher"}`,
+		"error", errors.New("bad sec-key"),
+		"count", 3)
+
+	entries := logger.logged()
+	assert.Equal(1, len(entries))
+	entry := entries[0]
+	assert.Equal(WPSLogWarn, entry.level)
+	assert.Equal("request to https://ps.pndsn.com/publish?auth=[REDACTED]&uuid=u&signature=[REDACTED]", entry.msg)
+	assert.Equal([]interface{}{
+		"subsystem", "request",
+		"token", "[REDACTED]",
+		"body", `{"token":"[REDACTED]","text":"[REDACTED] [REDACTED]"}`,
+		"error", "bad [REDACTED]",
+		"count", 3,
+	}, entry.fields)
+}
+
+func TestLoggerSubsystemLoggers(t *testing.T) {
+	assert := assert.New(t)
+
+	config := NewConfig("uuid")
+	general := &recordingLogger{}
+	subscribe := &recordingLogger{}
+	config.Logger = general
+	config.SubsystemLoggers = SubsystemLoggers{
+		WPSSubscribeSubsystem: subscribe,
+	}
+
+	config.logger(WPSSubscribeSubsystem).Info("reconnected")
+	config.logger(WPSHeartbeatSubsystem).Debug("heartbeat")
+
+	assert.Equal(1, len(subscribe.logged()))
+	assert.Equal(WPSLogInfo, subscribe.logged()[0].level)
+	assert.Equal(1, len(general.logged()))
+	assert.Equal([]interface{}{"subsystem", "heartbeat"}, general.logged()[0].fields)
+}
+
+func TestStdLogger(t *testing.T) {
+	assert := assert.New(t)
+
+	var buf bytes.Buffer
+	config := NewConfig("uuid")
+	config.Log = log.New(&buf, "", 0)
+	config.AuthKey = "akey"
+
+	config.logger(WPSRequestSubsystem).Debug("request", "url", "https://ps.pndsn.com/time/0?auth=akey")
+	assert.Equal("DEBUG request subsystem=request url=https://ps.pndsn.com/time/0?auth=[REDACTED]\n", buf.String())
+
+	buf.Reset()
+	config.Logger = NewStdLogger(config.Log, WPSLogWarn)
+	config.logger(WPSRequestSubsystem).Info("dropped")
+	config.logger(WPSRequestSubsystem).Error("kept")
+	assert.Equal("ERROR kept subsystem=request\n", buf.String())
+}
+
+func TestLoggerDisabled(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := NewWebPubSub(NewDemoConfig())
+	l := pn.Config.logger(WPSGeneralSubsystem)
+	assert.Nil(l.logger)
+	l.Error("not logged")
+}
+
+func TestLogLevelString(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("DEBUG", WPSLogDebug.String())
+	assert.Equal("ERROR", WPSLogError.String())
+	assert.Equal("LogLevel(9)", LogLevel(9).String())
+}
+
+func TestLoggerRedactsSignedInput(t *testing.T) {
+	assert := assert.New(t)
+
+	transport := &capturingTransport{status: 200, body: `[1,"Sent","15"]`}
+	pn := NewWebPubSub(NewDemoConfig())
+	pn.SetClient(&http.Client{Transport: transport})
+	pn.Config.UsePAMV3 = true
+	logger := &recordingLogger{}
+	pn.Config.Logger = logger
+	token := newTestToken(t, time.Now(), 60, GrantResources{
+		Channels: map[string]int64{"chat": int64(WPSWrite)},
+	}, GrantResources{})
+	pn.SetToken(token)
+
+	_, _, err := pn.Publish().Channel("chat").Message("hi").Execute()
+	assert.Nil(err)
+	if assert.Len(transport.requests, 1) {
+		assert.Equal(token, transport.requests[0].URL.Query().Get("auth"))
+	}
+
+	signed := false
+	for _, entry := range logger.logged() {
+		line := fmt.Sprint(entry.msg, entry.fields)
+		assert.NotContains(line, token)
+		if entry.msg == "signedInputV2" {
+			signed = true
+			assert.Contains(line, "auth=[REDACTED]")
+		}
+	}
+	assert.True(signed)
+}
@@ -145,7 +145,7 @@ func (o *addMessageActionsOpts) buildBody() ([]byte, error) {
 	jsonEncBytes, errEnc := json.Marshal(o.Action)
 
 	if errEnc != nil {
-		o.webpubsub.Config.Log.Printf("ERROR: Serialization error: %s\n", errEnc.Error())
+		o.webpubsub.Config.logger(WPSRequestSubsystem).Error("serialization error", "error", errEnc)
 		return []byte{}, errEnc
 	}
 	return jsonEncBytes, nil
@@ -180,10 +180,18 @@ func (o *addMessageActionsOpts) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *addMessageActionsOpts) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *addMessageActionsOpts) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
 
+func (o *addMessageActionsOpts) tokenResources() (channels, groups, uuids []string) {
+	return []string{o.Channel}, nil, nil
+}
+
 // WPSMessageActionsResponse Message Actions response.
 type WPSMessageActionsResponse struct {
 	ActionType       string `json:"type"`
@@ -187,10 +187,18 @@ func (o *getMessageActionsOpts) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *getMessageActionsOpts) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *getMessageActionsOpts) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
 
+func (o *getMessageActionsOpts) tokenResources() (channels, groups, uuids []string) {
+	return []string{o.Channel}, nil, nil
+}
+
 // WPSGetMessageActionsMore is the struct used when the WPSGetMessageActionsResponse has more link
 type WPSGetMessageActionsMore struct {
 	URL   string `json:"url"`
@@ -168,10 +168,18 @@ func (o *removeMessageActionsOpts) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *removeMessageActionsOpts) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *removeMessageActionsOpts) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
 
+func (o *removeMessageActionsOpts) tokenResources() (channels, groups, uuids []string) {
+	return []string{o.Channel}, nil, nil
+}
+
 // WPSRemoveMessageActionsResponse is the Objects API Response for create space
 type WPSRemoveMessageActionsResponse struct {
 	status int         `json:"status"`
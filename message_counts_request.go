@@ -202,10 +202,18 @@ func (o *messageCountsOpts) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *messageCountsOpts) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *messageCountsOpts) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
 
+func (o *messageCountsOpts) tokenResources() (channels, groups, uuids []string) {
+	return o.Channels, nil, nil
+}
+
 // MessageCountsResponse is the response to MessageCounts request. It contains a map of type MessageCountsResponseItem
 type MessageCountsResponse struct {
 	Channels map[string]int
@@ -229,7 +237,7 @@ func newMessageCountsResponse(jsonBytes []byte, o *messageCountsOpts,
 	}
 
 	if result, ok := value.(map[string]interface{}); ok {
-		o.webpubsub.Config.Log.Println(result["channels"])
+		o.webpubsub.Config.logger(WPSRequestSubsystem).Debug("message counts", "channels", result["channels"])
 		if channels, ok1 := result["channels"].(map[string]interface{}); ok1 {
 			if channels != nil {
 				resp.Channels = make(map[string]int)
@@ -237,13 +245,13 @@ func newMessageCountsResponse(jsonBytes []byte, o *messageCountsOpts,
 					resp.Channels[ch] = int(v.(float64))
 				}
 			} else {
-				o.webpubsub.Config.Log.Printf("type assertion to map failed %v\n", result)
+				o.webpubsub.Config.logger(WPSRequestSubsystem).Warn("type assertion to map failed", "result", result)
 			}
 		} else {
-			o.webpubsub.Config.Log.Println("Assertion failed", reflect.TypeOf(result["channels"]))
+			o.webpubsub.Config.logger(WPSRequestSubsystem).Warn("type assertion failed", "type", reflect.TypeOf(result["channels"]))
 		}
 	} else {
-		o.webpubsub.Config.Log.Printf("type assertion to map failed %v\n", value)
+		o.webpubsub.Config.logger(WPSRequestSubsystem).Warn("type assertion to map failed", "value", value)
 	}
 
 	return resp, status, nil
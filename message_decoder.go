@@ -0,0 +1,38 @@
+package webpubsub
+
+import (
+	"encoding/json"
+)
+
+// MessagePayload is implemented by the results carrying a message payload:
+// *WPSMessage, FetchResponseItem and HistoryResponseItem.
+type MessagePayload interface {
+	messagePayload() (interface{}, []byte)
+}
+
+func (m *WPSMessage) messagePayload() (interface{}, []byte) {
+	return m.Message, m.rawMessage
+}
+
+func (i FetchResponseItem) messagePayload() (interface{}, []byte) {
+	return i.Message, i.rawMessage
+}
+
+func (i HistoryResponseItem) messagePayload() (interface{}, []byte) {
+	return i.Message, i.rawMessage
+}
+
+// DecodeMessageInto unmarshals the payload of the message into v. The raw
+// (decrypted) payload bytes are used when available, otherwise the already
+// parsed Message is converted.
+func DecodeMessageInto(msg MessagePayload, v interface{}) error {
+	message, raw := msg.messagePayload()
+	if len(raw) == 0 {
+		var err error
+		raw, err = json.Marshal(message)
+		if err != nil {
+			return err
+		}
+	}
+	return json.Unmarshal(raw, v)
+}
@@ -0,0 +1,123 @@
+//go:build go1.18
+// +build go1.18
+
+package webpubsub
+
+// DecodeMessage unmarshals the payload of a *WPSMessage, FetchResponseItem or
+// HistoryResponseItem into a value of type T.
+func DecodeMessage[T any](msg MessagePayload) (T, error) {
+	var v T
+	err := DecodeMessageInto(msg, &v)
+	return v, err
+}
+
+// TypedMessageListener is an EventListener which decodes the payload of the
+// received messages and signals into T before calling OnTypedMessage and
+// OnTypedSignal. Decoding failures are reported to OnDecodeError.
+type TypedMessageListener[T any] struct {
+	BaseEventListener
+	OnTypedMessage func(message *WPSMessage, payload T)
+	OnTypedSignal  func(signal *WPSMessage, payload T)
+	OnDecodeError  func(message *WPSMessage, err error)
+}
+
+// NewTypedMessageListener creates a TypedMessageListener calling onMessage for
+// each decoded message.
+func NewTypedMessageListener[T any](onMessage func(message *WPSMessage, payload T), onError func(message *WPSMessage, err error)) *TypedMessageListener[T] {
+	return &TypedMessageListener[T]{
+		OnTypedMessage: onMessage,
+		OnDecodeError:  onError,
+	}
+}
+
+// OnMessage decodes the message and calls OnTypedMessage.
+func (l *TypedMessageListener[T]) OnMessage(message *WPSMessage) {
+	l.decode(message, l.OnTypedMessage)
+}
+
+// OnSignal decodes the signal and calls OnTypedSignal.
+func (l *TypedMessageListener[T]) OnSignal(signal *WPSMessage) {
+	l.decode(signal, l.OnTypedSignal)
+}
+
+func (l *TypedMessageListener[T]) decode(message *WPSMessage, handler func(*WPSMessage, T)) {
+	if handler == nil {
+		return
+	}
+	payload, err := DecodeMessage[T](message)
+	if err != nil {
+		if l.OnDecodeError != nil {
+			l.OnDecodeError(message, err)
+		}
+		return
+	}
+	handler(message, payload)
+}
+
+// TypedFetchResponseItem is a FetchResponseItem with the decoded payload.
+type TypedFetchResponseItem[T any] struct {
+	FetchResponseItem
+	Payload T
+	// DecodeError is set when the payload could not be decoded into T.
+	DecodeError error
+}
+
+// TypedFetchResponse is the response of ExecuteFetch, messages are grouped by channel.
+type TypedFetchResponse[T any] struct {
+	Messages map[string][]TypedFetchResponseItem[T]
+}
+
+// ExecuteFetch runs the Fetch request and decodes the payload of each message into T.
+func ExecuteFetch[T any](b *fetchBuilder) (*TypedFetchResponse[T], StatusResponse, error) {
+	resp, status, err := b.Execute()
+	if err != nil {
+		return nil, status, err
+	}
+
+	typed := &TypedFetchResponse[T]{
+		Messages: make(map[string][]TypedFetchResponseItem[T], len(resp.Messages)),
+	}
+	for channel, items := range resp.Messages {
+		typedItems := make([]TypedFetchResponseItem[T], len(items))
+		for i, item := range items {
+			typedItems[i].FetchResponseItem = item
+			typedItems[i].Payload, typedItems[i].DecodeError = DecodeMessage[T](item)
+		}
+		typed.Messages[channel] = typedItems
+	}
+	return typed, status, nil
+}
+
+// TypedHistoryResponseItem is a HistoryResponseItem with the decoded payload.
+type TypedHistoryResponseItem[T any] struct {
+	HistoryResponseItem
+	Payload T
+	// DecodeError is set when the payload could not be decoded into T.
+	DecodeError error
+}
+
+// TypedHistoryResponse is the response of ExecuteHistory.
+type TypedHistoryResponse[T any] struct {
+	Messages       []TypedHistoryResponseItem[T]
+	StartTimetoken int64
+	EndTimetoken   int64
+}
+
+// ExecuteHistory runs the History request and decodes the payload of each message into T.
+func ExecuteHistory[T any](b *historyBuilder) (*TypedHistoryResponse[T], StatusResponse, error) {
+	resp, status, err := b.Execute()
+	if err != nil {
+		return nil, status, err
+	}
+
+	typed := &TypedHistoryResponse[T]{
+		Messages:       make([]TypedHistoryResponseItem[T], len(resp.Messages)),
+		StartTimetoken: resp.StartTimetoken,
+		EndTimetoken:   resp.EndTimetoken,
+	}
+	for i, item := range resp.Messages {
+		typed.Messages[i].HistoryResponseItem = item
+		typed.Messages[i].Payload, typed.Messages[i].DecodeError = DecodeMessage[T](item)
+	}
+	return typed, status, nil
+}
@@ -0,0 +1,90 @@
+//go:build go1.18
+// +build go1.18
+
+package webpubsub
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/webpubsub/sdk-go/v7/utils"
+)
+
+type chatMessage struct {
+	Text string `json:"text"`
+	ID   int64  `json:"id"`
+}
+
+func TestDecodeMessageFromSubscribePayload(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewWebPubSub(NewDemoConfig())
+
+	var envelope subscribeEnvelope
+	err := json.Unmarshal([]byte(`{"t":{"t":"15000000000000001","r":4},"m":[{"a":"1","f":0,"i":"publisher","p":{"t":"15000000000000000","r":4},"k":"demo","c":"chat","d":{"text":"hi","id":9007199254740993}}]}`), &envelope)
+	assert.Nil(err)
+	assert.Equal(1, len(envelope.Messages))
+
+	received := make(chan chatMessage, 1)
+	listener := NewTypedMessageListener[chatMessage](func(message *WPSMessage, payload chatMessage) {
+		received <- payload
+	}, func(message *WPSMessage, err error) {
+		assert.Fail(err.Error())
+	})
+	pn.AddListener(listener)
+
+	processSubscribePayload(pn.subscriptionManager, envelope.Messages[0])
+
+	select {
+	case payload := <-received:
+		assert.Equal("hi", payload.Text)
+		assert.Equal(int64(9007199254740993), payload.ID)
+	case <-time.After(2 * time.Second):
+		assert.Fail("message not delivered")
+	}
+}
+
+func TestDecodeMessageEncrypted(t *testing.T) {
+	assert := assert.New(t)
+	encrypted := utils.EncryptString("enigma", `{"text":"secret","id":7}`, false)
+
+	jsonString := []byte(fmt.Sprintf(`{"status": 200, "error": false, "error_message": "", "channels": {"ch":[{"message":"%s","timetoken":"15229448184080121"},{"message":"plain","timetoken":"15229448184080122"}]}}`, encrypted))
+
+	resp, _, err := newFetchResponse(jsonString, initFetchOpts("enigma"), fakeResponseState)
+	assert.Nil(err)
+
+	msg, err := DecodeMessage[chatMessage](resp.Messages["ch"][0])
+	assert.Nil(err)
+	assert.Equal("secret", msg.Text)
+	assert.Equal(int64(7), msg.ID)
+
+	_, err = DecodeMessage[chatMessage](resp.Messages["ch"][1])
+	assert.NotNil(err)
+}
+
+func TestDecodeMessageHistory(t *testing.T) {
+	assert := assert.New(t)
+
+	jsonString := []byte(`[[{"message":{"text":"a","id":1},"timetoken":15},{"message":{"text":"b","id":2},"timetoken":16}],15,16]`)
+
+	resp, _, err := newHistoryResponse(jsonString, initHistoryOpts(), fakeResponseState)
+	assert.Nil(err)
+
+	msg, err := DecodeMessage[chatMessage](resp.Messages[1])
+	assert.Nil(err)
+	assert.Equal("b", msg.Text)
+	assert.Equal(int64(2), msg.ID)
+}
+
+func TestDecodeMessageWithoutRaw(t *testing.T) {
+	assert := assert.New(t)
+
+	msg, err := DecodeMessage[chatMessage](&WPSMessage{
+		Message: map[string]interface{}{"text": "manual", "id": 3},
+	})
+	assert.Nil(err)
+	assert.Equal("manual", msg.Text)
+	assert.Equal(int64(3), msg.ID)
+}
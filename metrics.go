@@ -0,0 +1,52 @@
+package webpubsub
+
+import (
+	"time"
+)
+
+// Metrics receives the measurements of the SDK, set it in Config.Metrics.
+// The methods are called from the goroutines of the SDK and must not block.
+type Metrics interface {
+	// RequestCompleted is called after each attempt of a request. statusCode
+	// is 0 when the request failed before receiving a response.
+	RequestCompleted(operation OperationType, latency time.Duration, statusCode int, err error)
+	// SubscribeReconnected is called each time the subscribe loop reconnects
+	// after a network failure.
+	SubscribeReconnected()
+	// MessageReceived is called for each message received by the subscribe loop.
+	MessageReceived(channel string)
+	// ListenerQueueDepth is called with the number of events waiting in the
+	// queues of the EventListeners, of the client and of the Subscriptions,
+	// when it changes.
+	ListenerQueueDepth(depth int)
+	// RequestWorkersBusy is called with the number of busy request workers
+	// when it changes.
+	RequestWorkersBusy(busy, total int)
+}
+
+type noMetrics struct{}
+
+func (noMetrics) RequestCompleted(operation OperationType, latency time.Duration, statusCode int, err error) {
+}
+
+func (noMetrics) SubscribeReconnected() {}
+
+func (noMetrics) MessageReceived(channel string) {}
+
+func (noMetrics) ListenerQueueDepth(depth int) {}
+
+func (noMetrics) RequestWorkersBusy(busy, total int) {}
+
+// metrics returns the configured Metrics, or a no-op implementation.
+func (c *Config) metrics() Metrics {
+	if c.Metrics != nil {
+		return c.Metrics
+	}
+	return noMetrics{}
+}
+
+// endpointMetrics returns the Metrics of the configuration of o.
+func endpointMetrics(o endpointOpts) Metrics {
+	config := o.config()
+	return config.metrics()
+}
@@ -0,0 +1,155 @@
+package webpubsub
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordedRequest struct {
+	operation  OperationType
+	statusCode int
+	err        error
+}
+
+type recordingMetrics struct {
+	sync.Mutex
+	requests   []recordedRequest
+	reconnects int
+	messages   map[string]int
+	depths     []int
+	busy       []int
+}
+
+func newRecordingMetrics() *recordingMetrics {
+	return &recordingMetrics{
+		messages: make(map[string]int),
+	}
+}
+
+func (m *recordingMetrics) RequestCompleted(operation OperationType, latency time.Duration, statusCode int, err error) {
+	m.Lock()
+	m.requests = append(m.requests, recordedRequest{operation, statusCode, err})
+	m.Unlock()
+}
+
+func (m *recordingMetrics) SubscribeReconnected() {
+	m.Lock()
+	m.reconnects++
+	m.Unlock()
+}
+
+func (m *recordingMetrics) MessageReceived(channel string) {
+	m.Lock()
+	m.messages[channel]++
+	m.Unlock()
+}
+
+func (m *recordingMetrics) ListenerQueueDepth(depth int) {
+	m.Lock()
+	m.depths = append(m.depths, depth)
+	m.Unlock()
+}
+
+func (m *recordingMetrics) RequestWorkersBusy(busy, total int) {
+	m.Lock()
+	m.busy = append(m.busy, busy)
+	m.Unlock()
+}
+
+func TestMetricsRequestCompleted(t *testing.T) {
+	assert := assert.New(t)
+
+	metrics := newRecordingMetrics()
+	pn := newTestWebPubSub(&sequenceTransport{responses: []sequenceResponse{
+		{statusCode: 200, body: "[15078947309567840]"},
+		{statusCode: 503, body: "unavailable"},
+		{err: errors.New("connection reset")},
+	}})
+	pn.Config.Metrics = metrics
+
+	for i := 0; i < 3; i++ {
+		pn.Time().Execute()
+	}
+
+	assert.Equal(3, len(metrics.requests))
+	assert.Equal(recordedRequest{WPSTimeOperation, 200, nil}, metrics.requests[0])
+	assert.Equal(WPSTimeOperation, metrics.requests[1].operation)
+	assert.Equal(503, metrics.requests[1].statusCode)
+	assert.NotNil(metrics.requests[1].err)
+	assert.Equal(0, metrics.requests[2].statusCode)
+	assert.NotNil(metrics.requests[2].err)
+}
+
+func TestMetricsListenerQueueDepth(t *testing.T) {
+	assert := assert.New(t)
+
+	metrics := newRecordingMetrics()
+	pn := NewWebPubSub(NewDemoConfig())
+	pn.Config.Metrics = metrics
+	listener := newRecordingEventListener()
+	pn.AddListener(listener)
+
+	pn.subscriptionManager.listenerManager.announceMessage(&WPSMessage{Channel: "ch"})
+	<-listener.messages
+
+	assert.Eventually(func() bool {
+		metrics.Lock()
+		defer metrics.Unlock()
+		return len(metrics.depths) >= 2 && metrics.depths[len(metrics.depths)-1] == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestMetricsListenerQueueDepthOfSubscriptions(t *testing.T) {
+	assert := assert.New(t)
+
+	metrics := newRecordingMetrics()
+	pn := NewWebPubSub(NewDemoConfig())
+	pn.Config.Metrics = metrics
+	subscription := pn.Channel("ch").Subscription(SubscriptionOptions{})
+	pn.subscriptionManager.subscriptionsMutex.Lock()
+	pn.subscriptionManager.subscriptions[subscription] = true
+	pn.subscriptionManager.subscriptionsMutex.Unlock()
+
+	for _, lm := range []*ListenerManager{pn.subscriptionManager.listenerManager, subscription.listenerManager} {
+		listener := newBlockingEventListener()
+		defer close(listener.release)
+		lm.addListener(listener)
+		lm.announceMessage(&WPSMessage{Timetoken: 1})
+		<-listener.started
+		lm.announceMessage(&WPSMessage{Timetoken: 2})
+	}
+
+	metrics.Lock()
+	assert.Equal(2, metrics.depths[len(metrics.depths)-1])
+	metrics.Unlock()
+	assert.Equal(2, pn.QueueStats().ListenerQueueDepth)
+}
+
+func TestMetricsRequestWorkersBusy(t *testing.T) {
+	assert := assert.New(t)
+
+	metrics := newRecordingMetrics()
+	pn := NewWebPubSub(NewDemoConfig())
+	pn.Config.Metrics = metrics
+
+	workers := &RequestWorkers{MaxWorkers: 2}
+	workers.reportBusy(pn, 1)
+	workers.reportBusy(pn, 1)
+	workers.reportBusy(pn, -1)
+	assert.Equal([]int{1, 2, 1}, metrics.busy)
+}
+
+func TestNoMetrics(t *testing.T) {
+	assert := assert.New(t)
+
+	config := NewConfig("uuid")
+	assert.Equal(noMetrics{}, config.metrics())
+
+	metrics := newRecordingMetrics()
+	config.Metrics = metrics
+	assert.Equal(metrics, config.metrics())
+}
@@ -0,0 +1,59 @@
+package webpubsub
+
+import (
+	"net/http"
+)
+
+// WPSRequest is a request passing through the middleware chain.
+type WPSRequest struct {
+	Operation OperationType
+	Channels  []string // Channels of the request, empty when the operation doesn't target channels.
+	Context   Context  // Context set on the request builder, nil when none was set.
+
+	// HTTPRequest is the request to send. Its URL is already signed, headers
+	// can be added but changing the URL invalidates the signature.
+	HTTPRequest *http.Request
+}
+
+// WPSResponse is the response of a request passing through the middleware chain.
+type WPSResponse struct {
+	Body         []byte         // Body of the response.
+	Status       StatusResponse // Status of the response, set on errors too.
+	HTTPResponse *http.Response // Response received from the server, nil when a middleware answered the request.
+}
+
+// Handler sends a request and returns its response.
+type Handler func(req *WPSRequest) (*WPSResponse, error)
+
+// Middleware wraps a Handler. It can inspect or change the request, answer
+// it without calling next, or inspect the response.
+type Middleware func(next Handler) Handler
+
+// Use adds middlewares wrapping every request, subscribe included. The first
+// middleware added is the outermost one.
+func (pn *WebPubSub) Use(middlewares ...Middleware) {
+	pn.middlewareMutex.Lock()
+	pn.middlewares = append(pn.middlewares, middlewares...)
+	pn.middlewareMutex.Unlock()
+}
+
+func (pn *WebPubSub) copyMiddlewares() []Middleware {
+	pn.middlewareMutex.RLock()
+	defer pn.middlewareMutex.RUnlock()
+	return append([]Middleware{}, pn.middlewares...)
+}
+
+// chainMiddlewares wraps handler with the middlewares, the first one being
+// the outermost.
+func chainMiddlewares(middlewares []Middleware, handler Handler) Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+// requestChannels returns the channels targeted by the request of opts.
+func requestChannels(opts endpointOpts) []string {
+	channels, _, _ := requestResources(opts)
+	return channels
+}
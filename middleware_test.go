@@ -0,0 +1,133 @@
+package webpubsub
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddlewareOrderAndRequest(t *testing.T) {
+	assert := assert.New(t)
+
+	transport := &capturingTransport{status: 200, body: `[1,"Sent","15000000000000000"]`}
+	pn := newTestWebPubSub(transport)
+
+	var calls []string
+	var seen *WPSRequest
+	var seenResponse *WPSResponse
+	pn.Use(func(next Handler) Handler {
+		return func(req *WPSRequest) (*WPSResponse, error) {
+			calls = append(calls, "outer")
+			seen = req
+			req.HTTPRequest.Header.Set("X-Request-Id", "42")
+			resp, err := next(req)
+			seenResponse = resp
+			return resp, err
+		}
+	}, func(next Handler) Handler {
+		return func(req *WPSRequest) (*WPSResponse, error) {
+			calls = append(calls, "inner")
+			return next(req)
+		}
+	})
+
+	res, _, err := pn.Publish().Channel("ch").Message("hi").Execute()
+	assert.Nil(err)
+	assert.Equal(int64(15000000000000000), res.Timestamp)
+
+	assert.Equal([]string{"outer", "inner"}, calls)
+	assert.Equal(WPSPublishOperation, seen.Operation)
+	assert.Equal([]string{"ch"}, seen.Channels)
+	assert.Contains(seen.HTTPRequest.URL.String(), "/publish/demo/demo/0/ch/")
+	assert.Equal("42", transport.requests[0].Header.Get("X-Request-Id"))
+	assert.Equal(200, seenResponse.HTTPResponse.StatusCode)
+	assert.Equal(`[1,"Sent","15000000000000000"]`, string(seenResponse.Body))
+}
+
+func TestMiddlewareShortCircuit(t *testing.T) {
+	assert := assert.New(t)
+
+	transport := &capturingTransport{status: 500, body: "unexpected"}
+	pn := newTestWebPubSub(transport)
+
+	pn.Use(func(next Handler) Handler {
+		return func(req *WPSRequest) (*WPSResponse, error) {
+			if req.Operation == WPSTimeOperation {
+				return &WPSResponse{Body: []byte("[15000000000000000]"), Status: StatusResponse{StatusCode: 200}}, nil
+			}
+			return next(req)
+		}
+	})
+
+	res, status, err := pn.Time().Execute()
+	assert.Nil(err)
+	assert.Equal(int64(15000000000000000), res.Timetoken)
+	assert.Equal(200, status.StatusCode)
+	assert.Equal(0, len(transport.requests))
+}
+
+func TestMiddlewareFaultInjection(t *testing.T) {
+	assert := assert.New(t)
+
+	transport := &capturingTransport{status: 200, body: `[1,"Sent","15000000000000000"]`}
+	pn := newTestWebPubSub(transport)
+
+	injected := errors.New("injected")
+	pn.Use(func(next Handler) Handler {
+		return func(req *WPSRequest) (*WPSResponse, error) {
+			return nil, injected
+		}
+	})
+
+	_, status, err := pn.Publish().Channel("ch").Message("hi").Execute()
+	assert.Equal(injected, err)
+	assert.Equal(injected, status.Error)
+	assert.Equal(0, len(transport.requests))
+}
+
+func TestMiddlewareServerError(t *testing.T) {
+	assert := assert.New(t)
+
+	transport := &capturingTransport{status: 403, body: `{"error":true}`}
+	pn := newTestWebPubSub(transport)
+
+	var statusCode int
+	var responseErr error
+	pn.Use(func(next Handler) Handler {
+		return func(req *WPSRequest) (*WPSResponse, error) {
+			resp, err := next(req)
+			statusCode = resp.HTTPResponse.StatusCode
+			responseErr = err
+			return resp, err
+		}
+	})
+
+	_, status, err := pn.Fetch().Channels([]string{"a", "b"}).Execute()
+	assert.NotNil(err)
+	assert.Equal(err, responseErr)
+	assert.Equal(403, statusCode)
+	assert.Equal(403, status.StatusCode)
+}
+
+func TestMiddlewareRequestChannels(t *testing.T) {
+	assert := assert.New(t)
+
+	transport := &capturingTransport{status: 200, body: `{"status":200,"message":"OK","service":"Presence","uuids":[],"occupancy":0}`}
+	pn := newTestWebPubSub(transport)
+
+	var seen *WPSRequest
+	pn.Use(func(next Handler) Handler {
+		return func(req *WPSRequest) (*WPSResponse, error) {
+			seen = req
+			return next(req)
+		}
+	})
+
+	pn.HereNow().Channels([]string{"a", "b"}).Execute()
+	assert.Equal(WPSHereNowOperation, seen.Operation)
+	assert.Equal([]string{"a", "b"}, seen.Channels)
+
+	pn.Time().Execute()
+	assert.Empty(seen.Channels)
+}
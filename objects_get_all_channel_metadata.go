@@ -106,6 +106,26 @@ func (b *getAllChannelMetadataBuilder) Execute() (*WPSGetAllChannelMetadataRespo
 	return newWPSGetAllChannelMetadataResponse(rawJSON, b.opts, status)
 }
 
+// Iterate returns an iterator over the channel metadata of all the pages, the page
+// size is set with Limit. The requests are made with ctx when it is not nil.
+func (b *getAllChannelMetadataBuilder) Iterate(ctx Context) *ChannelMetadataIterator {
+	opts := *b.opts
+	if ctx != nil {
+		opts.ctx = ctx
+	}
+	iterator := &ChannelMetadataIterator{}
+	iterator.pageIterator = newPageIterator(opts.ctx, opts.Start, func(cursor string) (int, string, error) {
+		opts.Start = cursor
+		resp, _, err := (&getAllChannelMetadataBuilder{opts: &opts}).Execute()
+		if err != nil {
+			return 0, "", err
+		}
+		iterator.page = resp.Data
+		return len(resp.Data), resp.Next, nil
+	})
+	return iterator
+}
+
 type getAllChannelMetadataOpts struct {
 	webpubsub *WebPubSub
 
@@ -219,6 +239,10 @@ func (o *getAllChannelMetadataOpts) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *getAllChannelMetadataOpts) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *getAllChannelMetadataOpts) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
@@ -247,3 +271,25 @@ func newWPSGetAllChannelMetadataResponse(jsonBytes []byte, o *getAllChannelMetad
 
 	return resp, status, nil
 }
+
+// ChannelMetadataIterator iterates over the results of GetAllChannelMetadata across the pages.
+type ChannelMetadataIterator struct {
+	*pageIterator
+	page []WPSChannel
+}
+
+// Next advances to the next item, requesting the next page when needed.
+// It returns false when all the items are consumed or on error.
+func (it *ChannelMetadataIterator) Next() bool {
+	return it.next()
+}
+
+// Value returns the current item.
+func (it *ChannelMetadataIterator) Value() WPSChannel {
+	return it.page[it.index]
+}
+
+// Err returns the error which stopped the iteration, if any.
+func (it *ChannelMetadataIterator) Err() error {
+	return it.err
+}
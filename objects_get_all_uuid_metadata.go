@@ -106,6 +106,26 @@ func (b *getAllUUIDMetadataBuilder) Execute() (*WPSGetAllUUIDMetadataResponse, S
 	return newWPSGetAllUUIDMetadataResponse(rawJSON, b.opts, status)
 }
 
+// Iterate returns an iterator over the UUID metadata of all the pages, the page
+// size is set with Limit. The requests are made with ctx when it is not nil.
+func (b *getAllUUIDMetadataBuilder) Iterate(ctx Context) *UUIDMetadataIterator {
+	opts := *b.opts
+	if ctx != nil {
+		opts.ctx = ctx
+	}
+	iterator := &UUIDMetadataIterator{}
+	iterator.pageIterator = newPageIterator(opts.ctx, opts.Start, func(cursor string) (int, string, error) {
+		opts.Start = cursor
+		resp, _, err := (&getAllUUIDMetadataBuilder{opts: &opts}).Execute()
+		if err != nil {
+			return 0, "", err
+		}
+		iterator.page = resp.Data
+		return len(resp.Data), resp.Next, nil
+	})
+	return iterator
+}
+
 type getAllUUIDMetadataOpts struct {
 	webpubsub *WebPubSub
 
@@ -219,6 +239,10 @@ func (o *getAllUUIDMetadataOpts) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *getAllUUIDMetadataOpts) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *getAllUUIDMetadataOpts) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
@@ -247,3 +271,25 @@ func newWPSGetAllUUIDMetadataResponse(jsonBytes []byte, o *getAllUUIDMetadataOpt
 
 	return resp, status, nil
 }
+
+// UUIDMetadataIterator iterates over the results of GetAllUUIDMetadata across the pages.
+type UUIDMetadataIterator struct {
+	*pageIterator
+	page []WPSUUID
+}
+
+// Next advances to the next item, requesting the next page when needed.
+// It returns false when all the items are consumed or on error.
+func (it *UUIDMetadataIterator) Next() bool {
+	return it.next()
+}
+
+// Value returns the current item.
+func (it *UUIDMetadataIterator) Value() WPSUUID {
+	return it.page[it.index]
+}
+
+// Err returns the error which stopped the iteration, if any.
+func (it *UUIDMetadataIterator) Err() error {
+	return it.err
+}
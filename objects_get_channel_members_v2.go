@@ -112,6 +112,26 @@ func (b *getChannelMembersBuilderV2) Execute() (*WPSGetChannelMembersResponse, S
 	return newWPSGetChannelMembersResponse(rawJSON, b.opts, status)
 }
 
+// Iterate returns an iterator over the channel members of all the pages, the page
+// size is set with Limit. The requests are made with ctx when it is not nil.
+func (b *getChannelMembersBuilderV2) Iterate(ctx Context) *ChannelMembersIterator {
+	opts := *b.opts
+	if ctx != nil {
+		opts.ctx = ctx
+	}
+	iterator := &ChannelMembersIterator{}
+	iterator.pageIterator = newPageIterator(opts.ctx, opts.Start, func(cursor string) (int, string, error) {
+		opts.Start = cursor
+		resp, _, err := (&getChannelMembersBuilderV2{opts: &opts}).Execute()
+		if err != nil {
+			return 0, "", err
+		}
+		iterator.page = resp.Data
+		return len(resp.Data), resp.Next, nil
+	})
+	return iterator
+}
+
 type getChannelMembersOptsV2 struct {
 	webpubsub  *WebPubSub
 	Channel    string
@@ -229,10 +249,18 @@ func (o *getChannelMembersOptsV2) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *getChannelMembersOptsV2) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *getChannelMembersOptsV2) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
 
+func (o *getChannelMembersOptsV2) tokenResources() (channels, groups, uuids []string) {
+	return []string{o.Channel}, nil, nil
+}
+
 // WPSGetChannelMembersResponse is the Objects API Response for Get Members
 type WPSGetChannelMembersResponse struct {
 	status     int                 `json:"status"`
@@ -257,3 +285,25 @@ func newWPSGetChannelMembersResponse(jsonBytes []byte, o *getChannelMembersOptsV
 
 	return resp, status, nil
 }
+
+// ChannelMembersIterator iterates over the results of GetChannelMembers across the pages.
+type ChannelMembersIterator struct {
+	*pageIterator
+	page []WPSChannelMembers
+}
+
+// Next advances to the next item, requesting the next page when needed.
+// It returns false when all the items are consumed or on error.
+func (it *ChannelMembersIterator) Next() bool {
+	return it.next()
+}
+
+// Value returns the current item.
+func (it *ChannelMembersIterator) Value() WPSChannelMembers {
+	return it.page[it.index]
+}
+
+// Err returns the error which stopped the iteration, if any.
+func (it *ChannelMembersIterator) Err() error {
+	return it.err
+}
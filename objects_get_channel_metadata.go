@@ -165,10 +165,18 @@ func (o *getChannelMetadataOpts) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *getChannelMetadataOpts) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *getChannelMetadataOpts) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
 
+func (o *getChannelMetadataOpts) tokenResources() (channels, groups, uuids []string) {
+	return []string{o.Channel}, nil, nil
+}
+
 // WPSGetChannelMetadataResponse is the Objects API Response for Get Space
 type WPSGetChannelMetadataResponse struct {
 	status int        `json:"status"`
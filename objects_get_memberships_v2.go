@@ -116,6 +116,26 @@ func (b *getMembershipsBuilderV2) Execute() (*WPSGetMembershipsResponse, StatusR
 	return newWPSGetMembershipsResponse(rawJSON, b.opts, status)
 }
 
+// Iterate returns an iterator over the memberships of all the pages, the page
+// size is set with Limit. The requests are made with ctx when it is not nil.
+func (b *getMembershipsBuilderV2) Iterate(ctx Context) *MembershipsIterator {
+	opts := *b.opts
+	if ctx != nil {
+		opts.ctx = ctx
+	}
+	iterator := &MembershipsIterator{}
+	iterator.pageIterator = newPageIterator(opts.ctx, opts.Start, func(cursor string) (int, string, error) {
+		opts.Start = cursor
+		resp, _, err := (&getMembershipsBuilderV2{opts: &opts}).Execute()
+		if err != nil {
+			return 0, "", err
+		}
+		iterator.page = resp.Data
+		return len(resp.Data), resp.Next, nil
+	})
+	return iterator
+}
+
 type getMembershipsOptsV2 struct {
 	webpubsub  *WebPubSub
 	UUID       string
@@ -229,10 +249,22 @@ func (o *getMembershipsOptsV2) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *getMembershipsOptsV2) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *getMembershipsOptsV2) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
 
+func (o *getMembershipsOptsV2) tokenResources() (channels, groups, uuids []string) {
+	uuid := o.UUID
+	if uuid == "" {
+		uuid = o.webpubsub.Config.UUID
+	}
+	return nil, nil, []string{uuid}
+}
+
 // WPSGetMembershipsResponse is the Objects API Response for Get Memberships
 type WPSGetMembershipsResponse struct {
 	status     int              `json:"status"`
@@ -257,3 +289,25 @@ func newWPSGetMembershipsResponse(jsonBytes []byte, o *getMembershipsOptsV2,
 
 	return resp, status, nil
 }
+
+// MembershipsIterator iterates over the results of GetMemberships across the pages.
+type MembershipsIterator struct {
+	*pageIterator
+	page []WPSMemberships
+}
+
+// Next advances to the next item, requesting the next page when needed.
+// It returns false when all the items are consumed or on error.
+func (it *MembershipsIterator) Next() bool {
+	return it.next()
+}
+
+// Value returns the current item.
+func (it *MembershipsIterator) Value() WPSMemberships {
+	return it.page[it.index]
+}
+
+// Err returns the error which stopped the iteration, if any.
+func (it *MembershipsIterator) Err() error {
+	return it.err
+}
@@ -166,10 +166,22 @@ func (o *getUUIDMetadataOpts) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *getUUIDMetadataOpts) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *getUUIDMetadataOpts) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
 
+func (o *getUUIDMetadataOpts) tokenResources() (channels, groups, uuids []string) {
+	uuid := o.UUID
+	if uuid == "" {
+		uuid = o.webpubsub.Config.UUID
+	}
+	return nil, nil, []string{uuid}
+}
+
 // WPSGetUUIDMetadataResponse is the Objects API Response for Get User
 type WPSGetUUIDMetadataResponse struct {
 	status int     `json:"status"`
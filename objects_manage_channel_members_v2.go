@@ -219,7 +219,7 @@ func (o *manageMembersOptsV2) buildBody() ([]byte, error) {
 	jsonEncBytes, errEnc := json.Marshal(b)
 
 	if errEnc != nil {
-		o.webpubsub.Config.Log.Printf("ERROR: Serialization error: %s\n", errEnc.Error())
+		o.webpubsub.Config.logger(WPSRequestSubsystem).Error("serialization error", "error", errEnc)
 		return []byte{}, errEnc
 	}
 	return jsonEncBytes, nil
@@ -254,10 +254,18 @@ func (o *manageMembersOptsV2) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *manageMembersOptsV2) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *manageMembersOptsV2) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
 
+func (o *manageMembersOptsV2) tokenResources() (channels, groups, uuids []string) {
+	return []string{o.Channel}, nil, nil
+}
+
 // WPSManageMembersResponse is the Objects API Response for ManageMembers
 type WPSManageMembersResponse struct {
 	status     int                 `json:"status"`
@@ -219,7 +219,7 @@ func (o *manageMembershipsOptsV2) buildBody() ([]byte, error) {
 	jsonEncBytes, errEnc := json.Marshal(b)
 
 	if errEnc != nil {
-		o.webpubsub.Config.Log.Printf("ERROR: Serialization error: %s\n", errEnc.Error())
+		o.webpubsub.Config.logger(WPSRequestSubsystem).Error("serialization error", "error", errEnc)
 		return []byte{}, errEnc
 	}
 	return jsonEncBytes, nil
@@ -253,10 +253,22 @@ func (o *manageMembershipsOptsV2) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *manageMembershipsOptsV2) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *manageMembershipsOptsV2) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
 
+func (o *manageMembershipsOptsV2) tokenResources() (channels, groups, uuids []string) {
+	uuid := o.UUID
+	if uuid == "" {
+		uuid = o.webpubsub.Config.UUID
+	}
+	return nil, nil, []string{uuid}
+}
+
 // WPSManageMembershipsResponse is the Objects API Response for ManageMemberships
 type WPSManageMembershipsResponse struct {
 	status     int              `json:"status"`
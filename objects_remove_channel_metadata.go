@@ -154,10 +154,18 @@ func (o *removeChannelMetadataOpts) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *removeChannelMetadataOpts) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *removeChannelMetadataOpts) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
 
+func (o *removeChannelMetadataOpts) tokenResources() (channels, groups, uuids []string) {
+	return []string{o.Channel}, nil, nil
+}
+
 // WPSRemoveChannelMetadataResponse is the Objects API Response for delete space
 type WPSRemoveChannelMetadataResponse struct {
 	status int         `json:"status"`
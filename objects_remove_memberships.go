@@ -217,7 +217,7 @@ func (o *removeMembershipsOpts) buildBody() ([]byte, error) {
 	jsonEncBytes, errEnc := json.Marshal(b)
 
 	if errEnc != nil {
-		o.webpubsub.Config.Log.Printf("ERROR: Serialization error: %s\n", errEnc.Error())
+		o.webpubsub.Config.logger(WPSRequestSubsystem).Error("serialization error", "error", errEnc)
 		return []byte{}, errEnc
 	}
 	return jsonEncBytes, nil
@@ -252,10 +252,22 @@ func (o *removeMembershipsOpts) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *removeMembershipsOpts) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *removeMembershipsOpts) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
 
+func (o *removeMembershipsOpts) tokenResources() (channels, groups, uuids []string) {
+	uuid := o.UUID
+	if uuid == "" {
+		uuid = o.webpubsub.Config.UUID
+	}
+	return nil, nil, []string{uuid}
+}
+
 // WPSRemoveMembershipsResponse is the Objects API Response for RemoveMemberships
 type WPSRemoveMembershipsResponse struct {
 	status     int              `json:"status"`
@@ -156,10 +156,22 @@ func (o *removeUUIDMetadataOpts) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *removeUUIDMetadataOpts) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *removeUUIDMetadataOpts) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
 
+func (o *removeUUIDMetadataOpts) tokenResources() (channels, groups, uuids []string) {
+	uuid := o.UUID
+	if uuid == "" {
+		uuid = o.webpubsub.Config.UUID
+	}
+	return nil, nil, []string{uuid}
+}
+
 // WPSRemoveUUIDMetadataResponse is the Objects API Response for delete user
 type WPSRemoveUUIDMetadataResponse struct {
 	status int         `json:"status"`
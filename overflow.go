@@ -0,0 +1,202 @@
+package webpubsub
+
+import (
+	"sync"
+)
+
+// defaultMessageQueueSize is the number of received messages buffered before
+// their processing when Config.MessageQueueSize is not set.
+const defaultMessageQueueSize = 1000
+
+// QueueStats is a snapshot of the queues between the subscribe loop and the
+// listeners, returned by WebPubSub.QueueStats.
+type QueueStats struct {
+	MessageQueueDepth  int // Received messages waiting to be processed.
+	MessageQueueSize   int // Capacity of the message queue.
+	ListenerQueueDepth int // Events waiting in the queues of the EventListeners.
+	PendingDeliveries  int // Deliveries to the channel based Listeners in progress.
+	DroppedMessages    int // Messages dropped from the message queue by the OverflowPolicy since the start.
+	DroppedEvents      int // Events dropped from the listener queues by the OverflowPolicy since the start.
+}
+
+// dropCounter counts the events dropped by the OverflowPolicy, pending holds
+// the ones not reported in a WPSQueueOverflowCategory status yet.
+type dropCounter struct {
+	sync.Mutex
+	pending int
+	total   int
+}
+
+func (c *dropCounter) add(n int) {
+	if n == 0 {
+		return
+	}
+	c.Lock()
+	c.pending += n
+	c.total += n
+	c.Unlock()
+}
+
+func (c *dropCounter) take() int {
+	c.Lock()
+	defer c.Unlock()
+
+	n := c.pending
+	c.pending = 0
+	return n
+}
+
+func (c *dropCounter) count() int {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.total
+}
+
+// offer enqueues event applying policy when the queue is full, it returns
+// the number of dropped events.
+func (q *eventListenerQueue) offer(event func(EventListener), policy OverflowPolicy) int {
+	dropped := 0
+	for {
+		select {
+		case q.events <- event:
+			return dropped
+		case <-q.done:
+			return dropped
+		default:
+		}
+		if policy == WPSOverflowDropNewest {
+			return dropped + 1
+		}
+		select {
+		case <-q.events:
+			dropped++
+		default:
+		}
+	}
+}
+
+// deliver runs send, which delivers an event to the channel based Listeners,
+// in a new goroutine. Unless policy is WPSOverflowBlock, the event is dropped
+// when ListenerQueueSize deliveries are already in progress: they are blocked
+// on the channels of a slow consumer.
+func (m *ListenerManager) deliver(send func(), policy OverflowPolicy) {
+	m.RLock()
+	listeners := len(m.listeners)
+	m.RUnlock()
+	if listeners == 0 {
+		return
+	}
+
+	size := m.webpubsub.Config.ListenerQueueSize
+	if size <= 0 {
+		size = defaultListenerQueueSize
+	}
+	m.deliveriesMutex.Lock()
+	if policy != WPSOverflowBlock && m.deliveries >= size {
+		m.deliveriesMutex.Unlock()
+		m.dropped.add(listeners)
+		return
+	}
+	m.deliveries++
+	m.deliveriesMutex.Unlock()
+
+	go func() {
+		defer func() {
+			m.deliveriesMutex.Lock()
+			m.deliveries--
+			m.deliveriesMutex.Unlock()
+		}()
+		send()
+	}()
+}
+
+func (m *ListenerManager) pendingDeliveries() int {
+	m.deliveriesMutex.Lock()
+	defer m.deliveriesMutex.Unlock()
+
+	return m.deliveries
+}
+
+func (m *ListenerManager) queueDepth() int {
+	depth := 0
+	m.RLock()
+	for _, q := range m.eventListeners {
+		if q != nil {
+			depth += len(q.events)
+		}
+	}
+	m.RUnlock()
+	return depth
+}
+
+// enqueueMessage adds a received message to the message queue, applying the
+// OverflowPolicy when it is full.
+func (m *SubscriptionManager) enqueueMessage(message subscribeMessage) {
+	policy := m.webpubsub.Config.OverflowPolicy
+	if policy == WPSOverflowBlock {
+		m.messages <- message
+		return
+	}
+	for {
+		select {
+		case m.messages <- message:
+			return
+		default:
+		}
+		if policy == WPSOverflowDropNewest {
+			m.droppedMessages.add(1)
+			return
+		}
+		select {
+		case <-m.messages:
+			m.droppedMessages.add(1)
+		default:
+		}
+	}
+}
+
+// reportOverflow announces a WPSQueueOverflowCategory status when messages or
+// events were dropped since the previous report.
+func (m *SubscriptionManager) reportOverflow(channels, groups []string) {
+	messages := m.droppedMessages.take()
+	events := m.listenerManager.dropped.take()
+	if messages == 0 && events == 0 {
+		return
+	}
+
+	pnStatus := &WPSStatus{
+		Category:              WPSQueueOverflowCategory,
+		Operation:             WPSSubscribeOperation,
+		AffectedChannels:      channels,
+		AffectedChannelGroups: groups,
+		DroppedMessages:       messages,
+		DroppedEvents:         events,
+	}
+	m.webpubsub.Config.logger(WPSSubscribeSubsystem).Warn("queue overflow", "policy", m.webpubsub.Config.OverflowPolicy.String(),
+		"droppedMessages", messages, "droppedEvents", events)
+	m.listenerManager.announceStatus(pnStatus)
+}
+
+// listenerQueueDepth returns the number of events waiting in the queues of
+// the EventListeners of the client and of the Subscriptions.
+func (m *SubscriptionManager) listenerQueueDepth() int {
+	depth := m.listenerManager.queueDepth()
+	m.subscriptionsMutex.RLock()
+	for s := range m.subscriptions {
+		depth += s.listenerManager.queueDepth()
+	}
+	m.subscriptionsMutex.RUnlock()
+	return depth
+}
+
+func (m *SubscriptionManager) queueStats() QueueStats {
+	return QueueStats{
+		MessageQueueDepth:  len(m.messages),
+		MessageQueueSize:   cap(m.messages),
+		ListenerQueueDepth: m.listenerQueueDepth(),
+		PendingDeliveries:  m.listenerManager.pendingDeliveries(),
+		DroppedMessages:    m.droppedMessages.count(),
+		DroppedEvents:      m.listenerManager.dropped.count(),
+	}
+}
@@ -0,0 +1,177 @@
+package webpubsub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// blockingEventListener blocks in OnMessage until release is closed.
+type blockingEventListener struct {
+	BaseEventListener
+	started  chan bool
+	release  chan bool
+	received chan int64
+}
+
+func newBlockingEventListener() *blockingEventListener {
+	return &blockingEventListener{
+		started:  make(chan bool, 1),
+		release:  make(chan bool),
+		received: make(chan int64, 100),
+	}
+}
+
+func (l *blockingEventListener) OnMessage(message *WPSMessage) {
+	if message.Timetoken == 1 {
+		l.started <- true
+		<-l.release
+	}
+	l.received <- message.Timetoken
+}
+
+func receiveTimetokens(t *testing.T, l *blockingEventListener, count int) []int64 {
+	var timetokens []int64
+	for i := 0; i < count; i++ {
+		select {
+		case tt := <-l.received:
+			timetokens = append(timetokens, tt)
+		case <-time.After(2 * time.Second):
+			t.Fatal("message not delivered")
+		}
+	}
+	return timetokens
+}
+
+func testEventListenerOverflow(t *testing.T, policy OverflowPolicy) []int64 {
+	assert := assert.New(t)
+	pn := NewWebPubSub(NewDemoConfig())
+	pn.Config.ListenerQueueSize = 2
+	pn.Config.OverflowPolicy = policy
+	listener := newBlockingEventListener()
+	pn.AddListener(listener)
+	lm := pn.subscriptionManager.listenerManager
+
+	lm.announceMessage(&WPSMessage{Timetoken: 1})
+	<-listener.started
+	for i := 2; i <= 5; i++ {
+		lm.announceMessage(&WPSMessage{Timetoken: int64(i)})
+	}
+	stats := pn.QueueStats()
+	assert.Equal(2, stats.ListenerQueueDepth)
+	assert.Equal(2, stats.DroppedEvents)
+
+	close(listener.release)
+	return receiveTimetokens(t, listener, 3)
+}
+
+func TestEventListenerOverflowDropOldest(t *testing.T) {
+	assert.Equal(t, []int64{1, 4, 5}, testEventListenerOverflow(t, WPSOverflowDropOldest))
+}
+
+func TestEventListenerOverflowDropNewest(t *testing.T) {
+	assert.Equal(t, []int64{1, 2, 3}, testEventListenerOverflow(t, WPSOverflowDropNewest))
+}
+
+func TestMessageQueueOverflow(t *testing.T) {
+	assert := assert.New(t)
+	config := NewDemoConfig()
+	config.MessageQueueSize = 2
+	config.OverflowPolicy = WPSOverflowDropOldest
+	pn := NewWebPubSub(config)
+	listener := newRecordingEventListener()
+	pn.AddListener(listener)
+	m := pn.subscriptionManager
+
+	for i := 1; i <= 3; i++ {
+		m.enqueueMessage(subscribeMessage{Payload: i})
+	}
+	stats := pn.QueueStats()
+	assert.Equal(2, stats.MessageQueueDepth)
+	assert.Equal(2, stats.MessageQueueSize)
+	assert.Equal(1, stats.DroppedMessages)
+	assert.Equal(2, (<-m.messages).Payload)
+
+	m.reportOverflow([]string{"ch"}, nil)
+	status := nextStatus(t, listener)
+	assert.Equal(WPSQueueOverflowCategory, status.Category)
+	assert.Equal(1, status.DroppedMessages)
+	assert.Equal(0, status.DroppedEvents)
+	assert.Equal([]string{"ch"}, status.AffectedChannels)
+
+	// Nothing is reported until more messages are dropped.
+	m.reportOverflow([]string{"ch"}, nil)
+	select {
+	case status := <-listener.statuses:
+		t.Fatalf("unexpected status %s", status.Category)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestChannelListenerOverflow(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewWebPubSub(NewDemoConfig())
+	pn.Config.ListenerQueueSize = 1
+	pn.Config.OverflowPolicy = WPSOverflowDropNewest
+	listener := NewListener()
+	pn.AddListener(listener)
+	lm := pn.subscriptionManager.listenerManager
+
+	for i := 1; i <= 3; i++ {
+		lm.announceMessage(&WPSMessage{Timetoken: int64(i)})
+	}
+	stats := pn.QueueStats()
+	assert.Equal(1, stats.PendingDeliveries)
+	assert.Equal(2, stats.DroppedEvents)
+
+	select {
+	case message := <-listener.Message:
+		assert.Equal(int64(1), message.Timetoken)
+	case <-time.After(2 * time.Second):
+		t.Fatal("message not delivered")
+	}
+}
+
+func TestOverflowKeepsStatuses(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewWebPubSub(NewDemoConfig())
+	pn.Config.ListenerQueueSize = 1
+	pn.Config.OverflowPolicy = WPSOverflowDropNewest
+	listener := NewListener()
+	pn.AddListener(listener)
+	lm := pn.subscriptionManager.listenerManager
+
+	lm.announceMessage(&WPSMessage{Timetoken: 1})
+	lm.announceStatus(&WPSStatus{Category: WPSQueueOverflowCategory})
+	assert.Equal(0, pn.QueueStats().DroppedEvents)
+
+	<-listener.Message
+	select {
+	case status := <-listener.Status:
+		assert.Equal(WPSQueueOverflowCategory, status.Category)
+	case <-time.After(2 * time.Second):
+		t.Fatal("status not delivered")
+	}
+}
+
+func TestSubscriptionOverflowIsReported(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewWebPubSub(NewDemoConfig())
+	pn.Config.ListenerQueueSize = 1
+	pn.Config.OverflowPolicy = WPSOverflowDropNewest
+	listener := newRecordingEventListener()
+	pn.AddListener(listener)
+	subscription := pn.Channel("ch").Subscription(SubscriptionOptions{})
+	subscription.AddListener(NewListener())
+
+	for i := 1; i <= 3; i++ {
+		subscription.listenerManager.announceMessage(&WPSMessage{Timetoken: int64(i)})
+	}
+	assert.Equal(2, pn.QueueStats().DroppedEvents)
+
+	pn.subscriptionManager.reportOverflow([]string{"ch"}, nil)
+	status := nextStatus(t, listener)
+	assert.Equal(WPSQueueOverflowCategory, status.Category)
+	assert.Equal(2, status.DroppedEvents)
+}
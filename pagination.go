@@ -0,0 +1,58 @@
+package webpubsub
+
+// pageFetcher fetches the page starting at the cursor, stores its items in the
+// typed iterator and returns the number of items and the cursor of the next page.
+type pageFetcher func(cursor string) (int, string, error)
+
+// pageIterator walks the items of the paginated list APIs, requesting the next
+// page only when the items of the current page are consumed.
+type pageIterator struct {
+	ctx    Context
+	fetch  pageFetcher
+	cursor string
+	index  int
+	size   int
+	last   bool
+	err    error
+}
+
+func newPageIterator(ctx Context, cursor string, fetch pageFetcher) *pageIterator {
+	return &pageIterator{
+		ctx:    ctx,
+		fetch:  fetch,
+		cursor: cursor,
+		index:  -1,
+	}
+}
+
+// next advances to the next item and fetches the next page when needed.
+// It returns false when there are no more items or on error.
+func (it *pageIterator) next() bool {
+	for it.err == nil {
+		if it.index+1 < it.size {
+			it.index++
+			return true
+		}
+		if it.last {
+			return false
+		}
+		if it.ctx != nil {
+			select {
+			case <-it.ctx.Done():
+				it.err = it.ctx.Err()
+				return false
+			default:
+			}
+		}
+		size, cursor, err := it.fetch(it.cursor)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.index = -1
+		it.size = size
+		it.last = size == 0 || cursor == "" || cursor == it.cursor
+		it.cursor = cursor
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package webpubsub
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/webpubsub/sdk-go/v7/tests/stubs"
+)
+
+var paginationIgnoreQueryKeys = []string{"uuid", "pnsdk", "signature", "timestamp", "count", "l_obj", "l_file"}
+
+func TestGetAllChannelMetadataIterate(t *testing.T) {
+	assert := assert.New(t)
+
+	interceptor := stubs.NewInterceptor()
+	interceptor.AddStub(&stubs.Stub{
+		Method:             "GET",
+		Path:               "/v2/objects/demo/channels",
+		Query:              "limit=2&filter=name%20like%20%27a%2A%27",
+		ResponseBody:       `{"status":200,"data":[{"id":"a1"},{"id":"a2"}],"next":"p2"}`,
+		IgnoreQueryKeys:    paginationIgnoreQueryKeys,
+		ResponseStatusCode: 200,
+	})
+	interceptor.AddStub(&stubs.Stub{
+		Method:             "GET",
+		Path:               "/v2/objects/demo/channels",
+		Query:              "limit=2&filter=name%20like%20%27a%2A%27&start=p2",
+		ResponseBody:       `{"status":200,"data":[{"id":"a3"}],"next":"p3"}`,
+		IgnoreQueryKeys:    paginationIgnoreQueryKeys,
+		ResponseStatusCode: 200,
+	})
+	interceptor.AddStub(&stubs.Stub{
+		Method:             "GET",
+		Path:               "/v2/objects/demo/channels",
+		Query:              "limit=2&filter=name%20like%20%27a%2A%27&start=p3",
+		ResponseBody:       `{"status":200,"data":[]}`,
+		IgnoreQueryKeys:    paginationIgnoreQueryKeys,
+		ResponseStatusCode: 200,
+	})
+	pn := newTestWebPubSub(interceptor.Transport)
+
+	it := pn.GetAllChannelMetadata().Filter("name like 'a*'").Limit(2).Iterate(nil)
+	var ids []string
+	for it.Next() {
+		ids = append(ids, it.Value().ID)
+	}
+	assert.Nil(it.Err())
+	assert.Equal([]string{"a1", "a2", "a3"}, ids)
+	assert.False(it.Next())
+}
+
+func TestGetAllUUIDMetadataIterateError(t *testing.T) {
+	assert := assert.New(t)
+
+	interceptor := stubs.NewInterceptor()
+	interceptor.AddStub(&stubs.Stub{
+		Method:             "GET",
+		Path:               "/v2/objects/demo/uuids",
+		Query:              "limit=1",
+		ResponseBody:       `{"status":200,"data":[{"id":"u1"}],"next":"p2"}`,
+		IgnoreQueryKeys:    paginationIgnoreQueryKeys,
+		ResponseStatusCode: 200,
+	})
+	pn := newTestWebPubSub(interceptor.Transport)
+
+	it := pn.GetAllUUIDMetadata().Limit(1).Iterate(nil)
+	assert.True(it.Next())
+	assert.Equal("u1", it.Value().ID)
+	assert.False(it.Next())
+	assert.NotNil(it.Err())
+}
+
+func TestListFilesIterateEarlyStop(t *testing.T) {
+	assert := assert.New(t)
+
+	interceptor := stubs.NewInterceptor()
+	interceptor.AddStub(&stubs.Stub{
+		Method:             "GET",
+		Path:               "/v1/files/demo/channels/ch/files",
+		Query:              "limit=2",
+		ResponseBody:       `{"status":200,"data":[{"name":"f1","id":"1"},{"name":"f2","id":"2"}],"next":"p2","count":2}`,
+		IgnoreQueryKeys:    paginationIgnoreQueryKeys,
+		ResponseStatusCode: 200,
+	})
+	pn := newTestWebPubSub(interceptor.Transport)
+
+	it := pn.ListFiles().Channel("ch").Limit(2).Iterate(nil)
+	assert.True(it.Next())
+	assert.Equal("f1", it.Value().Name)
+	assert.Nil(it.Err())
+}
+
+func TestPageIteratorContextCanceled(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	calls := 0
+	it := newPageIterator(ctx, "", func(cursor string) (int, string, error) {
+		calls++
+		return 1, "next", nil
+	})
+	assert.False(it.next())
+	assert.Equal(context.Canceled, it.err)
+	assert.Equal(0, calls)
+}
+
+func TestPageIteratorRepeatedCursor(t *testing.T) {
+	assert := assert.New(t)
+
+	calls := 0
+	it := newPageIterator(nil, "", func(cursor string) (int, string, error) {
+		calls++
+		return 1, "same", nil
+	})
+	count := 0
+	for it.next() {
+		count++
+	}
+	assert.Nil(it.err)
+	assert.Equal(2, count)
+	assert.Equal(2, calls)
+}
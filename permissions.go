@@ -0,0 +1,253 @@
+package webpubsub
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/webpubsub/sdk-go/v7/pnerr"
+)
+
+// PermissionResource is a channel, channel group or UUID checked by
+// WebPubSub.CanPerform.
+type PermissionResource struct {
+	Type WPSResourceType
+	Name string
+}
+
+// ChannelResource returns the PermissionResource of a channel.
+func ChannelResource(name string) PermissionResource {
+	return PermissionResource{Type: WPSChannels, Name: name}
+}
+
+// GroupResource returns the PermissionResource of a channel group.
+func GroupResource(name string) PermissionResource {
+	return PermissionResource{Type: WPSGroups, Name: name}
+}
+
+// UUIDResource returns the PermissionResource of a UUID.
+func UUIDResource(name string) PermissionResource {
+	return PermissionResource{Type: WPSUUIDs, Name: name}
+}
+
+func (r PermissionResource) String() string {
+	switch r.Type {
+	case WPSChannels:
+		return "channel " + r.Name
+	case WPSGroups:
+		return "channel group " + r.Name
+	default:
+		return "uuid " + r.Name
+	}
+}
+
+// operationPermissions are the permissions an operation requires on its
+// channels, channel groups and UUIDs. The operations which are missing don't
+// require any.
+var operationPermissions = map[OperationType]struct {
+	channel, group, uuid WPSGrantBitMask
+}{
+	WPSSubscribeOperation:                     {channel: WPSRead, group: WPSRead},
+	WPSHereNowOperation:                       {channel: WPSRead, group: WPSRead},
+	WPSGetStateOperation:                      {channel: WPSRead, group: WPSRead},
+	WPSSetStateOperation:                      {channel: WPSRead, group: WPSRead},
+	WPSPublishOperation:                       {channel: WPSWrite},
+	WPSFireOperation:                          {channel: WPSWrite},
+	WPSSignalOperation:                        {channel: WPSWrite},
+	WPSSendFileOperation:                      {channel: WPSWrite},
+	WPSPublishFileMessageOperation:            {channel: WPSWrite},
+	WPSAddMessageActionsOperation:             {channel: WPSWrite},
+	WPSHistoryOperation:                       {channel: WPSRead},
+	WPSFetchMessagesOperation:                 {channel: WPSRead},
+	WPSMessageCountsOperation:                 {channel: WPSRead},
+	WPSGetMessageActionsOperation:             {channel: WPSRead},
+	WPSListFilesOperation:                     {channel: WPSRead},
+	WPSDownloadFileOperation:                  {channel: WPSRead},
+	WPSGetFileURLOperation:                    {channel: WPSRead},
+	WPSDeleteMessagesOperation:                {channel: WPSDelete},
+	WPSRemoveMessageActionsOperation:          {channel: WPSDelete},
+	WPSDeleteFileOperation:                    {channel: WPSDelete},
+	WPSGetChannelMetadataOperation:            {channel: WPSGet},
+	WPSSetChannelMetadataOperation:            {channel: WPSUpdate},
+	WPSRemoveChannelMetadataOperation:         {channel: WPSDelete},
+	WPSGetChannelMembersOperation:             {channel: WPSGet},
+	WPSSetChannelMembersOperation:             {channel: WPSManage},
+	WPSRemoveChannelMembersOperation:          {channel: WPSManage},
+	WPSManageMembersOperation:                 {channel: WPSManage},
+	WPSGetUUIDMetadataOperation:               {uuid: WPSGet},
+	WPSSetUUIDMetadataOperation:               {uuid: WPSUpdate},
+	WPSRemoveUUIDMetadataOperation:            {uuid: WPSDelete},
+	WPSGetMembershipsOperation:                {uuid: WPSGet},
+	WPSSetMembershipsOperation:                {uuid: WPSUpdate},
+	WPSRemoveMembershipsOperation:             {uuid: WPSUpdate},
+	WPSManageMembershipsOperation:             {uuid: WPSUpdate},
+	WPSAddChannelsToChannelGroupOperation:     {group: WPSManage},
+	WPSRemoveChannelFromChannelGroupOperation: {group: WPSManage},
+	WPSRemoveGroupOperation:                   {group: WPSManage},
+	WPSChannelsForGroupOperation:              {group: WPSRead},
+}
+
+// requiredPermission returns the permission operation requires on a resource
+// of resourceType, 0 when it doesn't require any.
+func requiredPermission(operation OperationType, resourceType WPSResourceType) WPSGrantBitMask {
+	required := operationPermissions[operation]
+	switch resourceType {
+	case WPSChannels:
+		return required.channel
+	case WPSGroups:
+		return required.group
+	default:
+		return required.uuid
+	}
+}
+
+// grants reports whether the token grants permission on the resource, by name
+// or by one of its patterns.
+func (t *StoredToken) grants(resource PermissionResource, permission WPSGrantBitMask) bool {
+	if t.Parsed == nil {
+		return false
+	}
+	name := strings.TrimSuffix(resource.Name, "-pnpres")
+	granted := WPSGrantBitMask(0)
+	switch resource.Type {
+	case WPSChannels:
+		if p, ok := t.Parsed.Resources.Channels[name]; ok {
+			granted |= channelPermissionsMask(p)
+		}
+		for pattern, re := range t.channelPatterns {
+			if re.MatchString(name) {
+				granted |= channelPermissionsMask(t.Parsed.Patterns.Channels[pattern])
+			}
+		}
+	case WPSGroups:
+		if p, ok := t.Parsed.Resources.ChannelGroups[name]; ok {
+			granted |= groupPermissionsMask(p)
+		}
+		for pattern, re := range t.groupPatterns {
+			if re.MatchString(name) {
+				granted |= groupPermissionsMask(t.Parsed.Patterns.ChannelGroups[pattern])
+			}
+		}
+	default:
+		if p, ok := t.Parsed.Resources.UUIDs[name]; ok {
+			granted |= uuidPermissionsMask(p)
+		}
+		for pattern, re := range t.uuidPatterns {
+			if re.MatchString(name) {
+				granted |= uuidPermissionsMask(t.Parsed.Patterns.UUIDs[pattern])
+			}
+		}
+	}
+	return granted&permission == permission
+}
+
+func channelPermissionsMask(p ChannelPermissions) WPSGrantBitMask {
+	mask := WPSGrantBitMask(0)
+	for _, perm := range []struct {
+		granted bool
+		mask    WPSGrantBitMask
+	}{
+		{p.Read, WPSRead}, {p.Write, WPSWrite}, {p.Manage, WPSManage}, {p.Delete, WPSDelete},
+		{p.Get, WPSGet}, {p.Update, WPSUpdate}, {p.Join, WPSJoin},
+	} {
+		if perm.granted {
+			mask |= perm.mask
+		}
+	}
+	return mask
+}
+
+func groupPermissionsMask(p GroupPermissions) WPSGrantBitMask {
+	return channelPermissionsMask(ChannelPermissions{Read: p.Read, Manage: p.Manage})
+}
+
+func uuidPermissionsMask(p UUIDPermissions) WPSGrantBitMask {
+	return channelPermissionsMask(ChannelPermissions{Get: p.Get, Update: p.Update, Delete: p.Delete})
+}
+
+func permissionName(permission WPSGrantBitMask) string {
+	switch permission {
+	case WPSRead:
+		return "read"
+	case WPSWrite:
+		return "write"
+	case WPSManage:
+		return "manage"
+	case WPSDelete:
+		return "delete"
+	case WPSCreate:
+		return "create"
+	case WPSGet:
+		return "get"
+	case WPSUpdate:
+		return "update"
+	case WPSJoin:
+		return "join"
+	}
+	return fmt.Sprintf("%d", permission)
+}
+
+// canPerform checks the permission operation requires on resource against the
+// token selected for it. It returns a *pnerr.PermissionError when the token
+// doesn't grant it or when there is no decoded token to check.
+func (m *TokenManager) canPerform(operation OperationType, resource PermissionResource) error {
+	permission := requiredPermission(operation, resource.Type)
+	if permission == 0 {
+		return nil
+	}
+
+	var channels, groups, uuids []string
+	switch resource.Type {
+	case WPSChannels:
+		channels = []string{resource.Name}
+	case WPSGroups:
+		groups = []string{resource.Name}
+	default:
+		uuids = []string{resource.Name}
+	}
+	token := m.storedTokenFor(channels, groups, uuids)
+	if token == nil || !token.grants(resource, permission) {
+		return pnerr.NewPermissionError(operation.String(), resource.String(), permissionName(permission))
+	}
+	return nil
+}
+
+// preflight checks the permissions of the request of o against its token
+// before it is sent. The requests whose token can't be decoded are sent.
+func preflight(o endpointOpts) error {
+	if !o.config().PermissionPreflight {
+		return nil
+	}
+	r, ok := o.(tokenResourcesOpts)
+	if !ok {
+		return nil
+	}
+	channels, groups, uuids := r.tokenResources()
+	resources := make([]PermissionResource, 0, len(channels)+len(groups)+len(uuids))
+	for _, channel := range channels {
+		resources = append(resources, ChannelResource(channel))
+	}
+	for _, group := range groups {
+		resources = append(resources, GroupResource(group))
+	}
+	for _, uuid := range uuids {
+		resources = append(resources, UUIDResource(uuid))
+	}
+
+	token := o.tokenManager().storedTokenFor(channels, groups, uuids)
+	if token == nil || token.Parsed == nil {
+		return nil
+	}
+	for _, resource := range resources {
+		permission := requiredPermission(o.operationType(), resource.Type)
+		if permission != 0 && !token.grants(resource, permission) {
+			return pnerr.NewPermissionError(o.operationType().String(), resource.String(), permissionName(permission))
+		}
+	}
+	return nil
+}
+
+func isPermissionError(err error) bool {
+	var permissionError *pnerr.PermissionError
+	return errors.As(err, &permissionError)
+}
@@ -0,0 +1,91 @@
+package webpubsub
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/webpubsub/sdk-go/v7/pnerr"
+)
+
+func TestCanPerform(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := NewWebPubSub(NewDemoConfig())
+	assert.False(pn.CanPerform(WPSPublishOperation, ChannelResource("chat")))
+	assert.True(pn.CanPerform(WPSTimeOperation, ChannelResource("chat")))
+
+	pn.SetToken(newTestToken(t, time.Now(), 60, GrantResources{
+		Channels: map[string]int64{"chat": int64(WPSRead | WPSWrite)},
+		Groups:   map[string]int64{"cg": int64(WPSRead)},
+		UUIDs:    map[string]int64{"alice": int64(WPSGet)},
+	}, GrantResources{
+		Channels: map[string]int64{"room-.*": int64(WPSRead)},
+	}))
+
+	assert.True(pn.CanPerform(WPSPublishOperation, ChannelResource("chat")))
+	assert.True(pn.CanPerform(WPSSubscribeOperation, ChannelResource("chat-pnpres")))
+	assert.False(pn.CanPerform(WPSDeleteMessagesOperation, ChannelResource("chat")))
+	assert.True(pn.CanPerform(WPSHistoryOperation, ChannelResource("room-1")))
+	assert.False(pn.CanPerform(WPSPublishOperation, ChannelResource("room-1")))
+	assert.False(pn.CanPerform(WPSHistoryOperation, ChannelResource("lobby")))
+	assert.True(pn.CanPerform(WPSSubscribeOperation, GroupResource("cg")))
+	assert.False(pn.CanPerform(WPSRemoveGroupOperation, GroupResource("cg")))
+	assert.True(pn.CanPerform(WPSGetUUIDMetadataOperation, UUIDResource("alice")))
+	assert.False(pn.CanPerform(WPSSetUUIDMetadataOperation, UUIDResource("alice")))
+}
+
+func TestPermissionPreflight(t *testing.T) {
+	assert := assert.New(t)
+
+	transport := &capturingTransport{status: 200, body: `[1,"Sent","15"]`}
+	pn := NewWebPubSub(NewDemoConfig())
+	pn.SetClient(&http.Client{Transport: transport})
+	pn.Config.PermissionPreflight = true
+	pn.SetToken(newTestToken(t, time.Now(), 60, GrantResources{
+		Channels: map[string]int64{"chat": int64(WPSRead)},
+	}, GrantResources{}))
+
+	_, status, err := pn.Publish().Channel("chat").Message("hi").Execute()
+	assert.Equal(WPSAccessDeniedCategory, status.Category)
+	if assert.IsType(&pnerr.PermissionError{}, err) {
+		permissionError := err.(*pnerr.PermissionError)
+		assert.Equal("Publish", permissionError.Operation)
+		assert.Equal("channel chat", permissionError.Resource)
+		assert.Equal("write", permissionError.Permission)
+	}
+	assert.Empty(transport.requests)
+
+	pn.Config.PermissionPreflight = false
+	_, _, err = pn.Publish().Channel("chat").Message("hi").Execute()
+	assert.Nil(err)
+	assert.Len(transport.requests, 1)
+}
+
+func TestPermissionPreflightRefreshesToken(t *testing.T) {
+	assert := assert.New(t)
+
+	readOnly := newTestToken(t, time.Now(), 60, GrantResources{
+		Channels: map[string]int64{"chat": int64(WPSRead)},
+	}, GrantResources{})
+	readWrite := newTestToken(t, time.Now(), 60, GrantResources{
+		Channels: map[string]int64{"chat": int64(WPSRead | WPSWrite)},
+	}, GrantResources{})
+
+	transport := &capturingTransport{status: 200, body: `[1,"Sent","15"]`}
+	pn := NewWebPubSub(NewDemoConfig())
+	defer pn.Destroy()
+	pn.SetClient(&http.Client{Transport: transport})
+	pn.Config.PermissionPreflight = true
+	pn.Config.TokenProvider = func(token string) (string, error) {
+		return readWrite, nil
+	}
+	pn.SetToken(readOnly)
+
+	_, _, err := pn.Publish().Channel("chat").Message("hi").Execute()
+	assert.Nil(err)
+	if assert.Len(transport.requests, 1) {
+		assert.Equal(readWrite, transport.requests[0].URL.Query().Get("auth"))
+	}
+}
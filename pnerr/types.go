@@ -109,3 +109,24 @@ func NewResponseParsingError(msg string,
 		OrigError: origError,
 	}
 }
+
+// The PAMv3 token doesn't grant the permission required by the request,
+// detected before sending it when the permission preflight is enabled.
+type PermissionError struct {
+	Operation  string
+	Resource   string
+	Permission string
+}
+
+func (e PermissionError) Error() string {
+	return fmt.Sprintf("webpubsub/permission: %s requires the %s permission on %s",
+		e.Operation, e.Permission, e.Resource)
+}
+
+func NewPermissionError(operation, resource, permission string) *PermissionError {
+	return &PermissionError{
+		Operation:  operation,
+		Resource:   resource,
+		Permission: permission,
+	}
+}
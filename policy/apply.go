@@ -0,0 +1,168 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	webpubsub "github.com/webpubsub/sdk-go/v7"
+)
+
+// GrantRequest is a Grant request of a plan: the same permissions and TTL on
+// resources of one type for auth keys. A request without permissions
+// revokes them.
+type GrantRequest struct {
+	AuthKeys    []string
+	Type        webpubsub.WPSResourceType
+	Names       []string
+	Permissions webpubsub.WPSGrantBitMask
+	TTL         int
+}
+
+// Execute issues the Grant request.
+func (r *GrantRequest) Execute(pn *webpubsub.WebPubSub) (*webpubsub.GrantResponse, webpubsub.StatusResponse, error) {
+	b := pn.Grant().
+		AuthKeys(r.AuthKeys).
+		Read(r.Permissions&webpubsub.WPSRead != 0).
+		Write(r.Permissions&webpubsub.WPSWrite != 0).
+		Manage(r.Permissions&webpubsub.WPSManage != 0).
+		Delete(r.Permissions&webpubsub.WPSDelete != 0).
+		Get(r.Permissions&webpubsub.WPSGet != 0).
+		Update(r.Permissions&webpubsub.WPSUpdate != 0).
+		Join(r.Permissions&webpubsub.WPSJoin != 0)
+	if r.Permissions != 0 {
+		b.TTL(r.TTL)
+	}
+	switch r.Type {
+	case webpubsub.WPSChannels:
+		b.Channels(r.Names)
+	case webpubsub.WPSGroups:
+		b.ChannelGroups(r.Names)
+	default:
+		b.UUIDs(r.Names)
+	}
+	return b.Execute()
+}
+
+func (r *GrantRequest) String() string {
+	target := fmt.Sprintf("%s %s to %s", typeName(r.Type), strings.Join(r.Names, ","), strings.Join(r.AuthKeys, ","))
+	if r.Permissions == 0 {
+		return "revoke " + target
+	}
+	return fmt.Sprintf("grant %s on %s (ttl %d)", permissionNames(r.Permissions), target, r.TTL)
+}
+
+// Requests groups the changes of the plan which aren't Unchanged in Grant
+// requests. The auth keys getting the same permissions on the same
+// resources share the requests, which have opts.ChunkSize resources and
+// auth keys at most.
+func (p *Plan) Requests(opts Options) []*GrantRequest {
+	type keyGroup struct {
+		t       webpubsub.WPSResourceType
+		mask    webpubsub.WPSGrantBitMask
+		ttl     int
+		authKey string
+	}
+	type namesGroup struct {
+		t     webpubsub.WPSResourceType
+		mask  webpubsub.WPSGrantBitMask
+		ttl   int
+		names string
+	}
+
+	// The resources of each auth key with the same permissions and TTL.
+	keyGroups := []keyGroup{}
+	resources := make(map[keyGroup][]string)
+	for _, c := range p.Changes {
+		if c.Action == Unchanged {
+			continue
+		}
+		g := keyGroup{c.Type, c.Desired, c.TTL, c.AuthKey}
+		if c.Desired == 0 {
+			g.ttl = 0
+		}
+		if _, ok := resources[g]; !ok {
+			keyGroups = append(keyGroups, g)
+		}
+		resources[g] = append(resources[g], c.Name)
+	}
+
+	// The auth keys which get them on the same resources.
+	namesGroups := []namesGroup{}
+	authKeys := make(map[namesGroup][]string)
+	for _, g := range keyGroups {
+		n := namesGroup{g.t, g.mask, g.ttl, strings.Join(resources[g], "\x00")}
+		if _, ok := authKeys[n]; !ok {
+			namesGroups = append(namesGroups, n)
+		}
+		authKeys[n] = append(authKeys[n], g.authKey)
+	}
+
+	requests := []*GrantRequest{}
+	size := opts.chunkSize()
+	for _, n := range namesGroups {
+		for _, names := range chunk(strings.Split(n.names, "\x00"), size) {
+			for _, keys := range chunk(authKeys[n], size) {
+				requests = append(requests, &GrantRequest{
+					AuthKeys:    keys,
+					Type:        n.t,
+					Names:       names,
+					Permissions: n.mask,
+					TTL:         n.ttl,
+				})
+			}
+		}
+	}
+	return requests
+}
+
+// ApplyResult aggregates the results of the requests issued by Apply.
+type ApplyResult struct {
+	// Tokens maps the names of the tokens to the tokens granted.
+	Tokens map[string]string
+
+	Succeeded int
+	Failed    int
+	Errors    []error
+}
+
+// Err returns an error summing up the failed requests, nil when all of them
+// succeeded.
+func (r *ApplyResult) Err() error {
+	if r.Failed == 0 {
+		return nil
+	}
+	messages := make([]string, 0, len(r.Errors))
+	for _, err := range r.Errors {
+		messages = append(messages, err.Error())
+	}
+	return fmt.Errorf("%d of %d requests failed: %s", r.Failed, r.Succeeded+r.Failed,
+		strings.Join(messages, "; "))
+}
+
+// Apply grants the tokens of the plan and issues its Grant requests. A
+// failed request doesn't stop the next ones, the failures are collected in
+// the result.
+func (p *Plan) Apply(pn *webpubsub.WebPubSub, opts Options) *ApplyResult {
+	result := &ApplyResult{Tokens: make(map[string]string)}
+
+	for _, r := range p.Requests(opts) {
+		if _, _, err := r.Execute(pn); err != nil {
+			result.Failed++
+			result.Errors = append(result.Errors, fmt.Errorf("%s: %w", r, err))
+			continue
+		}
+		result.Succeeded++
+	}
+
+	for _, t := range p.Tokens {
+		resp, _, err := t.Execute(pn)
+		if err != nil {
+			result.Failed++
+			result.Errors = append(result.Errors, fmt.Errorf("token %s: %w", t.Name, err))
+			continue
+		}
+		result.Tokens[t.Name] = resp.Data.Token
+		result.Succeeded++
+	}
+	return result
+}
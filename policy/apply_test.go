@@ -0,0 +1,98 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	webpubsub "github.com/webpubsub/sdk-go/v7"
+)
+
+func TestPlanRequests(t *testing.T) {
+	assert := assert.New(t)
+
+	rw := webpubsub.WPSGrantBitMask(webpubsub.WPSRead | webpubsub.WPSWrite)
+	plan := &Plan{Changes: []*Change{
+		{Action: Unchanged, AuthKey: "k1", Type: webpubsub.WPSChannels, Name: "a", Current: rw, Desired: rw, TTL: 60},
+		{Action: Create, AuthKey: "k1", Type: webpubsub.WPSChannels, Name: "b", Desired: rw, TTL: 60},
+		{Action: Create, AuthKey: "k2", Type: webpubsub.WPSChannels, Name: "b", Desired: rw, TTL: 60},
+		{Action: Create, AuthKey: "k1", Type: webpubsub.WPSChannels, Name: "c", Desired: rw, TTL: 60},
+		{Action: Create, AuthKey: "k2", Type: webpubsub.WPSChannels, Name: "c", Desired: rw, TTL: 60},
+		{Action: Update, AuthKey: "k3", Type: webpubsub.WPSChannels, Name: "c", Current: rw, Desired: rw, TTL: 60, CurrentTTL: 30},
+		{Action: Revoke, AuthKey: "k3", Type: webpubsub.WPSChannels, Name: "d", Current: rw, CurrentTTL: 30},
+		{Action: Create, AuthKey: "k1", Type: webpubsub.WPSGroups, Name: "g", Desired: webpubsub.WPSManage},
+	}}
+
+	assert.Equal([]*GrantRequest{
+		{AuthKeys: []string{"k1", "k2"}, Type: webpubsub.WPSChannels, Names: []string{"b", "c"}, Permissions: rw, TTL: 60},
+		{AuthKeys: []string{"k3"}, Type: webpubsub.WPSChannels, Names: []string{"c"}, Permissions: rw, TTL: 60},
+		{AuthKeys: []string{"k3"}, Type: webpubsub.WPSChannels, Names: []string{"d"}},
+		{AuthKeys: []string{"k1"}, Type: webpubsub.WPSGroups, Names: []string{"g"}, Permissions: webpubsub.WPSManage},
+	}, plan.Requests(Options{}))
+
+	requests := plan.Requests(Options{ChunkSize: 1})
+	assert.Len(requests, 7)
+	assert.Equal([]string{"k1"}, requests[0].AuthKeys)
+	assert.Equal([]string{"b"}, requests[0].Names)
+	assert.Equal([]string{"k2"}, requests[3].AuthKeys)
+	assert.Equal([]string{"c"}, requests[3].Names)
+
+	requests = plan.Requests(Options{})
+	assert.Equal("grant read,write on channel b,c to k1,k2 (ttl 60)", requests[0].String())
+	assert.Equal("revoke channel d to k3", requests[2].String())
+}
+
+func TestApply(t *testing.T) {
+	assert := assert.New(t)
+
+	p, err := Parse([]byte(testGrantsPolicy + `
+  - auth_keys: [bot-3]
+    ttl: 30
+    roles: [alerts]
+tokens:
+  - name: bot-token
+    ttl: 60
+    roles: [bot]
+`))
+	assert.Nil(err)
+	f := newTestManager()
+	f.failing = map[string]bool{"alerts": true}
+	pn := newFakeWebPubSub(f)
+
+	plan, err := p.Plan(pn, Options{})
+	assert.Nil(err)
+	result := plan.Apply(pn, Options{})
+
+	// bot-2 lobby, bot-1 and bot-2 news, and the alerts revoke and grant.
+	assert.Equal(4, f.count("/v2/auth/grant/"))
+	assert.Equal(1, f.count("/grant?"))
+	assert.Equal(map[string]string{"bot-token": "token-1"}, result.Tokens)
+	if tokens := pn.GetTokens(); assert.Len(tokens, 1) {
+		assert.Equal("token-1", tokens[0].Token)
+	}
+	assert.Equal(3, result.Succeeded)
+	assert.Equal(2, result.Failed)
+	if assert.Len(result.Errors, 2) {
+		assert.Contains(result.Errors[0].Error(), "revoke channel alerts to bot-2: ")
+		assert.Contains(result.Errors[1].Error(), "grant read on channel alerts to bot-3 (ttl 30): ")
+	}
+	assert.Contains(result.Err().Error(), "2 of 5 requests failed: revoke channel alerts to bot-2")
+
+	for _, req := range f.requests {
+		q := req.URL.Query()
+		if q.Get("channel") == "lobby" {
+			assert.Equal("bot-2", q.Get("auth"))
+			assert.Equal("1", q.Get("r"))
+			assert.Equal("0", q.Get("w"))
+			assert.Equal("1440", q.Get("ttl"))
+		}
+	}
+}
+
+func TestApplyNothing(t *testing.T) {
+	assert := assert.New(t)
+
+	result := (&Plan{}).Apply(newFakeWebPubSub(newTestManager()), Options{})
+	assert.Nil(result.Err())
+	assert.Zero(result.Succeeded)
+	assert.Empty(result.Tokens)
+}
@@ -0,0 +1,127 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	webpubsub "github.com/webpubsub/sdk-go/v7"
+)
+
+// resourceTypes are the resource types in the order they're planned and
+// printed.
+var resourceTypes = []webpubsub.WPSResourceType{
+	webpubsub.WPSChannels,
+	webpubsub.WPSGroups,
+	webpubsub.WPSUUIDs,
+}
+
+// permissions are the names of the permissions in a policy, in the order
+// they're printed.
+var permissions = []struct {
+	name string
+	mask webpubsub.WPSGrantBitMask
+}{
+	{"read", webpubsub.WPSRead},
+	{"write", webpubsub.WPSWrite},
+	{"manage", webpubsub.WPSManage},
+	{"delete", webpubsub.WPSDelete},
+	{"get", webpubsub.WPSGet},
+	{"update", webpubsub.WPSUpdate},
+	{"join", webpubsub.WPSJoin},
+}
+
+// allowedPermissions are the permissions which can be granted on each
+// resource type.
+var allowedPermissions = map[webpubsub.WPSResourceType]webpubsub.WPSGrantBitMask{
+	webpubsub.WPSChannels: webpubsub.WPSRead | webpubsub.WPSWrite | webpubsub.WPSManage | webpubsub.WPSDelete |
+		webpubsub.WPSGet | webpubsub.WPSUpdate | webpubsub.WPSJoin,
+	webpubsub.WPSGroups: webpubsub.WPSRead | webpubsub.WPSManage,
+	webpubsub.WPSUUIDs:  webpubsub.WPSGet | webpubsub.WPSUpdate | webpubsub.WPSDelete,
+}
+
+// permissionMask returns the bitmask of the permissions named on a resource
+// of type t.
+func permissionMask(t webpubsub.WPSResourceType, names []string) (webpubsub.WPSGrantBitMask, error) {
+	var mask webpubsub.WPSGrantBitMask
+	for _, name := range names {
+		found := false
+		for _, p := range permissions {
+			if p.name == strings.ToLower(name) {
+				mask |= p.mask
+				found = true
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("unknown permission %s", name)
+		}
+	}
+	if extra := mask &^ allowedPermissions[t]; extra != 0 {
+		return 0, fmt.Errorf("%s can't be granted on a %s", permissionNames(extra), typeName(t))
+	}
+	return mask, nil
+}
+
+// permissionNames returns the names of the permissions of mask, separated by
+// commas.
+func permissionNames(mask webpubsub.WPSGrantBitMask) string {
+	names := []string{}
+	for _, p := range permissions {
+		if mask&p.mask != 0 {
+			names = append(names, p.name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ",")
+}
+
+func typeName(t webpubsub.WPSResourceType) string {
+	switch t {
+	case webpubsub.WPSChannels:
+		return "channel"
+	case webpubsub.WPSGroups:
+		return "channel group"
+	default:
+		return "uuid"
+	}
+}
+
+func channelPermissions(masks map[string]webpubsub.WPSGrantBitMask) map[string]webpubsub.ChannelPermissions {
+	result := make(map[string]webpubsub.ChannelPermissions, len(masks))
+	for name, mask := range masks {
+		result[name] = webpubsub.ChannelPermissions{
+			Read:   mask&webpubsub.WPSRead != 0,
+			Write:  mask&webpubsub.WPSWrite != 0,
+			Manage: mask&webpubsub.WPSManage != 0,
+			Delete: mask&webpubsub.WPSDelete != 0,
+			Get:    mask&webpubsub.WPSGet != 0,
+			Update: mask&webpubsub.WPSUpdate != 0,
+			Join:   mask&webpubsub.WPSJoin != 0,
+		}
+	}
+	return result
+}
+
+func groupPermissions(masks map[string]webpubsub.WPSGrantBitMask) map[string]webpubsub.GroupPermissions {
+	result := make(map[string]webpubsub.GroupPermissions, len(masks))
+	for name, mask := range masks {
+		result[name] = webpubsub.GroupPermissions{
+			Read:   mask&webpubsub.WPSRead != 0,
+			Manage: mask&webpubsub.WPSManage != 0,
+		}
+	}
+	return result
+}
+
+func uuidPermissions(masks map[string]webpubsub.WPSGrantBitMask) map[string]webpubsub.UUIDPermissions {
+	result := make(map[string]webpubsub.UUIDPermissions, len(masks))
+	for name, mask := range masks {
+		result[name] = webpubsub.UUIDPermissions{
+			Get:    mask&webpubsub.WPSGet != 0,
+			Update: mask&webpubsub.WPSUpdate != 0,
+			Delete: mask&webpubsub.WPSDelete != 0,
+		}
+	}
+	return result
+}
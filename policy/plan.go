@@ -0,0 +1,417 @@
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	webpubsub "github.com/webpubsub/sdk-go/v7"
+)
+
+// DefaultChunkSize is the number of resources, and of auth keys, of each
+// Audit and Grant request when Options.ChunkSize isn't set.
+const DefaultChunkSize = 50
+
+// Options configure Plan and Apply.
+type Options struct {
+	// ChunkSize is the maximum number of resources, and of auth keys, of
+	// each Audit and Grant request.
+	ChunkSize int
+}
+
+func (o Options) chunkSize() int {
+	if o.ChunkSize <= 0 {
+		return DefaultChunkSize
+	}
+	return o.ChunkSize
+}
+
+// Masks maps the names of the resources of each type to their permissions.
+type Masks map[webpubsub.WPSResourceType]map[string]webpubsub.WPSGrantBitMask
+
+func (m Masks) add(t webpubsub.WPSResourceType, name string, mask webpubsub.WPSGrantBitMask) {
+	if m[t] == nil {
+		m[t] = make(map[string]webpubsub.WPSGrantBitMask)
+	}
+	m[t][name] |= mask
+}
+
+// TokenGrant is a token of a policy compiled to a GrantToken request.
+type TokenGrant struct {
+	Name           string
+	AuthorizedUUID string
+	TTL            int
+	Meta           map[string]interface{}
+	Resources      Masks
+	Patterns       Masks
+}
+
+// Execute issues the GrantToken request of the token.
+func (t *TokenGrant) Execute(pn *webpubsub.WebPubSub) (*webpubsub.WPSGrantTokenResponse, webpubsub.StatusResponse, error) {
+	b := pn.GrantToken().
+		TTL(t.TTL).
+		Channels(channelPermissions(t.Resources[webpubsub.WPSChannels])).
+		ChannelGroups(groupPermissions(t.Resources[webpubsub.WPSGroups])).
+		UUIDs(uuidPermissions(t.Resources[webpubsub.WPSUUIDs])).
+		ChannelsPattern(channelPermissions(t.Patterns[webpubsub.WPSChannels])).
+		ChannelGroupsPattern(groupPermissions(t.Patterns[webpubsub.WPSGroups])).
+		UUIDsPattern(uuidPermissions(t.Patterns[webpubsub.WPSUUIDs]))
+	if t.AuthorizedUUID != "" {
+		b.AuthorizedUUID(t.AuthorizedUUID)
+	}
+	if t.Meta != nil {
+		b.Meta(t.Meta)
+	}
+	return b.Execute()
+}
+
+// TokenGrants compiles the tokens of the policy, the permissions of their
+// roles are merged.
+func (p *Policy) TokenGrants() []*TokenGrant {
+	grants := make([]*TokenGrant, 0, len(p.Tokens))
+	for _, t := range p.Tokens {
+		grant := &TokenGrant{
+			Name:           t.Name,
+			AuthorizedUUID: t.AuthorizedUUID,
+			TTL:            t.TTL,
+			Meta:           t.Meta,
+			Resources:      make(Masks),
+			Patterns:       make(Masks),
+		}
+		for _, name := range t.Roles {
+			role := p.Roles[name]
+			for _, rt := range resourceTypes {
+				for resource, permissions := range role.Resources.byType(rt) {
+					mask, _ := permissionMask(rt, permissions)
+					grant.Resources.add(rt, resource, mask)
+				}
+				for pattern, permissions := range role.Patterns.byType(rt) {
+					mask, _ := permissionMask(rt, permissions)
+					grant.Patterns.add(rt, pattern, mask)
+				}
+			}
+		}
+		grants = append(grants, grant)
+	}
+	return grants
+}
+
+// Action is what a plan does to the permissions of an auth key on a
+// resource.
+type Action int
+
+const (
+	// Unchanged permissions are already granted.
+	Unchanged Action = iota
+	// Create grants permissions on a resource the auth key has none on.
+	Create
+	// Update replaces the permissions or the TTL of the auth key.
+	Update
+	// Revoke removes the permissions the policy doesn't grant.
+	Revoke
+)
+
+func (a Action) String() string {
+	switch a {
+	case Create:
+		return "create"
+	case Update:
+		return "update"
+	case Revoke:
+		return "revoke"
+	default:
+		return "unchanged"
+	}
+}
+
+func (a Action) symbol() string {
+	switch a {
+	case Create:
+		return "+"
+	case Update:
+		return "~"
+	case Revoke:
+		return "-"
+	default:
+		return "="
+	}
+}
+
+// Change is the difference between the permissions of an auth key on a
+// resource and the ones granted by the policy.
+type Change struct {
+	Action  Action
+	AuthKey string
+	Type    webpubsub.WPSResourceType
+	Name    string
+	Current webpubsub.WPSGrantBitMask
+	Desired webpubsub.WPSGrantBitMask
+	TTL     int
+
+	// CurrentTTL is the TTL of the current permissions, 0 when unknown.
+	CurrentTTL int
+}
+
+func (c *Change) String() string {
+	target := fmt.Sprintf("%s %s %s", c.AuthKey, typeName(c.Type), c.Name)
+	switch c.Action {
+	case Create:
+		return fmt.Sprintf("%s %s: %s (ttl %d)", c.Action.symbol(), target, permissionNames(c.Desired), c.TTL)
+	case Update:
+		ttl := fmt.Sprint(c.TTL)
+		if c.CurrentTTL != 0 && c.CurrentTTL != c.TTL {
+			ttl = fmt.Sprintf("%d -> %d", c.CurrentTTL, c.TTL)
+		}
+		return fmt.Sprintf("%s %s: %s -> %s (ttl %s)", c.Action.symbol(), target,
+			permissionNames(c.Current), permissionNames(c.Desired), ttl)
+	default:
+		return fmt.Sprintf("%s %s: %s", c.Action.symbol(), target, permissionNames(c.Current))
+	}
+}
+
+// Plan is what Apply does to bring the grants in place to the policy: the
+// tokens are always granted, as tokens can't be read back, and the changes
+// of the PAM v2 grants are sorted by resource type, resource and auth key.
+type Plan struct {
+	Tokens  []*TokenGrant
+	Changes []*Change
+}
+
+// Count returns the number of changes with the action.
+func (p *Plan) Count(action Action) int {
+	count := 0
+	for _, c := range p.Changes {
+		if c.Action == action {
+			count++
+		}
+	}
+	return count
+}
+
+// String renders the plan, one line per token resource and per change
+// which isn't Unchanged, followed by a summary.
+func (p *Plan) String() string {
+	var b strings.Builder
+	for _, t := range p.Tokens {
+		fmt.Fprintf(&b, "+ token %s", t.Name)
+		if t.AuthorizedUUID != "" {
+			fmt.Fprintf(&b, " (authorized uuid %s, ttl %d)\n", t.AuthorizedUUID, t.TTL)
+		} else {
+			fmt.Fprintf(&b, " (ttl %d)\n", t.TTL)
+		}
+		for _, rt := range resourceTypes {
+			for _, name := range sortedMaskNames(t.Resources[rt]) {
+				fmt.Fprintf(&b, "    %s %s: %s\n", typeName(rt), name, permissionNames(t.Resources[rt][name]))
+			}
+			for _, name := range sortedMaskNames(t.Patterns[rt]) {
+				fmt.Fprintf(&b, "    %s pattern %s: %s\n", typeName(rt), name, permissionNames(t.Patterns[rt][name]))
+			}
+		}
+	}
+	for _, c := range p.Changes {
+		if c.Action != Unchanged {
+			fmt.Fprintln(&b, c)
+		}
+	}
+	fmt.Fprintf(&b, "Plan: %d tokens to grant, %d to create, %d to update, %d to revoke, %d unchanged.\n",
+		len(p.Tokens), p.Count(Create), p.Count(Update), p.Count(Revoke), p.Count(Unchanged))
+	return b.String()
+}
+
+// resourceKey identifies the permissions of an auth key on a resource.
+type resourceKey struct {
+	authKey string
+	t       webpubsub.WPSResourceType
+	name    string
+}
+
+type grantEntry struct {
+	mask webpubsub.WPSGrantBitMask
+	ttl  int
+}
+
+// authKeyGrants returns the permissions the PAM v2 grants of the policy give
+// to each auth key. When several grants give permissions on the same
+// resource, the longest TTL is kept, 0 being the longest.
+func (p *Policy) authKeyGrants() map[resourceKey]*grantEntry {
+	entries := make(map[resourceKey]*grantEntry)
+	for _, g := range p.Grants {
+		for _, name := range g.Roles {
+			role := p.Roles[name]
+			for _, rt := range resourceTypes {
+				for resource, permissions := range role.Resources.byType(rt) {
+					mask, _ := permissionMask(rt, permissions)
+					for _, authKey := range g.AuthKeys {
+						key := resourceKey{authKey, rt, resource}
+						entry, ok := entries[key]
+						if !ok {
+							entries[key] = &grantEntry{mask: mask, ttl: g.TTL}
+							continue
+						}
+						entry.mask |= mask
+						if entry.ttl != 0 && (g.TTL == 0 || g.TTL > entry.ttl) {
+							entry.ttl = g.TTL
+						}
+					}
+				}
+			}
+		}
+	}
+	return entries
+}
+
+// Plan compiles the tokens of the policy and compares the permissions its
+// grants give to auth keys with the ones read back with Audit. Only the
+// auth keys and the resources named in the policy are audited: the
+// permissions of the auth keys on the resources of the policy which no role
+// gives them are revoked.
+func (p *Policy) Plan(pn *webpubsub.WebPubSub, opts Options) (*Plan, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	desired := p.authKeyGrants()
+
+	authKeys := []string{}
+	names := make(map[webpubsub.WPSResourceType][]string)
+	seen := make(map[resourceKey]bool)
+	for key := range desired {
+		if k := (resourceKey{authKey: key.authKey}); !seen[k] {
+			seen[k] = true
+			authKeys = append(authKeys, key.authKey)
+		}
+		if k := (resourceKey{t: key.t, name: key.name}); !seen[k] {
+			seen[k] = true
+			names[key.t] = append(names[key.t], key.name)
+		}
+	}
+	sort.Strings(authKeys)
+	for _, rt := range resourceTypes {
+		sort.Strings(names[rt])
+	}
+
+	current, err := audit(pn, authKeys, names, opts.chunkSize())
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{Tokens: p.TokenGrants()}
+	for _, rt := range resourceTypes {
+		for _, name := range names[rt] {
+			for _, authKey := range authKeys {
+				key := resourceKey{authKey, rt, name}
+				if c := diff(key, current[key], desired[key]); c != nil {
+					plan.Changes = append(plan.Changes, c)
+				}
+			}
+		}
+	}
+	return plan, nil
+}
+
+// diff returns the change from the current permissions of an auth key on a
+// resource to the desired ones, nil when it has none of both.
+func diff(key resourceKey, current, desired *grantEntry) *Change {
+	c := &Change{AuthKey: key.authKey, Type: key.t, Name: key.name}
+	if current != nil {
+		c.Current = current.mask
+		c.CurrentTTL = current.ttl
+	}
+	if desired != nil {
+		c.Desired = desired.mask
+		c.TTL = desired.ttl
+	}
+
+	switch {
+	case c.Current == 0 && c.Desired == 0:
+		return nil
+	case c.Desired == 0:
+		c.Action = Revoke
+	case c.Current == 0:
+		c.Action = Create
+	case c.Current != c.Desired:
+		c.Action = Update
+	// The audit can't tell a grant which never expires from the TTL of the
+	// resource, so only the TTLs which are both set are compared.
+	case current.ttl != 0 && desired.ttl != 0 && current.ttl != desired.ttl:
+		c.Action = Update
+	default:
+		c.Action = Unchanged
+	}
+	return c
+}
+
+// audit reads back the permissions of the auth keys on the resources, in
+// chunks of size resources and auth keys.
+func audit(pn *webpubsub.WebPubSub, authKeys []string,
+	names map[webpubsub.WPSResourceType][]string, size int) (map[resourceKey]*grantEntry, error) {
+	current := make(map[resourceKey]*grantEntry)
+	for _, rt := range resourceTypes {
+		for _, resources := range chunk(names[rt], size) {
+			for _, keys := range chunk(authKeys, size) {
+				b := pn.Audit().AuthKeys(keys)
+				switch rt {
+				case webpubsub.WPSChannels:
+					b.Channels(resources)
+				case webpubsub.WPSGroups:
+					b.ChannelGroups(resources)
+				default:
+					b.UUIDs(resources)
+				}
+				resp, _, err := b.Execute()
+				if err != nil {
+					return nil, fmt.Errorf("audit %s %s: %w", typeName(rt), strings.Join(resources, ","), err)
+				}
+
+				for _, authKey := range keys {
+					permissions := resp.Permissions(authKey)
+					switch rt {
+					case webpubsub.WPSChannels:
+						for name, p := range permissions.Channels {
+							current[resourceKey{authKey, rt, name}] = &grantEntry{webpubsub.WPSGrantBitMask(p.BitMaskPerms), p.TTL}
+						}
+					case webpubsub.WPSGroups:
+						for name, p := range permissions.Groups {
+							current[resourceKey{authKey, rt, name}] = &grantEntry{webpubsub.WPSGrantBitMask(p.BitMaskPerms), p.TTL}
+						}
+					default:
+						for name, p := range permissions.UUIDs {
+							current[resourceKey{authKey, rt, name}] = &grantEntry{webpubsub.WPSGrantBitMask(p.BitMaskPerms), p.TTL}
+						}
+					}
+				}
+			}
+		}
+	}
+	return current, nil
+}
+
+// chunk splits items in slices of size items at most.
+func chunk(items []string, size int) [][]string {
+	chunks := [][]string{}
+	for len(items) > size {
+		chunks = append(chunks, items[:size])
+		items = items[size:]
+	}
+	if len(items) > 0 {
+		chunks = append(chunks, items)
+	}
+	return chunks
+}
+
+func sortedNames(m map[string][]string) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedMaskNames(m map[string]webpubsub.WPSGrantBitMask) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
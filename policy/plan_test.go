@@ -0,0 +1,222 @@
+package policy
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	webpubsub "github.com/webpubsub/sdk-go/v7"
+)
+
+// fakeAccessManager answers the Audit, Grant and GrantToken requests.
+type fakeAccessManager struct {
+	sync.Mutex
+
+	// audited maps the names of the resources to their audit data.
+	audited map[string]string
+	// failing are the resources the Grant requests fail on.
+	failing map[string]bool
+
+	requests []*http.Request
+	tokens   int
+}
+
+func (f *fakeAccessManager) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.Lock()
+	defer f.Unlock()
+	f.requests = append(f.requests, req)
+
+	u := req.URL.String()
+	q := req.URL.Query()
+	status, body := 200, ""
+	switch {
+	case strings.Contains(u, "/v2/auth/audit/"):
+		entities := []string{}
+		key := "channels"
+		names := q.Get("channel")
+		if q.Get("channel-group") != "" {
+			key, names = "channel-groups", q.Get("channel-group")
+		} else if q.Get("target-uuid") != "" {
+			key, names = "uuids", q.Get("target-uuid")
+		}
+		for _, name := range strings.Split(names, ",") {
+			if data, ok := f.audited[name]; ok {
+				entities = append(entities, fmt.Sprintf("%q:%s", name, data))
+			}
+		}
+		body = fmt.Sprintf(`{"message":"Success","payload":{"level":"channel+auth","subscribe_key":"demo","ttl":1440,%q:{%s}},"service":"Access Manager","status":200}`,
+			key, strings.Join(entities, ","))
+	case strings.Contains(u, "/v2/auth/grant/"):
+		for _, name := range strings.Split(q.Get("channel"), ",") {
+			if f.failing[name] {
+				status = 400
+			}
+		}
+		if status == 400 {
+			body = `{"error":true,"message":"Invalid","service":"Access Manager","status":400}`
+		} else {
+			body = `{"message":"Success","payload":{"level":"channel+auth","subscribe_key":"demo","ttl":1440,"channels":{}},"service":"Access Manager","status":200}`
+		}
+	default:
+		f.tokens++
+		body = fmt.Sprintf(`{"status":200,"data":{"message":"Success","token":"token-%d"},"service":"Access Manager"}`, f.tokens)
+	}
+
+	return &http.Response{
+		StatusCode: status,
+		Body:       ioutil.NopCloser(bytes.NewBufferString(body)),
+		Header:     http.Header{},
+		Request:    req,
+	}, nil
+}
+
+func (f *fakeAccessManager) count(path string) int {
+	f.Lock()
+	defer f.Unlock()
+	count := 0
+	for _, req := range f.requests {
+		if strings.Contains(req.URL.String(), path) {
+			count++
+		}
+	}
+	return count
+}
+
+func newFakeWebPubSub(f *fakeAccessManager) *webpubsub.WebPubSub {
+	pn := webpubsub.NewWebPubSub(webpubsub.NewDemoConfig())
+	pn.SetClient(&http.Client{Transport: f})
+	return pn
+}
+
+const testGrantsPolicy = `
+roles:
+  bot:
+    channels:
+      lobby: [read]
+      news: [read, write]
+  alerts:
+    channels:
+      alerts: [read]
+grants:
+  - auth_keys: [bot-1, bot-2]
+    ttl: 1440
+    roles: [bot]
+  - auth_keys: [bot-1]
+    ttl: 60
+    roles: [alerts]
+`
+
+func newTestManager() *fakeAccessManager {
+	return &fakeAccessManager{
+		audited: map[string]string{
+			"alerts": `{"auths":{"bot-1":{"r":1,"ttl":60},"bot-2":{"r":1,"ttl":60}}}`,
+			"lobby":  `{"auths":{"bot-1":{"r":1,"ttl":1440},"bot-2":{"r":1,"w":1,"ttl":1440}}}`,
+			"news":   `{"auths":{"bot-2":{"r":1,"w":1,"ttl":60}}}`,
+		},
+	}
+}
+
+func TestPlan(t *testing.T) {
+	assert := assert.New(t)
+
+	p, err := Parse([]byte(testGrantsPolicy))
+	assert.Nil(err)
+	f := newTestManager()
+	pn := newFakeWebPubSub(f)
+
+	plan, err := p.Plan(pn, Options{})
+	assert.Nil(err)
+	assert.Empty(plan.Tokens)
+	assert.Equal(1, f.count("/v2/auth/audit/"))
+
+	rw := webpubsub.WPSGrantBitMask(webpubsub.WPSRead | webpubsub.WPSWrite)
+	assert.Equal([]*Change{
+		{Action: Unchanged, AuthKey: "bot-1", Type: webpubsub.WPSChannels, Name: "alerts", Current: webpubsub.WPSRead, Desired: webpubsub.WPSRead, TTL: 60, CurrentTTL: 60},
+		{Action: Revoke, AuthKey: "bot-2", Type: webpubsub.WPSChannels, Name: "alerts", Current: webpubsub.WPSRead, CurrentTTL: 60},
+		{Action: Unchanged, AuthKey: "bot-1", Type: webpubsub.WPSChannels, Name: "lobby", Current: webpubsub.WPSRead, Desired: webpubsub.WPSRead, TTL: 1440, CurrentTTL: 1440},
+		{Action: Update, AuthKey: "bot-2", Type: webpubsub.WPSChannels, Name: "lobby", Current: rw, Desired: webpubsub.WPSRead, TTL: 1440, CurrentTTL: 1440},
+		{Action: Create, AuthKey: "bot-1", Type: webpubsub.WPSChannels, Name: "news", Desired: rw, TTL: 1440},
+		{Action: Update, AuthKey: "bot-2", Type: webpubsub.WPSChannels, Name: "news", Current: rw, Desired: rw, TTL: 1440, CurrentTTL: 60},
+	}, plan.Changes)
+
+	assert.Equal(`- bot-2 channel alerts: read
+~ bot-2 channel lobby: read,write -> read (ttl 1440)
++ bot-1 channel news: read,write (ttl 1440)
+~ bot-2 channel news: read,write -> read,write (ttl 60 -> 1440)
+Plan: 0 tokens to grant, 1 to create, 2 to update, 1 to revoke, 2 unchanged.
+`, plan.String())
+}
+
+func TestPlanChunksAudits(t *testing.T) {
+	assert := assert.New(t)
+
+	p, err := Parse([]byte(testGrantsPolicy))
+	assert.Nil(err)
+	f := newTestManager()
+	pn := newFakeWebPubSub(f)
+
+	plan, err := p.Plan(pn, Options{ChunkSize: 2})
+	assert.Nil(err)
+	// 3 channels in 2 chunks, 2 auth keys in 1 chunk.
+	assert.Equal(2, f.count("/v2/auth/audit/"))
+	assert.Len(plan.Changes, 6)
+
+	f.requests = nil
+	_, err = p.Plan(pn, Options{ChunkSize: 1})
+	assert.Nil(err)
+	assert.Equal(6, f.count("/v2/auth/audit/"))
+}
+
+func TestPlanAuditError(t *testing.T) {
+	assert := assert.New(t)
+
+	p, err := Parse([]byte(testGrantsPolicy))
+	assert.Nil(err)
+	pn := newFakeWebPubSub(newTestManager())
+	pn.Config.SecretKey = ""
+
+	_, err = p.Plan(pn, Options{})
+	assert.Contains(err.Error(), "audit channel alerts,lobby,news")
+}
+
+func TestPlanTokens(t *testing.T) {
+	assert := assert.New(t)
+
+	p, err := Parse([]byte(testPolicy))
+	assert.Nil(err)
+	f := newTestManager()
+	pn := newFakeWebPubSub(f)
+
+	plan, err := p.Plan(pn, Options{})
+	assert.Nil(err)
+	if assert.Len(plan.Tokens, 1) {
+		token := plan.Tokens[0]
+		assert.Equal("alice", token.Name)
+		assert.Equal(webpubsub.WPSGrantBitMask(webpubsub.WPSRead|webpubsub.WPSWrite|webpubsub.WPSDelete),
+			token.Resources[webpubsub.WPSChannels]["lobby"])
+		assert.Equal(webpubsub.WPSGrantBitMask(webpubsub.WPSRead), token.Resources[webpubsub.WPSGroups]["rooms"])
+		assert.Equal(webpubsub.WPSGrantBitMask(webpubsub.WPSGet|webpubsub.WPSUpdate), token.Resources[webpubsub.WPSUUIDs]["alice"])
+		assert.Equal(webpubsub.WPSGrantBitMask(webpubsub.WPSRead), token.Patterns[webpubsub.WPSChannels]["room-.*"])
+	}
+	assert.Len(plan.Changes, 4)
+
+	assert.Contains(plan.String(), `+ token alice (authorized uuid alice, ttl 60)
+    channel lobby: read,write,delete
+    channel pattern room-.*: read
+    channel group rooms: read
+    uuid alice: get,update
+`)
+}
+
+func TestPlanValidates(t *testing.T) {
+	assert := assert.New(t)
+
+	p := &Policy{Tokens: []*Token{{Name: "t", TTL: 10, Roles: []string{"missing"}}}}
+	_, err := p.Plan(newFakeWebPubSub(newTestManager()), Options{})
+	assert.Contains(err.Error(), "unknown role missing")
+}
@@ -0,0 +1,207 @@
+// Package policy manages the access grants of an application from a
+// declarative file. A policy describes roles, the permissions they hold on
+// channels, channel groups and UUIDs by name or pattern, and the PAMv3 tokens
+// and PAM v2 auth keys which receive them.
+//
+//	roles:
+//	  chat-user:
+//	    channels:
+//	      lobby: [read, write]
+//	    patterns:
+//	      channels:
+//	        "room-.*": [read]
+//	tokens:
+//	  - name: alice
+//	    authorized_uuid: alice
+//	    ttl: 60
+//	    roles: [chat-user]
+//	grants:
+//	  - auth_keys: [bot-key]
+//	    ttl: 1440
+//	    roles: [chat-user]
+//
+// Plan compares the policy with the grants in place, read back with Audit,
+// and Apply issues the GrantToken and Grant requests of the plan.
+//
+//	p, err := policy.Load("access.yaml")
+//	plan, err := p.Plan(pn, policy.Options{})
+//	fmt.Print(plan)
+//	result := plan.Apply(pn, policy.Options{})
+package policy
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+
+	webpubsub "github.com/webpubsub/sdk-go/v7"
+	"gopkg.in/yaml.v3"
+)
+
+// Policy is the content of a policy file.
+type Policy struct {
+	Roles  map[string]*Role `yaml:"roles" json:"roles"`
+	Tokens []*Token         `yaml:"tokens" json:"tokens"`
+	Grants []*Grant         `yaml:"grants" json:"grants"`
+}
+
+// Resources maps the names of the channels, channel groups and UUIDs to the
+// permissions granted on them: read, write, manage, delete, get, update and
+// join.
+type Resources struct {
+	Channels      map[string][]string `yaml:"channels" json:"channels"`
+	ChannelGroups map[string][]string `yaml:"channel_groups" json:"channel_groups"`
+	UUIDs         map[string][]string `yaml:"uuids" json:"uuids"`
+}
+
+// Role is a set of permissions on resources, and on the resources matching
+// regular expressions. Patterns are only granted to tokens.
+type Role struct {
+	Resources `yaml:",inline"`
+	Patterns  Resources `yaml:"patterns" json:"patterns"`
+}
+
+// Token is a PAMv3 token granted with GrantToken.
+type Token struct {
+	Name           string                 `yaml:"name" json:"name"`
+	AuthorizedUUID string                 `yaml:"authorized_uuid" json:"authorized_uuid"`
+	TTL            int                    `yaml:"ttl" json:"ttl"` // Minutes.
+	Roles          []string               `yaml:"roles" json:"roles"`
+	Meta           map[string]interface{} `yaml:"meta" json:"meta"`
+}
+
+// Grant is a PAM v2 grant of the permissions of roles to auth keys.
+type Grant struct {
+	AuthKeys []string `yaml:"auth_keys" json:"auth_keys"`
+	TTL      int      `yaml:"ttl" json:"ttl"` // Minutes, 0 never expires.
+	Roles    []string `yaml:"roles" json:"roles"`
+}
+
+// maxTTL is the longest TTL accepted by the server, in minutes.
+const maxTTL = 525600
+
+// Load reads and validates a YAML or JSON policy file.
+func Load(path string) (*Policy, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
+
+// Parse decodes and validates a YAML or JSON policy.
+func Parse(data []byte) (*Policy, error) {
+	p := &Policy{}
+	if err := yaml.Unmarshal(data, p); err != nil {
+		return nil, err
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Validate checks the roles referenced by the tokens and the grants, the
+// names of the permissions, the patterns and the TTLs.
+func (p *Policy) Validate() error {
+	names := make([]string, 0, len(p.Roles))
+	for name := range p.Roles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		role := p.Roles[name]
+		if role == nil {
+			return fmt.Errorf("role %s: empty", name)
+		}
+		if err := role.Resources.validate(false); err != nil {
+			return fmt.Errorf("role %s: %w", name, err)
+		}
+		if err := role.Patterns.validate(true); err != nil {
+			return fmt.Errorf("role %s: patterns: %w", name, err)
+		}
+	}
+
+	tokenNames := make(map[string]bool)
+	for i, t := range p.Tokens {
+		if t.Name == "" {
+			return fmt.Errorf("token %d: missing name", i)
+		}
+		if tokenNames[t.Name] {
+			return fmt.Errorf("token %s: duplicate name", t.Name)
+		}
+		tokenNames[t.Name] = true
+		if t.TTL < 1 || t.TTL > maxTTL {
+			return fmt.Errorf("token %s: ttl must be between 1 and %d", t.Name, maxTTL)
+		}
+		if err := p.checkRoles(t.Roles); err != nil {
+			return fmt.Errorf("token %s: %w", t.Name, err)
+		}
+	}
+
+	for i, g := range p.Grants {
+		if len(g.AuthKeys) == 0 {
+			return fmt.Errorf("grant %d: missing auth_keys", i)
+		}
+		if g.TTL < 0 || g.TTL > maxTTL {
+			return fmt.Errorf("grant %d: ttl must be between 0 and %d", i, maxTTL)
+		}
+		if err := p.checkRoles(g.Roles); err != nil {
+			return fmt.Errorf("grant %d: %w", i, err)
+		}
+		for _, name := range g.Roles {
+			if !p.Roles[name].Patterns.empty() {
+				return fmt.Errorf("grant %d: role %s has patterns, which PAM v2 can't grant", i, name)
+			}
+		}
+	}
+	return nil
+}
+
+func (p *Policy) checkRoles(roles []string) error {
+	if len(roles) == 0 {
+		return fmt.Errorf("missing roles")
+	}
+	for _, name := range roles {
+		if p.Roles[name] == nil {
+			return fmt.Errorf("unknown role %s", name)
+		}
+	}
+	return nil
+}
+
+func (r Resources) validate(patterns bool) error {
+	for _, t := range resourceTypes {
+		for _, name := range sortedNames(r.byType(t)) {
+			if patterns {
+				if _, err := regexp.Compile(name); err != nil {
+					return fmt.Errorf("%s %s: %w", typeName(t), name, err)
+				}
+			}
+			if _, err := permissionMask(t, r.byType(t)[name]); err != nil {
+				return fmt.Errorf("%s %s: %w", typeName(t), name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (r Resources) byType(t webpubsub.WPSResourceType) map[string][]string {
+	switch t {
+	case webpubsub.WPSChannels:
+		return r.Channels
+	case webpubsub.WPSGroups:
+		return r.ChannelGroups
+	default:
+		return r.UUIDs
+	}
+}
+
+func (r Resources) empty() bool {
+	return len(r.Channels) == 0 && len(r.ChannelGroups) == 0 && len(r.UUIDs) == 0
+}
@@ -0,0 +1,125 @@
+package policy
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testPolicy = `
+roles:
+  chat-user:
+    channels:
+      lobby: [read, write]
+    channel_groups:
+      rooms: [read]
+    patterns:
+      channels:
+        "room-.*": [read]
+  moderator:
+    channels:
+      lobby: [delete]
+    uuids:
+      alice: [get, update]
+  bot:
+    channels:
+      lobby: [read]
+      news: [read, write]
+tokens:
+  - name: alice
+    authorized_uuid: alice
+    ttl: 60
+    roles: [chat-user, moderator]
+    meta:
+      team: support
+grants:
+  - auth_keys: [bot-1, bot-2]
+    ttl: 1440
+    roles: [bot]
+`
+
+func TestParse(t *testing.T) {
+	assert := assert.New(t)
+
+	p, err := Parse([]byte(testPolicy))
+	assert.Nil(err)
+	assert.Len(p.Roles, 3)
+	assert.Equal([]string{"read", "write"}, p.Roles["chat-user"].Channels["lobby"])
+	assert.Equal([]string{"read"}, p.Roles["chat-user"].Patterns.Channels["room-.*"])
+	assert.Equal([]string{"get", "update"}, p.Roles["moderator"].UUIDs["alice"])
+	if assert.Len(p.Tokens, 1) {
+		assert.Equal("alice", p.Tokens[0].AuthorizedUUID)
+		assert.Equal(60, p.Tokens[0].TTL)
+		assert.Equal("support", p.Tokens[0].Meta["team"])
+	}
+	if assert.Len(p.Grants, 1) {
+		assert.Equal([]string{"bot-1", "bot-2"}, p.Grants[0].AuthKeys)
+		assert.Equal(1440, p.Grants[0].TTL)
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	p, err := Parse([]byte(`{
+		"roles": {"reader": {"channels": {"news": ["read"]}}},
+		"tokens": [{"name": "reader", "ttl": 30, "roles": ["reader"]}]
+	}`))
+	assert.Nil(err)
+	assert.Equal([]string{"read"}, p.Roles["reader"].Channels["news"])
+	assert.Equal("reader", p.Tokens[0].Name)
+}
+
+func TestLoad(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "policy")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "access.yaml")
+	assert.Nil(ioutil.WriteFile(path, []byte(testPolicy), 0600))
+
+	p, err := Load(path)
+	assert.Nil(err)
+	assert.Len(p.Roles, 3)
+
+	_, err = Load(filepath.Join(dir, "missing.yaml"))
+	assert.NotNil(err)
+
+	assert.Nil(ioutil.WriteFile(path, []byte("tokens:\n  - ttl: 1\n"), 0600))
+	_, err = Load(path)
+	assert.Contains(err.Error(), "access.yaml: token 0: missing name")
+}
+
+func TestValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, tc := range []struct {
+		policy string
+		err    string
+	}{
+		{"roles: {r: {channels: {c: [fly]}}}", "role r: channel c: unknown permission fly"},
+		{"roles: {r: {channel_groups: {g: [write]}}}", "role r: channel group g: write can't be granted on a channel group"},
+		{"roles: {r: {uuids: {u: [read]}}}", "role r: uuid u: read can't be granted on a uuid"},
+		{"roles: {r: {patterns: {channels: {'room-(': [read]}}}}", "role r: patterns: channel room-(: error parsing regexp"},
+		{"roles: {r: }", "role r: empty"},
+		{"tokens: [{name: t, ttl: 10, roles: [missing]}]", "token t: unknown role missing"},
+		{"tokens: [{name: t, ttl: 10}]", "token t: missing roles"},
+		{"roles: {r: {}}\ntokens: [{name: t, ttl: 0, roles: [r]}]", "token t: ttl must be between 1 and 525600"},
+		{"roles: {r: {}}\ntokens: [{name: t, ttl: 1, roles: [r]}, {name: t, ttl: 1, roles: [r]}]", "token t: duplicate name"},
+		{"grants: [{ttl: 10}]", "grant 0: missing auth_keys"},
+		{"roles: {r: {}}\ngrants: [{auth_keys: [k], ttl: -1, roles: [r]}]", "grant 0: ttl must be between 0 and 525600"},
+		{"roles: {r: {patterns: {channels: {'.*': [read]}}}}\ngrants: [{auth_keys: [k], roles: [r]}]", "grant 0: role r has patterns"},
+	} {
+		_, err := Parse([]byte(tc.policy))
+		if assert.NotNil(err, tc.policy) {
+			assert.Contains(err.Error(), tc.err)
+		}
+	}
+
+	_, err := Parse([]byte("roles: [not, a, map]"))
+	assert.NotNil(err)
+}
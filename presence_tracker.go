@@ -0,0 +1,291 @@
+package webpubsub
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// PresenceOccupant is a UUID present on a channel tracked by a PresenceTracker.
+type PresenceOccupant struct {
+	UUID  string
+	State map[string]interface{}
+}
+
+// RosterChange describes a change of the roster of a channel tracked by a
+// PresenceTracker.
+type RosterChange struct {
+	Channel   string
+	Event     string   // Presence event which changed the roster, "refresh" when it was fetched with HereNow.
+	Joined    []string // UUIDs added to the roster.
+	Left      []string // UUIDs removed from the roster, after a leave or a timeout.
+	Updated   []string // UUIDs whose state changed.
+	Occupancy int      // Number of occupants after the change.
+}
+
+func (c *RosterChange) empty() bool {
+	return len(c.Joined) == 0 && len(c.Left) == 0 && len(c.Updated) == 0
+}
+
+// PresenceTracker keeps the list of the UUIDs present on channels up to date.
+// It is seeded with HereNow and applies the presence events received by the
+// subscribe loop, the channels must be subscribed with presence to receive
+// them. Create it with WebPubSub.PresenceTracker.
+type PresenceTracker struct {
+	sync.RWMutex
+
+	webpubsub *WebPubSub
+	channels  []string
+	rosters   map[string]map[string]*PresenceOccupant
+	handlers  []func(change *RosterChange)
+	listener  *presenceTrackerListener
+}
+
+func newPresenceTracker(pn *WebPubSub, channels []string) *PresenceTracker {
+	t := &PresenceTracker{
+		webpubsub: pn,
+		channels:  channels,
+		rosters:   make(map[string]map[string]*PresenceOccupant),
+	}
+	for _, channel := range channels {
+		t.rosters[channel] = make(map[string]*PresenceOccupant)
+	}
+	return t
+}
+
+// OnChange adds a handler called after each change of a roster.
+func (t *PresenceTracker) OnChange(handler func(change *RosterChange)) *PresenceTracker {
+	t.Lock()
+	t.handlers = append(t.handlers, handler)
+	t.Unlock()
+	return t
+}
+
+// Start listens to the presence events and seeds the rosters with HereNow.
+func (t *PresenceTracker) Start() error {
+	t.Lock()
+	if t.listener == nil {
+		t.listener = &presenceTrackerListener{tracker: t}
+		t.webpubsub.subscriptionManager.AddListener(t.listener)
+	}
+	t.Unlock()
+
+	return t.Refresh()
+}
+
+// Stop stops applying the presence events, the rosters are kept as they are.
+func (t *PresenceTracker) Stop() {
+	t.Lock()
+	listener := t.listener
+	t.listener = nil
+	t.Unlock()
+
+	if listener != nil {
+		t.webpubsub.subscriptionManager.RemoveListener(listener)
+	}
+}
+
+// Refresh fetches the rosters of all the tracked channels with HereNow.
+func (t *PresenceTracker) Refresh() error {
+	return t.refresh(t.channels)
+}
+
+func (t *PresenceTracker) refresh(channels []string) error {
+	res, _, err := t.webpubsub.HereNow().
+		Channels(channels).
+		IncludeUUIDs(true).
+		IncludeState(true).
+		Execute()
+	if err != nil {
+		t.webpubsub.Config.logger(WPSSubscribeSubsystem).Warn("presence tracker refresh failed", "error", err)
+		return err
+	}
+
+	// The channels without occupants are missing from the response.
+	fetched := make(map[string][]HereNowOccupantsData)
+	for _, channel := range channels {
+		fetched[channel] = nil
+	}
+	for _, data := range res.Channels {
+		if _, ok := fetched[data.ChannelName]; ok {
+			fetched[data.ChannelName] = data.Occupants
+		}
+	}
+
+	changes := []*RosterChange{}
+	t.Lock()
+	for _, channel := range channels {
+		roster := t.rosters[channel]
+		change := &RosterChange{Channel: channel, Event: "refresh"}
+		present := make(map[string]bool)
+		for _, occupant := range fetched[channel] {
+			present[occupant.UUID] = true
+			if existing, ok := roster[occupant.UUID]; !ok {
+				roster[occupant.UUID] = &PresenceOccupant{UUID: occupant.UUID, State: occupant.State}
+				change.Joined = append(change.Joined, occupant.UUID)
+			} else if !presenceStateEqual(existing.State, occupant.State) {
+				existing.State = occupant.State
+				change.Updated = append(change.Updated, occupant.UUID)
+			}
+		}
+		for uuid := range roster {
+			if !present[uuid] {
+				delete(roster, uuid)
+				change.Left = append(change.Left, uuid)
+			}
+		}
+		sort.Strings(change.Left)
+		change.Occupancy = len(roster)
+		if !change.empty() {
+			changes = append(changes, change)
+		}
+	}
+	t.Unlock()
+
+	t.notify(changes...)
+	return nil
+}
+
+// apply updates the roster of the channel of presence.
+func (t *PresenceTracker) apply(presence *WPSPresence) {
+	channel := presence.Channel
+	t.RLock()
+	_, tracked := t.rosters[channel]
+	t.RUnlock()
+	if !tracked {
+		return
+	}
+
+	if presence.Event == "interval" && presence.HereNowRefresh {
+		// The interval event doesn't list the changes when there are too many
+		// of them, the roster is fetched again.
+		go t.refresh([]string{channel})
+		return
+	}
+
+	change := &RosterChange{Channel: channel, Event: presence.Event}
+	t.Lock()
+	roster := t.rosters[channel]
+	switch presence.Event {
+	case "join", "state-change":
+		t.join(roster, change, presence.UUID, presence.State)
+	case "leave", "timeout":
+		t.leave(roster, change, presence.UUID)
+	case "interval":
+		for _, uuid := range presence.Join {
+			t.join(roster, change, uuid, nil)
+		}
+		for _, uuid := range presence.Leave {
+			t.leave(roster, change, uuid)
+		}
+		for _, uuid := range presence.Timeout {
+			t.leave(roster, change, uuid)
+		}
+	}
+	change.Occupancy = len(roster)
+	t.Unlock()
+
+	if !change.empty() {
+		t.notify(change)
+	}
+}
+
+// join adds uuid to roster, or updates its state when state is a map and
+// uuid is already present.
+func (t *PresenceTracker) join(roster map[string]*PresenceOccupant, change *RosterChange, uuid string, state interface{}) {
+	if uuid == "" {
+		return
+	}
+	newState, hasState := state.(map[string]interface{})
+	occupant, ok := roster[uuid]
+	if !ok {
+		if newState == nil {
+			newState = make(map[string]interface{})
+		}
+		roster[uuid] = &PresenceOccupant{UUID: uuid, State: newState}
+		change.Joined = append(change.Joined, uuid)
+		return
+	}
+	if hasState && !presenceStateEqual(occupant.State, newState) {
+		occupant.State = newState
+		change.Updated = append(change.Updated, uuid)
+	}
+}
+
+func (t *PresenceTracker) leave(roster map[string]*PresenceOccupant, change *RosterChange, uuid string) {
+	if _, ok := roster[uuid]; ok {
+		delete(roster, uuid)
+		change.Left = append(change.Left, uuid)
+	}
+}
+
+func (t *PresenceTracker) notify(changes ...*RosterChange) {
+	if len(changes) == 0 {
+		return
+	}
+	t.RLock()
+	handlers := make([]func(change *RosterChange), len(t.handlers))
+	copy(handlers, t.handlers)
+	t.RUnlock()
+
+	for _, change := range changes {
+		for _, handler := range handlers {
+			handler(change)
+		}
+	}
+}
+
+// Occupants returns the UUIDs present on channel sorted by UUID, nil when the
+// channel isn't tracked.
+func (t *PresenceTracker) Occupants(channel string) []PresenceOccupant {
+	t.RLock()
+	defer t.RUnlock()
+
+	roster, ok := t.rosters[channel]
+	if !ok {
+		return nil
+	}
+	occupants := make([]PresenceOccupant, 0, len(roster))
+	for _, occupant := range roster {
+		state := make(map[string]interface{}, len(occupant.State))
+		for k, v := range occupant.State {
+			state[k] = v
+		}
+		occupants = append(occupants, PresenceOccupant{UUID: occupant.UUID, State: state})
+	}
+	sort.Slice(occupants, func(i, j int) bool {
+		return occupants[i].UUID < occupants[j].UUID
+	})
+	return occupants
+}
+
+// Occupancy returns the number of UUIDs present on channel.
+func (t *PresenceTracker) Occupancy(channel string) int {
+	t.RLock()
+	defer t.RUnlock()
+
+	return len(t.rosters[channel])
+}
+
+// presenceTrackerListener feeds the presence events to a PresenceTracker.
+type presenceTrackerListener struct {
+	BaseEventListener
+	tracker *PresenceTracker
+}
+
+func (l *presenceTrackerListener) OnPresence(presence *WPSPresence) {
+	l.tracker.apply(presence)
+}
+
+func presenceStateEqual(a, b map[string]interface{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		w, ok := b[k]
+		if !ok || !reflect.DeepEqual(v, w) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,116 @@
+package webpubsub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func nextRosterChange(t *testing.T, changes chan *RosterChange) *RosterChange {
+	select {
+	case change := <-changes:
+		return change
+	case <-time.After(5 * time.Second):
+		t.Fatal("roster change not received")
+	}
+	return nil
+}
+
+func startTestTracker(t *testing.T, responses ...sequenceResponse) (*PresenceTracker, chan *RosterChange) {
+	pn := newTestWebPubSub(&sequenceTransport{responses: responses})
+	t.Cleanup(pn.Destroy)
+
+	changes := make(chan *RosterChange, 10)
+	tracker := pn.PresenceTracker("ch").OnChange(func(change *RosterChange) {
+		changes <- change
+	})
+	if err := tracker.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(tracker.Stop)
+	return tracker, changes
+}
+
+func TestPresenceTrackerSeed(t *testing.T) {
+	assert := assert.New(t)
+
+	tracker, changes := startTestTracker(t, sequenceResponse{statusCode: 200,
+		body: `{"status":200,"uuids":[{"uuid":"b","state":{"mood":"ok"}},{"uuid":"a"}],"occupancy":2}`})
+
+	change := nextRosterChange(t, changes)
+	assert.Equal("refresh", change.Event)
+	assert.ElementsMatch([]string{"a", "b"}, change.Joined)
+	assert.Equal(2, change.Occupancy)
+
+	occupants := tracker.Occupants("ch")
+	assert.Len(occupants, 2)
+	assert.Equal("a", occupants[0].UUID)
+	assert.Equal("b", occupants[1].UUID)
+	assert.Equal(map[string]interface{}{"mood": "ok"}, occupants[1].State)
+	assert.Equal(2, tracker.Occupancy("ch"))
+	assert.Nil(tracker.Occupants("other"))
+}
+
+func TestPresenceTrackerEvents(t *testing.T) {
+	assert := assert.New(t)
+
+	tracker, changes := startTestTracker(t, sequenceResponse{statusCode: 200,
+		body: `{"status":200,"uuids":[{"uuid":"a"}],"occupancy":1}`})
+	nextRosterChange(t, changes)
+	announce := tracker.webpubsub.subscriptionManager.listenerManager.announcePresence
+
+	announce(&WPSPresence{Event: "join", UUID: "b", Channel: "ch", State: map[string]interface{}{"mood": "ok"}})
+	change := nextRosterChange(t, changes)
+	assert.Equal("join", change.Event)
+	assert.Equal([]string{"b"}, change.Joined)
+	assert.Equal(2, change.Occupancy)
+
+	announce(&WPSPresence{Event: "state-change", UUID: "a", Channel: "ch", State: map[string]interface{}{"mood": "busy"}})
+	change = nextRosterChange(t, changes)
+	assert.Equal([]string{"a"}, change.Updated)
+
+	announce(&WPSPresence{Event: "join", UUID: "c", Channel: "other"})
+	announce(&WPSPresence{Event: "timeout", UUID: "b", Channel: "ch"})
+	change = nextRosterChange(t, changes)
+	assert.Equal("timeout", change.Event)
+	assert.Equal([]string{"b"}, change.Left)
+	assert.Equal(1, change.Occupancy)
+
+	announce(&WPSPresence{Event: "interval", Channel: "ch", Join: []string{"d", "e"}, Leave: []string{"a"}})
+	change = nextRosterChange(t, changes)
+	assert.Equal([]string{"d", "e"}, change.Joined)
+	assert.Equal([]string{"a"}, change.Left)
+
+	occupants := tracker.Occupants("ch")
+	assert.Len(occupants, 2)
+	assert.Equal("d", occupants[0].UUID)
+	assert.Equal("e", occupants[1].UUID)
+}
+
+func TestPresenceTrackerHereNowRefresh(t *testing.T) {
+	assert := assert.New(t)
+
+	tracker, changes := startTestTracker(t, sequenceResponse{statusCode: 200,
+		body: `{"status":200,"uuids":[{"uuid":"a"},{"uuid":"b"}],"occupancy":2}`,
+	}, sequenceResponse{statusCode: 200,
+		body: `{"status":200,"uuids":[{"uuid":"b","state":{"mood":"ok"}},{"uuid":"c"}],"occupancy":2}`})
+	nextRosterChange(t, changes)
+
+	tracker.webpubsub.subscriptionManager.listenerManager.announcePresence(
+		&WPSPresence{Event: "interval", Channel: "ch", Occupancy: 120, HereNowRefresh: true})
+
+	change := nextRosterChange(t, changes)
+	assert.Equal("refresh", change.Event)
+	assert.Equal([]string{"c"}, change.Joined)
+	assert.Equal([]string{"a"}, change.Left)
+	assert.Equal([]string{"b"}, change.Updated)
+	assert.Equal(2, tracker.Occupancy("ch"))
+}
+
+func TestPresenceUUIDs(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal([]string{"a", "b"}, presenceUUIDs([]interface{}{"a", "b"}))
+	assert.Nil(presenceUUIDs(nil))
+}
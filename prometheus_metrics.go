@@ -0,0 +1,192 @@
+package webpubsub
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// prometheusLatencyBuckets are the upper bounds, in seconds, of the request
+// duration histogram.
+var prometheusLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// PrometheusMetrics is a Metrics which serves the measurements in the
+// Prometheus text format. Set it in Config.Metrics and register it as the
+// http.Handler of the scrape endpoint.
+type PrometheusMetrics struct {
+	sync.RWMutex
+
+	requests      map[requestMetricKey]int64
+	latencies     map[OperationType]*latencyHistogram
+	reconnects    int64
+	messages      map[string]int64
+	listenerDepth int
+	workersBusy   int
+	workersTotal  int
+}
+
+type requestMetricKey struct {
+	operation  OperationType
+	statusCode int
+}
+
+type latencyHistogram struct {
+	buckets []int64
+	count   int64
+	sum     float64
+}
+
+// NewPrometheusMetrics creates a PrometheusMetrics.
+func NewPrometheusMetrics() *PrometheusMetrics {
+	return &PrometheusMetrics{
+		requests:  make(map[requestMetricKey]int64),
+		latencies: make(map[OperationType]*latencyHistogram),
+		messages:  make(map[string]int64),
+	}
+}
+
+// RequestCompleted counts the request and observes its latency.
+func (p *PrometheusMetrics) RequestCompleted(operation OperationType, latency time.Duration, statusCode int, err error) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.requests[requestMetricKey{operation, statusCode}]++
+
+	h, ok := p.latencies[operation]
+	if !ok {
+		h = &latencyHistogram{buckets: make([]int64, len(prometheusLatencyBuckets))}
+		p.latencies[operation] = h
+	}
+	seconds := latency.Seconds()
+	for i, bound := range prometheusLatencyBuckets {
+		if seconds <= bound {
+			h.buckets[i]++
+		}
+	}
+	h.count++
+	h.sum += seconds
+}
+
+// SubscribeReconnected counts the reconnection.
+func (p *PrometheusMetrics) SubscribeReconnected() {
+	p.Lock()
+	p.reconnects++
+	p.Unlock()
+}
+
+// MessageReceived counts the message of the channel.
+func (p *PrometheusMetrics) MessageReceived(channel string) {
+	p.Lock()
+	p.messages[channel]++
+	p.Unlock()
+}
+
+// ListenerQueueDepth sets the listener queue depth gauge.
+func (p *PrometheusMetrics) ListenerQueueDepth(depth int) {
+	p.Lock()
+	p.listenerDepth = depth
+	p.Unlock()
+}
+
+// RequestWorkersBusy sets the request worker gauges.
+func (p *PrometheusMetrics) RequestWorkersBusy(busy, total int) {
+	p.Lock()
+	p.workersBusy = busy
+	p.workersTotal = total
+	p.Unlock()
+}
+
+// ServeHTTP writes the measurements in the Prometheus text format.
+func (p *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	p.WriteTo(w)
+}
+
+// WriteTo writes the measurements in the Prometheus text format to w.
+func (p *PrometheusMetrics) WriteTo(w io.Writer) (int64, error) {
+	p.RLock()
+	defer p.RUnlock()
+
+	var b strings.Builder
+
+	b.WriteString("# HELP wps_requests_total Number of requests by operation and HTTP status code.\n")
+	b.WriteString("# TYPE wps_requests_total counter\n")
+	keys := make([]requestMetricKey, 0, len(p.requests))
+	for key := range p.requests {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].operation != keys[j].operation {
+			return keys[i].operation.String() < keys[j].operation.String()
+		}
+		return keys[i].statusCode < keys[j].statusCode
+	})
+	for _, key := range keys {
+		fmt.Fprintf(&b, "wps_requests_total{operation=%s,status_code=\"%d\"} %d\n",
+			prometheusLabel(key.operation.String()), key.statusCode, p.requests[key])
+	}
+
+	b.WriteString("# HELP wps_request_duration_seconds Latency of the requests by operation.\n")
+	b.WriteString("# TYPE wps_request_duration_seconds histogram\n")
+	operations := make([]OperationType, 0, len(p.latencies))
+	for operation := range p.latencies {
+		operations = append(operations, operation)
+	}
+	sort.Slice(operations, func(i, j int) bool {
+		return operations[i].String() < operations[j].String()
+	})
+	for _, operation := range operations {
+		h := p.latencies[operation]
+		label := prometheusLabel(operation.String())
+		for i, bound := range prometheusLatencyBuckets {
+			fmt.Fprintf(&b, "wps_request_duration_seconds_bucket{operation=%s,le=\"%s\"} %d\n",
+				label, strconv.FormatFloat(bound, 'g', -1, 64), h.buckets[i])
+		}
+		fmt.Fprintf(&b, "wps_request_duration_seconds_bucket{operation=%s,le=\"+Inf\"} %d\n", label, h.count)
+		fmt.Fprintf(&b, "wps_request_duration_seconds_sum{operation=%s} %s\n", label, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(&b, "wps_request_duration_seconds_count{operation=%s} %d\n", label, h.count)
+	}
+
+	b.WriteString("# HELP wps_subscribe_reconnects_total Number of reconnections of the subscribe loop.\n")
+	b.WriteString("# TYPE wps_subscribe_reconnects_total counter\n")
+	fmt.Fprintf(&b, "wps_subscribe_reconnects_total %d\n", p.reconnects)
+
+	b.WriteString("# HELP wps_messages_received_total Number of messages received by channel.\n")
+	b.WriteString("# TYPE wps_messages_received_total counter\n")
+	channels := make([]string, 0, len(p.messages))
+	for channel := range p.messages {
+		channels = append(channels, channel)
+	}
+	sort.Strings(channels)
+	for _, channel := range channels {
+		fmt.Fprintf(&b, "wps_messages_received_total{channel=%s} %d\n", prometheusLabel(channel), p.messages[channel])
+	}
+
+	b.WriteString("# HELP wps_listener_queue_depth Number of events waiting in the listener queues.\n")
+	b.WriteString("# TYPE wps_listener_queue_depth gauge\n")
+	fmt.Fprintf(&b, "wps_listener_queue_depth %d\n", p.listenerDepth)
+
+	b.WriteString("# HELP wps_request_workers_busy Number of busy request workers.\n")
+	b.WriteString("# TYPE wps_request_workers_busy gauge\n")
+	fmt.Fprintf(&b, "wps_request_workers_busy %d\n", p.workersBusy)
+
+	b.WriteString("# HELP wps_request_workers Number of request workers.\n")
+	b.WriteString("# TYPE wps_request_workers gauge\n")
+	fmt.Fprintf(&b, "wps_request_workers %d\n", p.workersTotal)
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// prometheusLabel quotes and escapes a label value.
+func prometheusLabel(value string) string {
+	value = strings.Replace(value, `\`, `\\`, -1)
+	value = strings.Replace(value, "\n", `\n`, -1)
+	value = strings.Replace(value, `"`, `\"`, -1)
+	return `"` + value + `"`
+}
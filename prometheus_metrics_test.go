@@ -0,0 +1,49 @@
+package webpubsub
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrometheusMetrics(t *testing.T) {
+	assert := assert.New(t)
+
+	metrics := NewPrometheusMetrics()
+	metrics.RequestCompleted(WPSPublishOperation, 20*time.Millisecond, 200, nil)
+	metrics.RequestCompleted(WPSPublishOperation, 2*time.Second, 503, nil)
+	metrics.RequestCompleted(WPSTimeOperation, time.Millisecond, 200, nil)
+	metrics.SubscribeReconnected()
+	metrics.MessageReceived("ch")
+	metrics.MessageReceived("ch")
+	metrics.MessageReceived(`say "hi"`)
+	metrics.ListenerQueueDepth(4)
+	metrics.RequestWorkersBusy(1, 5)
+
+	rec := httptest.NewRecorder()
+	metrics.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+
+	assert.Equal("text/plain; version=0.0.4; charset=utf-8", rec.Header().Get("Content-Type"))
+	for _, line := range []string{
+		`wps_requests_total{operation="Publish",status_code="200"} 1`,
+		`wps_requests_total{operation="Publish",status_code="503"} 1`,
+		`wps_requests_total{operation="Time",status_code="200"} 1`,
+		`wps_request_duration_seconds_bucket{operation="Publish",le="0.025"} 1`,
+		`wps_request_duration_seconds_bucket{operation="Publish",le="2.5"} 2`,
+		`wps_request_duration_seconds_bucket{operation="Publish",le="+Inf"} 2`,
+		`wps_request_duration_seconds_sum{operation="Publish"} 2.02`,
+		`wps_request_duration_seconds_count{operation="Publish"} 2`,
+		`wps_subscribe_reconnects_total 1`,
+		`wps_messages_received_total{channel="ch"} 2`,
+		`wps_messages_received_total{channel="say \"hi\""} 1`,
+		`wps_listener_queue_depth 4`,
+		`wps_request_workers_busy 1`,
+		`wps_request_workers 5`,
+	} {
+		assert.Contains(strings.Split(body, "\n"), line)
+	}
+}
@@ -134,6 +134,34 @@ func (b *publishFileMessageBuilder) Execute() (*PublishFileMessageResponse, Stat
 	return newPublishFileMessageResponse(rawJSON, b.opts, status)
 }
 
+// Enqueue adds the PublishFileMessage request to the offline publish queue
+// and returns the id of the queued item.
+func (b *publishFileMessageBuilder) Enqueue() (string, error) {
+	if err := b.opts.validate(); err != nil {
+		return "", err
+	}
+	message := b.opts.Message
+	if message == nil {
+		message = WPSPublishFileMessage{
+			WPSFile: &WPSFileInfoForPublish{
+				ID:   b.opts.FileID,
+				Name: b.opts.FileName,
+			},
+			WPSMessage: &WPSPublishMessage{
+				Text: b.opts.MessageText,
+			},
+		}
+	}
+	item := newQueuedPublish(WPSPublishFileMessageOperation, b.opts.Channel, message)
+	item.Meta = b.opts.Meta
+	item.TTL = b.opts.TTL
+	item.SetTTL = b.opts.setTTL
+	item.ShouldStore = b.opts.ShouldStore
+	item.SetShouldStore = b.opts.setShouldStore
+
+	return item.ID, b.opts.webpubsub.publishQueueManager.enqueue(item)
+}
+
 type publishFileMessageOpts struct {
 	webpubsub      *WebPubSub
 	Message        interface{}
@@ -226,7 +254,7 @@ func (o *publishFileMessageOpts) buildPath() (string, error) {
 		}
 	}
 
-	if cipherKey := o.webpubsub.Config.CipherKey; cipherKey != "" {
+	if module := o.webpubsub.Config.cryptoModule(); module != nil {
 		var msg string
 		var p *publishBuilder
 		if o.context() != nil {
@@ -236,12 +264,12 @@ func (o *publishFileMessageOpts) buildPath() (string, error) {
 		}
 		p.opts.Message = o.Message
 
-		msg, errJSONMarshal := p.opts.encryptProcessing(cipherKey)
+		msg, errJSONMarshal := p.opts.encryptProcessing(module)
 		if errJSONMarshal != nil {
 			return "", errJSONMarshal
 		}
 
-		o.webpubsub.Config.Log.Println("EncryptString: encrypted", msg)
+		o.webpubsub.Config.logger(WPSCryptoSubsystem).Debug("EncryptString: encrypted", "message", msg)
 		return fmt.Sprintf(publishFileMessageGetPath,
 			o.webpubsub.Config.PublishKey,
 			o.webpubsub.Config.SubscribeKey,
@@ -252,7 +280,7 @@ func (o *publishFileMessageOpts) buildPath() (string, error) {
 	var msg string
 	jsonEncBytes, errEnc := json.Marshal(o.Message)
 	if errEnc != nil {
-		o.webpubsub.Config.Log.Printf("ERROR: Publish error: %s\n", errEnc.Error())
+		o.webpubsub.Config.logger(WPSRequestSubsystem).Error("publish error", "error", errEnc)
 		return "", errEnc
 	}
 	msg = string(jsonEncBytes)
@@ -282,7 +310,7 @@ func (o *publishFileMessageOpts) buildBody() ([]byte, error) {
 	if o.UsePost {
 		jsonEncBytes, errEnc := json.Marshal(o.Message)
 		if errEnc != nil {
-			o.webpubsub.Config.Log.Printf("ERROR: PublishFileMessage error: %s\n", errEnc.Error())
+			o.webpubsub.Config.logger(WPSRequestSubsystem).Error("publish file message error", "error", errEnc)
 			return []byte{}, errEnc
 		}
 		return jsonEncBytes, nil
@@ -317,14 +345,26 @@ func (o *publishFileMessageOpts) operationType() OperationType {
 	return WPSPublishFileMessageOperation
 }
 
+func (o *publishFileMessageOpts) setTraceParent(traceParent string) {
+	o.Meta = withTraceParent(o.Meta, traceParent)
+}
+
 func (o *publishFileMessageOpts) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *publishFileMessageOpts) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *publishFileMessageOpts) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
 
+func (o *publishFileMessageOpts) tokenResources() (channels, groups, uuids []string) {
+	return []string{o.Channel}, nil, nil
+}
+
 // PublishFileMessageResponse is the response to PublishFileMessage request.
 type PublishFileMessageResponse struct {
 	Timestamp int64
@@ -0,0 +1,368 @@
+package webpubsub
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	publishQueueMinBackoff = 1 * time.Second
+	publishQueueMaxBackoff = 32 * time.Second
+)
+
+// QueuedPublish is a publish, signal or file message waiting in the offline
+// publish queue.
+type QueuedPublish struct {
+	ID             string        `json:"id"`
+	Operation      OperationType `json:"operation"`
+	Channel        string        `json:"channel"`
+	Message        interface{}   `json:"message"`
+	Meta           interface{}   `json:"meta,omitempty"`
+	TTL            int           `json:"ttl,omitempty"`
+	SetTTL         bool          `json:"set_ttl,omitempty"`
+	ShouldStore    bool          `json:"store,omitempty"`
+	SetShouldStore bool          `json:"set_store,omitempty"`
+	UsePost        bool          `json:"use_post,omitempty"`
+	Serialize      bool          `json:"serialize,omitempty"`
+	DoNotReplicate bool          `json:"norep,omitempty"`
+	Attempts       int           `json:"attempts"`
+	EnqueuedAt     int64         `json:"enqueued_at"`
+}
+
+// PublishQueueStore persists the pending items of the offline publish queue.
+type PublishQueueStore interface {
+	// Load returns the items saved by the last Save.
+	Load() ([]*QueuedPublish, error)
+	// Save replaces the saved items.
+	Save(items []*QueuedPublish) error
+}
+
+// NewMemoryPublishQueueStore creates a store which keeps the queue in memory only.
+func NewMemoryPublishQueueStore() PublishQueueStore {
+	return &memoryPublishQueueStore{}
+}
+
+type memoryPublishQueueStore struct {
+	sync.RWMutex
+	items []*QueuedPublish
+}
+
+func (s *memoryPublishQueueStore) Load() ([]*QueuedPublish, error) {
+	s.RLock()
+	defer s.RUnlock()
+	return append([]*QueuedPublish{}, s.items...), nil
+}
+
+func (s *memoryPublishQueueStore) Save(items []*QueuedPublish) error {
+	s.Lock()
+	s.items = append([]*QueuedPublish{}, items...)
+	s.Unlock()
+	return nil
+}
+
+// NewFilePublishQueueStore creates a store which saves the queue as JSON in
+// the file at path. The messages are saved as given, they aren't encrypted
+// with the CryptoModule.
+func NewFilePublishQueueStore(path string) PublishQueueStore {
+	return &filePublishQueueStore{
+		path: path,
+	}
+}
+
+type filePublishQueueStore struct {
+	sync.Mutex
+	path string
+}
+
+func (s *filePublishQueueStore) Load() ([]*QueuedPublish, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	data, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var items []*QueuedPublish
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// Save writes a temporary file and renames it, so a crash never leaves a
+// partially written queue. The directory of the file is created if needed.
+func (s *filePublishQueueStore) Save(items []*QueuedPublish) error {
+	s.Lock()
+	defer s.Unlock()
+
+	data, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// publishQueueManager sends the queued items of each channel in order, one at
+// a time, the channels are sent independently so a failing channel doesn't
+// hold back the others. The failed deliveries are retried with backoff, or as
+// soon as the network is reconnected. The items saved by a previous run are
+// sent from the start, when the client is built.
+type publishQueueManager struct {
+	sync.RWMutex
+
+	webpubsub *WebPubSub
+	store     PublishQueueStore
+	items     []*QueuedPublish
+	// sending are the channels whose items are being sent.
+	sending map[string]bool
+	// reconnected is closed and replaced when the network is reconnected.
+	reconnected chan struct{}
+	ctx         Context
+}
+
+func newPublishQueueManager(webpubsub *WebPubSub, ctx Context) *publishQueueManager {
+	return &publishQueueManager{
+		webpubsub:   webpubsub,
+		sending:     make(map[string]bool),
+		reconnected: make(chan struct{}),
+		ctx:         ctx,
+	}
+}
+
+// start loads the items of the store set in the config and starts sending
+// them.
+func (m *publishQueueManager) start() {
+	config := m.webpubsub.Config
+	store := config.PublishQueueStore
+	if store == nil {
+		if config.PublishQueueFile != "" {
+			store = NewFilePublishQueueStore(config.PublishQueueFile)
+		} else {
+			store = NewMemoryPublishQueueStore()
+		}
+	}
+
+	items, err := store.Load()
+	if err != nil {
+		config.logger(WPSGeneralSubsystem).Error("publish queue load error", "error", err)
+	}
+	m.Lock()
+	m.store = store
+	m.items = items
+	m.Unlock()
+
+	for _, item := range items {
+		m.startSending(item.Channel)
+	}
+}
+
+func (m *publishQueueManager) enqueue(item *QueuedPublish) error {
+	m.Lock()
+	m.items = append(m.items, item)
+	err := m.store.Save(m.items)
+	if err != nil {
+		m.items = m.items[:len(m.items)-1]
+	}
+	m.Unlock()
+	if err != nil {
+		return err
+	}
+
+	m.startSending(item.Channel)
+	return nil
+}
+
+// startSending starts sending the items of channel, unless they are already
+// being sent.
+func (m *publishQueueManager) startSending(channel string) {
+	m.Lock()
+	defer m.Unlock()
+	if m.sending[channel] {
+		return
+	}
+	m.sending[channel] = true
+	go m.run(channel)
+}
+
+// onReconnection retries the pending items without waiting for the backoff.
+func (m *publishQueueManager) onReconnection() {
+	m.Lock()
+	close(m.reconnected)
+	m.reconnected = make(chan struct{})
+	m.Unlock()
+}
+
+func (m *publishQueueManager) pending() []QueuedPublish {
+	m.RLock()
+	defer m.RUnlock()
+	items := make([]QueuedPublish, len(m.items))
+	for i, item := range m.items {
+		items[i] = *item
+	}
+	return items
+}
+
+// next returns the first item of channel and the channel of the reconnection
+// of the network. When there is no item, the channel isn't being sent
+// anymore.
+func (m *publishQueueManager) next(channel string) (*QueuedPublish, chan struct{}) {
+	m.Lock()
+	defer m.Unlock()
+	for _, item := range m.items {
+		if item.Channel == channel {
+			return item, m.reconnected
+		}
+	}
+	delete(m.sending, channel)
+	return nil, nil
+}
+
+func (m *publishQueueManager) remove(item *QueuedPublish) {
+	m.Lock()
+	for i, v := range m.items {
+		if v == item {
+			m.items = append(m.items[:i:i], m.items[i+1:]...)
+			break
+		}
+	}
+	if err := m.store.Save(m.items); err != nil {
+		m.webpubsub.Config.logger(WPSGeneralSubsystem).Error("publish queue save error", "error", err)
+	}
+	m.Unlock()
+}
+
+func (m *publishQueueManager) failed(item *QueuedPublish) int {
+	m.Lock()
+	item.Attempts++
+	attempts := item.Attempts
+	if err := m.store.Save(m.items); err != nil {
+		m.webpubsub.Config.logger(WPSGeneralSubsystem).Error("publish queue save error", "error", err)
+	}
+	m.Unlock()
+	return attempts
+}
+
+// run sends the items of channel until there is none left.
+func (m *publishQueueManager) run(channel string) {
+	backoff := publishQueueMinBackoff
+	for {
+		item, reconnected := m.next(channel)
+		if item == nil {
+			return
+		}
+
+		status, err := m.send(item)
+		if err == nil {
+			m.remove(item)
+			m.announce(item, WPSPublishQueueDeliveredCategory, status.StatusCode, nil)
+			backoff = publishQueueMinBackoff
+			continue
+		}
+		if m.ctx.Err() != nil {
+			return
+		}
+
+		attempts := m.failed(item)
+		maxAttempts := m.webpubsub.Config.PublishQueueMaxAttempts
+		if !isRetryableError(status, err) || (maxAttempts > 0 && attempts >= maxAttempts) {
+			m.remove(item)
+			m.announce(item, WPSPublishQueueAbandonedCategory, status.StatusCode, err)
+			continue
+		}
+		m.webpubsub.Config.logger(WPSGeneralSubsystem).Warn("publish queue: attempt failed", "attempt", attempts, "id", item.ID, "backoff", backoff, "error", err)
+
+		select {
+		case <-time.After(backoff):
+		case <-reconnected:
+		case <-m.ctx.Done():
+			return
+		}
+		if backoff *= 2; backoff > publishQueueMaxBackoff {
+			backoff = publishQueueMaxBackoff
+		}
+	}
+}
+
+func (m *publishQueueManager) send(item *QueuedPublish) (StatusResponse, error) {
+	switch item.Operation {
+	case WPSSignalOperation:
+		b := newSignalBuilderWithContext(m.webpubsub, m.ctx)
+		b.opts.Channel = item.Channel
+		b.opts.Message = item.Message
+		b.opts.UsePost = item.UsePost
+		_, status, err := b.Execute()
+		return status, err
+	case WPSPublishFileMessageOperation:
+		b := newPublishFileMessageBuilderWithContext(m.webpubsub, m.ctx)
+		b.opts.Channel = item.Channel
+		b.opts.Message = item.Message
+		b.opts.Meta = item.Meta
+		b.opts.TTL = item.TTL
+		b.opts.setTTL = item.SetTTL
+		b.opts.ShouldStore = item.ShouldStore
+		b.opts.setShouldStore = item.SetShouldStore
+		_, status, err := b.Execute()
+		return status, err
+	default:
+		b := newPublishBuilderWithContext(m.webpubsub, m.ctx)
+		b.opts.Channel = item.Channel
+		b.opts.Message = item.Message
+		b.opts.Meta = item.Meta
+		b.opts.TTL = item.TTL
+		b.opts.setTTL = item.SetTTL
+		b.opts.ShouldStore = item.ShouldStore
+		b.opts.setShouldStore = item.SetShouldStore
+		b.opts.UsePost = item.UsePost
+		b.opts.Serialize = item.Serialize
+		b.opts.DoNotReplicate = item.DoNotReplicate
+		_, status, err := b.Execute()
+		return status, err
+	}
+}
+
+func (m *publishQueueManager) announce(item *QueuedPublish, category StatusCategory, statusCode int, err error) {
+	pnStatus := &WPSStatus{
+		Category:         category,
+		Operation:        item.Operation,
+		ErrorData:        err,
+		Error:            err != nil,
+		StatusCode:       statusCode,
+		AffectedChannels: []string{item.Channel},
+		ClientRequest:    *item,
+	}
+	m.webpubsub.Config.logger(WPSGeneralSubsystem).Info("publish queue", "status", pnStatus)
+	m.webpubsub.subscriptionManager.listenerManager.announceStatus(pnStatus)
+}
+
+// isRetryableError returns true when the request failed because of the
+// network, a timeout or a server side error.
+func isRetryableError(status StatusResponse, err error) bool {
+	if isConnectionError(err) {
+		return true
+	}
+	return status.StatusCode == 408 || status.StatusCode == 429 || status.StatusCode >= 500
+}
+
+func newQueuedPublish(operation OperationType, channel string, message interface{}) *QueuedPublish {
+	return &QueuedPublish{
+		ID:         GenerateUUID(),
+		Operation:  operation,
+		Channel:    channel,
+		Message:    message,
+		EnqueuedAt: time.Now().Unix(),
+	}
+}
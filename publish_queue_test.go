@@ -0,0 +1,228 @@
+package webpubsub
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/webpubsub/sdk-go/v7/pnerr"
+	"github.com/webpubsub/sdk-go/v7/tests/stubs"
+)
+
+var publishQueueIgnoreQueryKeys = []string{"uuid", "pnsdk", "seqn", "signature", "timestamp", "l_pub", "l_sig"}
+
+func publishQueueStub(path string, statusCode int) *stubs.Stub {
+	return &stubs.Stub{
+		Method:             "GET",
+		Path:               path,
+		Query:              "",
+		ResponseBody:       `[1,"Sent","15000000000000000"]`,
+		IgnoreQueryKeys:    publishQueueIgnoreQueryKeys,
+		ResponseStatusCode: statusCode,
+	}
+}
+
+func nextStatus(t *testing.T, listener *recordingEventListener) *WPSStatus {
+	select {
+	case status := <-listener.statuses:
+		return status
+	case <-time.After(5 * time.Second):
+		t.Fatal("status not received")
+		return nil
+	}
+}
+
+func TestPublishQueueDeliversInOrder(t *testing.T) {
+	assert := assert.New(t)
+
+	interceptor := stubs.NewInterceptor()
+	interceptor.AddStub(publishQueueStub("/publish/demo/demo/0/ch/0/%22one%22", 200))
+	interceptor.AddStub(publishQueueStub("/signal/demo/demo/0/ch/0/%22two%22", 200))
+	pn := newTestWebPubSub(interceptor.Transport)
+	listener := newRecordingEventListener()
+	pn.AddListener(listener)
+
+	id1, err := pn.Publish().Channel("ch").Message("one").Serialize(true).Enqueue()
+	assert.Nil(err)
+	id2, err := pn.Signal().Channel("ch").Message("two").Enqueue()
+	assert.Nil(err)
+
+	status := nextStatus(t, listener)
+	assert.Equal(WPSPublishQueueDeliveredCategory, status.Category)
+	assert.Equal(WPSPublishOperation, status.Operation)
+	assert.Equal(id1, status.ClientRequest.(QueuedPublish).ID)
+
+	status = nextStatus(t, listener)
+	assert.Equal(WPSPublishQueueDeliveredCategory, status.Category)
+	assert.Equal(WPSSignalOperation, status.Operation)
+	assert.Equal(id2, status.ClientRequest.(QueuedPublish).ID)
+	assert.Equal([]string{"ch"}, status.AffectedChannels)
+
+	assert.Empty(pn.PendingPublishes())
+}
+
+func TestPublishQueueAbandonsOnClientError(t *testing.T) {
+	assert := assert.New(t)
+
+	interceptor := stubs.NewInterceptor()
+	interceptor.AddStub(publishQueueStub("/publish/demo/demo/0/ch/0/%22bad%22", 400))
+	pn := newTestWebPubSub(interceptor.Transport)
+	listener := newRecordingEventListener()
+	pn.AddListener(listener)
+
+	_, err := pn.Publish().Channel("ch").Message("bad").Serialize(true).Enqueue()
+	assert.Nil(err)
+
+	status := nextStatus(t, listener)
+	assert.Equal(WPSPublishQueueAbandonedCategory, status.Category)
+	assert.True(status.Error)
+	assert.Equal(400, status.StatusCode)
+	assert.Empty(pn.PendingPublishes())
+}
+
+func TestPublishQueueAbandonsAfterMaxAttempts(t *testing.T) {
+	assert := assert.New(t)
+
+	interceptor := stubs.NewInterceptor()
+	interceptor.AddStub(publishQueueStub("/publish/demo/demo/0/ch/0/%22down%22", 503))
+	pn := newTestWebPubSub(interceptor.Transport)
+	pn.Config.PublishQueueMaxAttempts = 1
+	listener := newRecordingEventListener()
+	pn.AddListener(listener)
+
+	_, err := pn.Publish().Channel("ch").Message("down").Serialize(true).Enqueue()
+	assert.Nil(err)
+
+	status := nextStatus(t, listener)
+	assert.Equal(WPSPublishQueueAbandonedCategory, status.Category)
+	assert.Equal(1, status.ClientRequest.(QueuedPublish).Attempts)
+}
+
+func TestPublishQueueEnqueueValidation(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := NewWebPubSub(NewDemoConfig())
+	_, err := pn.Publish().Message("no channel").Enqueue()
+	assert.NotNil(err)
+	assert.Empty(pn.PendingPublishes())
+}
+
+func TestPublishQueueReplaysStoredItems(t *testing.T) {
+	assert := assert.New(t)
+
+	store := NewMemoryPublishQueueStore()
+	item := newQueuedPublish(WPSPublishOperation, "ch", "stored")
+	item.Serialize = true
+	assert.Nil(store.Save([]*QueuedPublish{item}))
+
+	interceptor := stubs.NewInterceptor()
+	interceptor.AddStub(publishQueueStub("/publish/demo/demo/0/ch/0/%22stored%22", 200))
+	pn := newTestWebPubSub(interceptor.Transport)
+	listener := newRecordingEventListener()
+	pn.AddListener(listener)
+
+	// start again, as a client built with the store does.
+	pn.Config.PublishQueueStore = store
+	pn.publishQueueManager.start()
+
+	status := nextStatus(t, listener)
+	assert.Equal(WPSPublishQueueDeliveredCategory, status.Category)
+	assert.Equal(item.ID, status.ClientRequest.(QueuedPublish).ID)
+	assert.Empty(pn.PendingPublishes())
+
+	items, err := store.Load()
+	assert.Nil(err)
+	assert.Empty(items)
+}
+
+func TestPublishQueueChannelsAreIndependent(t *testing.T) {
+	assert := assert.New(t)
+
+	interceptor := stubs.NewInterceptor()
+	interceptor.AddStub(publishQueueStub("/publish/demo/demo/0/down/0/%22first%22", 503))
+	interceptor.AddStub(publishQueueStub("/publish/demo/demo/0/up/0/%22second%22", 200))
+	pn := newTestWebPubSub(interceptor.Transport)
+	pn.Config.PublishQueueMaxAttempts = 2
+	listener := newRecordingEventListener()
+	pn.AddListener(listener)
+
+	_, err := pn.Publish().Channel("down").Message("first").Serialize(true).Enqueue()
+	assert.Nil(err)
+	id, err := pn.Publish().Channel("up").Message("second").Serialize(true).Enqueue()
+	assert.Nil(err)
+
+	status := nextStatus(t, listener)
+	assert.Equal(WPSPublishQueueDeliveredCategory, status.Category)
+	assert.Equal(id, status.ClientRequest.(QueuedPublish).ID)
+	if pending := pn.PendingPublishes(); assert.Len(pending, 1) {
+		assert.Equal("down", pending[0].Channel)
+	}
+
+	status = nextStatus(t, listener)
+	assert.Equal(WPSPublishQueueAbandonedCategory, status.Category)
+	assert.Equal([]string{"down"}, status.AffectedChannels)
+}
+
+func TestPublishQueueStoreFromConfig(t *testing.T) {
+	assert := assert.New(t)
+
+	_, ok := NewWebPubSub(NewDemoConfig()).publishQueueManager.store.(*memoryPublishQueueStore)
+	assert.True(ok)
+
+	dir, err := ioutil.TempDir("", "publish-queue")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	interceptor := stubs.NewInterceptor()
+	interceptor.AddStub(publishQueueStub("/publish/demo/demo/0/ch/0/%22one%22", 200))
+	config := NewDemoConfig()
+	config.PublishQueueFile = filepath.Join(dir, "queue", "pending.json")
+	pn := NewWebPubSub(config)
+	pn.SetClient(&http.Client{Transport: interceptor.Transport})
+	listener := newRecordingEventListener()
+	pn.AddListener(listener)
+
+	_, err = pn.Publish().Channel("ch").Message("one").Serialize(true).Enqueue()
+	assert.Nil(err)
+	assert.Equal(WPSPublishQueueDeliveredCategory, nextStatus(t, listener).Category)
+	_, err = os.Stat(config.PublishQueueFile)
+	assert.Nil(err)
+}
+
+func TestFilePublishQueueStore(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "publish-queue")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	store := NewFilePublishQueueStore(filepath.Join(dir, "queue.json"))
+	items, err := store.Load()
+	assert.Nil(err)
+	assert.Empty(items)
+
+	item := newQueuedPublish(WPSSignalOperation, "ch", map[string]interface{}{"a": "b"})
+	assert.Nil(store.Save([]*QueuedPublish{item}))
+
+	items, err = NewFilePublishQueueStore(filepath.Join(dir, "queue.json")).Load()
+	assert.Nil(err)
+	assert.Equal(1, len(items))
+	assert.Equal(item.ID, items[0].ID)
+	assert.Equal(WPSSignalOperation, items[0].Operation)
+	assert.Equal(map[string]interface{}{"a": "b"}, items[0].Message)
+}
+
+func TestIsRetryableError(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(isRetryableError(StatusResponse{}, pnerr.NewConnectionError("Failed to execute request", errors.New("down"))))
+	assert.True(isRetryableError(StatusResponse{StatusCode: 503}, errors.New("unavailable")))
+	assert.True(isRetryableError(StatusResponse{StatusCode: 429}, errors.New("too many")))
+	assert.False(isRetryableError(StatusResponse{StatusCode: 403}, errors.New("forbidden")))
+	assert.False(isRetryableError(StatusResponse{}, errors.New("validation")))
+}
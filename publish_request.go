@@ -186,6 +186,26 @@ func (b *publishBuilder) Execute() (*PublishResponse, StatusResponse, error) {
 	return newPublishResponse(rawJSON, status)
 }
 
+// Enqueue adds the Publish request to the offline publish queue, which
+// delivers it when the network is available and reports the delivery or the
+// abandonment with a status event. It returns the id of the queued item.
+func (b *publishBuilder) Enqueue() (string, error) {
+	if err := b.opts.validate(); err != nil {
+		return "", err
+	}
+	item := newQueuedPublish(WPSPublishOperation, b.opts.Channel, b.opts.Message)
+	item.Meta = b.opts.Meta
+	item.TTL = b.opts.TTL
+	item.SetTTL = b.opts.setTTL
+	item.ShouldStore = b.opts.ShouldStore
+	item.SetShouldStore = b.opts.setShouldStore
+	item.UsePost = b.opts.UsePost
+	item.Serialize = b.opts.Serialize
+	item.DoNotReplicate = b.opts.DoNotReplicate
+
+	return item.ID, b.opts.webpubsub.publishQueueManager.enqueue(item)
+}
+
 func (o *publishOpts) config() Config {
 	return *o.webpubsub.Config
 }
@@ -218,48 +238,48 @@ func (o *publishOpts) validate() error {
 	return nil
 }
 
-func (o *publishOpts) encryptProcessing(cipherKey string) (string, error) {
+func (o *publishOpts) encryptProcessing(module CryptoModule) (string, error) {
 	var msg string
 	var errJSONMarshal error
 
-	o.webpubsub.Config.Log.Println("EncryptString: encrypting", fmt.Sprintf("%s", o.Message))
+	o.webpubsub.Config.logger(WPSCryptoSubsystem).Debug("EncryptString: encrypting", "message", o.Message)
 	if o.webpubsub.Config.DisableWPSOtherProcessing {
-		if msg, errJSONMarshal = utils.SerializeEncryptAndSerialize(o.Message, cipherKey, o.Serialize, o.webpubsub.Config.UseRandomInitializationVector); errJSONMarshal != nil {
-			o.webpubsub.Config.Log.Printf("error in serializing: %v\n", errJSONMarshal)
+		if msg, errJSONMarshal = serializeEncryptAndSerialize(module, o.Message, o.Serialize); errJSONMarshal != nil {
+			o.webpubsub.Config.logger(WPSRequestSubsystem).Error("error in serializing", "error", errJSONMarshal)
 			return "", errJSONMarshal
 		}
 	} else {
 		//encrypt pn_other only
-		o.webpubsub.Config.Log.Println("encrypt pn_other only", "reflect.TypeOf(data).Kind()", reflect.TypeOf(o.Message).Kind(), o.Message)
+		o.webpubsub.Config.logger(WPSCryptoSubsystem).Debug("encrypt pn_other only", "kind", reflect.TypeOf(o.Message).Kind(), "message", o.Message)
 		switch v := o.Message.(type) {
 		case map[string]interface{}:
 
 			msgPart, ok := v["pn_other"].(string)
 
 			if ok {
-				o.webpubsub.Config.Log.Println(ok, msgPart)
-				encMsg, errJSONMarshal := utils.SerializeAndEncrypt(msgPart, cipherKey, o.Serialize, o.webpubsub.Config.UseRandomInitializationVector)
+				o.webpubsub.Config.logger(WPSCryptoSubsystem).Debug("encrypt pn_other", "found", ok, "message", msgPart)
+				encMsg, errJSONMarshal := serializeAndEncrypt(module, msgPart, o.Serialize)
 				if errJSONMarshal != nil {
-					o.webpubsub.Config.Log.Printf("error in serializing: %v\n", errJSONMarshal)
+					o.webpubsub.Config.logger(WPSRequestSubsystem).Error("error in serializing", "error", errJSONMarshal)
 					return "", errJSONMarshal
 				}
 				v["pn_other"] = encMsg
 				jsonEncBytes, errEnc := json.Marshal(v)
 				if errEnc != nil {
-					o.webpubsub.Config.Log.Printf("ERROR: Publish error: %s\n", errEnc.Error())
+					o.webpubsub.Config.logger(WPSRequestSubsystem).Error("publish error", "error", errEnc)
 					return "", errEnc
 				}
 				msg = string(jsonEncBytes)
 			} else {
-				if msg, errJSONMarshal = utils.SerializeEncryptAndSerialize(o.Message, cipherKey, o.Serialize, o.webpubsub.Config.UseRandomInitializationVector); errJSONMarshal != nil {
-					o.webpubsub.Config.Log.Printf("error in serializing: %v\n", errJSONMarshal)
+				if msg, errJSONMarshal = serializeEncryptAndSerialize(module, o.Message, o.Serialize); errJSONMarshal != nil {
+					o.webpubsub.Config.logger(WPSRequestSubsystem).Error("error in serializing", "error", errJSONMarshal)
 					return "", errJSONMarshal
 				}
 			}
 			break
 		default:
-			if msg, errJSONMarshal = utils.SerializeEncryptAndSerialize(o.Message, cipherKey, o.Serialize, o.webpubsub.Config.UseRandomInitializationVector); errJSONMarshal != nil {
-				o.webpubsub.Config.Log.Printf("error in serializing: %v\n", errJSONMarshal)
+			if msg, errJSONMarshal = serializeEncryptAndSerialize(module, o.Message, o.Serialize); errJSONMarshal != nil {
+				o.webpubsub.Config.logger(WPSRequestSubsystem).Error("error in serializing", "error", errJSONMarshal)
 				return "", errJSONMarshal
 			}
 
@@ -281,21 +301,21 @@ func (o *publishOpts) buildPath() (string, error) {
 	var msg string
 	var errJSONMarshal error
 
-	if cipherKey := o.webpubsub.Config.CipherKey; cipherKey != "" {
-		if msg, errJSONMarshal = o.encryptProcessing(cipherKey); errJSONMarshal != nil {
+	if module := o.webpubsub.Config.cryptoModule(); module != nil {
+		if msg, errJSONMarshal = o.encryptProcessing(module); errJSONMarshal != nil {
 			return "", errJSONMarshal
 		}
 
-		o.webpubsub.Config.Log.Println("EncryptString: encrypted", msg)
+		o.webpubsub.Config.logger(WPSCryptoSubsystem).Debug("EncryptString: encrypted", "message", msg)
 	} else {
 		if o.Serialize {
 			jsonEncBytes, errEnc := json.Marshal(o.Message)
 			if errEnc != nil {
-				o.webpubsub.Config.Log.Printf("ERROR: Publish error: %s\n", errEnc.Error())
+				o.webpubsub.Config.logger(WPSRequestSubsystem).Error("publish error", "error", errEnc)
 				return "", errEnc
 			}
 			msg = string(jsonEncBytes)
-			o.webpubsub.Config.Log.Println("len(jsonEncBytes)", len(jsonEncBytes))
+			o.webpubsub.Config.logger(WPSCryptoSubsystem).Debug("encrypted", "length", len(jsonEncBytes))
 
 		} else {
 			if serializedMsg, ok := o.Message.(string); ok {
@@ -341,7 +361,7 @@ func (o *publishOpts) buildQuery() (*url.Values, error) {
 	}
 
 	seqn := strconv.Itoa(o.webpubsub.getPublishSequence())
-	o.webpubsub.Config.Log.Println("seqn:", seqn)
+	o.webpubsub.Config.logger(WPSRequestSubsystem).Debug("publish", "seqn", seqn)
 	q.Set("seqn", seqn)
 
 	SetQueryParam(q, o.QueryParam)
@@ -349,7 +369,7 @@ func (o *publishOpts) buildQuery() (*url.Values, error) {
 	if o.DoNotReplicate == true {
 		q.Set("norep", "true")
 	}
-	o.webpubsub.Config.Log.Println(q)
+	o.webpubsub.Config.logger(WPSRequestSubsystem).Debug("publish", "query", q.Encode())
 
 	return q, nil
 }
@@ -360,8 +380,8 @@ func (o *publishOpts) jobQueue() chan *JobQItem {
 
 func (o *publishOpts) buildBody() ([]byte, error) {
 	if o.UsePost {
-		if cipherKey := o.webpubsub.Config.CipherKey; cipherKey != "" {
-			msg, errJSONMarshal := o.encryptProcessing(cipherKey)
+		if module := o.webpubsub.Config.cryptoModule(); module != nil {
+			msg, errJSONMarshal := o.encryptProcessing(module)
 			if errJSONMarshal != nil {
 				return []byte{}, errJSONMarshal
 			}
@@ -370,7 +390,7 @@ func (o *publishOpts) buildBody() ([]byte, error) {
 		if o.Serialize {
 			jsonEncBytes, errEnc := json.Marshal(o.Message)
 			if errEnc != nil {
-				o.webpubsub.Config.Log.Printf("ERROR: Publish error: %s\n", errEnc.Error())
+				o.webpubsub.Config.logger(WPSRequestSubsystem).Error("publish error", "error", errEnc)
 				return []byte{}, errEnc
 			}
 			return jsonEncBytes, nil
@@ -411,10 +431,22 @@ func (o *publishOpts) operationType() OperationType {
 	return WPSPublishOperation
 }
 
+func (o *publishOpts) setTraceParent(traceParent string) {
+	o.Meta = withTraceParent(o.Meta, traceParent)
+}
+
 func (o *publishOpts) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *publishOpts) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *publishOpts) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
+
+func (o *publishOpts) tokenResources() (channels, groups, uuids []string) {
+	return []string{o.Channel}, nil, nil
+}
@@ -1,8 +1,6 @@
 package webpubsub
 
 import (
-	"fmt"
-	"math"
 	"sync"
 	"time"
 )
@@ -19,10 +17,11 @@ type ReconnectionManager struct {
 
 	timerMutex sync.RWMutex
 
-	ExponentialMultiplier       int
+	ExponentialMultiplier       int // Deprecated: the delays come from Config.Reconnector.
 	FailedCalls                 int
 	Milliseconds                int
 	OnReconnection              func()
+	OnDisconnection             func(err error)
 	OnMaxReconnectionExhaustion func()
 	DoneTimer                   chan bool
 	hbRunning                   bool
@@ -51,6 +50,13 @@ func (m *ReconnectionManager) HandleReconnection(handler func()) {
 	m.Unlock()
 }
 
+// HandleDisconnection sets the handler that will be called when the network is lost, with the error of the failed call.
+func (m *ReconnectionManager) HandleDisconnection(handler func(err error)) {
+	m.Lock()
+	m.OnDisconnection = handler
+	m.Unlock()
+}
+
 // HandleOnMaxReconnectionExhaustion sets the handler that will be called when the max reconnection attempts are exhausted.
 func (m *ReconnectionManager) HandleOnMaxReconnectionExhaustion(handler func()) {
 	m.Lock()
@@ -60,8 +66,8 @@ func (m *ReconnectionManager) HandleOnMaxReconnectionExhaustion(handler func())
 
 func (m *ReconnectionManager) startPolling() {
 
-	if m.webpubsub.Config.WPSReconnectionPolicy == WPSNonePolicy {
-		m.webpubsub.Config.Log.Println("Reconnection policy is disabled, please handle reconnection manually.")
+	if m.webpubsub.Config.reconnector() == nil {
+		m.webpubsub.Config.logger(WPSSubscribeSubsystem).Info("Reconnection policy is disabled, please handle reconnection manually.")
 		return
 	}
 
@@ -72,18 +78,28 @@ func (m *ReconnectionManager) startPolling() {
 	m.Unlock()
 
 	if !hbRunning {
-		m.webpubsub.Config.Log.Println(fmt.Sprintf("Reconnection policy: %d, retries: %d", m.webpubsub.Config.WPSReconnectionPolicy, m.webpubsub.Config.MaximumReconnectionRetries))
+		m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("reconnection manager", "policy", m.webpubsub.Config.WPSReconnectionPolicy, "retries", m.webpubsub.Config.MaximumReconnectionRetries)
 
 		m.startHeartbeatTimer()
 	} else {
-		m.webpubsub.Config.Log.Println("hb already running")
+		m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("hb already running")
 	}
 
 }
 
+// startHeartbeatTimer polls Time() every reconnectionInterval seconds while
+// the network is up. After a failure the delays come from the Reconnector and
+// the ReconnectHook, until a call succeeds or the Reconnector gives up.
 func (m *ReconnectionManager) startHeartbeatTimer() {
+	reconnector := m.webpubsub.Config.reconnector()
+	if reconnector == nil {
+		reconnector = NewFixedReconnector(reconnectionInterval*time.Second, 0)
+	}
+	hook := m.webpubsub.Config.ReconnectHook
 
-	timerInterval := reconnectionInterval
+	delay := time.Duration(reconnectionInterval) * time.Second
+	var previous time.Duration
+	vetoed := false
 
 	for {
 
@@ -91,80 +107,76 @@ func (m *ReconnectionManager) startHeartbeatTimer() {
 		m.hbRunning = true
 		failedCalls := m.FailedCalls
 		m.Unlock()
-		_, status, err := m.webpubsub.Time().Execute()
-		if status.Error == nil {
-			if failedCalls > 0 {
-				timerInterval = reconnectionInterval
+		if !vetoed {
+			_, status, err := m.webpubsub.Time().Execute()
+			if status.Error == nil {
+				delay = time.Duration(reconnectionInterval) * time.Second
+				previous = 0
+				if failedCalls > 0 {
+					m.Lock()
+					m.FailedCalls = 0
+					m.Unlock()
+					failedCalls = 0
+					m.webpubsub.Config.logger(WPSSubscribeSubsystem).Info("Network reconnected")
+					m.OnReconnection()
+				}
+			} else {
 				m.Lock()
-				m.FailedCalls = 0
-				m.Unlock()
-				m.webpubsub.Config.Log.Println(fmt.Sprintf("Network reconnected"))
-				m.OnReconnection()
-			}
-		} else {
-			if m.webpubsub.Config.WPSReconnectionPolicy == WPSExponentialPolicycy {
-				timerInterval = m.getExponentialInterval()
-			}
-			m.Lock()
-			m.FailedCalls++
-			m.webpubsub.Config.Log.Println(fmt.Sprintf("Network disconnected, reconnection try %d of %d\n %v %v", m.FailedCalls, m.webpubsub.Config.MaximumReconnectionRetries, status, err))
-			m.ExponentialMultiplier++
+				m.FailedCalls++
+				m.webpubsub.Config.logger(WPSSubscribeSubsystem).Warn("Network disconnected", "try", m.FailedCalls, "retries", m.webpubsub.Config.MaximumReconnectionRetries, "status", status, "error", err)
+				m.ExponentialMultiplier++
 
-			failedCalls := m.FailedCalls
-			retries := m.webpubsub.Config.MaximumReconnectionRetries
-			m.Unlock()
-			if retries != -1 && failedCalls >= retries {
-				m.webpubsub.Config.Log.Printf(fmt.Sprintf("Network connection retry limit (%d) exceeded", retries))
-				m.Lock()
-				m.hbRunning = false
+				failedCalls = m.FailedCalls
+				onDisconnection := m.OnDisconnection
 				m.Unlock()
-				m.OnMaxReconnectionExhaustion()
-				return
+				if failedCalls == 1 && onDisconnection != nil {
+					onDisconnection(err)
+				}
+
+				next, ok := reconnector.NextDelay(failedCalls, previous)
+				if !ok {
+					m.webpubsub.Config.logger(WPSSubscribeSubsystem).Error("Network connection retry limit exceeded", "retries", failedCalls)
+					m.Lock()
+					m.hbRunning = false
+					m.Unlock()
+					m.OnMaxReconnectionExhaustion()
+					return
+				}
+				delay = next
+				previous = next
 			}
 		}
 
+		vetoed = false
+		if failedCalls > 0 && hook != nil {
+			allowed := true
+			delay, allowed = hook(failedCalls+1, delay)
+			vetoed = !allowed
+			m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("reconnect hook", "attempt", failedCalls+1, "delay", delay, "vetoed", vetoed)
+		}
+
 		select {
-		case <-time.After(time.Duration(timerInterval) * time.Second):
+		case <-time.After(delay):
 		case <-m.webpubsub.ctx.Done():
-			m.webpubsub.Config.Log.Printf(fmt.Sprintf("webpubsub.ctx.Done\n"))
+			m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("webpubsub.ctx.Done")
 			m.Lock()
 			m.hbRunning = false
 			m.Unlock()
 			return
 		case <-m.exitReconnectionManager:
-			m.webpubsub.Config.Log.Printf(fmt.Sprintf("exitReconnectionManager\n"))
+			m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("exitReconnectionManager")
 			return
 		}
 	}
 }
 
-func (m *ReconnectionManager) getExponentialInterval() int {
-	timerInterval := int(math.Pow(2, float64(m.ExponentialMultiplier)) - 1)
-	if timerInterval > reconnectionMaxExponentialBackoff {
-		timerInterval = reconnectionMinExponentialBackoff
-
-		m.Lock()
-		m.ExponentialMultiplier = 1
-		m.webpubsub.Config.Log.Printf(fmt.Sprintf("timerInterval > MaxExponentialBackoff at: %d\n", m.ExponentialMultiplier))
-		m.Unlock()
-
-	} else if timerInterval < 1 {
-		timerInterval = reconnectionMinExponentialBackoff
-		m.Lock()
-		m.ExponentialMultiplier = 1
-		m.webpubsub.Config.Log.Printf(fmt.Sprintf("timerInterval < 1 at: %d\n", m.ExponentialMultiplier))
-		m.Unlock()
-	}
-	return timerInterval
-}
-
 func (m *ReconnectionManager) stopHeartbeatTimer() {
-	m.webpubsub.Config.Log.Printf("stopHeartbeatTimer")
+	m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("stopHeartbeatTimer")
 	m.Lock()
 	if m.hbRunning {
 		m.hbRunning = false
 		m.exitReconnectionManager <- true
 	}
 	m.Unlock()
-	m.webpubsub.Config.Log.Printf("stopHeartbeatTimer true")
+	m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("stopHeartbeatTimer true")
 }
@@ -1,6 +1,7 @@
 package webpubsub
 
 import (
+	"errors"
 	"testing"
 	"time"
 
@@ -97,3 +98,47 @@ func TestReconnect(t *testing.T) {
 	assert.True(reconnected)
 	r.stopHeartbeatTimer()
 }
+
+func TestReconnectHookVetoesAttempt(t *testing.T) {
+	assert := assert.New(t)
+
+	transport := &sequenceTransport{responses: []sequenceResponse{
+		{err: errors.New("connection refused")},
+		{statusCode: 200, body: `[15078947309567840]`},
+	}}
+	pn := newTestWebPubSub(transport)
+	pn.Config.Reconnector = NewFixedReconnector(time.Millisecond, 0)
+	var attempts []int
+	pn.Config.ReconnectHook = func(attempt int, delay time.Duration) (time.Duration, bool) {
+		attempts = append(attempts, attempt)
+		return delay, len(attempts) > 1
+	}
+
+	r := newReconnectionManager(pn)
+	disconnected := make(chan error, 1)
+	reconnected := make(chan bool, 1)
+	r.HandleDisconnection(func(err error) {
+		disconnected <- err
+	})
+	r.HandleReconnection(func() {
+		reconnected <- true
+	})
+	go r.startHeartbeatTimer()
+
+	select {
+	case err := <-disconnected:
+		assert.NotNil(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("disconnection not reported")
+	}
+	select {
+	case <-reconnected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("reconnection not reported")
+	}
+	r.stopHeartbeatTimer()
+
+	// The vetoed attempt is asked again without calling Time().
+	assert.Equal([]int{2, 2}, attempts)
+	assert.Equal(2, transport.count())
+}
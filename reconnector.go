@@ -0,0 +1,150 @@
+package webpubsub
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Reconnector decides how long the SDK waits before trying to reach the
+// server again after a failure. It is used by the Time() polling of the
+// reconnection manager and to retry the failed heartbeats. Set it in
+// Config.Reconnector, when nil it is derived from WPSReconnectionPolicy and
+// MaximumReconnectionRetries.
+type Reconnector interface {
+	// NextDelay returns the delay before the next attempt, attempt counts the
+	// failures since the connection was lost starting at 1, previous is the
+	// delay returned for the previous attempt, 0 for the first one. It
+	// returns false to give up.
+	NextDelay(attempt int, previous time.Duration) (time.Duration, bool)
+}
+
+// ReconnectHook is called before each reconnection attempt with the delay
+// chosen by the Reconnector. It returns the delay to wait instead, and false
+// to veto the attempt: it is skipped and the hook is asked again after the
+// delay.
+type ReconnectHook func(attempt int, delay time.Duration) (time.Duration, bool)
+
+// FixedReconnector waits the same Interval between the attempts.
+type FixedReconnector struct {
+	Interval    time.Duration // Delay between the attempts.
+	Jitter      time.Duration // Upper bound of the random time added to each delay.
+	MaxAttempts int           // Number of failed attempts after which it gives up, 0 never gives up.
+}
+
+// NewFixedReconnector creates a FixedReconnector.
+func NewFixedReconnector(interval time.Duration, maxAttempts int) *FixedReconnector {
+	return &FixedReconnector{
+		Interval:    interval,
+		MaxAttempts: maxAttempts,
+	}
+}
+
+// NextDelay implements Reconnector.
+func (r *FixedReconnector) NextDelay(attempt int, previous time.Duration) (time.Duration, bool) {
+	if reconnectionExhausted(attempt, r.MaxAttempts) {
+		return 0, false
+	}
+	return r.Interval + randomJitter(r.Jitter), true
+}
+
+// LinearReconnector waits Min more after each attempt, up to Max.
+type LinearReconnector struct {
+	Min         time.Duration // Delay before the first attempt, added after each attempt.
+	Max         time.Duration // Upper bound of the delay, 0 for no bound.
+	Jitter      time.Duration // Upper bound of the random time added to each delay.
+	MaxAttempts int           // Number of failed attempts after which it gives up, 0 never gives up.
+}
+
+// NewLinearReconnector creates a LinearReconnector.
+func NewLinearReconnector(min, max time.Duration, maxAttempts int) *LinearReconnector {
+	return &LinearReconnector{
+		Min:         min,
+		Max:         max,
+		MaxAttempts: maxAttempts,
+	}
+}
+
+// NextDelay implements Reconnector.
+func (r *LinearReconnector) NextDelay(attempt int, previous time.Duration) (time.Duration, bool) {
+	if reconnectionExhausted(attempt, r.MaxAttempts) {
+		return 0, false
+	}
+	delay := r.Min * time.Duration(attempt)
+	if r.Max > 0 && delay > r.Max {
+		delay = r.Max
+	}
+	return delay + randomJitter(r.Jitter), true
+}
+
+// ExponentialReconnector grows the delay exponentially with decorrelated
+// jitter: the first attempt waits Min, the next ones a random delay between
+// Min and three times the previous delay, up to Max. The instances which lost
+// the connection at the same time don't retry in lockstep.
+type ExponentialReconnector struct {
+	Min         time.Duration // Delay before the first attempt.
+	Max         time.Duration // Upper bound of the delay.
+	MaxAttempts int           // Number of failed attempts after which it gives up, 0 never gives up.
+}
+
+// NewExponentialReconnector creates an ExponentialReconnector.
+func NewExponentialReconnector(min, max time.Duration, maxAttempts int) *ExponentialReconnector {
+	return &ExponentialReconnector{
+		Min:         min,
+		Max:         max,
+		MaxAttempts: maxAttempts,
+	}
+}
+
+// NextDelay implements Reconnector.
+func (r *ExponentialReconnector) NextDelay(attempt int, previous time.Duration) (time.Duration, bool) {
+	if reconnectionExhausted(attempt, r.MaxAttempts) {
+		return 0, false
+	}
+	delay := r.Min
+	if previous > 0 {
+		if upper := previous * 3; upper > r.Min {
+			delay = r.Min + time.Duration(rand.Int63n(int64(upper-r.Min)))
+		}
+	}
+	if r.Max > 0 && delay > r.Max {
+		delay = r.Max
+	}
+	return delay, true
+}
+
+func reconnectionExhausted(attempt, maxAttempts int) bool {
+	return maxAttempts > 0 && attempt >= maxAttempts
+}
+
+func randomJitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(max)))
+}
+
+// reconnector returns the Reconnector of the config, or the one matching the
+// WPSReconnectionPolicy, nil when the reconnection is disabled.
+func (c *Config) reconnector() Reconnector {
+	if c.Reconnector != nil {
+		return c.Reconnector
+	}
+
+	// MaximumReconnectionRetries is -1 for no limit, and 0 gives up after the
+	// first failure like 1.
+	maxAttempts := c.MaximumReconnectionRetries
+	if maxAttempts == -1 {
+		maxAttempts = 0
+	} else if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+
+	switch c.WPSReconnectionPolicy {
+	case WPSLinearPolicy:
+		return NewFixedReconnector(reconnectionInterval*time.Second, maxAttempts)
+	case WPSExponentialPolicycy:
+		return NewExponentialReconnector(reconnectionMinExponentialBackoff*time.Second,
+			reconnectionMaxExponentialBackoff*time.Second, maxAttempts)
+	}
+	return nil
+}
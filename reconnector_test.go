@@ -0,0 +1,110 @@
+package webpubsub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFixedReconnector(t *testing.T) {
+	assert := assert.New(t)
+
+	r := NewFixedReconnector(2*time.Second, 3)
+	for attempt := 1; attempt < 3; attempt++ {
+		delay, ok := r.NextDelay(attempt, 2*time.Second)
+		assert.True(ok)
+		assert.Equal(2*time.Second, delay)
+	}
+	_, ok := r.NextDelay(3, 2*time.Second)
+	assert.False(ok)
+
+	r.Jitter = time.Second
+	for i := 0; i < 20; i++ {
+		delay, _ := r.NextDelay(1, 0)
+		assert.True(delay >= 2*time.Second && delay < 3*time.Second)
+	}
+}
+
+func TestLinearReconnector(t *testing.T) {
+	assert := assert.New(t)
+
+	r := NewLinearReconnector(time.Second, 3*time.Second, 0)
+	var delays []time.Duration
+	for attempt := 1; attempt <= 5; attempt++ {
+		delay, ok := r.NextDelay(attempt, 0)
+		assert.True(ok)
+		delays = append(delays, delay)
+	}
+	assert.Equal([]time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second, 3 * time.Second}, delays)
+}
+
+func TestExponentialReconnectorDecorrelatedJitter(t *testing.T) {
+	assert := assert.New(t)
+
+	r := NewExponentialReconnector(time.Second, 30*time.Second, 0)
+	delay, ok := r.NextDelay(1, 0)
+	assert.True(ok)
+	assert.Equal(time.Second, delay)
+
+	distinct := map[time.Duration]bool{}
+	for attempt := 2; attempt < 50; attempt++ {
+		previous := delay
+		delay, ok = r.NextDelay(attempt, previous)
+		assert.True(ok)
+		assert.True(delay >= time.Second, delay)
+		assert.True(delay <= 30*time.Second, delay)
+		assert.True(delay <= 3*previous, delay)
+		distinct[delay] = true
+	}
+	assert.True(len(distinct) > 1)
+
+	r.MaxAttempts = 2
+	_, ok = r.NextDelay(2, delay)
+	assert.False(ok)
+}
+
+func TestConfigReconnector(t *testing.T) {
+	assert := assert.New(t)
+
+	config := NewConfig(GenerateUUID())
+	assert.Nil(config.reconnector())
+
+	config.WPSReconnectionPolicy = WPSLinearPolicy
+	config.MaximumReconnectionRetries = -1
+	assert.Equal(&FixedReconnector{Interval: 10 * time.Second}, config.reconnector())
+
+	config.WPSReconnectionPolicy = WPSExponentialPolicycy
+	config.MaximumReconnectionRetries = 0
+	assert.Equal(&ExponentialReconnector{Min: time.Second, Max: 32 * time.Second, MaxAttempts: 1}, config.reconnector())
+
+	custom := NewLinearReconnector(time.Second, time.Minute, 5)
+	config.Reconnector = custom
+	assert.Equal(custom, config.reconnector())
+}
+
+func TestHeartbeatRetryUsesReconnector(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := NewWebPubSub(NewDemoConfig())
+	pn.Config.HeartbeatInterval = 100
+	pn.Config.Reconnector = NewLinearReconnector(40*time.Second, 0, 0)
+	m := pn.heartbeatManager
+	m.Lock()
+	m.hbRunning = true
+	m.hbTimer = time.NewTicker(100 * time.Second)
+	m.Unlock()
+	defer m.hbTimer.Stop()
+
+	m.scheduleRetry(true)
+	assert.Equal(40*time.Second, m.retryDelay)
+	m.scheduleRetry(true)
+	assert.Equal(80*time.Second, m.retryDelay)
+	m.scheduleRetry(true)
+	assert.Equal(100*time.Second, m.retryDelay)
+	assert.Equal(3, m.failedHeartbeats)
+
+	m.scheduleRetry(false)
+	assert.Equal(0, m.failedHeartbeats)
+	assert.Equal(time.Duration(0), m.retryDelay)
+}
@@ -192,6 +192,10 @@ func (o *removeAllPushChannelsForDeviceOpts) telemetryManager() *TelemetryManage
 	return o.webpubsub.telemetryManager
 }
 
+func (o *removeAllPushChannelsForDeviceOpts) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *removeAllPushChannelsForDeviceOpts) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
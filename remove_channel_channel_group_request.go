@@ -171,10 +171,18 @@ func (o *removeChannelOpts) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *removeChannelOpts) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *removeChannelOpts) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
 
+func (o *removeChannelOpts) tokenResources() (channels, groups, uuids []string) {
+	return nil, []string{o.ChannelGroup}, nil
+}
+
 // RemoveChannelFromChannelGroupResponse is the struct returned when the Execute function of RemoveChannelFromChannelGroup is called.
 type RemoveChannelFromChannelGroupResponse struct {
 }
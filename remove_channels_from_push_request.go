@@ -208,6 +208,10 @@ func (o *removeChannelsFromPushOpts) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *removeChannelsFromPushOpts) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *removeChannelsFromPushOpts) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
@@ -2,7 +2,6 @@ package webpubsub
 
 import (
 	"bytes"
-	"fmt"
 	"io"
 	"io/ioutil"
 	"mime/multipart"
@@ -67,34 +66,97 @@ func buildBody(opts endpointOpts, url *url.URL) (io.Reader, error) {
 
 	b, err := opts.buildBody()
 	if err != nil {
-		opts.config().Log.Println("WPSUnknownCategory", err, url)
+		endpointLogger(opts).Error("WPSUnknownCategory", "error", err, "url", url)
 		return nil, err
 	}
-	opts.config().Log.Println("BODY", string(b))
+	endpointLogger(opts).Debug("body", "body", string(b))
 
 	return bytes.NewReader(b), nil
 }
 
 func executeRequest(opts endpointOpts) ([]byte, StatusResponse, error) {
+	return traceRequest(opts, func() ([]byte, StatusResponse, int, error) {
+		val, status, retries, err := executeRequestWithRetries(opts)
+		if err != nil && refreshTokenOnAccessDenied(opts, status, err) {
+			endpointLogger(opts).Debug("retrying with the refreshed token", "operation", opts.operationType())
+			var r int
+			val, status, r, err = executeRequestWithRetries(opts)
+			retries += r + 1
+		}
+		return val, status, retries, err
+	})
+}
+
+// executeRequestWithRetries makes the request and retries it according to the
+// RetryPolicy, it returns the number of retries.
+func executeRequestWithRetries(opts endpointOpts) ([]byte, StatusResponse, int, error) {
+	policy := opts.config().RetryPolicy
+	if policy == nil || policy.excludes(opts.operationType()) {
+		val, status, err := executeRequestAttempt(opts, nil)
+		return val, status, 0, err
+	}
+
+	for attempt := 1; ; attempt++ {
+		var retryAfter time.Duration
+		val, status, err := executeRequestAttempt(opts, &retryAfter)
+		if err == nil || attempt >= policy.MaxAttempts || !policy.retryable(status, err) {
+			return val, status, attempt - 1, err
+		}
+
+		delay := policy.delay(attempt, retryAfter)
+		ctx := opts.context()
+		if ctx != nil {
+			if ctx.Err() != nil {
+				return val, status, attempt - 1, err
+			}
+			if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+				endpointLogger(opts).Debug("retry skipped, the delay exceeds the context deadline", "delay", delay)
+				return val, status, attempt - 1, err
+			}
+		}
+		endpointLogger(opts).Warn("attempt failed, retrying", "attempt", attempt, "operation", opts.operationType(), "delay", delay, "error", err)
+
+		if ctx != nil {
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return val, status, attempt - 1, err
+			}
+		} else {
+			time.Sleep(delay)
+		}
+	}
+}
+
+// executeRequestAttempt makes a single request, retryAfter is set to the
+// Retry-After of the response when not nil.
+func executeRequestAttempt(opts endpointOpts, retryAfter *time.Duration) ([]byte, StatusResponse, error) {
 	err := opts.validate()
 
 	if err != nil {
-		opts.config().Log.Println("WPSUnknownCategory", err)
+		endpointLogger(opts).Error("WPSUnknownCategory", "error", err)
 		return nil,
 			createStatus(WPSUnknownCategory, "", ResponseInfo{}, err),
 			err
 	}
 
+	if err := preflight(opts); err != nil {
+		endpointLogger(opts).Warn("WPSAccessDeniedCategory", "error", err)
+		return nil,
+			createStatus(WPSAccessDeniedCategory, "", ResponseInfo{}, err),
+			err
+	}
+
 	url, err := buildURL(opts)
 
 	if err != nil {
-		opts.config().Log.Println("WPSUnknownCategory", err)
+		endpointLogger(opts).Error("WPSUnknownCategory", "error", err)
 		return nil,
 			createStatus(WPSUnknownCategory, "", ResponseInfo{}, err),
 			err
 	}
 
-	opts.config().Log.Println(fmt.Sprintf("url:%s\nmethod:%s", url, opts.httpMethod()))
+	endpointLogger(opts).Debug("request", "url", url, "method", opts.httpMethod())
 
 	var req *http.Request
 
@@ -114,7 +176,7 @@ func executeRequest(opts endpointOpts) ([]byte, StatusResponse, error) {
 
 		req, err = newRequestForMultipartWriter("POST", url.RequestURI(), &body, w, opts.config().UseHTTP2)
 		if err != nil {
-			opts.config().Log.Println("POST ERROR : ", err)
+			endpointLogger(opts).Error("POST error", "error", err)
 			return nil, createStatus(WPSUnknownCategory, "", ResponseInfo{}, err), err
 		}
 
@@ -133,7 +195,7 @@ func executeRequest(opts endpointOpts) ([]byte, StatusResponse, error) {
 	}
 
 	if err != nil {
-		opts.config().Log.Println("WPSUnknownCategory", err, url)
+		endpointLogger(opts).Error("WPSUnknownCategory", "error", err, "url", url)
 		return nil,
 			createStatus(WPSUnknownCategory, "", ResponseInfo{}, err),
 			err
@@ -147,46 +209,28 @@ func executeRequest(opts endpointOpts) ([]byte, StatusResponse, error) {
 		req = setRequestContext(req, ctx)
 	}
 
-	client := opts.client()
-
 	startTimestamp := time.Now()
 
-	var res *http.Response
-	runRequestWorker := false
-
-	switch opts.operationType() {
-	case WPSPublishOperation, WPSAccessManagerGrant:
-		runRequestWorker = true
-	}
-
-	if runRequestWorker && opts.config().MaxWorkers > 0 {
-		j := make(chan *JobQResponse)
-		go addToJobQ(req, client, opts, j, ctx)
-		jr := <-j
-		close(j)
-		res = jr.Resp
-		err = jr.Error
-	} else {
-		res, err = client.Do(req)
+	send := func(r *WPSRequest) (*WPSResponse, error) {
+		return sendRequest(opts, r.HTTPRequest, r.Context, startTimestamp, retryAfter)
 	}
 
-	// Host lookup failed
-	if err != nil {
-		opts.config().Log.Println("err.Error()", err.Error())
-		e := pnerr.NewConnectionError("Failed to execute request", err)
-
-		opts.config().Log.Println("WPSUnknownCategory", e.Error(), url)
-		return nil,
-			createStatus(WPSUnknownCategory, "", ResponseInfo{}, e),
-			e
+	resp, err := chainMiddlewares(opts.middlewares(), send)(&WPSRequest{
+		Operation:   opts.operationType(),
+		Channels:    requestChannels(opts),
+		Context:     ctx,
+		HTTPRequest: req,
+	})
+	if resp == nil {
+		resp = &WPSResponse{}
+		if err != nil {
+			resp.Status = createStatus(WPSUnknownCategory, "", ResponseInfo{}, err)
+		}
 	}
-
-	val, status, err := parseResponse(res, opts)
-	// Already wrapped error
 	if err != nil {
-		opts.config().Log.Println("res.StatusCode, status, err.Error()", res.StatusCode, status, err.Error())
-		return nil, status, err
+		return nil, resp.Status, err
 	}
+	val := resp.Body
 
 	elapsedTime := time.Since(startTimestamp)
 
@@ -194,11 +238,14 @@ func executeRequest(opts endpointOpts) ([]byte, StatusResponse, error) {
 	manager.StoreLatency(elapsedTime.Seconds(), opts.operationType())
 
 	responseInfo := ResponseInfo{
-		StatusCode:       res.StatusCode,
-		OriginalResponse: res,
+		StatusCode:       resp.Status.StatusCode,
+		OriginalResponse: resp.HTTPResponse,
 		Operation:        opts.operationType(),
 		Origin:           url.Host,
 	}
+	if resp.HTTPResponse != nil {
+		responseInfo.StatusCode = resp.HTTPResponse.StatusCode
+	}
 
 	if url.Scheme == "https" {
 		responseInfo.TLSEnabled = true
@@ -213,13 +260,64 @@ func executeRequest(opts endpointOpts) ([]byte, StatusResponse, error) {
 	}
 
 	if opts.httpMethod() != "POSTFORM" {
-		opts.config().Log.Println("WPSUnknownCategory", string(val), responseInfo)
+		endpointLogger(opts).Debug("response parsed", "response", string(val), "statusCode", responseInfo.StatusCode)
 	}
-	status = createStatus(WPSUnknownCategory, string(val), responseInfo, nil)
+	status := createStatus(WPSUnknownCategory, string(val), responseInfo, nil)
 
 	return val, status, nil
 }
 
+// sendRequest is the innermost Handler of the middleware chain, it sends req
+// and parses the response.
+func sendRequest(opts endpointOpts, req *http.Request, ctx Context, startTimestamp time.Time, retryAfter *time.Duration) (*WPSResponse, error) {
+	client := opts.client()
+
+	var res *http.Response
+	var err error
+	runRequestWorker := false
+
+	switch opts.operationType() {
+	case WPSPublishOperation, WPSAccessManagerGrant:
+		runRequestWorker = true
+	}
+
+	if runRequestWorker && opts.config().MaxWorkers > 0 {
+		j := make(chan *JobQResponse)
+		go addToJobQ(req, client, opts, j, ctx)
+		jr := <-j
+		close(j)
+		res = jr.Resp
+		err = jr.Error
+	} else {
+		res, err = client.Do(req)
+	}
+
+	// Host lookup failed
+	if err != nil {
+		endpointMetrics(opts).RequestCompleted(opts.operationType(), time.Since(startTimestamp), 0, err)
+		endpointLogger(opts).Error("request error", "error", err)
+		e := pnerr.NewConnectionError("Failed to execute request", err)
+
+		endpointLogger(opts).Error("WPSUnknownCategory", "error", e, "url", req.URL)
+		return &WPSResponse{Status: createStatus(WPSUnknownCategory, "", ResponseInfo{}, e)}, e
+	}
+
+	if retryAfter != nil {
+		*retryAfter = parseRetryAfter(res.Header.Get("Retry-After"))
+	}
+
+	val, status, err := parseResponse(res, opts)
+	endpointMetrics(opts).RequestCompleted(opts.operationType(), time.Since(startTimestamp), res.StatusCode, err)
+	// Already wrapped error
+	if err != nil {
+		endpointLogger(opts).Warn("request failed", "statusCode", res.StatusCode, "status", status, "error", err)
+		return &WPSResponse{Status: status, HTTPResponse: res}, err
+	}
+
+	status.StatusCode = res.StatusCode
+	return &WPSResponse{Body: val, Status: status, HTTPResponse: res}, nil
+}
+
 func newRequestForMultipartWriter(method string, URL string, body io.Reader, writer *multipart.Writer, useHTTP2 bool) (*http.Request, error) {
 	req, err := http.NewRequest(method, URL, body)
 	if useHTTP2 {
@@ -281,22 +379,22 @@ func parseResponse(resp *http.Response, opts endpointOpts) ([]byte, StatusRespon
 		// Errors like 400, 403, 500
 		e := pnerr.NewServerError(resp.StatusCode, resp.Body)
 
-		opts.config().Log.Println(e.Error())
+		endpointLogger(opts).Error("response error", "error", e)
 
 		if resp.StatusCode == 408 {
-			opts.config().Log.Println("WPSTimeoutCategory: resp.StatusCode, resp.Body, resp.Request.URL", resp.StatusCode, resp.Body, resp.Request.URL)
+			endpointLogger(opts).Warn("WPSTimeoutCategory", "statusCode", resp.StatusCode, "url", resp.Request.URL)
 			status = createStatus(WPSTimeoutCategory, "", ResponseInfo{StatusCode: resp.StatusCode}, e)
 
 			return nil, status, e
 		}
 
 		if resp.StatusCode == 400 {
-			opts.config().Log.Println("WPSBadRequestCategory: resp.StatusCode, resp.Body, resp.Request.URL", resp.StatusCode, resp.Body, resp.Request.URL)
+			endpointLogger(opts).Warn("WPSBadRequestCategory", "statusCode", resp.StatusCode, "url", resp.Request.URL)
 			status = createStatus(WPSBadRequestCategory, "", ResponseInfo{StatusCode: resp.StatusCode}, e)
 
 			return nil, status, e
 		}
-		opts.config().Log.Println("WPSUnknownCategory: resp.StatusCode, resp.Body, resp.Request.URL", resp.StatusCode, resp.Body, resp.Request.URL)
+		endpointLogger(opts).Warn("WPSUnknownCategory", "statusCode", resp.StatusCode, "url", resp.Request.URL)
 		status = createStatus(WPSUnknownCategory, "", ResponseInfo{StatusCode: resp.StatusCode, Operation: opts.operationType()}, e)
 
 		return nil, status, e
@@ -305,14 +403,13 @@ func parseResponse(resp *http.Response, opts endpointOpts) ([]byte, StatusRespon
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		e := pnerr.NewResponseParsingError("Error reading response body", resp.Body, err)
-		opts.config().Log.Println("Read All error: resp.Body, resp.Request.URL, e", resp.StatusCode, resp.Body, resp.Request.URL, e)
+		endpointLogger(opts).Error("read body error", "statusCode", resp.StatusCode, "url", resp.Request.URL, "error", e)
 
 		return nil, status, e
 	}
 
-	opts.config().Log.Println("200 OK: resp.StatusCode, resp.Status, resp.Body, resp.Request.URL, string(body)", resp.StatusCode, resp.Status, resp.Body, resp.Request.URL, string(body))
+	endpointLogger(opts).Debug("response", "statusCode", resp.StatusCode, "url", resp.Request.URL, "body", string(body))
 
-	//opts.config().Log.Println("200 OK: resp.StatusCode, resp.Status, resp.Request.URL", resp.StatusCode, resp.Status, resp.Request.URL)
 	return body, status, nil
 }
 
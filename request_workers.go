@@ -1,6 +1,9 @@
 package webpubsub
 
-import "net/http"
+import (
+	"net/http"
+	"sync/atomic"
+)
 
 type nonSubMsgType int
 
@@ -28,6 +31,7 @@ type RequestWorkers struct {
 	WorkersChannel chan chan *JobQItem
 	MaxWorkers     int
 	Sem            chan bool
+	busy           int32
 }
 
 // Worker is the type to store the worker info
@@ -36,6 +40,7 @@ type Worker struct {
 	JobChannel     chan *JobQItem
 	ctx            Context
 	id             int
+	pool           *RequestWorkers
 }
 
 func newRequestWorkers(workers chan chan *JobQItem, id int, ctx Context) Worker {
@@ -47,6 +52,12 @@ func newRequestWorkers(workers chan chan *JobQItem, id int, ctx Context) Worker
 	}
 }
 
+// reportBusy adds delta to the busy workers and reports them to the Metrics.
+func (p *RequestWorkers) reportBusy(webpubsub *WebPubSub, delta int32) {
+	busy := atomic.AddInt32(&p.busy, delta)
+	webpubsub.Config.metrics().RequestWorkersBusy(int(busy), p.MaxWorkers)
+}
+
 // Process runs a goroutine for the worker
 func (pw Worker) Process(webpubsub *WebPubSub) {
 	go func() {
@@ -56,19 +67,25 @@ func (pw Worker) Process(webpubsub *WebPubSub) {
 			case pw.WorkersChannel <- pw.JobChannel:
 				job := <-pw.JobChannel
 				if job != nil {
+					if pw.pool != nil {
+						pw.pool.reportBusy(webpubsub, 1)
+					}
 					res, err := job.Client.Do(job.Req)
+					if pw.pool != nil {
+						pw.pool.reportBusy(webpubsub, -1)
+					}
 					jqr := &JobQResponse{
 						Error: err,
 						Resp:  res,
 					}
 					job.JobResponse <- jqr
-					webpubsub.Config.Log.Println("Request sent using worker id ", pw.id)
+					webpubsub.Config.logger(WPSRequestSubsystem).Debug("request sent", "worker", pw.id)
 				}
 			case <-pw.ctx.Done():
-				webpubsub.Config.Log.Println("Exiting Worker Process by worker ctx, id ", pw.id)
+				webpubsub.Config.logger(WPSRequestSubsystem).Debug("exiting worker process by worker ctx", "worker", pw.id)
 				break ProcessLabel
 			case <-webpubsub.ctx.Done():
-				webpubsub.Config.Log.Println("Exiting Worker Process by WPS ctx, id ", pw.id)
+				webpubsub.Config.logger(WPSRequestSubsystem).Debug("exiting worker process by WPS ctx", "worker", pw.id)
 				break ProcessLabel
 			}
 		}
@@ -77,11 +94,12 @@ func (pw Worker) Process(webpubsub *WebPubSub) {
 
 // Start starts the workers
 func (p *RequestWorkers) Start(webpubsub *WebPubSub, ctx Context) {
-	webpubsub.Config.Log.Println("Start: Running with workers ", p.MaxWorkers)
+	webpubsub.Config.logger(WPSRequestSubsystem).Debug("Start: running", "workers", p.MaxWorkers)
 	p.Workers = make([]Worker, p.MaxWorkers)
 	for i := 0; i < p.MaxWorkers; i++ {
-		webpubsub.Config.Log.Println("Start: StartNonSubWorker ", i)
+		webpubsub.Config.logger(WPSRequestSubsystem).Debug("Start: StartNonSubWorker", "worker", i)
 		worker := newRequestWorkers(p.WorkersChannel, i, ctx)
+		worker.pool = p
 		worker.Process(webpubsub)
 		p.Workers[i] = worker
 	}
@@ -91,13 +109,13 @@ func (p *RequestWorkers) Start(webpubsub *WebPubSub, ctx Context) {
 // ReadQueue reads the queue and passes on the job to the workers
 func (p *RequestWorkers) ReadQueue(webpubsub *WebPubSub) {
 	for job := range webpubsub.jobQueue {
-		webpubsub.Config.Log.Println("ReadQueue: Got job for channel ", job.Req)
+		webpubsub.Config.logger(WPSRequestSubsystem).Debug("ReadQueue: got job", "url", job.Req.URL)
 		go func(job *JobQItem) {
 			jobChannel := <-p.WorkersChannel
 			jobChannel <- job
 		}(job)
 	}
-	webpubsub.Config.Log.Println("ReadQueue: Exit")
+	webpubsub.Config.logger(WPSRequestSubsystem).Debug("ReadQueue: Exit")
 }
 
 // Close closes the workers
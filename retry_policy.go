@@ -0,0 +1,152 @@
+package webpubsub
+
+import (
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/webpubsub/sdk-go/v7/pnerr"
+)
+
+const (
+	defaultRetryMaxAttempts = 3
+	defaultRetryDelay       = 2 * time.Second
+	defaultRetryMaxDelay    = 150 * time.Second
+	defaultRetryJitter      = 1 * time.Second
+)
+
+// RetryPolicy configures the automatic retry of the failed non-subscribe
+// requests. Set it in Config.RetryPolicy, a nil policy disables the retries.
+type RetryPolicy struct {
+	Policy               ReconnectionPolicy // WPSLinearPolicy waits Delay between the attempts, WPSExponentialPolicycy doubles it after each attempt.
+	MaxAttempts          int                // Number of attempts including the first one.
+	Delay                time.Duration      // Delay before the first retry.
+	MaxDelay             time.Duration      // Upper bound of the delay between the attempts.
+	Jitter               time.Duration      // Upper bound of the random time added to each delay.
+	RetryableStatusCodes []int              // HTTP status codes which are retried, network errors are always retried.
+	ExcludedOperations   []OperationType    // Operations which are never retried, in addition to subscribe and heartbeat.
+	// RetryPublish retries the publishes and the signals too. The delivery is
+	// then at least once: a message is received twice when it was delivered
+	// but its response was lost.
+	RetryPublish bool
+}
+
+// NewLinearRetryPolicy creates a RetryPolicy which waits the same delay
+// between the attempts.
+func NewLinearRetryPolicy(delay time.Duration, maxAttempts int) *RetryPolicy {
+	policy := newRetryPolicy(WPSLinearPolicy, maxAttempts)
+	policy.Delay = delay
+	policy.MaxDelay = delay
+	return policy
+}
+
+// NewExponentialRetryPolicy creates a RetryPolicy which doubles the delay
+// after each attempt, from minDelay up to maxDelay.
+func NewExponentialRetryPolicy(minDelay, maxDelay time.Duration, maxAttempts int) *RetryPolicy {
+	policy := newRetryPolicy(WPSExponentialPolicycy, maxAttempts)
+	policy.Delay = minDelay
+	policy.MaxDelay = maxDelay
+	return policy
+}
+
+func newRetryPolicy(policy ReconnectionPolicy, maxAttempts int) *RetryPolicy {
+	if maxAttempts <= 0 {
+		maxAttempts = defaultRetryMaxAttempts
+	}
+	return &RetryPolicy{
+		Policy:      policy,
+		MaxAttempts: maxAttempts,
+		Delay:       defaultRetryDelay,
+		MaxDelay:    defaultRetryMaxDelay,
+		Jitter:      defaultRetryJitter,
+		RetryableStatusCodes: []int{
+			http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+		// the file upload consumes the file and PublishFileMessage has its
+		// own retries.
+		ExcludedOperations: []OperationType{
+			WPSSendFileToS3Operation,
+			WPSPublishFileMessageOperation,
+		},
+	}
+}
+
+// excludes returns true when operation is never retried. Subscribe and
+// heartbeat have their own loops, the publishes and signals are retried only
+// with RetryPublish.
+func (p *RetryPolicy) excludes(operation OperationType) bool {
+	switch operation {
+	case WPSSubscribeOperation, WPSHeartBeatOperation:
+		return true
+	case WPSPublishOperation, WPSFireOperation, WPSSignalOperation:
+		if !p.RetryPublish {
+			return true
+		}
+	}
+	for _, o := range p.ExcludedOperations {
+		if o == operation {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *RetryPolicy) retryable(status StatusResponse, err error) bool {
+	if isConnectionError(err) {
+		return true
+	}
+	for _, code := range p.RetryableStatusCodes {
+		if code == status.StatusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// delay returns the time to wait after the failed attempt, the Retry-After
+// of the server wins when it is longer.
+func (p *RetryPolicy) delay(attempt int, retryAfter time.Duration) time.Duration {
+	delay := p.Delay
+	if p.Policy == WPSExponentialPolicycy {
+		for i := 1; i < attempt && (p.MaxDelay <= 0 || delay < p.MaxDelay); i++ {
+			delay *= 2
+		}
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(p.Jitter)))
+	}
+	if retryAfter > delay {
+		delay = retryAfter
+	}
+	return delay
+}
+
+// parseRetryAfter parses the Retry-After header, in seconds or as an HTTP date.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if d := time.Until(date); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+func isConnectionError(err error) bool {
+	var connectionError *pnerr.ConnectionError
+	return errors.As(err, &connectionError)
+}
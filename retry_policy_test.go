@@ -0,0 +1,207 @@
+package webpubsub
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type sequenceResponse struct {
+	statusCode int
+	body       string
+	header     http.Header
+	err        error
+}
+
+// sequenceTransport returns the responses in order, repeating the last one.
+type sequenceTransport struct {
+	sync.Mutex
+	responses []sequenceResponse
+	calls     int
+}
+
+func (s *sequenceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	s.Lock()
+	r := s.responses[len(s.responses)-1]
+	if s.calls < len(s.responses) {
+		r = s.responses[s.calls]
+	}
+	s.calls++
+	s.Unlock()
+
+	if r.err != nil {
+		return nil, r.err
+	}
+	return &http.Response{
+		StatusCode: r.statusCode,
+		Header:     r.header,
+		Body:       ioutil.NopCloser(bytes.NewBufferString(r.body)),
+		Request:    req,
+	}, nil
+}
+
+func (s *sequenceTransport) count() int {
+	s.Lock()
+	defer s.Unlock()
+	return s.calls
+}
+
+func noJitter(policy *RetryPolicy) *RetryPolicy {
+	policy.Jitter = 0
+	return policy
+}
+
+func TestRetryPolicyRetriesServerErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	transport := &sequenceTransport{responses: []sequenceResponse{
+		{statusCode: 503, body: "unavailable"},
+		{err: errors.New("connection reset")},
+		{statusCode: 200, body: "[15078947309567840]"},
+	}}
+	pn := newTestWebPubSub(transport)
+	pn.Config.RetryPolicy = noJitter(NewLinearRetryPolicy(time.Millisecond, 3))
+
+	res, _, err := pn.Time().Execute()
+	assert.Nil(err)
+	assert.Equal(int64(15078947309567840), res.Timetoken)
+	assert.Equal(3, transport.count())
+}
+
+func TestRetryPolicyStopsAtMaxAttempts(t *testing.T) {
+	assert := assert.New(t)
+
+	transport := &sequenceTransport{responses: []sequenceResponse{
+		{statusCode: 500, body: "error"},
+	}}
+	pn := newTestWebPubSub(transport)
+	pn.Config.RetryPolicy = noJitter(NewLinearRetryPolicy(time.Millisecond, 2))
+
+	_, status, err := pn.Time().Execute()
+	assert.NotNil(err)
+	assert.Equal(500, status.StatusCode)
+	assert.Equal(2, transport.count())
+}
+
+func TestRetryPolicyDoesNotRetryClientErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	transport := &sequenceTransport{responses: []sequenceResponse{
+		{statusCode: 403, body: "forbidden"},
+	}}
+	pn := newTestWebPubSub(transport)
+	pn.Config.RetryPolicy = noJitter(NewLinearRetryPolicy(time.Millisecond, 3))
+
+	_, _, err := pn.Time().Execute()
+	assert.NotNil(err)
+	assert.Equal(1, transport.count())
+}
+
+func TestRetryPolicyExcludedOperation(t *testing.T) {
+	assert := assert.New(t)
+
+	policy := noJitter(NewLinearRetryPolicy(time.Millisecond, 3))
+	policy.ExcludedOperations = []OperationType{WPSTimeOperation}
+	transport := &sequenceTransport{responses: []sequenceResponse{
+		{statusCode: 503, body: "unavailable"},
+	}}
+	pn := newTestWebPubSub(transport)
+	pn.Config.RetryPolicy = policy
+
+	_, _, err := pn.Time().Execute()
+	assert.NotNil(err)
+	assert.Equal(1, transport.count())
+}
+
+func TestRetryPolicyAlwaysExcludes(t *testing.T) {
+	assert := assert.New(t)
+
+	policy := &RetryPolicy{}
+	assert.True(policy.excludes(WPSSubscribeOperation))
+	assert.True(policy.excludes(WPSHeartBeatOperation))
+	assert.True(policy.excludes(WPSPublishOperation))
+	assert.True(policy.excludes(WPSSignalOperation))
+	assert.False(policy.excludes(WPSTimeOperation))
+
+	policy.RetryPublish = true
+	assert.False(policy.excludes(WPSPublishOperation))
+	assert.True(policy.excludes(WPSSubscribeOperation))
+}
+
+func TestRetryPolicyRetryPublish(t *testing.T) {
+	assert := assert.New(t)
+
+	transport := &sequenceTransport{responses: []sequenceResponse{
+		{statusCode: 503, body: "unavailable"},
+		{statusCode: 503, body: "unavailable"},
+		{statusCode: 200, body: `[1,"Sent","15000000000000000"]`},
+	}}
+	pn := newTestWebPubSub(transport)
+	pn.Config.RetryPolicy = noJitter(NewLinearRetryPolicy(time.Millisecond, 3))
+
+	_, _, err := pn.Publish().Channel("ch").Message("hi").Execute()
+	assert.NotNil(err)
+	assert.Equal(1, transport.count())
+
+	pn.Config.RetryPolicy.RetryPublish = true
+	_, _, err = pn.Publish().Channel("ch").Message("hi").Execute()
+	assert.Nil(err)
+	assert.Equal(3, transport.count())
+}
+
+func TestRetryPolicyContextDeadline(t *testing.T) {
+	assert := assert.New(t)
+
+	transport := &sequenceTransport{responses: []sequenceResponse{
+		{statusCode: 503, body: "unavailable"},
+	}}
+	pn := newTestWebPubSub(transport)
+	pn.Config.RetryPolicy = noJitter(NewLinearRetryPolicy(time.Second, 3))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, _, err := pn.TimeWithContext(ctx).Execute()
+	assert.NotNil(err)
+	assert.Equal(1, transport.count())
+	assert.True(time.Since(start) < time.Second)
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	assert := assert.New(t)
+
+	linear := noJitter(NewLinearRetryPolicy(2*time.Second, 5))
+	assert.Equal(2*time.Second, linear.delay(1, 0))
+	assert.Equal(2*time.Second, linear.delay(4, 0))
+	assert.Equal(5*time.Second, linear.delay(1, 5*time.Second))
+
+	exponential := noJitter(NewExponentialRetryPolicy(time.Second, 5*time.Second, 5))
+	assert.Equal(time.Second, exponential.delay(1, 0))
+	assert.Equal(2*time.Second, exponential.delay(2, 0))
+	assert.Equal(4*time.Second, exponential.delay(3, 0))
+	assert.Equal(5*time.Second, exponential.delay(4, 0))
+
+	jitter := NewLinearRetryPolicy(time.Second, 5)
+	jitter.Jitter = 100 * time.Millisecond
+	d := jitter.delay(1, 0)
+	assert.True(d >= time.Second && d < 1100*time.Millisecond)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(3*time.Second, parseRetryAfter("3"))
+	assert.Equal(time.Duration(0), parseRetryAfter(""))
+	assert.Equal(time.Duration(0), parseRetryAfter("soon"))
+
+	d := parseRetryAfter(time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat))
+	assert.True(d > 8*time.Second && d <= 10*time.Second)
+}
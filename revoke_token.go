@@ -153,6 +153,10 @@ func (o *revokeTokenOpts) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *revokeTokenOpts) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *revokeTokenOpts) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
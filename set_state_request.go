@@ -205,10 +205,22 @@ func (o *setStateOpts) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *setStateOpts) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *setStateOpts) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
 
+func (o *setStateOpts) tokenResources() (channels, groups, uuids []string) {
+	uuid := o.UUID
+	if uuid == "" {
+		uuid = o.webpubsub.Config.UUID
+	}
+	return o.Channels, o.ChannelGroups, []string{uuid}
+}
+
 func newSetStateResponse(jsonBytes []byte, status StatusResponse) (
 	*SetStateResponse, StatusResponse, error) {
 	resp := &SetStateResponse{}
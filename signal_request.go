@@ -91,6 +91,18 @@ func (b *signalBuilder) Execute() (*SignalResponse, StatusResponse, error) {
 	return newSignalResponse(rawJSON, b.opts, status)
 }
 
+// Enqueue adds the Signal request to the offline publish queue and returns
+// the id of the queued item.
+func (b *signalBuilder) Enqueue() (string, error) {
+	if err := b.opts.validate(); err != nil {
+		return "", err
+	}
+	item := newQueuedPublish(WPSSignalOperation, b.opts.Channel, b.opts.Message)
+	item.UsePost = b.opts.UsePost
+
+	return item.ID, b.opts.webpubsub.publishQueueManager.enqueue(item)
+}
+
 type signalOpts struct {
 	webpubsub  *WebPubSub
 	Message    interface{}
@@ -137,7 +149,7 @@ func (o *signalOpts) buildPath() (string, error) {
 	var msg string
 	jsonEncBytes, errEnc := json.Marshal(o.Message)
 	if errEnc != nil {
-		o.webpubsub.Config.Log.Printf("ERROR: Publish error: %s\n", errEnc.Error())
+		o.webpubsub.Config.logger(WPSRequestSubsystem).Error("publish error", "error", errEnc)
 		return "", errEnc
 	}
 	msg = string(jsonEncBytes)
@@ -166,7 +178,7 @@ func (o *signalOpts) buildBody() ([]byte, error) {
 	if o.UsePost {
 		jsonEncBytes, errEnc := json.Marshal(o.Message)
 		if errEnc != nil {
-			o.webpubsub.Config.Log.Printf("ERROR: Signal error: %s\n", errEnc.Error())
+			o.webpubsub.Config.logger(WPSRequestSubsystem).Error("signal error", "error", errEnc)
 			return []byte{}, errEnc
 		}
 		return jsonEncBytes, nil
@@ -205,10 +217,18 @@ func (o *signalOpts) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *signalOpts) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *signalOpts) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
 
+func (o *signalOpts) tokenResources() (channels, groups, uuids []string) {
+	return []string{o.Channel}, nil, nil
+}
+
 // SignalResponse is the response to Signal request.
 type SignalResponse struct {
 	Timestamp int64
@@ -14,6 +14,15 @@ type StateManager struct {
 	groups           map[string]*SubscriptionItem
 	presenceChannels map[string]*SubscriptionItem
 	presenceGroups   map[string]*SubscriptionItem
+
+	// reference counts of the Subscription handles, keyed by name. Presence
+	// references are stored with the -pnpres suffix.
+	channelRefs map[string]int
+	groupRefs   map[string]int
+	// names subscribed with Subscribe, which hold one reference until they
+	// are unsubscribed with Unsubscribe.
+	globalChannels map[string]bool
+	globalGroups   map[string]bool
 }
 
 // SubscriptionItem is used to store the subscription item's properties.
@@ -28,6 +37,10 @@ func newStateManager() *StateManager {
 		presenceChannels: make(map[string]*SubscriptionItem),
 		groups:           make(map[string]*SubscriptionItem),
 		presenceGroups:   make(map[string]*SubscriptionItem),
+		channelRefs:      make(map[string]int),
+		groupRefs:        make(map[string]int),
+		globalChannels:   make(map[string]bool),
+		globalGroups:     make(map[string]bool),
 	}
 }
 
@@ -68,6 +81,10 @@ func (m *StateManager) adaptSubscribeOperation(
 	m.Lock()
 
 	for _, ch := range subscribeOperation.Channels {
+		holdGlobal(m.globalChannels, m.channelRefs, ch)
+		if subscribeOperation.PresenceEnabled && !strings.Contains(ch, "-pnpres") {
+			holdGlobal(m.globalChannels, m.channelRefs, ch+"-pnpres")
+		}
 		if strings.Contains(ch, "-pnpres") {
 			if len(subscribeOperation.State) > 0 {
 				m.presenceChannels[ch] = newSubscriptionItemWithState(ch, subscribeOperation.State)
@@ -89,6 +106,10 @@ func (m *StateManager) adaptSubscribeOperation(
 	}
 
 	for _, cg := range subscribeOperation.ChannelGroups {
+		holdGlobal(m.globalGroups, m.groupRefs, cg)
+		if subscribeOperation.PresenceEnabled && !strings.Contains(cg, "-pnpres") {
+			holdGlobal(m.globalGroups, m.groupRefs, cg+"-pnpres")
+		}
 		if strings.Contains(cg, "-pnpres") {
 			if len(subscribeOperation.State) > 0 {
 				m.presenceGroups[cg] = newSubscriptionItemWithState(cg, subscribeOperation.State)
@@ -139,6 +160,8 @@ func (m *StateManager) adaptUnsubscribeOperation(unsubscribeOperation *Unsubscri
 	m.Lock()
 
 	for _, ch := range unsubscribeOperation.Channels {
+		delete(m.channelRefs, ch)
+		delete(m.globalChannels, ch)
 		if strings.Contains(ch, "-pnpres") {
 			delete(m.presenceChannels, strings.Replace(ch, "-pnpres", "", -1))
 		} else {
@@ -147,6 +170,8 @@ func (m *StateManager) adaptUnsubscribeOperation(unsubscribeOperation *Unsubscri
 	}
 
 	for _, cg := range unsubscribeOperation.ChannelGroups {
+		delete(m.groupRefs, cg)
+		delete(m.globalGroups, cg)
 		if strings.Contains(cg, "-pnpres") {
 			delete(m.presenceGroups, strings.Replace(cg, "-pnpres", "", -1))
 		} else {
@@ -157,6 +182,114 @@ func (m *StateManager) adaptUnsubscribeOperation(unsubscribeOperation *Unsubscri
 	m.Unlock()
 }
 
+// retainSubscription increments the reference counts of the channels and
+// groups and adds the ones which are not subscribed yet.
+// Returns true when the subscribed channel mix changed.
+func (m *StateManager) retainSubscription(channels, groups []string, withPresence bool) bool {
+	m.Lock()
+	defer m.Unlock()
+
+	changed := retainItems(m.channels, m.channelRefs, channels)
+	if withPresence {
+		changed = retainPresenceItems(m.presenceChannels, m.channelRefs, channels) || changed
+	}
+	changed = retainItems(m.groups, m.groupRefs, groups) || changed
+	if withPresence {
+		changed = retainPresenceItems(m.presenceGroups, m.groupRefs, groups) || changed
+	}
+
+	return changed
+}
+
+// releaseSubscription decrements the reference counts of the channels and
+// groups and returns the ones no longer referenced, presence entries carry the
+// -pnpres suffix so that they can be passed to an UnsubscribeOperation.
+func (m *StateManager) releaseSubscription(channels, groups []string, withPresence bool) ([]string, []string) {
+	m.Lock()
+	defer m.Unlock()
+
+	releasedChannels := releaseRefs(m.channelRefs, channels, "")
+	releasedGroups := releaseRefs(m.groupRefs, groups, "")
+	if withPresence {
+		releasedChannels = append(releasedChannels, releaseRefs(m.channelRefs, channels, "-pnpres")...)
+		releasedGroups = append(releasedGroups, releaseRefs(m.groupRefs, groups, "-pnpres")...)
+	}
+
+	return releasedChannels, releasedGroups
+}
+
+// releaseGlobal releases the references held by Subscribe on the channels
+// and groups and returns the ones no longer referenced, which include the
+// ones never subscribed.
+func (m *StateManager) releaseGlobal(channels, groups []string) ([]string, []string) {
+	m.Lock()
+	defer m.Unlock()
+
+	return releaseGlobalRefs(m.globalChannels, m.channelRefs, channels),
+		releaseGlobalRefs(m.globalGroups, m.groupRefs, groups)
+}
+
+func holdGlobal(global map[string]bool, refs map[string]int, name string) {
+	if !global[name] {
+		global[name] = true
+		refs[name]++
+	}
+}
+
+func releaseGlobalRefs(global map[string]bool, refs map[string]int, names []string) []string {
+	released := []string{}
+	for _, name := range names {
+		if global[name] {
+			delete(global, name)
+			releaseRefs(refs, []string{name}, "")
+		}
+		if _, ok := refs[name]; !ok {
+			released = append(released, name)
+		}
+	}
+	return released
+}
+
+func retainItems(items map[string]*SubscriptionItem, refs map[string]int, names []string) bool {
+	changed := false
+	for _, name := range names {
+		refs[name]++
+		if _, ok := items[name]; !ok {
+			items[name] = newSubscriptionItem(name)
+			changed = true
+		}
+	}
+	return changed
+}
+
+func retainPresenceItems(items map[string]*SubscriptionItem, refs map[string]int, names []string) bool {
+	changed := false
+	for _, name := range names {
+		refs[fmt.Sprintf("%s-pnpres", name)]++
+		if _, ok := items[name]; !ok {
+			items[name] = newSubscriptionItem(name)
+			changed = true
+		}
+	}
+	return changed
+}
+
+func releaseRefs(refs map[string]int, names []string, suffix string) []string {
+	released := []string{}
+	for _, name := range names {
+		key := name + suffix
+		if count, ok := refs[key]; ok {
+			if count <= 1 {
+				delete(refs, key)
+				released = append(released, key)
+			} else {
+				refs[key] = count - 1
+			}
+		}
+	}
+	return released
+}
+
 func (m *StateManager) createStatePayload() map[string]interface{} {
 	m.RLock()
 	defer m.RUnlock()
@@ -213,6 +213,14 @@ func (o *subscribeOpts) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *subscribeOpts) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *subscribeOpts) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
+
+func (o *subscribeOpts) tokenResources() (channels, groups, uuids []string) {
+	return o.Channels, o.ChannelGroups, nil
+}
@@ -0,0 +1,161 @@
+package webpubsub
+
+import (
+	"strings"
+	"sync"
+)
+
+// SubscriptionOptions is used to configure a Subscription.
+type SubscriptionOptions struct {
+	ReceivePresenceEvents bool  // Subscribe to the presence channels as well.
+	Timetoken             int64 // Timetoken to start fetching the messages from.
+}
+
+// ChannelEntity represents a single channel, use it to create Subscriptions.
+type ChannelEntity struct {
+	webpubsub *WebPubSub
+	Name      string
+}
+
+// ChannelGroupEntity represents a single channel group, use it to create Subscriptions.
+type ChannelGroupEntity struct {
+	webpubsub *WebPubSub
+	Name      string
+}
+
+// Channel returns the entity of the channel.
+func (pn *WebPubSub) Channel(name string) *ChannelEntity {
+	return &ChannelEntity{
+		webpubsub: pn,
+		Name:      name,
+	}
+}
+
+// ChannelGroup returns the entity of the channel group.
+func (pn *WebPubSub) ChannelGroup(name string) *ChannelGroupEntity {
+	return &ChannelGroupEntity{
+		webpubsub: pn,
+		Name:      name,
+	}
+}
+
+// Subscription creates a new Subscription handle for the channel.
+func (c *ChannelEntity) Subscription(options SubscriptionOptions) *Subscription {
+	return newSubscription(c.webpubsub, []string{c.Name}, []string{}, options)
+}
+
+// Subscription creates a new Subscription handle for the channel group.
+func (c *ChannelGroupEntity) Subscription(options SubscriptionOptions) *Subscription {
+	return newSubscription(c.webpubsub, []string{}, []string{c.Name}, options)
+}
+
+// Subscription is a handle to the subscription of a channel or a channel
+// group with its own listeners. The listeners of a Subscription only receive
+// the events of its channels or channel groups.
+// The subscribed channels are reference counted, so unsubscribing a
+// Subscription only leaves the channels which are not used by another active
+// Subscription or by Subscribe, which holds one reference until Unsubscribe.
+type Subscription struct {
+	sync.RWMutex
+
+	webpubsub       *WebPubSub
+	channels        []string
+	channelGroups   []string
+	options         SubscriptionOptions
+	listenerManager *ListenerManager
+	subscribed      bool
+}
+
+func newSubscription(webpubsub *WebPubSub, channels, channelGroups []string, options SubscriptionOptions) *Subscription {
+	listenerManager := newListenerManager(webpubsub.ctx, webpubsub)
+	// the dropped events are reported with the ones of the client.
+	listenerManager.dropped = webpubsub.subscriptionManager.listenerManager.dropped
+	return &Subscription{
+		webpubsub:       webpubsub,
+		channels:        channels,
+		channelGroups:   channelGroups,
+		options:         options,
+		listenerManager: listenerManager,
+	}
+}
+
+// Channels returns a copy of the channels of the Subscription.
+func (s *Subscription) Channels() []string {
+	return append([]string{}, s.channels...)
+}
+
+// ChannelGroups returns a copy of the channel groups of the Subscription.
+func (s *Subscription) ChannelGroups() []string {
+	return append([]string{}, s.channelGroups...)
+}
+
+// AddListener adds a listener which receives the events of the Subscription.
+func (s *Subscription) AddListener(listener EventListener) {
+	s.listenerManager.addListener(listener)
+}
+
+// RemoveListener removes the listener from the Subscription.
+func (s *Subscription) RemoveListener(listener EventListener) {
+	s.listenerManager.removeListener(listener)
+}
+
+// Subscribe starts receiving the events of the Subscription.
+// Calling Subscribe on an active Subscription has no effect.
+func (s *Subscription) Subscribe() {
+	s.Lock()
+	if s.subscribed {
+		s.Unlock()
+		return
+	}
+	s.subscribed = true
+	s.Unlock()
+
+	s.listenerManager.openQueues()
+	s.webpubsub.subscriptionManager.adaptSubscription(s)
+}
+
+// Unsubscribe stops receiving the events of the Subscription and the
+// goroutines delivering them to its listeners, which are kept for the next
+// Subscribe. The channels and channel groups are left only when no other
+// Subscription or Subscribe call uses them.
+func (s *Subscription) Unsubscribe() {
+	s.Lock()
+	if !s.subscribed {
+		s.Unlock()
+		return
+	}
+	s.subscribed = false
+	s.Unlock()
+
+	s.webpubsub.subscriptionManager.releaseSubscription(s)
+	s.listenerManager.closeQueues()
+}
+
+// IsSubscribed returns true between Subscribe and Unsubscribe calls.
+func (s *Subscription) IsSubscribed() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.subscribed
+}
+
+func (s *Subscription) matches(channel, subscriptionMatch string, presence bool) bool {
+	if presence && !s.options.ReceivePresenceEvents {
+		return false
+	}
+	for _, ch := range s.channels {
+		if ch == channel || (subscriptionMatch != "" && ch == subscriptionMatch) {
+			return true
+		}
+		if strings.HasSuffix(ch, ".*") && strings.HasPrefix(channel, strings.TrimSuffix(ch, "*")) {
+			return true
+		}
+	}
+	if subscriptionMatch != "" {
+		for _, cg := range s.channelGroups {
+			if cg == subscriptionMatch {
+				return true
+			}
+		}
+	}
+	return false
+}
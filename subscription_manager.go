@@ -3,15 +3,12 @@ package webpubsub
 import (
 	"encoding/json"
 	"errors"
-	"log"
 	"net/http"
 	"reflect"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
-
-	"github.com/webpubsub/sdk-go/v7/utils"
 )
 
 // SubscriptionManager Events:
@@ -25,6 +22,21 @@ import (
 // to subscribe
 // - WPSUnsubscribeOperation - after leave request was fulfilled and server is
 // notified about unsubscibed items
+// Connection state:
+// The connection moves between the ConnectionState values Disconnected,
+// Connecting, Connected, Reconnecting, DisconnectedWithError and Failed. Each
+// transition is announced by exactly one status, carrying the
+// ConnectionStateChange:
+// - ConnectingCategory - the channel mix changed, waiting for the first response
+// - ConnectedCategory - the first response of the subscribe loop was received
+// - DisconnectedCategory - nothing left to subscribe, or the network was lost
+// (Reconnecting)
+// - ReconnectedCategory - the network is back after Reconnecting
+// - AccessDenied, BadRequest, NoStubMatched and UnknownCategory - the loop
+// stopped on an error (DisconnectedWithError)
+// On AccessDenied the loop first restarts once with the token returned by the
+// Config.TokenProvider, when set.
+// - ReconnectionAttemptsExhausted - the reconnection gave up (Failed)
 // Announcement:
 // Status, Message and Presence announcement happens in a distinct goroutine.
 // It doesn't block subscribe loop.
@@ -59,13 +71,24 @@ type SubscriptionManager struct {
 
 	region int8
 
-	subscriptionStateAnnounced   bool
 	heartbeatStopCalled          bool
 	exitSubscriptionManagerMutex sync.RWMutex
 	exitSubscriptionManager      chan bool
 	queryParam                   map[string]string
 	channelsOpen                 bool
 	requestSentAt                int64
+	subscriptionsMutex           sync.RWMutex
+	subscriptions                map[*Subscription]bool
+	catchUpMutex                 sync.Mutex
+	catchUpTimetokens            map[string]bool
+	catchUpUntil                 int64
+	droppedMessages              dropCounter
+	transitionMutex              sync.Mutex
+	pendingTransitions           []*WPSStatus
+	announcingTransitions        bool
+	connectionStateMutex         sync.RWMutex
+	connectionState              ConnectionState
+	connectionStateSince         time.Time
 }
 
 // SubscribeOperation is the type to store the subscribe op params
@@ -104,21 +127,22 @@ func newSubscriptionManager(webpubsub *WebPubSub, ctx Context) *SubscriptionMana
 	manager.Lock()
 	manager.timetoken = 0
 	manager.storedTimetoken = -1
-	manager.subscriptionStateAnnounced = false
+	manager.connectionStateSince = time.Now()
 	manager.ctx, manager.subscribeCancel = contextWithCancel(backgroundContext)
-	manager.messages = make(chan subscribeMessage, 1000)
+	messageQueueSize := webpubsub.Config.MessageQueueSize
+	if messageQueueSize <= 0 {
+		messageQueueSize = defaultMessageQueueSize
+	}
+	manager.messages = make(chan subscribeMessage, messageQueueSize)
 	manager.reconnectionManager = newReconnectionManager(webpubsub)
 	manager.channelsOpen = true
+	manager.subscriptions = make(map[*Subscription]bool)
 	manager.Unlock()
 
-	if manager.webpubsub.Config.WPSReconnectionPolicy != WPSNonePolicy {
+	if manager.webpubsub.Config.reconnector() != nil {
 
 		manager.reconnectionManager.HandleReconnection(func() {
-			go manager.reconnect()
-
-			manager.Lock()
-			manager.subscriptionStateAnnounced = true
-			manager.Unlock()
+			webpubsub.Config.metrics().SubscribeReconnected()
 			combinedChannels := manager.stateManager.prepareChannelList(true)
 			combinedGroups := manager.stateManager.prepareGroupList(true)
 
@@ -129,12 +153,38 @@ func newSubscriptionManager(webpubsub *WebPubSub, ctx Context) *SubscriptionMana
 				Category:              WPSReconnectedCategory,
 			}
 
-			webpubsub.Config.Log.Println("Status: ", pnStatus)
+			webpubsub.Config.logger(WPSSubscribeSubsystem).Info("reconnected", "status", pnStatus)
+
+			manager.transition(WPSConnectedState, pnStatus,
+				WPSConnectingState, WPSReconnectingState, WPSDisconnectedWithErrorState)
 
-			manager.listenerManager.announceStatus(pnStatus)
+			if webpubsub.Config.RestoreOnReconnect {
+				go func() {
+					manager.catchUp()
+					manager.reconnect()
+				}()
+			} else {
+				go manager.reconnect()
+			}
+			if webpubsub.publishQueueManager != nil {
+				webpubsub.publishQueueManager.onReconnection()
+			}
 		})
 	}
 
+	manager.reconnectionManager.HandleDisconnection(func(err error) {
+		pnStatus := &WPSStatus{
+			Error:                 true,
+			ErrorData:             err,
+			AffectedChannels:      manager.stateManager.prepareChannelList(true),
+			AffectedChannelGroups: manager.stateManager.prepareGroupList(true),
+			Category:              WPSDisconnectedCategory,
+		}
+		webpubsub.Config.logger(WPSSubscribeSubsystem).Warn("network disconnected", "status", pnStatus)
+
+		manager.transition(WPSReconnectingState, pnStatus, WPSConnectingState, WPSConnectedState)
+	})
+
 	manager.reconnectionManager.HandleOnMaxReconnectionExhaustion(func() {
 		combinedChannels := manager.stateManager.prepareChannelList(true)
 		combinedGroups := manager.stateManager.prepareGroupList(true)
@@ -145,9 +195,9 @@ func newSubscriptionManager(webpubsub *WebPubSub, ctx Context) *SubscriptionMana
 			AffectedChannelGroups: combinedGroups,
 			Category:              WPSReconnectionAttemptsExhausted,
 		}
-		webpubsub.Config.Log.Println("Status: ", pnStatus)
+		webpubsub.Config.logger(WPSSubscribeSubsystem).Error("reconnection attempts exhausted", "status", pnStatus)
 
-		manager.listenerManager.announceStatus(pnStatus)
+		manager.transition(WPSFailedState, pnStatus)
 
 		manager.Disconnect()
 	})
@@ -183,6 +233,12 @@ func (m *SubscriptionManager) Destroy() {
 		if m.listenerManager.exitListenerAnnounce != nil {
 			close(m.listenerManager.exitListenerAnnounce)
 		}
+		m.subscriptionsMutex.Lock()
+		for s := range m.subscriptions {
+			s.listenerManager.removeAllListeners()
+			delete(m.subscriptions, s)
+		}
+		m.subscriptionsMutex.Unlock()
 		if m.reconnectionManager.exitReconnectionManager != nil {
 			m.reconnectionManager.stopHeartbeatTimer()
 			close(m.reconnectionManager.exitReconnectionManager)
@@ -199,12 +255,18 @@ func (m *SubscriptionManager) adaptState(stateOperation StateOperation) {
 func (m *SubscriptionManager) adaptSubscribe(
 	subscribeOperation *SubscribeOperation) {
 	m.stateManager.adaptSubscribeOperation(subscribeOperation)
-	m.webpubsub.Config.Log.Println("adapting a new subscription", subscribeOperation.Channels,
-		subscribeOperation.PresenceEnabled)
+	m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("adapting a new subscription", "channels", subscribeOperation.Channels,
+		"presence", subscribeOperation.PresenceEnabled)
+
+	m.transition(WPSConnectingState, &WPSStatus{
+		Category:              WPSConnectingCategory,
+		Operation:             WPSSubscribeOperation,
+		AffectedChannels:      m.stateManager.prepareChannelList(true),
+		AffectedChannelGroups: m.stateManager.prepareGroupList(true),
+	})
 
 	m.Lock()
 
-	m.subscriptionStateAnnounced = false
 	m.queryParam = subscribeOperation.QueryParam
 
 	if subscribeOperation.Timetoken != 0 {
@@ -222,15 +284,45 @@ func (m *SubscriptionManager) adaptSubscribe(
 	m.reconnect()
 }
 
+// unsubscribe releases the channels and groups held by Subscribe and leaves
+// the ones no Subscription holds.
+func (m *SubscriptionManager) unsubscribe(unsubscribeOperation *UnsubscribeOperation) {
+	channels, groups := m.stateManager.releaseGlobal(unsubscribeOperation.Channels, unsubscribeOperation.ChannelGroups)
+	held := len(unsubscribeOperation.Channels) + len(unsubscribeOperation.ChannelGroups) - len(channels) - len(groups)
+	if held > 0 && len(channels) == 0 && len(groups) == 0 {
+		return
+	}
+
+	m.adaptUnsubscribe(&UnsubscribeOperation{
+		Channels:      channels,
+		ChannelGroups: groups,
+		QueryParam:    unsubscribeOperation.QueryParam,
+	})
+}
+
 func (m *SubscriptionManager) adaptUnsubscribe(
 	unsubscribeOperation *UnsubscribeOperation) {
-	m.webpubsub.Config.Log.Println("before adaptUnsubscribeOperation")
+	m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("before adaptUnsubscribeOperation")
 	m.stateManager.adaptUnsubscribeOperation(unsubscribeOperation)
-	m.webpubsub.Config.Log.Println("after adaptUnsubscribeOperation")
-
-	m.Lock()
-	m.subscriptionStateAnnounced = false
-	m.Unlock()
+	m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("after adaptUnsubscribeOperation")
+
+	remainingChannels := m.stateManager.prepareChannelList(true)
+	remainingGroups := m.stateManager.prepareGroupList(true)
+	if len(remainingChannels) == 0 && len(remainingGroups) == 0 {
+		m.transition(WPSDisconnectedState, &WPSStatus{
+			Category:              WPSDisconnectedCategory,
+			Operation:             WPSUnsubscribeOperation,
+			AffectedChannels:      unsubscribeOperation.Channels,
+			AffectedChannelGroups: unsubscribeOperation.ChannelGroups,
+		}, WPSConnectingState, WPSConnectedState, WPSReconnectingState)
+	} else {
+		m.transition(WPSConnectingState, &WPSStatus{
+			Category:              WPSConnectingCategory,
+			Operation:             WPSUnsubscribeOperation,
+			AffectedChannels:      remainingChannels,
+			AffectedChannelGroups: remainingGroups,
+		})
+	}
 
 	go func() {
 		announceAck := false
@@ -247,7 +339,7 @@ func (m *SubscriptionManager) adaptUnsubscribe(
 					AffectedChannels:      unsubscribeOperation.Channels,
 					AffectedChannelGroups: unsubscribeOperation.ChannelGroups,
 				}
-				m.webpubsub.Config.Log.Println("Leave: err", err, pnStatus)
+				m.webpubsub.Config.logger(WPSSubscribeSubsystem).Warn("leave failed", "error", err, "status", pnStatus)
 				m.listenerManager.announceStatus(pnStatus)
 			} else {
 				announceAck = true
@@ -265,12 +357,12 @@ func (m *SubscriptionManager) adaptUnsubscribe(
 				AffectedChannels:      unsubscribeOperation.Channels,
 				AffectedChannelGroups: unsubscribeOperation.ChannelGroups,
 			}
-			m.webpubsub.Config.Log.Println("Leave: ack", pnStatus)
+			m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("Leave: ack", "status", pnStatus)
 			m.listenerManager.announceStatus(pnStatus)
-			m.webpubsub.Config.Log.Println("After Leave: ack", pnStatus)
+			m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("After Leave: ack", "status", pnStatus)
 		}
 	}()
-	m.webpubsub.Config.Log.Println("before storedTimetoken reset")
+	m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("before storedTimetoken reset")
 	m.Lock()
 	if m.stateManager.isEmpty() {
 		m.region = 0
@@ -281,28 +373,31 @@ func (m *SubscriptionManager) adaptUnsubscribe(
 		m.timetoken = 0
 	}
 	m.Unlock()
-	m.webpubsub.Config.Log.Println("after storedTimetoken reset")
+	m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("after storedTimetoken reset")
 
 	m.reconnect()
-	m.webpubsub.Config.Log.Println("after reconnect")
+	m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("after reconnect")
 }
 
 func (m *SubscriptionManager) startSubscribeLoop() {
-	m.webpubsub.Config.Log.Println("startSubscribeLoop")
+	m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("startSubscribeLoop")
 	go subscribeMessageWorker(m)
 
 	go m.reconnectionManager.startPolling()
 
+	// tokenRefreshed is set when the token was refreshed after a 403, the loop
+	// stops if the new token is rejected too.
+	tokenRefreshed := false
 	for {
-		m.webpubsub.Config.Log.Println("startSubscribeLoop looping...")
+		m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("startSubscribeLoop looping...")
 		combinedChannels := m.stateManager.prepareChannelList(true)
 		combinedGroups := m.stateManager.prepareGroupList(true)
 
 		if len(combinedChannels) == 0 && len(combinedGroups) == 0 {
-			m.webpubsub.Config.Log.Println("no channels left to subscribe")
-			m.listenerManager.announceStatus(&WPSStatus{
+			m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("no channels left to subscribe")
+			m.transition(WPSDisconnectedState, &WPSStatus{
 				Category: WPSDisconnectedCategory,
-			})
+			}, WPSConnectingState, WPSConnectedState, WPSReconnectingState)
 
 			m.reconnectionManager.stopHeartbeatTimer()
 
@@ -336,13 +431,13 @@ func (m *SubscriptionManager) startSubscribeLoop() {
 
 		res, _, err := executeRequest(opts)
 		if err != nil {
-			m.webpubsub.Config.Log.Println(err.Error())
+			m.webpubsub.Config.logger(WPSSubscribeSubsystem).Warn("subscribe failed", "error", err)
 
 			if strings.Contains(err.Error(), "timeout") || strings.Contains(err.Error(), "request canceled") {
 				m.listenerManager.announceStatus(&WPSStatus{
 					Category: WPSTimeoutCategory,
 				})
-				m.webpubsub.Config.Log.Println("continue")
+				m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("continue")
 				continue
 			} else {
 
@@ -350,42 +445,56 @@ func (m *SubscriptionManager) startSubscribeLoop() {
 					pnStatus := &WPSStatus{
 						Category: WPSCancelledCategory,
 					}
-					m.webpubsub.Config.Log.Println("Status:", pnStatus)
+					m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("subscribe cancelled", "status", pnStatus)
 					m.listenerManager.announceStatus(pnStatus)
-					m.webpubsub.Config.Log.Println("context canceled")
+					m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("context canceled")
 					break
 				} else if strings.Contains(err.Error(), "Forbidden") ||
-					strings.Contains(err.Error(), "403") {
+					strings.Contains(err.Error(), "403") || isPermissionError(err) {
+					if !tokenRefreshed && m.webpubsub.Config.TokenProvider != nil &&
+						m.webpubsub.tokenManager.refreshRejected(requestResources(opts)) == nil {
+						m.webpubsub.Config.logger(WPSSubscribeSubsystem).Info("subscribe access denied, restarting with the refreshed token")
+						tokenRefreshed = true
+						continue
+					}
 					pnStatus := &WPSStatus{
-						Category: WPSAccessDeniedCategory,
+						Category:  WPSAccessDeniedCategory,
+						Error:     true,
+						ErrorData: err,
 					}
-					m.webpubsub.Config.Log.Println("Status:", pnStatus)
-					m.listenerManager.announceStatus(pnStatus)
+					m.webpubsub.Config.logger(WPSSubscribeSubsystem).Error("subscribe access denied", "status", pnStatus)
+					m.transition(WPSDisconnectedWithErrorState, pnStatus)
 					m.unsubscribeAll()
 					break
 				} else if strings.Contains(err.Error(), "400") ||
 					strings.Contains(err.Error(), "Bad Request") {
 					pnStatus := &WPSStatus{
-						Category: WPSBadRequestCategory,
+						Category:  WPSBadRequestCategory,
+						Error:     true,
+						ErrorData: err,
 					}
-					m.webpubsub.Config.Log.Println("Status:", pnStatus)
-					m.listenerManager.announceStatus(pnStatus)
+					m.webpubsub.Config.logger(WPSSubscribeSubsystem).Error("subscribe bad request", "status", pnStatus)
+					m.transition(WPSDisconnectedWithErrorState, pnStatus)
 					m.unsubscribeAll()
 					break
 				} else if strings.Contains(err.Error(), "530") || strings.Contains(err.Error(), "No Stub Matched") {
 					pnStatus := &WPSStatus{
-						Category: WPSNoStubMatchedCategory,
+						Category:  WPSNoStubMatchedCategory,
+						Error:     true,
+						ErrorData: err,
 					}
-					m.webpubsub.Config.Log.Println("Status:", pnStatus)
-					m.listenerManager.announceStatus(pnStatus)
+					m.webpubsub.Config.logger(WPSSubscribeSubsystem).Error("subscribe no stub matched", "status", pnStatus)
+					m.transition(WPSDisconnectedWithErrorState, pnStatus)
 					m.unsubscribeAll()
 					break
 				} else {
 					pnStatus := &WPSStatus{
-						Category: WPSUnknownCategory,
+						Category:  WPSUnknownCategory,
+						Error:     true,
+						ErrorData: err,
 					}
-					m.webpubsub.Config.Log.Println("Status:", pnStatus)
-					m.listenerManager.announceStatus(pnStatus)
+					m.webpubsub.Config.logger(WPSSubscribeSubsystem).Error("subscribe failed", "status", pnStatus)
+					m.transition(WPSDisconnectedWithErrorState, pnStatus)
 
 					break
 				}
@@ -393,20 +502,13 @@ func (m *SubscriptionManager) startSubscribeLoop() {
 
 		}
 
-		m.Lock()
-		announced := m.subscriptionStateAnnounced
-
-		if announced == false {
-
-			m.listenerManager.announceStatus(&WPSStatus{
-				Category: WPSConnectedCategory,
-			})
-			m.subscriptionStateAnnounced = true
-		}
-		m.Unlock()
+		tokenRefreshed = false
+		m.transition(WPSConnectedState, &WPSStatus{
+			Category: WPSConnectedCategory,
+		}, WPSConnectingState, WPSReconnectingState)
 
 		var envelope subscribeEnvelope
-		log.Printf("res: %v\n", string(res))
+		m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("subscribe response", "body", string(res))
 		err = json.Unmarshal(res, &envelope)
 		if err != nil {
 			pnStatus := &WPSStatus{
@@ -417,7 +519,7 @@ func (m *SubscriptionManager) startSubscribeLoop() {
 				AffectedChannels:      combinedChannels,
 				AffectedChannelGroups: combinedGroups,
 			}
-			m.webpubsub.Config.Log.Println("Unmarshal: err", err, pnStatus)
+			m.webpubsub.Config.logger(WPSSubscribeSubsystem).Error("subscribe response unmarshal error", "error", err, "status", pnStatus)
 
 			m.listenerManager.announceStatus(pnStatus)
 		}
@@ -430,14 +532,20 @@ func (m *SubscriptionManager) startSubscribeLoop() {
 					AffectedChannelGroups: combinedGroups,
 					Category:              WPSRequestMessageCountExceededCategory,
 				}
-				m.webpubsub.Config.Log.Println("Status: ", pnStatus)
+				m.webpubsub.Config.logger(WPSSubscribeSubsystem).Warn("message count exceeded", "status", pnStatus)
 
 				m.listenerManager.announceStatus(pnStatus)
+
+				if m.webpubsub.Config.RecoverMessageCountExceeded {
+					m.recoverGap(tt, envelope.Messages)
+				}
 			}
 			for _, message := range envelope.Messages {
-				m.messages <- message
+				m.webpubsub.Config.metrics().MessageReceived(message.Channel)
+				m.enqueueMessage(message)
 			}
 		}
+		m.reportOverflow(combinedChannels, combinedGroups)
 
 		m.Lock()
 		if m.storedTimetoken != -1 {
@@ -456,7 +564,7 @@ func (m *SubscriptionManager) startSubscribeLoop() {
 					AffectedChannels:      combinedChannels,
 					AffectedChannelGroups: combinedGroups,
 				}
-				m.webpubsub.Config.Log.Println("ParseInt: err", err, pnStatus)
+				m.webpubsub.Config.logger(WPSSubscribeSubsystem).Error("timetoken parse error", "error", err, "status", pnStatus)
 				m.listenerManager.announceStatus(pnStatus)
 			}
 
@@ -489,6 +597,31 @@ type subscribeMessage struct {
 	SequenceNumber    int            `json:"s"`
 
 	PublishMetaData publishMetadata `json:"p"`
+
+	// rawPayload keeps the payload bytes as received, for typed decoding.
+	rawPayload json.RawMessage
+
+	// recovered is set for the messages fetched by recoverGap, they are
+	// delivered as is.
+	recovered *WPSMessage
+}
+
+// UnmarshalJSON parses the subscribe message and retains the raw payload.
+func (m *subscribeMessage) UnmarshalJSON(data []byte) error {
+	type subscribeMessageAlias subscribeMessage
+	if err := json.Unmarshal(data, (*subscribeMessageAlias)(m)); err != nil {
+		return err
+	}
+
+	var raw struct {
+		Payload json.RawMessage `json:"d"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	m.rawPayload = raw.Payload
+
+	return nil
 }
 
 type presenceEnvelope struct {
@@ -512,44 +645,48 @@ type originationMetadata struct {
 func subscribeMessageWorker(m *SubscriptionManager) {
 	m.Lock()
 	if m.ctx == nil && m.subscribeCancel == nil {
-		m.webpubsub.Config.Log.Println("subscribeMessageWorker setting context")
+		m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("subscribeMessageWorker setting context")
 		m.ctx, m.subscribeCancel = contextWithCancel(backgroundContext)
-		m.webpubsub.Config.Log.Println("subscribeMessageWorker after setting context")
+		m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("subscribeMessageWorker after setting context")
 	}
 
-	m.webpubsub.Config.Log.Println("subscribeMessageWorker")
+	m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("subscribeMessageWorker")
 
 	m.Unlock()
-	m.webpubsub.Config.Log.Println("acquiring lock exitSubscriptionManagerMutex")
+	m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("acquiring lock exitSubscriptionManagerMutex")
 	m.exitSubscriptionManagerMutex.Lock()
 	if m.exitSubscriptionManager != nil {
 		m.exitSubscriptionManager <- true
-		m.webpubsub.Config.Log.Println("close exitSubscriptionManager")
+		m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("close exitSubscriptionManager")
 	}
-	m.webpubsub.Config.Log.Println("make channel exitSubscriptionManager")
+	m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("make channel exitSubscriptionManager")
 	m.exitSubscriptionManager = make(chan bool)
 	m.exitSubscriptionManagerMutex.Unlock()
 
 SubscribeMessageWorkerLabel:
 	for {
-		m.webpubsub.Config.Log.Println("subscribeMessageWorker looping...")
+		m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("subscribeMessageWorker looping...")
 		combinedChannels := m.stateManager.prepareChannelList(true)
 		combinedGroups := m.stateManager.prepareGroupList(true)
 
 		if len(combinedChannels) == 0 && len(combinedGroups) == 0 {
-			m.webpubsub.Config.Log.Println("subscribeMessageWorker all channels unsubscribed")
+			m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("subscribeMessageWorker all channels unsubscribed")
 			break
 		}
 		select {
 		case <-m.exitSubscriptionManager:
-			m.webpubsub.Config.Log.Println("subscribeMessageWorker context done")
+			m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("subscribeMessageWorker context done")
 			break SubscribeMessageWorkerLabel
 		case message := <-m.messages:
-			m.webpubsub.Config.Log.Println("subscribeMessageWorker messages")
+			m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("subscribeMessageWorker messages")
+			if message.recovered != nil {
+				m.announceCaughtUp(message.Channel, message.recovered)
+				continue
+			}
 			processSubscribePayload(m, message)
 		}
 	}
-	m.webpubsub.Config.Log.Println("subscribeMessageWorker after for")
+	m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("subscribeMessageWorker after for")
 
 }
 
@@ -576,10 +713,10 @@ func processPresencePayload(m *SubscriptionManager, payload subscribeMessage, ch
 	if presencePayload["occupancy"] != nil {
 		occupancyFromJSON, _ := presencePayload["occupancy"].(float64)
 		occupancy = int(occupancyFromJSON)
-		m.webpubsub.Config.Log.Println("occupancy ", occupancy)
+		m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("presence", "occupancy", occupancy)
 	}
 	if presencePayload["timestamp"] != nil {
-		m.webpubsub.Config.Log.Println("presencePayload['timestamp'] type", reflect.TypeOf(presencePayload["timestamp"]).Kind())
+		m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("presence", "timestampKind", reflect.TypeOf(presencePayload["timestamp"]).Kind())
 		switch presencePayload["timestamp"].(type) {
 		case int:
 			timestamp = int64(presencePayload["timestamp"].(int))
@@ -595,6 +732,9 @@ func processPresencePayload(m *SubscriptionManager, payload subscribeMessage, ch
 	}
 
 	data = presencePayload["data"]
+	join := presenceUUIDs(presencePayload["join"])
+	leave := presenceUUIDs(presencePayload["leave"])
+	timeout := presenceUUIDs(presencePayload["timeout"])
 	if presencePayload["here_now_refresh"] != nil {
 		hereNowRefresh = presencePayload["here_now_refresh"].(bool)
 	}
@@ -626,9 +766,31 @@ func processPresencePayload(m *SubscriptionManager, payload subscribeMessage, ch
 		Occupancy:         occupancy,
 		UUID:              uuid,
 		Timestamp:         timestamp,
+		Join:              join,
+		Leave:             leave,
+		Timeout:           timeout,
 		HereNowRefresh:    hereNowRefresh,
 	}
 	m.listenerManager.announcePresence(pnPresenceResult)
+	for _, l := range m.subscriptionListeners(strippedPresenceChannel, strings.Replace(subscriptionMatch, "-pnpres", "", -1), true) {
+		l.announcePresence(pnPresenceResult)
+	}
+}
+
+// presenceUUIDs reads the join, leave and timeout UUID lists of the interval
+// presence events.
+func presenceUUIDs(value interface{}) []string {
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil
+	}
+	uuids := make([]string, 0, len(list))
+	for _, v := range list {
+		if uuid, ok := v.(string); ok {
+			uuids = append(uuids, uuid)
+		}
+	}
+	return uuids
 }
 
 func processNonPresencePayload(m *SubscriptionManager, payload subscribeMessage, channel, subscriptionMatch string, publishMeta publishMetadata) {
@@ -636,35 +798,57 @@ func processNonPresencePayload(m *SubscriptionManager, payload subscribeMessage,
 	subscribedCh := channel
 	timetoken, _ := strconv.ParseInt(publishMeta.PublishTimetoken, 10, 64)
 
+	if m.caughtUp(channel, timetoken) {
+		m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("skipping message delivered by the catch up", "channel", channel, "timetoken", timetoken)
+		return
+	}
+
 	if subscriptionMatch != "" {
 		actualCh = channel
 		subscribedCh = subscriptionMatch
 	}
 	var messagePayload interface{}
+	subscriptionListeners := m.subscriptionListeners(channel, subscriptionMatch, false)
 
 	switch payload.MessageType {
 	case WPSMessageTypeSignal:
 		pnMessageResult := createWPSMessageResult(payload.Payload, actualCh, subscribedCh, channel, subscriptionMatch, payload.IssuingClientID, payload.UserMetadata, timetoken)
-		m.webpubsub.Config.Log.Println("announceSignal,", pnMessageResult)
+		pnMessageResult.rawMessage = payload.rawPayload
+		m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("announceSignal", "signal", pnMessageResult)
 		m.listenerManager.announceSignal(pnMessageResult)
+		for _, l := range subscriptionListeners {
+			l.announceSignal(pnMessageResult)
+		}
 	case WPSMessageTypeObjects:
 		pnUUIDEvent, pnChannelEvent, pnMembershipEvent, eventType := createPNObjectsResult(payload.Payload, m, actualCh, subscribedCh, channel, subscriptionMatch)
-		m.webpubsub.Config.Log.Println("announceObjects,", pnUUIDEvent, pnChannelEvent, pnMembershipEvent, eventType)
+		m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("announceObjects", "eventType", eventType)
 		switch eventType {
 		case WPSObjectsUUIDEvent:
-			m.webpubsub.Config.Log.Println("pnUUIDEvent:", pnUUIDEvent)
+			m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("announceObjects", "uuidEvent", pnUUIDEvent)
 			m.listenerManager.announceUUIDEvent(pnUUIDEvent)
+			for _, l := range subscriptionListeners {
+				l.announceUUIDEvent(pnUUIDEvent)
+			}
 		case WPSObjectsChannelEvent:
-			m.webpubsub.Config.Log.Println("pnChannelEvent:", pnChannelEvent)
+			m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("announceObjects", "channelEvent", pnChannelEvent)
 			m.listenerManager.announceChannelEvent(pnChannelEvent)
+			for _, l := range subscriptionListeners {
+				l.announceChannelEvent(pnChannelEvent)
+			}
 		case WPSObjectsMembershipEvent:
-			m.webpubsub.Config.Log.Println("pnMembershipEvent:", pnMembershipEvent)
+			m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("announceObjects", "membershipEvent", pnMembershipEvent)
 			m.listenerManager.announceMembershipEvent(pnMembershipEvent)
+			for _, l := range subscriptionListeners {
+				l.announceMembershipEvent(pnMembershipEvent)
+			}
 		}
 	case WPSMessageTypeMessageActions:
 		pnMessageActionsEvent := createWPSMessageActionsEventResult(payload.Payload, m, actualCh, subscribedCh, channel, subscriptionMatch, payload.IssuingClientID)
-		m.webpubsub.Config.Log.Println("WPSMessageTypeMessageActions:", pnMessageActionsEvent)
+		m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("announceMessageActionsEvent", "event", pnMessageActionsEvent)
 		m.listenerManager.announceMessageActionsEvent(pnMessageActionsEvent)
+		for _, l := range subscriptionListeners {
+			l.announceMessageActionsEvent(pnMessageActionsEvent)
+		}
 	case WPSMessageTypeFile:
 		var err error
 		messagePayload, err = parseCipherInterface(payload.Payload, m.webpubsub.Config)
@@ -676,17 +860,22 @@ func processNonPresencePayload(m *SubscriptionManager, payload subscribeMessage,
 				Operation:        WPSSubscribeOperation,
 				AffectedChannels: []string{channel},
 			}
-			m.webpubsub.Config.Log.Println("DecryptString: err", err, pnStatus)
+			m.webpubsub.Config.logger(WPSCryptoSubsystem).Warn("DecryptString error", "error", err, "status", pnStatus)
 			m.listenerManager.announceStatus(pnStatus)
 
 		}
 
 		pnFilesEvent := createWPSFilesEvent(messagePayload, m, actualCh, subscribedCh, channel, subscriptionMatch, payload.IssuingClientID, payload.UserMetadata, timetoken)
-		m.webpubsub.Config.Log.Println("WPSMessageTypeFile:", WPSMessageTypeFile)
+		m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("announceFile")
 		m.listenerManager.announceFile(pnFilesEvent)
+		for _, l := range subscriptionListeners {
+			l.announceFile(pnFilesEvent)
+		}
 	default:
 		var err error
-		messagePayload, err = parseCipherInterface(payload.Payload, m.webpubsub.Config)
+		var rawMessage []byte
+		var keyIndex int
+		messagePayload, rawMessage, keyIndex, err = parseCipherInterfaceWithRaw(payload.Payload, payload.rawPayload, m.webpubsub.Config)
 		if err != nil {
 			pnStatus := &WPSStatus{
 				Category:         WPSBadRequestCategory,
@@ -695,15 +884,23 @@ func processNonPresencePayload(m *SubscriptionManager, payload subscribeMessage,
 				Operation:        WPSSubscribeOperation,
 				AffectedChannels: []string{channel},
 			}
-			m.webpubsub.Config.Log.Println("DecryptString: err", err, pnStatus)
+			m.webpubsub.Config.logger(WPSCryptoSubsystem).Warn("DecryptString error", "error", err, "status", pnStatus)
 			m.listenerManager.announceStatus(pnStatus)
 
 		}
 		pnMessageResult := createWPSMessageResult(messagePayload, actualCh, subscribedCh, channel, subscriptionMatch, payload.IssuingClientID, payload.UserMetadata, timetoken)
-		m.webpubsub.Config.Log.Println("announceMessage,", pnMessageResult)
+		pnMessageResult.rawMessage = rawMessage
+		pnMessageResult.DecryptKeyIndex = keyIndex
+		if m.webpubsub.Config.PropagateTraceContext {
+			pnMessageResult.TraceParent = traceParentFromMeta(payload.UserMetadata)
+		}
+		m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("announceMessage", "message", pnMessageResult)
 		m.listenerManager.announceMessage(pnMessageResult)
+		for _, l := range subscriptionListeners {
+			l.announceMessage(pnMessageResult)
+		}
 	}
-	m.webpubsub.Config.Log.Println("after announceMessage")
+	m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("after announceMessage")
 }
 
 func processSubscribePayload(m *SubscriptionManager, payload subscribeMessage) {
@@ -822,11 +1019,11 @@ func createPNObjectsResult(objPayload interface{}, m *SubscriptionManager, actua
 	if d, ok := objectsPayload["version"]; ok {
 		version = d.(string)
 		if version == "1.0" {
-			m.webpubsub.Config.Log.Println("Ignoring version 1.0 event")
+			m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("Ignoring version 1.0 event")
 			return &WPSUUIDEvent{}, &WPSChannelEvent{}, &WPSMembershipEvent{}, WPSObjectsNoneEvent
 		}
 	} else {
-		m.webpubsub.Config.Log.Println("Ignoring non versioned event")
+		m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("Ignoring non versioned event")
 		return &WPSUUIDEvent{}, &WPSChannelEvent{}, &WPSMembershipEvent{}, WPSObjectsNoneEvent
 	}
 	var id, UUID, channelID, description, timestamp, updated, eTag, name, externalID, profileURL, email string
@@ -950,6 +1147,7 @@ func createWPSMessageResult(messagePayload interface{}, actualCh, subscribedCh,
 		Timetoken:         timetoken,
 		Publisher:         issuingClientID,
 		UserMetadata:      userMetadata,
+		DecryptKeyIndex:   notDecrypted,
 	}
 
 	return pnMessageResult
@@ -965,8 +1163,18 @@ func createWPSMessageResult(messagePayload interface{}, actualCh, subscribedCh,
 //
 // returns the decrypted data as interface and error.
 func parseCipherInterface(data interface{}, pnConf *Config) (interface{}, error) {
-	if pnConf.CipherKey != "" {
-		pnConf.Log.Println("reflect.TypeOf(data).Kind()", reflect.TypeOf(data).Kind(), data)
+	intf, _, _, err := parseCipherInterfaceWithRaw(data, nil, pnConf)
+	return intf, err
+}
+
+// parseCipherInterfaceWithRaw works like parseCipherInterface and additionally
+// returns the JSON bytes of the returned data: the decrypted plain text when
+// the data was encrypted, raw as is otherwise, and the index of the key
+// of the keyring which decrypted the data, notDecrypted when it was not
+// encrypted.
+func parseCipherInterfaceWithRaw(data interface{}, raw []byte, pnConf *Config) (interface{}, []byte, int, error) {
+	if keyring := pnConf.decryptKeyring(); len(keyring) > 0 {
+		pnConf.logger(WPSCryptoSubsystem).Debug("decrypt", "kind", reflect.TypeOf(data).Kind(), "data", data)
 		switch v := data.(type) {
 		case map[string]interface{}:
 
@@ -974,62 +1182,63 @@ func parseCipherInterface(data interface{}, pnConf *Config) (interface{}, error)
 				//decrypt pn_other only
 				msg, ok := v["pn_other"].(string)
 				if ok {
-					pnConf.Log.Println("v[pn_other]", v["pn_other"], v, msg)
-					decrypted, errDecryption := utils.DecryptString(pnConf.CipherKey, msg, pnConf.UseRandomInitializationVector)
+					pnConf.logger(WPSCryptoSubsystem).Debug("decrypt pn_other", "data", v)
+					decrypted, keyIndex, errDecryption := decryptStringWithKeyring(keyring, msg)
 					if errDecryption != nil {
-						pnConf.Log.Println(errDecryption, msg)
-						return v, errDecryption
+						pnConf.logger(WPSCryptoSubsystem).Warn("decryption error", "error", errDecryption, "message", msg)
+						return v, raw, notDecrypted, errDecryption
 					} else {
 						var intf interface{}
-						err := json.Unmarshal([]byte(decrypted.(string)), &intf)
+						err := json.Unmarshal([]byte(decrypted), &intf)
 						if err != nil {
-							pnConf.Log.Println("Unmarshal: err", err)
-							return intf, err
+							pnConf.logger(WPSCryptoSubsystem).Warn("decrypted message unmarshal error", "error", err)
+							return intf, raw, notDecrypted, err
 						}
 						v["pn_other"] = intf
 
-						pnConf.Log.Println("reflect.TypeOf(v).Kind()", reflect.TypeOf(v).Kind(), v)
-						return v, nil
+						pnConf.logger(WPSCryptoSubsystem).Debug("decrypt", "kind", reflect.TypeOf(v).Kind(), "data", v)
+						decryptedRaw, _ := json.Marshal(v)
+						return v, decryptedRaw, keyIndex, nil
 					}
 				}
-				return v, nil
+				return v, raw, notDecrypted, nil
 			}
-			pnConf.Log.Println("return as is reflect.TypeOf(v).Kind()", reflect.TypeOf(v).Kind(), v)
-			return v, nil
+			pnConf.logger(WPSCryptoSubsystem).Debug("returning as is", "kind", reflect.TypeOf(v).Kind(), "data", v)
+			return v, raw, notDecrypted, nil
 		case string:
 			var intf interface{}
-			decrypted, errDecryption := utils.DecryptString(pnConf.CipherKey, data.(string), pnConf.UseRandomInitializationVector)
+			decrypted, keyIndex, errDecryption := decryptStringWithKeyring(keyring, v)
 			if errDecryption != nil {
-				pnConf.Log.Println(errDecryption, intf)
+				pnConf.logger(WPSCryptoSubsystem).Warn("decryption error", "error", errDecryption, "message", intf)
 				intf = data
-				return intf, errDecryption
+				return intf, raw, notDecrypted, errDecryption
 			}
-			pnConf.Log.Println("reflect.TypeOf(intf).Kind()", reflect.TypeOf(decrypted).Kind(), decrypted)
+			pnConf.logger(WPSCryptoSubsystem).Debug("decrypted", "kind", reflect.TypeOf(decrypted).Kind(), "data", decrypted)
 
-			err := json.Unmarshal([]byte(decrypted.(string)), &intf)
+			err := json.Unmarshal([]byte(decrypted), &intf)
 			if err != nil {
-				pnConf.Log.Println("Unmarshal: err", err)
-				return intf, err
+				pnConf.logger(WPSCryptoSubsystem).Warn("decrypted message unmarshal error", "error", err)
+				return intf, raw, notDecrypted, err
 			}
 
-			return intf, nil
+			return intf, []byte(decrypted), keyIndex, nil
 		default:
-			pnConf.Log.Println("returning as is", reflect.TypeOf(v).Kind())
-			return v, nil
+			pnConf.logger(WPSCryptoSubsystem).Debug("returning as is", "kind", reflect.TypeOf(v).Kind())
+			return v, raw, notDecrypted, nil
 		}
 	} else {
-		pnConf.Log.Println("No Cipher, returning as is ", data)
-		return data, nil
+		pnConf.logger(WPSCryptoSubsystem).Debug("no cipher, returning as is", "data", data)
+		return data, raw, notDecrypted, nil
 	}
 }
 
 // AddListener adds a new listener.
-func (m *SubscriptionManager) AddListener(listener *Listener) {
+func (m *SubscriptionManager) AddListener(listener EventListener) {
 	m.listenerManager.addListener(listener)
 }
 
 // RemoveListener removes the listener.
-func (m *SubscriptionManager) RemoveListener(listener *Listener) {
+func (m *SubscriptionManager) RemoveListener(listener EventListener) {
 	m.listenerManager.removeListener(listener)
 }
 
@@ -1044,17 +1253,87 @@ func (m *SubscriptionManager) GetListeners() map[*Listener]bool {
 	return listn
 }
 
+// subscriptionListeners returns the listener managers of the active Subscription
+// handles which match the channel or the subscription match (wildcard or channel
+// group) of an event.
+func (m *SubscriptionManager) subscriptionListeners(channel, subscriptionMatch string, presence bool) []*ListenerManager {
+	m.subscriptionsMutex.RLock()
+	defer m.subscriptionsMutex.RUnlock()
+
+	listeners := []*ListenerManager{}
+	for s := range m.subscriptions {
+		if s.matches(channel, subscriptionMatch, presence) {
+			listeners = append(listeners, s.listenerManager)
+		}
+	}
+	return listeners
+}
+
+func (m *SubscriptionManager) adaptSubscription(s *Subscription) {
+	m.subscriptionsMutex.Lock()
+	m.subscriptions[s] = true
+	m.subscriptionsMutex.Unlock()
+
+	if !m.stateManager.retainSubscription(s.channels, s.channelGroups, s.options.ReceivePresenceEvents) {
+		return
+	}
+	m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("adapting a new subscription handle", "channels", s.channels, "channelGroups", s.channelGroups)
+
+	m.transition(WPSConnectingState, &WPSStatus{
+		Category:              WPSConnectingCategory,
+		Operation:             WPSSubscribeOperation,
+		AffectedChannels:      m.stateManager.prepareChannelList(true),
+		AffectedChannelGroups: m.stateManager.prepareGroupList(true),
+	})
+
+	m.Lock()
+
+	if s.options.Timetoken != 0 {
+		m.timetoken = s.options.Timetoken
+	}
+
+	if m.timetoken != 0 {
+		m.storedTimetoken = m.timetoken
+	}
+
+	m.timetoken = 0
+	m.Unlock()
+
+	m.reconnect()
+}
+
+func (m *SubscriptionManager) releaseSubscription(s *Subscription) {
+	m.subscriptionsMutex.Lock()
+	delete(m.subscriptions, s)
+	m.subscriptionsMutex.Unlock()
+
+	channels, groups := m.stateManager.releaseSubscription(s.channels, s.channelGroups, s.options.ReceivePresenceEvents)
+	if len(channels) == 0 && len(groups) == 0 {
+		return
+	}
+
+	m.adaptUnsubscribe(&UnsubscribeOperation{
+		Channels:      channels,
+		ChannelGroups: groups,
+	})
+}
+
+// GetEventListeners gets all the callback based listeners.
+func (m *SubscriptionManager) GetEventListeners() []EventListener {
+	return m.listenerManager.copyEventListeners()
+}
+
 func (m *SubscriptionManager) reconnect() {
-	m.webpubsub.Config.Log.Println("reconnect")
+	m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("reconnect")
 	m.reconnectionManager.stopHeartbeatTimer()
-	m.webpubsub.Config.Log.Println("after stopHeartbeatTimer")
+	m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("after stopHeartbeatTimer")
 	m.stopSubscribeLoop()
 
 	combinedChannels := m.stateManager.prepareChannelList(true)
 	combinedGroups := m.stateManager.prepareGroupList(true)
 
 	if len(combinedChannels) == 0 && len(combinedGroups) == 0 {
-		m.webpubsub.Config.Log.Println("All channels or channel groups unsubscribed.")
+		m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("All channels or channel groups unsubscribed.")
 	} else {
 		go m.startSubscribeLoop()
 		go m.webpubsub.heartbeatManager.startHeartbeatTimer(false)
@@ -1063,7 +1342,7 @@ func (m *SubscriptionManager) reconnect() {
 
 // Disconnect stops all open subscribe requests, timers, heartbeats and unsubscribes from all channels
 func (m *SubscriptionManager) Disconnect() {
-	m.webpubsub.Config.Log.Println("disconnect")
+	m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug("disconnect")
 
 	if m.exitSubscriptionManager != nil {
 		m.exitSubscriptionManager <- true
@@ -1103,9 +1382,8 @@ func (m *SubscriptionManager) unsubscribeAll() {
 }
 
 func (m *SubscriptionManager) log(message string) {
-	m.webpubsub.Config.Log.Printf("webpubsub: subscribe: %s: %s: %s/%s\n",
-		message,
-		m.webpubsub.Config.UUID,
-		m.stateManager.prepareChannelList(true),
-		m.stateManager.prepareGroupList(true))
+	m.webpubsub.Config.logger(WPSSubscribeSubsystem).Debug(message,
+		"uuid", m.webpubsub.Config.UUID,
+		"channels", m.stateManager.prepareChannelList(true),
+		"channelGroups", m.stateManager.prepareGroupList(true))
 }
@@ -0,0 +1,150 @@
+package webpubsub
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStateManagerSubscriptionRefCount(t *testing.T) {
+	assert := assert.New(t)
+	m := newStateManager()
+
+	assert.True(m.retainSubscription([]string{"ch1"}, []string{}, false))
+	assert.True(m.retainSubscription([]string{"ch1", "ch2"}, []string{"cg1"}, true))
+	assert.False(m.retainSubscription([]string{"ch2"}, []string{}, false))
+
+	channels := m.prepareChannelList(true)
+	sort.Strings(channels)
+	assert.Equal([]string{"ch1", "ch1-pnpres", "ch2", "ch2-pnpres"}, channels)
+	assert.Equal([]string{"cg1", "cg1-pnpres"}, m.prepareGroupList(true))
+
+	releasedChannels, releasedGroups := m.releaseSubscription([]string{"ch1"}, []string{}, false)
+	assert.Equal([]string{}, releasedChannels)
+	assert.Equal([]string{}, releasedGroups)
+
+	releasedChannels, releasedGroups = m.releaseSubscription([]string{"ch1", "ch2"}, []string{"cg1"}, true)
+	assert.Equal([]string{"ch1", "ch1-pnpres", "ch2-pnpres"}, releasedChannels)
+	assert.Equal([]string{"cg1", "cg1-pnpres"}, releasedGroups)
+
+	releasedChannels, _ = m.releaseSubscription([]string{"ch2"}, []string{}, false)
+	assert.Equal([]string{"ch2"}, releasedChannels)
+}
+
+func TestStateManagerCountsSubscribeAsOneHolder(t *testing.T) {
+	assert := assert.New(t)
+	m := newStateManager()
+
+	m.adaptSubscribeOperation(&SubscribeOperation{Channels: []string{"ch1", "ch2"}, PresenceEnabled: true})
+	m.adaptSubscribeOperation(&SubscribeOperation{Channels: []string{"ch1"}})
+	assert.False(m.retainSubscription([]string{"ch1"}, []string{}, false))
+
+	releasedChannels, _ := m.releaseSubscription([]string{"ch1"}, []string{}, false)
+	assert.Equal([]string{}, releasedChannels, "ch1 is still held by Subscribe")
+
+	assert.True(m.retainSubscription([]string{"ch3"}, []string{}, false))
+	releasedChannels, _ = m.releaseGlobal([]string{"ch1", "ch3", "ch4"}, []string{})
+	assert.Equal([]string{"ch1", "ch4"}, releasedChannels, "ch3 is held by a Subscription")
+
+	releasedChannels, _ = m.releaseGlobal([]string{"ch2", "ch2-pnpres"}, []string{})
+	assert.Equal([]string{"ch2", "ch2-pnpres"}, releasedChannels)
+}
+
+func TestSubscriptionUnsubscribeClosesListenerQueues(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewWebPubSub(NewDemoConfig())
+	defer pn.Destroy()
+
+	s := pn.Channel("ch").Subscription(SubscriptionOptions{})
+	listener := newRecordingEventListener()
+	s.AddListener(listener)
+	s.Subscribe()
+
+	q := s.listenerManager.eventListeners[listener]
+	s.Unsubscribe()
+	select {
+	case <-q.done:
+	default:
+		t.Fatal("listener queue not closed")
+	}
+	assert.Nil(s.listenerManager.eventListeners[listener])
+
+	s.Subscribe()
+	assert.NotNil(s.listenerManager.eventListeners[listener])
+	s.Unsubscribe()
+}
+
+func TestSubscriptionMatches(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewWebPubSub(NewDemoConfig())
+
+	s := pn.Channel("chat.room1").Subscription(SubscriptionOptions{})
+	assert.True(s.matches("chat.room1", "", false))
+	assert.False(s.matches("chat.room2", "", false))
+	assert.False(s.matches("chat.room1", "", true))
+
+	wildcard := pn.Channel("chat.*").Subscription(SubscriptionOptions{ReceivePresenceEvents: true})
+	assert.True(wildcard.matches("chat.room2", "chat.*", false))
+	assert.True(wildcard.matches("chat.room2", "", true))
+	assert.False(wildcard.matches("news.room2", "", false))
+
+	group := pn.ChannelGroup("cg").Subscription(SubscriptionOptions{})
+	assert.True(group.matches("chat.room1", "cg", false))
+	assert.False(group.matches("chat.room1", "", false))
+}
+
+func TestSubscriptionChannelsAreCopies(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewWebPubSub(NewDemoConfig())
+
+	s := pn.Channel("ch").Subscription(SubscriptionOptions{})
+	s.Channels()[0] = "other"
+	assert.Equal([]string{"ch"}, s.Channels())
+
+	group := pn.ChannelGroup("cg").Subscription(SubscriptionOptions{})
+	group.ChannelGroups()[0] = "other"
+	assert.Equal([]string{"cg"}, group.ChannelGroups())
+	assert.Empty(group.Channels())
+}
+
+func TestSubscriptionReceivesOnlyOwnEvents(t *testing.T) {
+	assert := assert.New(t)
+	pn := NewWebPubSub(NewDemoConfig())
+
+	room1 := pn.Channel("room1").Subscription(SubscriptionOptions{})
+	room2 := pn.Channel("room2").Subscription(SubscriptionOptions{})
+	listener1 := newRecordingEventListener()
+	listener2 := newRecordingEventListener()
+	room1.AddListener(listener1)
+	room2.AddListener(listener2)
+
+	pn.subscriptionManager.subscriptionsMutex.Lock()
+	pn.subscriptionManager.subscriptions[room1] = true
+	pn.subscriptionManager.subscriptions[room2] = true
+	pn.subscriptionManager.subscriptionsMutex.Unlock()
+
+	processSubscribePayload(pn.subscriptionManager, subscribeMessage{
+		Shard:   "1",
+		Channel: "room1",
+		Payload: "hello",
+		PublishMetaData: publishMetadata{
+			PublishTimetoken: "15000000000000000",
+		},
+	})
+
+	select {
+	case m := <-listener1.messages:
+		assert.Equal("hello", m.Message)
+		assert.Equal("room1", m.Channel)
+	case <-time.After(2 * time.Second):
+		assert.Fail("message not delivered")
+	}
+
+	select {
+	case <-listener2.messages:
+		assert.Fail("message delivered to the other subscription")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
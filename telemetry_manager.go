@@ -173,6 +173,8 @@ func telemetryEndpointNameForOperation(t OperationType) string {
 		break
 	case WPSAccessManagerRevoke:
 		fallthrough
+	case WPSAccessManagerAudit:
+		fallthrough
 	case WPSAccessManagerGrant:
 		endpoint = "pam"
 		break
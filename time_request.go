@@ -134,6 +134,10 @@ func (o *timeOpts) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *timeOpts) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *timeOpts) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }
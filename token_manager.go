@@ -1,36 +1,332 @@
 package webpubsub
 
 import (
+	"regexp"
+	"strings"
 	"sync"
+	"time"
 )
 
-// TokenManager struct is used to for token manager operations
+// TokenManager struct is used to for token manager operations. It holds the
+// PAMv3 tokens of the client and picks, for each request, the most recently
+// stored one which covers the channels, channel groups and UUIDs it accesses.
 type TokenManager struct {
 	sync.RWMutex
-	Token string
+	// Deprecated: Token is the most recently stored token, use GetTokens.
+	Token  string
+	tokens []*StoredToken
+	// expired are the tokens dropped when they expired, the most recently
+	// stored first: a request rejected after its token expired refreshes it.
+	expired []*StoredToken
+
+	webpubsub    *WebPubSub
+	refreshMutex sync.Mutex
+	refreshTimer *time.Timer
+}
+
+// StoredToken is a token held by the TokenManager.
+type StoredToken struct {
+	Token     string
+	Parsed    *WPSToken // Decoded token, nil when it can't be parsed: it is used only when no other token covers the request.
+	ExpiresAt time.Time // Timestamp + TTL of the token, zero when it doesn't expire.
+
+	issued          bool // Stored by GrantToken: it is replaced by the next one and isn't refreshed.
+	channelPatterns map[string]*regexp.Regexp
+	groupPatterns   map[string]*regexp.Regexp
+	uuidPatterns    map[string]*regexp.Regexp
+}
+
+func newStoredToken(token string) *StoredToken {
+	t := &StoredToken{Token: token}
+	parsed, err := ParseToken(token)
+	if err != nil {
+		return t
+	}
+	t.Parsed = parsed
+	if parsed.TTL > 0 {
+		t.ExpiresAt = time.Unix(parsed.Timestamp, 0).Add(time.Duration(parsed.TTL) * time.Minute)
+	}
+	t.channelPatterns = make(map[string]*regexp.Regexp)
+	for pattern := range parsed.Patterns.Channels {
+		addTokenPattern(t.channelPatterns, pattern)
+	}
+	t.groupPatterns = make(map[string]*regexp.Regexp)
+	for pattern := range parsed.Patterns.ChannelGroups {
+		addTokenPattern(t.groupPatterns, pattern)
+	}
+	t.uuidPatterns = make(map[string]*regexp.Regexp)
+	for pattern := range parsed.Patterns.UUIDs {
+		addTokenPattern(t.uuidPatterns, pattern)
+	}
+	return t
+}
+
+// addTokenPattern compiles the pattern of a token, the patterns which are not
+// valid regular expressions match nothing.
+func addTokenPattern(patterns map[string]*regexp.Regexp, pattern string) {
+	if re, err := regexp.Compile("^(?:" + pattern + ")$"); err == nil {
+		patterns[pattern] = re
+	}
+}
+
+func (t *StoredToken) expired(now time.Time) bool {
+	return !t.ExpiresAt.IsZero() && !now.Before(t.ExpiresAt)
+}
+
+// covers reports whether the token grants access to all the resources, by
+// name or pattern. The presence channels are covered by their channel.
+func (t *StoredToken) covers(channels, groups, uuids []string) bool {
+	if t.Parsed == nil {
+		return false
+	}
+	for _, channel := range channels {
+		channel = strings.TrimSuffix(channel, "-pnpres")
+		if _, ok := t.Parsed.Resources.Channels[channel]; !ok && !matchesTokenPattern(t.channelPatterns, channel) {
+			return false
+		}
+	}
+	for _, group := range groups {
+		group = strings.TrimSuffix(group, "-pnpres")
+		if _, ok := t.Parsed.Resources.ChannelGroups[group]; !ok && !matchesTokenPattern(t.groupPatterns, group) {
+			return false
+		}
+	}
+	for _, uuid := range uuids {
+		if _, ok := t.Parsed.Resources.UUIDs[uuid]; !ok && !matchesTokenPattern(t.uuidPatterns, uuid) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesTokenPattern(patterns map[string]*regexp.Regexp, name string) bool {
+	for _, re := range patterns {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
 }
 
 func newTokenManager(webpubsub *WebPubSub, ctx Context) *TokenManager {
-	return &TokenManager{}
+	return &TokenManager{
+		webpubsub: webpubsub,
+	}
 }
 
 // CleanUp resets the token manager
 func (m *TokenManager) CleanUp() {
 	m.Lock()
 	m.Token = ""
+	m.tokens = nil
+	m.expired = nil
+	if m.refreshTimer != nil {
+		m.refreshTimer.Stop()
+		m.refreshTimer = nil
+	}
 	m.Unlock()
 }
 
+// GetToken returns the most recently stored token which isn't expired.
 func (m *TokenManager) GetToken() string {
-	m.RLock()
-	token := m.Token
-	m.RUnlock()
-	return token
+	return m.tokenFor(nil, nil, nil)
+}
+
+// GetTokens returns the stored tokens which aren't expired, the most recently
+// stored first.
+func (m *TokenManager) GetTokens() []StoredToken {
+	m.Lock()
+	defer m.Unlock()
+
+	m.dropExpired(time.Now())
+	tokens := make([]StoredToken, 0, len(m.tokens))
+	for _, t := range m.tokens {
+		tokens = append(tokens, *t)
+	}
+	return tokens
 }
 
 // StoreToken Aceepts PAMv3 token format token to store in the token manager
 func (m *TokenManager) StoreToken(token string) {
+	m.StoreTokens([]string{token})
+}
+
+// StoreTokens adds tokens to the token manager, a token already stored is
+// moved to the front. The oldest tokens are dropped beyond maxStoredTokens.
+func (m *TokenManager) StoreTokens(tokens []string) {
+	stored := make([]*StoredToken, 0, len(tokens))
+	for _, token := range tokens {
+		if token != "" {
+			stored = append(stored, newStoredToken(token))
+		}
+	}
+
 	m.Lock()
-	m.Token = token
+	for _, t := range stored {
+		m.addToken(t)
+	}
+	m.updateToken()
 	m.Unlock()
+
+	m.scheduleRefresh(0)
+}
+
+// storeIssuedToken stores the token returned by GrantToken in place of the
+// previous one, as the single token the client held before it could hold
+// several: the tokens a server issues for other clients don't accumulate.
+func (m *TokenManager) storeIssuedToken(token string) {
+	if token == "" {
+		return
+	}
+	t := newStoredToken(token)
+	t.issued = true
+
+	m.Lock()
+	defer m.Unlock()
+
+	for _, stored := range m.tokens {
+		if stored.issued {
+			m.removeToken(stored.Token)
+			break
+		}
+	}
+	m.addToken(t)
+	m.updateToken()
+}
+
+// maxStoredTokens is the number of tokens the token manager holds at most.
+const maxStoredTokens = 100
+
+func (m *TokenManager) addToken(t *StoredToken) {
+	m.removeToken(t.Token)
+	m.tokens = append([]*StoredToken{t}, m.tokens...)
+	if len(m.tokens) > maxStoredTokens {
+		m.tokens = m.tokens[:maxStoredTokens:maxStoredTokens]
+	}
+}
+
+// RemoveToken removes token from the token manager.
+func (m *TokenManager) RemoveToken(token string) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.removeToken(token)
+	m.updateToken()
+}
+
+func (m *TokenManager) removeToken(token string) {
+	m.tokens = withoutToken(m.tokens, token)
+	m.expired = withoutToken(m.expired, token)
+}
+
+func withoutToken(tokens []*StoredToken, token string) []*StoredToken {
+	for i, t := range tokens {
+		if t.Token == token {
+			return append(tokens[:i:i], tokens[i+1:]...)
+		}
+	}
+	return tokens
+}
+
+func (m *TokenManager) dropExpired(now time.Time) {
+	tokens := m.tokens[:0:0]
+	expired := []*StoredToken{}
+	for _, t := range m.tokens {
+		if t.expired(now) {
+			expired = append(expired, t)
+		} else {
+			tokens = append(tokens, t)
+		}
+	}
+	if len(expired) > 0 {
+		m.tokens = tokens
+		m.expired = append(expired, m.expired...)
+		if len(m.expired) > maxStoredTokens {
+			m.expired = m.expired[:maxStoredTokens:maxStoredTokens]
+		}
+		m.updateToken()
+	}
+}
+
+func (m *TokenManager) updateToken() {
+	m.Token = ""
+	if len(m.tokens) > 0 {
+		m.Token = m.tokens[0].Token
+	}
+}
+
+// tokenFor returns the most recently stored token which covers the
+// resources, or the most recently stored one when none covers them all.
+func (m *TokenManager) tokenFor(channels, groups, uuids []string) string {
+	if t := m.storedTokenFor(channels, groups, uuids); t != nil {
+		return t.Token
+	}
+	return ""
+}
+
+func (m *TokenManager) storedTokenFor(channels, groups, uuids []string) *StoredToken {
+	m.Lock()
+	defer m.Unlock()
+
+	m.dropExpired(time.Now())
+	if len(m.tokens) == 0 {
+		return nil
+	}
+	if len(channels) == 0 && len(groups) == 0 && len(uuids) == 0 {
+		return m.tokens[0]
+	}
+	if t := coveringToken(m.tokens, channels, groups, uuids); t != nil {
+		return t
+	}
+	return m.tokens[0]
+}
+
+// appliedToken returns the token which applied to a request on the
+// resources: the most recently stored one which covers them, expired or not,
+// or the one the request fell back to when it can't be decoded. It is empty
+// when no token applied.
+func (m *TokenManager) appliedToken(channels, groups, uuids []string) string {
+	m.Lock()
+	defer m.Unlock()
+
+	m.dropExpired(time.Now())
+	if t := coveringToken(m.tokens, channels, groups, uuids); t != nil {
+		return t.Token
+	}
+	if t := coveringToken(m.expired, channels, groups, uuids); t != nil {
+		return t.Token
+	}
+	if len(m.tokens) > 0 && m.tokens[0].Parsed == nil {
+		return m.tokens[0].Token
+	}
+	return ""
+}
+
+func coveringToken(tokens []*StoredToken, channels, groups, uuids []string) *StoredToken {
+	for _, t := range tokens {
+		if t.covers(channels, groups, uuids) {
+			return t
+		}
+	}
+	return nil
+}
+
+// tokenResourcesOpts is implemented by the endpoints which access channels,
+// channel groups or UUIDs, to select the token of the request.
+type tokenResourcesOpts interface {
+	tokenResources() (channels, groups, uuids []string)
+}
+
+// requestToken returns the token to attach to the request of o.
+func requestToken(o endpointOpts) string {
+	return o.tokenManager().tokenFor(requestResources(o))
+}
+
+// requestResources returns the resources accessed by the request of o, none
+// when the endpoint doesn't select its token by resource.
+func requestResources(o endpointOpts) (channels, groups, uuids []string) {
+	if r, ok := o.(tokenResourcesOpts); ok {
+		return r.tokenResources()
+	}
+	return nil, nil, nil
 }
@@ -0,0 +1,150 @@
+package webpubsub
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	cbor "github.com/brianolson/cbor_go"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestToken encodes a PAMv3 token granting read on resources and
+// patterns, issued at timestamp for ttl minutes.
+func newTestToken(t *testing.T, timestamp time.Time, ttl int, resources, patterns GrantResources) string {
+	data, err := cbor.Dumps(WPSGrantTokenDecoded{
+		Resources: resources,
+		Patterns:  patterns,
+		Version:   2,
+		Timestamp: timestamp.Unix(),
+		TTL:       ttl,
+		Signature: []byte("sig"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func TestTokenManagerSelectsTokenByResources(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Now()
+	chat := newTestToken(t, now, 60, GrantResources{Channels: map[string]int64{"chat": 1}}, GrantResources{})
+	files := newTestToken(t, now, 60, GrantResources{}, GrantResources{Channels: map[string]int64{"files-.*": 1}})
+	users := newTestToken(t, now, 60, GrantResources{UUIDs: map[string]int64{"alice": 32}, Groups: map[string]int64{"cg": 1}}, GrantResources{})
+
+	m := newTokenManager(nil, nil)
+	m.StoreTokens([]string{chat, files, users})
+
+	assert.Equal(chat, m.tokenFor([]string{"chat", "chat-pnpres"}, nil, nil))
+	assert.Equal(files, m.tokenFor([]string{"files-1"}, nil, nil))
+	assert.Equal(users, m.tokenFor(nil, []string{"cg"}, []string{"alice"}))
+	assert.Equal(users, m.tokenFor([]string{"files-1", "chat"}, nil, nil), "falls back to the last stored token")
+	assert.Equal(users, m.GetToken())
+	assert.Equal(users, m.Token)
+
+	m.StoreToken(chat)
+	assert.Equal(chat, m.GetToken())
+	assert.Len(m.GetTokens(), 3)
+
+	m.RemoveToken(chat)
+	assert.Equal(users, m.tokenFor([]string{"chat"}, nil, nil))
+	assert.Len(m.GetTokens(), 2)
+
+	m.CleanUp()
+	assert.Empty(m.GetToken())
+	assert.Empty(m.GetTokens())
+}
+
+func TestTokenManagerDropsExpiredTokens(t *testing.T) {
+	assert := assert.New(t)
+
+	resources := GrantResources{Channels: map[string]int64{"ch": 1}}
+	expired := newTestToken(t, time.Now().Add(-2*time.Hour), 60, resources, GrantResources{})
+	valid := newTestToken(t, time.Now(), 60, resources, GrantResources{})
+
+	m := newTokenManager(nil, nil)
+	m.StoreTokens([]string{valid, expired, "not-a-token"})
+
+	tokens := m.GetTokens()
+	if assert.Len(tokens, 2) {
+		assert.Equal("not-a-token", tokens[0].Token)
+		assert.Nil(tokens[0].Parsed)
+		assert.True(tokens[0].ExpiresAt.IsZero())
+		assert.Equal(valid, tokens[1].Token)
+		assert.Equal(60, tokens[1].Parsed.TTL)
+		assert.False(tokens[1].ExpiresAt.IsZero())
+	}
+	assert.Equal(valid, m.tokenFor([]string{"ch"}, nil, nil))
+}
+
+func TestTokenManagerLimitsStoredTokens(t *testing.T) {
+	assert := assert.New(t)
+
+	m := newTokenManager(nil, nil)
+	for i := 0; i < maxStoredTokens+10; i++ {
+		m.StoreToken(fmt.Sprintf("token-%d", i))
+	}
+
+	tokens := m.GetTokens()
+	assert.Len(tokens, maxStoredTokens)
+	assert.Equal(fmt.Sprintf("token-%d", maxStoredTokens+9), tokens[0].Token)
+	assert.Equal("token-10", tokens[maxStoredTokens-1].Token)
+}
+
+func TestGrantTokenReplacesIssuedToken(t *testing.T) {
+	assert := assert.New(t)
+
+	transport := &capturingTransport{status: 200}
+	pn := NewWebPubSub(NewDemoConfig())
+	pn.SetClient(&http.Client{Transport: transport})
+	refreshed := false
+	pn.Config.TokenProvider = func(token string) (string, error) {
+		refreshed = true
+		return "", nil
+	}
+	own := newTestToken(t, time.Now(), 60, GrantResources{Channels: map[string]int64{"own": 1}}, GrantResources{})
+	pn.SetToken(own)
+
+	for _, channel := range []string{"alice", "bob"} {
+		issued := newTestToken(t, time.Now().Add(-55*time.Minute), 60, GrantResources{Channels: map[string]int64{channel: 1}}, GrantResources{})
+		transport.body = `{"status":200,"data":{"message":"Success","token":"` + issued + `"},"service":"Access Manager"}`
+		_, _, err := pn.GrantToken().TTL(60).Channels(map[string]ChannelPermissions{channel: {Read: true}}).Execute()
+		assert.Nil(err)
+
+		tokens := pn.GetTokens()
+		if assert.Len(tokens, 2) {
+			assert.Equal(issued, tokens[0].Token)
+			assert.Equal(own, tokens[1].Token)
+		}
+	}
+	pn.tokenManager.refreshExpiring()
+	assert.False(refreshed)
+	pn.Destroy()
+}
+
+func TestRequestTokenAttachedToURL(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Now()
+	chat := newTestToken(t, now, 60, GrantResources{Channels: map[string]int64{"chat": 3}}, GrantResources{})
+	alice := newTestToken(t, now, 60, GrantResources{UUIDs: map[string]int64{"alice": 32}}, GrantResources{})
+
+	pn := NewWebPubSub(NewDemoConfig())
+	pn.SetTokens([]string{chat, alice})
+
+	u, err := buildURL(&publishOpts{webpubsub: pn, Channel: "chat", Message: "hi"})
+	assert.Nil(err)
+	assert.Contains(u.RawQuery, "auth="+chat)
+
+	u, err = buildURL(&getUUIDMetadataOpts{webpubsub: pn, UUID: "alice"})
+	assert.Nil(err)
+	assert.Contains(u.RawQuery, "auth="+alice)
+
+	u, err = buildURL(&timeOpts{webpubsub: pn})
+	assert.Nil(err)
+	assert.Contains(u.RawQuery, "auth="+alice)
+}
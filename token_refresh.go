@@ -0,0 +1,184 @@
+package webpubsub
+
+import (
+	"errors"
+	"time"
+)
+
+// tokenRefreshRetryDelay is the delay before refreshing again a token after
+// the TokenProvider failed.
+const tokenRefreshRetryDelay = 10 * time.Second
+
+// TokenProvider returns a new PAMv3 token to replace token, which is about to
+// expire or was rejected by the server with a 403. token is empty when no
+// token covered the request, the token returned is then added to the stored
+// ones. Set it in Config.TokenProvider.
+type TokenProvider func(token string) (string, error)
+
+// refreshAt returns when the token is refreshed, after 90% of its TTL.
+func (t *StoredToken) refreshAt() time.Time {
+	ttl := t.ExpiresAt.Sub(time.Unix(t.Parsed.Timestamp, 0))
+	return t.ExpiresAt.Add(-ttl / 10)
+}
+
+func (m *TokenManager) tokenProvider() TokenProvider {
+	if m.webpubsub == nil {
+		return nil
+	}
+	return m.webpubsub.Config.TokenProvider
+}
+
+// refresh replaces the token rejected with the one returned by the
+// TokenProvider. Nothing happens when a concurrent refresh already replaced
+// it.
+func (m *TokenManager) refresh(rejected string) error {
+	return m.renew(rejected, func() bool {
+		return !m.stored(rejected)
+	})
+}
+
+// refreshRejected refreshes the token which applied to a request on the
+// resources rejected by the server. When none applied, the token returned by
+// the TokenProvider for an empty token is added to the stored ones, unless a
+// concurrent refresh already added one which covers the resources.
+func (m *TokenManager) refreshRejected(channels, groups, uuids []string) error {
+	if rejected := m.appliedToken(channels, groups, uuids); rejected != "" {
+		return m.refresh(rejected)
+	}
+	return m.renew("", func() bool {
+		for _, t := range m.tokens {
+			if !t.expired(time.Now()) && t.covers(channels, groups, uuids) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// renew stores the token returned by the TokenProvider in place of rejected,
+// or in addition to the stored ones when rejected is empty. done reports
+// whether a concurrent refresh already did it.
+func (m *TokenManager) renew(rejected string, done func() bool) error {
+	provider := m.tokenProvider()
+	if provider == nil {
+		return errors.New("no TokenProvider")
+	}
+
+	m.refreshMutex.Lock()
+	defer m.refreshMutex.Unlock()
+
+	m.RLock()
+	renewed := done()
+	m.RUnlock()
+	if renewed {
+		return nil
+	}
+
+	token, err := provider(rejected)
+	if err == nil && token == "" {
+		err = errors.New("TokenProvider returned an empty token")
+	}
+	if err != nil {
+		m.webpubsub.Config.logger(WPSRequestSubsystem).Warn("token refresh failed", "error", err)
+		return err
+	}
+	m.webpubsub.Config.logger(WPSRequestSubsystem).Debug("token refreshed")
+
+	m.Lock()
+	m.removeToken(rejected)
+	m.addToken(newStoredToken(token))
+	m.updateToken()
+	m.Unlock()
+
+	m.scheduleRefresh(0)
+	return nil
+}
+
+func (m *TokenManager) stored(token string) bool {
+	for _, tokens := range [][]*StoredToken{m.tokens, m.expired} {
+		for _, t := range tokens {
+			if t.Token == token {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// scheduleRefresh starts the timer refreshing the token which expires first,
+// in minDelay at least.
+func (m *TokenManager) scheduleRefresh(minDelay time.Duration) {
+	if m.tokenProvider() == nil {
+		return
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	if m.refreshTimer != nil {
+		m.refreshTimer.Stop()
+		m.refreshTimer = nil
+	}
+	var next time.Time
+	for _, t := range m.tokens {
+		if t.ExpiresAt.IsZero() || t.issued {
+			continue
+		}
+		if at := t.refreshAt(); next.IsZero() || at.Before(next) {
+			next = at
+		}
+	}
+	if next.IsZero() {
+		return
+	}
+	delay := time.Until(next)
+	if delay < minDelay {
+		delay = minDelay
+	}
+	m.refreshTimer = time.AfterFunc(delay, m.refreshExpiring)
+}
+
+// refreshExpiring refreshes the tokens which are about to expire, the expired
+// ones are dropped: the next request rejected with a 403 refreshes them.
+func (m *TokenManager) refreshExpiring() {
+	now := time.Now()
+	due := []string{}
+	m.Lock()
+	m.dropExpired(now)
+	for _, t := range m.tokens {
+		if !t.ExpiresAt.IsZero() && !t.issued && !t.refreshAt().After(now) {
+			due = append(due, t.Token)
+		}
+	}
+	m.Unlock()
+
+	var minDelay time.Duration
+	for _, token := range due {
+		if err := m.refresh(token); err != nil {
+			minDelay = tokenRefreshRetryDelay
+		}
+	}
+	m.scheduleRefresh(minDelay)
+}
+
+// refreshesToken reports whether a 403 of the operation refreshes the token,
+// the subscribe loop refreshes it itself and Access Manager requests are
+// signed with the secret key.
+func refreshesToken(operation OperationType) bool {
+	switch operation {
+	case WPSSubscribeOperation, WPSAccessManagerGrant, WPSAccessManagerRevoke,
+		WPSAccessManagerGrantToken, WPSAccessManagerRevokeToken, WPSAccessManagerAudit:
+		return false
+	}
+	return true
+}
+
+// refreshTokenOnAccessDenied refreshes the token of the request of o when it
+// was rejected with a 403 or by the permission preflight, it returns true
+// when the request can be retried with the new token.
+func refreshTokenOnAccessDenied(o endpointOpts, status StatusResponse, err error) bool {
+	if (status.StatusCode != 403 && !isPermissionError(err)) || o.config().TokenProvider == nil || !refreshesToken(o.operationType()) {
+		return false
+	}
+	return o.tokenManager().refreshRejected(requestResources(o)) == nil
+}
@@ -0,0 +1,209 @@
+package webpubsub
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// tokenTransport rejects with a 403 the requests which aren't authorized with
+// token, and keeps the authorized subscribe requests open after the first one.
+type tokenTransport struct {
+	sync.Mutex
+	token      string
+	auths      []string
+	subscribed int
+}
+
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	auth := req.URL.Query().Get("auth")
+	t.Lock()
+	t.auths = append(t.auths, auth)
+	t.Unlock()
+
+	status, body := 200, `{"status":200,"message":"OK","payload":{"channels":{}}}`
+	if auth != t.token {
+		status, body = 403, `{"status":403,"error":true,"message":"Forbidden"}`
+	} else if strings.Contains(req.URL.String(), "/v2/subscribe/") {
+		t.Lock()
+		t.subscribed++
+		first := t.subscribed == 1
+		t.Unlock()
+		if !first {
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		}
+		body = `{"t":{"t":"15","r":1},"m":[]}`
+	}
+	return &http.Response{
+		StatusCode: status,
+		Body:       ioutil.NopCloser(bytes.NewBufferString(body)),
+		Request:    req,
+	}, nil
+}
+
+func (t *tokenTransport) requestAuths() []string {
+	t.Lock()
+	defer t.Unlock()
+
+	return append([]string{}, t.auths...)
+}
+
+func TestTokenProviderRetriesAccessDenied(t *testing.T) {
+	assert := assert.New(t)
+
+	transport := &tokenTransport{token: "new"}
+	pn := NewWebPubSub(NewDemoConfig())
+	pn.SetClient(&http.Client{Transport: transport})
+	defer pn.Destroy()
+
+	provided := []string{}
+	pn.Config.TokenProvider = func(token string) (string, error) {
+		provided = append(provided, token)
+		return "new", nil
+	}
+	pn.SetToken("old")
+
+	_, status, err := pn.GetState().Channels([]string{"ch"}).Execute()
+	assert.Nil(err)
+	assert.Equal(200, status.StatusCode)
+	assert.Equal([]string{"old"}, provided)
+	assert.Equal([]string{"old", "new"}, transport.requestAuths())
+	assert.Equal("new", pn.tokenManager.GetToken())
+	assert.Len(pn.GetTokens(), 1)
+}
+
+func TestTokenProviderNotRetriedTwice(t *testing.T) {
+	assert := assert.New(t)
+
+	transport := &tokenTransport{token: "valid"}
+	pn := NewWebPubSub(NewDemoConfig())
+	pn.SetClient(&http.Client{Transport: transport})
+	defer pn.Destroy()
+
+	pn.Config.TokenProvider = func(token string) (string, error) {
+		return "rejected", nil
+	}
+
+	_, status, err := pn.GetState().Channels([]string{"ch"}).Execute()
+	assert.NotNil(err)
+	assert.Equal(403, status.StatusCode)
+	assert.Equal([]string{"", "rejected"}, transport.requestAuths())
+}
+
+func TestTokenProviderRefreshesExpiredToken(t *testing.T) {
+	assert := assert.New(t)
+
+	anyUUID := GrantResources{UUIDs: map[string]int64{".*": 32}}
+	expired := newTestToken(t, time.Now().Add(-2*time.Hour), 60, GrantResources{Channels: map[string]int64{"a": 1}}, anyUUID)
+	other := newTestToken(t, time.Now(), 60, GrantResources{Channels: map[string]int64{"b": 1}}, anyUUID)
+	fresh := newTestToken(t, time.Now(), 60, GrantResources{Channels: map[string]int64{"a": 1}}, anyUUID)
+
+	transport := &tokenTransport{token: fresh}
+	pn := NewWebPubSub(NewDemoConfig())
+	pn.SetClient(&http.Client{Transport: transport})
+	defer pn.Destroy()
+
+	provided := []string{}
+	pn.Config.TokenProvider = func(token string) (string, error) {
+		provided = append(provided, token)
+		return fresh, nil
+	}
+	pn.SetTokens([]string{expired, other})
+
+	_, _, err := pn.GetState().Channels([]string{"a"}).Execute()
+	assert.Nil(err)
+	assert.Equal([]string{expired}, provided)
+	assert.Equal([]string{other, fresh}, transport.requestAuths())
+	tokens := pn.GetTokens()
+	if assert.Len(tokens, 2) {
+		assert.Equal(fresh, tokens[0].Token)
+		assert.Equal(other, tokens[1].Token)
+	}
+}
+
+func TestTokenProviderAddsTokenWhenNoneCovered(t *testing.T) {
+	assert := assert.New(t)
+
+	anyUUID := GrantResources{UUIDs: map[string]int64{".*": 32}}
+	other := newTestToken(t, time.Now(), 60, GrantResources{Channels: map[string]int64{"b": 1}}, anyUUID)
+	fresh := newTestToken(t, time.Now(), 60, GrantResources{Channels: map[string]int64{"a": 1}}, anyUUID)
+
+	transport := &tokenTransport{token: fresh}
+	pn := NewWebPubSub(NewDemoConfig())
+	pn.SetClient(&http.Client{Transport: transport})
+	defer pn.Destroy()
+
+	provided := []string{}
+	pn.Config.TokenProvider = func(token string) (string, error) {
+		provided = append(provided, token)
+		return fresh, nil
+	}
+	pn.SetToken(other)
+
+	_, _, err := pn.GetState().Channels([]string{"a"}).Execute()
+	assert.Nil(err)
+	assert.Equal([]string{""}, provided)
+	assert.Equal([]string{other, fresh}, transport.requestAuths())
+	assert.Len(pn.GetTokens(), 2)
+}
+
+func TestTokenProviderRefreshesBeforeExpiry(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := NewWebPubSub(NewDemoConfig())
+	defer pn.Destroy()
+
+	resources := GrantResources{Channels: map[string]int64{"ch": 1}}
+	expiring := newTestToken(t, time.Now().Add(-55*time.Minute), 60, resources, GrantResources{})
+	fresh := newTestToken(t, time.Now(), 60, resources, GrantResources{})
+
+	refreshed := make(chan string, 1)
+	pn.Config.TokenProvider = func(token string) (string, error) {
+		refreshed <- token
+		return fresh, nil
+	}
+	pn.SetToken(expiring)
+
+	select {
+	case token := <-refreshed:
+		assert.Equal(expiring, token)
+	case <-time.After(5 * time.Second):
+		t.Fatal("token not refreshed")
+	}
+	assert.Eventually(func() bool {
+		tokens := pn.GetTokens()
+		return len(tokens) == 1 && tokens[0].Token == fresh
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestTokenProviderRestartsSubscribeLoop(t *testing.T) {
+	assert := assert.New(t)
+
+	transport := &tokenTransport{token: "new"}
+	pn := newTestWebPubSub(&capturingTransport{status: 200, body: `{"status":200}`})
+	pn.Config.SuppressLeaveEvents = true
+	pn.SetSubscribeClient(&http.Client{Transport: transport})
+	listener := newRecordingEventListener()
+	pn.AddListener(listener)
+	defer pn.Destroy()
+
+	pn.Config.TokenProvider = func(token string) (string, error) {
+		return "new", nil
+	}
+	pn.SetToken("old")
+
+	pn.Subscribe().Channels([]string{"ch"}).Execute()
+
+	status := nextStateChange(t, listener)
+	assert.Equal(WPSConnectingCategory, status.Category)
+	status = nextStateChange(t, listener)
+	assert.Equal(WPSConnectedCategory, status.Category)
+	assert.Equal([]string{"ch"}, pn.GetSubscribedChannels())
+}
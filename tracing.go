@@ -0,0 +1,110 @@
+package webpubsub
+
+import (
+	"regexp"
+	"strings"
+)
+
+// traceParentMetaKey is the key of the W3C traceparent in the message meta.
+const traceParentMetaKey = "traceparent"
+
+var traceParentFormat = regexp.MustCompile(`^[0-9a-f]{2}-[0-9a-f]{32}-[0-9a-f]{16}-[0-9a-f]{2}$`)
+
+// Tracer creates the spans of the requests, set it in Config.Tracer.
+type Tracer interface {
+	// StartSpan starts the span of a request. ctx is the context set on the
+	// request builder, nil when none was set.
+	StartSpan(ctx Context, operation OperationType) Span
+}
+
+// Span is the span of a request created by a Tracer.
+type Span interface {
+	// SetAttribute sets an attribute of the span.
+	SetAttribute(key string, value interface{})
+	// TraceParent returns the W3C traceparent of the span, empty when the
+	// span is not recorded.
+	TraceParent() string
+	// End ends the span, err is the error of the request.
+	End(err error)
+}
+
+// The attributes set on the request spans.
+const (
+	TraceAttributeOperation  = "wps.operation"
+	TraceAttributeChannel    = "wps.channel"
+	TraceAttributeStatusCode = "http.status_code"
+	TraceAttributeRetries    = "wps.retries"
+)
+
+// traceContextCarrier is implemented by the endpoints which can carry the
+// traceparent of the request span in the message meta. Signals don't: the
+// signal API has no meta and the traceparent can't be added to the payload
+// without changing what the subscribers receive.
+type traceContextCarrier interface {
+	setTraceParent(traceParent string)
+}
+
+// traceRequest runs execute in the span of the request when a Tracer is set.
+func traceRequest(opts endpointOpts, execute func() ([]byte, StatusResponse, int, error)) ([]byte, StatusResponse, error) {
+	config := opts.config()
+	if config.Tracer == nil {
+		val, status, _, err := execute()
+		return val, status, err
+	}
+
+	span := config.Tracer.StartSpan(opts.context(), opts.operationType())
+	span.SetAttribute(TraceAttributeOperation, opts.operationType().String())
+	if channels := requestChannels(opts); len(channels) > 0 {
+		span.SetAttribute(TraceAttributeChannel, strings.Join(channels, ","))
+	}
+	if config.PropagateTraceContext {
+		if o, ok := opts.(traceContextCarrier); ok {
+			if traceParent := span.TraceParent(); traceParent != "" {
+				o.setTraceParent(traceParent)
+			}
+		}
+	}
+
+	val, status, retries, err := execute()
+	span.SetAttribute(TraceAttributeStatusCode, status.StatusCode)
+	span.SetAttribute(TraceAttributeRetries, retries)
+	span.End(err)
+	return val, status, err
+}
+
+// withTraceParent returns meta with the traceparent added. The meta which
+// isn't an object is returned unchanged.
+func withTraceParent(meta interface{}, traceParent string) interface{} {
+	switch m := meta.(type) {
+	case nil:
+		return map[string]interface{}{traceParentMetaKey: traceParent}
+	case map[string]interface{}:
+		withTrace := make(map[string]interface{}, len(m)+1)
+		for k, v := range m {
+			withTrace[k] = v
+		}
+		withTrace[traceParentMetaKey] = traceParent
+		return withTrace
+	case map[string]string:
+		withTrace := make(map[string]string, len(m)+1)
+		for k, v := range m {
+			withTrace[k] = v
+		}
+		withTrace[traceParentMetaKey] = traceParent
+		return withTrace
+	}
+	return meta
+}
+
+// traceParentFromMeta returns the valid traceparent of the message meta.
+func traceParentFromMeta(meta interface{}) string {
+	m, ok := meta.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	traceParent, ok := m[traceParentMetaKey].(string)
+	if !ok || !traceParentFormat.MatchString(traceParent) {
+		return ""
+	}
+	return traceParent
+}
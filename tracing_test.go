@@ -0,0 +1,191 @@
+package webpubsub
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+type recordingSpan struct {
+	operation   OperationType
+	attributes  map[string]interface{}
+	traceParent string
+	ended       bool
+	err         error
+}
+
+func (s *recordingSpan) SetAttribute(key string, value interface{}) {
+	s.attributes[key] = value
+}
+
+func (s *recordingSpan) TraceParent() string {
+	return s.traceParent
+}
+
+func (s *recordingSpan) End(err error) {
+	s.ended = true
+	s.err = err
+}
+
+type recordingTracer struct {
+	sync.Mutex
+	spans []*recordingSpan
+}
+
+func (t *recordingTracer) StartSpan(ctx Context, operation OperationType) Span {
+	span := &recordingSpan{
+		operation:   operation,
+		attributes:  make(map[string]interface{}),
+		traceParent: testTraceParent,
+	}
+	t.Lock()
+	t.spans = append(t.spans, span)
+	t.Unlock()
+	return span
+}
+
+// capturingTransport records the requests and answers with status and body.
+type capturingTransport struct {
+	sync.Mutex
+	requests []*http.Request
+	status   int
+	body     string
+}
+
+func (c *capturingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.Lock()
+	c.requests = append(c.requests, req)
+	c.Unlock()
+	return &http.Response{
+		StatusCode: c.status,
+		Body:       ioutil.NopCloser(bytes.NewBufferString(c.body)),
+		Request:    req,
+	}, nil
+}
+
+func TestTracingPublishSpan(t *testing.T) {
+	assert := assert.New(t)
+
+	transport := &capturingTransport{status: 200, body: `[1,"Sent","15000000000000000"]`}
+	pn := newTestWebPubSub(transport)
+	tracer := &recordingTracer{}
+	pn.Config.Tracer = tracer
+	pn.Config.PropagateTraceContext = true
+
+	_, _, err := pn.Publish().Channel("ch").Message("hi").Meta(map[string]interface{}{"a": "b"}).Execute()
+	assert.Nil(err)
+
+	assert.Equal(1, len(tracer.spans))
+	span := tracer.spans[0]
+	assert.Equal(WPSPublishOperation, span.operation)
+	assert.Equal("Publish", span.attributes[TraceAttributeOperation])
+	assert.Equal("ch", span.attributes[TraceAttributeChannel])
+	assert.Equal(200, span.attributes[TraceAttributeStatusCode])
+	assert.Equal(0, span.attributes[TraceAttributeRetries])
+	assert.True(span.ended)
+	assert.Nil(span.err)
+
+	var meta map[string]interface{}
+	assert.Nil(json.Unmarshal([]byte(transport.requests[0].URL.Query().Get("meta")), &meta))
+	assert.Equal(map[string]interface{}{"a": "b", "traceparent": testTraceParent}, meta)
+}
+
+func TestTracingWithoutPropagation(t *testing.T) {
+	assert := assert.New(t)
+
+	transport := &capturingTransport{status: 200, body: `[1,"Sent","15000000000000000"]`}
+	pn := newTestWebPubSub(transport)
+	tracer := &recordingTracer{}
+	pn.Config.Tracer = tracer
+
+	_, _, err := pn.Publish().Channel("ch").Message("hi").Execute()
+	assert.Nil(err)
+	assert.Equal(1, len(tracer.spans))
+	assert.Equal("", transport.requests[0].URL.Query().Get("meta"))
+}
+
+func TestTracingSignalCarriesNoTraceParent(t *testing.T) {
+	assert := assert.New(t)
+
+	transport := &capturingTransport{status: 200, body: `[1,"Sent","15000000000000000"]`}
+	pn := newTestWebPubSub(transport)
+	tracer := &recordingTracer{}
+	pn.Config.Tracer = tracer
+	pn.Config.PropagateTraceContext = true
+
+	_, _, err := pn.Signal().Channel("ch").Message("typing").Execute()
+	assert.Nil(err)
+	assert.Equal(1, len(tracer.spans))
+	assert.Equal(WPSSignalOperation, tracer.spans[0].operation)
+	assert.Equal("", transport.requests[0].URL.Query().Get("meta"))
+	assert.NotContains(transport.requests[0].URL.String(), "traceparent")
+}
+
+func TestTracingRetries(t *testing.T) {
+	assert := assert.New(t)
+
+	transport := &sequenceTransport{responses: []sequenceResponse{
+		{statusCode: 503, body: "unavailable"},
+		{statusCode: 503, body: "unavailable"},
+		{statusCode: 503, body: "unavailable"},
+	}}
+	pn := newTestWebPubSub(transport)
+	pn.Config.RetryPolicy = noJitter(NewLinearRetryPolicy(time.Millisecond, 3))
+	tracer := &recordingTracer{}
+	pn.Config.Tracer = tracer
+
+	_, _, err := pn.Time().Execute()
+	assert.NotNil(err)
+	assert.Equal(3, transport.count())
+	assert.Equal(1, len(tracer.spans))
+	assert.Equal(503, tracer.spans[0].attributes[TraceAttributeStatusCode])
+	assert.Equal(2, tracer.spans[0].attributes[TraceAttributeRetries])
+	assert.Equal(err, tracer.spans[0].err)
+}
+
+func TestTracingExtractsTraceParent(t *testing.T) {
+	assert := assert.New(t)
+
+	pn := NewWebPubSub(NewDemoConfig())
+	pn.Config.PropagateTraceContext = true
+	listener := newRecordingEventListener()
+	pn.AddListener(listener)
+
+	processNonPresencePayload(pn.subscriptionManager, subscribeMessage{
+		Channel:      "ch",
+		Payload:      "hi",
+		UserMetadata: map[string]interface{}{"traceparent": testTraceParent},
+	}, "ch", "", publishMetadata{PublishTimetoken: "15000000000000000"})
+
+	message := nextMessage(t, listener)
+	assert.Equal(testTraceParent, message.TraceParent)
+}
+
+func TestWithTraceParent(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(map[string]interface{}{"traceparent": "tp"}, withTraceParent(nil, "tp"))
+	assert.Equal(map[string]string{"a": "b", "traceparent": "tp"}, withTraceParent(map[string]string{"a": "b"}, "tp"))
+	assert.Equal("not an object", withTraceParent("not an object", "tp"))
+
+	meta := map[string]interface{}{"a": "b"}
+	withTraceParent(meta, "tp")
+	assert.Equal(map[string]interface{}{"a": "b"}, meta)
+}
+
+func TestTraceParentFromMeta(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(testTraceParent, traceParentFromMeta(map[string]interface{}{"traceparent": testTraceParent}))
+	assert.Equal("", traceParentFromMeta(map[string]interface{}{"traceparent": "invalid"}))
+	assert.Equal("", traceParentFromMeta("meta"))
+	assert.Equal("", traceParentFromMeta(nil))
+}
@@ -35,7 +35,8 @@ func (b *unsubscribeBuilder) QueryParam(queryParam map[string]string) *unsubscri
 	return b
 }
 
-// Execute runs the Unsubscribe request and unsubscribes from the specified channels.
+// Execute runs the Unsubscribe request and unsubscribes from the specified
+// channels, but the ones used by an active Subscription.
 func (b *unsubscribeBuilder) Execute() {
-	b.webpubsub.subscriptionManager.adaptUnsubscribe(b.operation)
+	b.webpubsub.subscriptionManager.unsubscribe(b.operation)
 }
@@ -29,11 +29,25 @@ var valIV = "0123456789012345"
 //
 // returns the base64 encoded encrypted string.
 func EncryptString(cipherKey string, message string, useRandomInitializationVector bool) string {
-	block, _ := aesCipher(cipherKey)
-
 	message = encodeNonASCIIChars(message)
-	value := []byte(message)
-	value = padWithPKCS7(value)
+	cipherBytes, _ := EncryptBytes(cipherKey, []byte(message), useRandomInitializationVector)
+	return base64.StdEncoding.EncodeToString(cipherBytes)
+}
+
+// EncryptBytes encrypts the data with AES-256-CBC and PKCS7 padding.
+// It accepts the following parameters:
+// cipherKey: cipher key to use to encrypt.
+// data: to encrypt.
+// useRandomInitializationVector: if true the IV is random and is prepended to the encrypted data
+//
+// returns the encrypted data.
+func EncryptBytes(cipherKey string, data []byte, useRandomInitializationVector bool) ([]byte, error) {
+	block, err := aesCipher(cipherKey)
+	if err != nil {
+		return nil, err
+	}
+
+	value := padWithPKCS7(data)
 	iv := make([]byte, aes.BlockSize)
 	if useRandomInitializationVector {
 		iv = generateIV(aes.BlockSize)
@@ -45,9 +59,95 @@ func EncryptString(cipherKey string, message string, useRandomInitializationVect
 	cipherBytes := make([]byte, len(value))
 	blockmode.CryptBlocks(cipherBytes, value)
 	if useRandomInitializationVector {
-		return base64.StdEncoding.EncodeToString(append(iv, cipherBytes...))
+		return append(iv, cipherBytes...), nil
 	}
-	return base64.StdEncoding.EncodeToString(cipherBytes)
+	return cipherBytes, nil
+}
+
+// DecryptBytes decrypts the data encrypted with EncryptBytes.
+// It accepts the following parameters:
+// cipherKey: cipher key to use to decrypt.
+// data: to decrypt.
+// useRandomInitializationVector: if true the IV is extracted from the beginning of the data.
+//
+// returns the decrypted data,
+// error if any.
+func DecryptBytes(cipherKey string, data []byte, useRandomInitializationVector bool) ([]byte, error) {
+	block, err := aesCipher(cipherKey)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt error aes cipher: %s", err)
+	}
+
+	iv := []byte(valIV)
+	if useRandomInitializationVector {
+		if len(data) < aes.BlockSize {
+			return nil, fmt.Errorf("decrypt error: invalid data len %d", len(data))
+		}
+		iv = data[:aes.BlockSize]
+		data = data[aes.BlockSize:]
+	}
+	if len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("decrypt error: invalid data len %d", len(data))
+	}
+
+	decrypted := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(decrypted, data)
+	val, err := unpadPKCS7(decrypted)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt error: %s", err)
+	}
+	return val, nil
+}
+
+// EncryptBytesGCM encrypts the data with AES-256-GCM, the key is the SHA-256
+// of the cipher key.
+// It accepts the following parameters:
+// cipherKey: cipher key to use to encrypt.
+// data: to encrypt.
+//
+// returns the random nonce, the encrypted data and error if any.
+func EncryptBytesGCM(cipherKey string, data []byte) ([]byte, []byte, error) {
+	aead, err := gcmCipher(cipherKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, err
+	}
+	return nonce, aead.Seal(nil, nonce, data, nil), nil
+}
+
+// DecryptBytesGCM decrypts the data encrypted with EncryptBytesGCM.
+// It accepts the following parameters:
+// cipherKey: cipher key to use to decrypt.
+// nonce: nonce used to encrypt the data.
+// data: to decrypt.
+//
+// returns the decrypted data,
+// error if any.
+func DecryptBytesGCM(cipherKey string, nonce, data []byte) ([]byte, error) {
+	aead, err := gcmCipher(cipherKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("decrypt error: invalid nonce len %d", len(nonce))
+	}
+	decrypted, err := aead.Open(nil, nonce, data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt error: %s", err)
+	}
+	return decrypted, nil
+}
+
+func gcmCipher(cipherKey string) (cipher.AEAD, error) {
+	key := sha256.Sum256([]byte(cipherKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
 }
 
 type A struct {
@@ -137,6 +237,12 @@ func EncryptCipherKey(cipherKey string) []byte {
 	return []byte(hex.EncodeToString(sha256String))
 }
 
+// EncodeNonASCIIChars creates unicode string of the non-ascii chars, as
+// EncryptString does before encrypting.
+func EncodeNonASCIIChars(message string) string {
+	return encodeNonASCIIChars(message)
+}
+
 // encodeNonAsciiChars creates unicode string of the non-ascii chars.
 // It accepts the following parameters:
 // message: to parse.
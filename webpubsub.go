@@ -1,7 +1,6 @@
 package webpubsub
 
 import (
-	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
@@ -74,6 +73,9 @@ type WebPubSub struct {
 	ctx                  Context
 	cancel               func()
 	tokenManager         *TokenManager
+	publishQueueManager  *publishQueueManager
+	middlewares          []Middleware
+	middlewareMutex      sync.RWMutex
 }
 
 // Publish is used to send a message to all subscribers of a channel.
@@ -321,7 +323,18 @@ func (pn *WebPubSub) GrantWithContext(ctx Context) *grantBuilder {
 	return newGrantBuilderWithContext(pn, ctx)
 }
 
+// Audit This function returns the access permissions established for WebPubSub Access Manager (PAM) by Grant on the channels, channel groups, UUIDs and auth keys.
+func (pn *WebPubSub) Audit() *auditBuilder {
+	return newAuditBuilder(pn)
+}
+
+// AuditWithContext This function returns the access permissions established for WebPubSub Access Manager (PAM) by Grant on the channels, channel groups, UUIDs and auth keys.
+func (pn *WebPubSub) AuditWithContext(ctx Context) *auditBuilder {
+	return newAuditBuilderWithContext(pn, ctx)
+}
+
 // GrantToken Use the Grant Token method to generate an auth token with embedded access control lists. The client sends the auth token to WebPubSub along with each request.
+// The token granted is stored in place of the one granted before, and isn't refreshed by the TokenProvider.
 func (pn *WebPubSub) GrantToken() *grantTokenBuilder {
 	return newGrantTokenBuilder(pn)
 }
@@ -371,11 +384,37 @@ func (pn *WebPubSub) RemoveMessageActionWithContext(ctx Context) *removeMessageA
 	return newRemoveMessageActionsBuilderWithContext(pn, ctx)
 }
 
-// SetToken Stores a single token in the Token Management System for use in API calls.
+// SetToken Stores a token in the Token Management System for use in API calls.
+// Each request is sent with the most recently stored token which covers its
+// channels, channel groups and UUIDs.
 func (pn *WebPubSub) SetToken(token string) {
 	pn.tokenManager.StoreToken(token)
 }
 
+// SetTokens Stores several tokens in the Token Management System for use in API calls.
+// It holds 100 tokens at most, the oldest ones are dropped.
+func (pn *WebPubSub) SetTokens(tokens []string) {
+	pn.tokenManager.StoreTokens(tokens)
+}
+
+// GetTokens returns the tokens stored in the Token Management System which
+// aren't expired, the most recently stored first.
+func (pn *WebPubSub) GetTokens() []StoredToken {
+	return pn.tokenManager.GetTokens()
+}
+
+// RemoveToken removes a token from the Token Management System.
+func (pn *WebPubSub) RemoveToken(token string) {
+	pn.tokenManager.RemoveToken(token)
+}
+
+// CanPerform reports whether the token selected for resource grants the
+// permission operation requires on it. It returns false when no stored token
+// can be decoded.
+func (pn *WebPubSub) CanPerform(operation OperationType, resource PermissionResource) bool {
+	return pn.tokenManager.canPerform(operation, resource) == nil
+}
+
 // ResetTokenManager resets the token manager.
 func (pn *WebPubSub) ResetTokenManager() {
 	pn.tokenManager.CleanUp()
@@ -386,13 +425,14 @@ func (pn *WebPubSub) Unsubscribe() *unsubscribeBuilder {
 	return newUnsubscribeBuilder(pn)
 }
 
-// AddListener lets you add a new listener.
-func (pn *WebPubSub) AddListener(listener *Listener) {
+// AddListener lets you add a new listener. Either a channel based *Listener
+// or an EventListener implementation can be passed.
+func (pn *WebPubSub) AddListener(listener EventListener) {
 	pn.subscriptionManager.AddListener(listener)
 }
 
 // RemoveListener lets you remove new listener.
-func (pn *WebPubSub) RemoveListener(listener *Listener) {
+func (pn *WebPubSub) RemoveListener(listener EventListener) {
 	pn.subscriptionManager.RemoveListener(listener)
 }
 
@@ -401,6 +441,37 @@ func (pn *WebPubSub) GetListeners() map[*Listener]bool {
 	return pn.subscriptionManager.GetListeners()
 }
 
+// GetEventListeners gets all the existing callback based listeners.
+func (pn *WebPubSub) GetEventListeners() []EventListener {
+	return pn.subscriptionManager.GetEventListeners()
+}
+
+// ConnectionState returns the current state of the subscribe connection.
+func (pn *WebPubSub) ConnectionState() ConnectionState {
+	return pn.subscriptionManager.getConnectionState()
+}
+
+// AddConnectionStateListener calls handler with every transition of the
+// connection state, in order. The returned EventListener can be passed to
+// RemoveListener to stop the notifications.
+func (pn *WebPubSub) AddConnectionStateListener(handler func(change *ConnectionStateChange)) EventListener {
+	listener := &connectionStateListener{handler: handler}
+	pn.subscriptionManager.AddListener(listener)
+	return listener
+}
+
+// PresenceTracker creates a PresenceTracker keeping the list of the UUIDs
+// present on channels, call Start to seed it and apply the presence events.
+func (pn *WebPubSub) PresenceTracker(channels ...string) *PresenceTracker {
+	return newPresenceTracker(pn, channels)
+}
+
+// QueueStats returns the depth of the queues between the subscribe loop and
+// the listeners, and the number of events dropped by the OverflowPolicy.
+func (pn *WebPubSub) QueueStats() QueueStats {
+	return pn.subscriptionManager.queueStats()
+}
+
 // Leave unsubscribes from a channel.
 func (pn *WebPubSub) Leave() *leaveBuilder {
 	return newLeaveBuilder(pn)
@@ -669,6 +740,7 @@ func (pn *WebPubSub) GetFileURLWithContext(ctx Context) *getFileURLBuilder {
 }
 
 // DownloadFile Provides the ability to fetch an individual file.
+// An encrypted file is read in memory to be decrypted.
 func (pn *WebPubSub) DownloadFile() *downloadFileBuilder {
 	return newDownloadFileBuilder(pn)
 }
@@ -700,31 +772,36 @@ func (pn *WebPubSub) PublishFileMessageWithContext(ctx Context) *publishFileMess
 
 // Destroy stops all open requests, removes listeners, closes heartbeats, and cleans up.
 func (pn *WebPubSub) Destroy() {
-	pn.Config.Log.Println("Calling Destroy")
+	pn.Config.logger(WPSGeneralSubsystem).Debug("Calling Destroy")
 	pn.UnsubscribeAll()
 	pn.cancel()
 
 	if pn.subscriptionManager != nil {
 		pn.subscriptionManager.Destroy()
-		pn.Config.Log.Println("after subscription manager Destroy")
+		pn.Config.logger(WPSGeneralSubsystem).Debug("after subscription manager Destroy")
 	}
 
-	pn.Config.Log.Println("calling subscriptionManager Destroy")
+	pn.Config.logger(WPSGeneralSubsystem).Debug("calling subscriptionManager Destroy")
 	if pn.heartbeatManager != nil {
 		pn.heartbeatManager.Destroy()
-		pn.Config.Log.Println("after heartbeat manager Destroy")
+		pn.Config.logger(WPSGeneralSubsystem).Debug("after heartbeat manager Destroy")
 	}
 
-	pn.Config.Log.Println("After Destroy")
-	pn.Config.Log.Println("calling RemoveAllListeners")
+	pn.Config.logger(WPSGeneralSubsystem).Debug("After Destroy")
+	pn.Config.logger(WPSGeneralSubsystem).Debug("calling RemoveAllListeners")
 	pn.subscriptionManager.RemoveAllListeners()
-	pn.Config.Log.Println("after RemoveAllListeners")
+	pn.Config.logger(WPSGeneralSubsystem).Debug("after RemoveAllListeners")
 	close(pn.jobQueue)
-	pn.Config.Log.Println("after close jobQueue")
+	pn.Config.logger(WPSGeneralSubsystem).Debug("after close jobQueue")
 	pn.requestWorkers.Close()
-	pn.Config.Log.Println("after close requestWorkers")
+	pn.Config.logger(WPSGeneralSubsystem).Debug("after close requestWorkers")
 	pn.tokenManager.CleanUp()
-	pn.client.CloseIdleConnections()
+	pn.Lock()
+	client := pn.client
+	pn.Unlock()
+	if client != nil {
+		client.CloseIdleConnections()
+	}
 
 }
 
@@ -752,7 +829,7 @@ func NewWebPubSub(pnconf *Config) *WebPubSub {
 	if pnconf.Log == nil {
 		pnconf.Log = log.New(ioutil.Discard, "", log.Ldate|log.Ltime|log.Lshortfile)
 	}
-	pnconf.Log.Println(fmt.Sprintf("WebPubSub Go v4 SDK: %s\npnconf: %v\n%s\n%s\n%s", Version, pnconf, runtime.Version(), runtime.GOARCH, runtime.GOOS))
+	pnconf.logger(WPSGeneralSubsystem).Info("WebPubSub Go SDK", "version", Version, "config", pnconf, "go", runtime.Version(), "arch", runtime.GOARCH, "os", runtime.GOOS)
 
 	utils.CheckUUID(pnconf.UUID)
 	pn := &WebPubSub{
@@ -768,6 +845,8 @@ func NewWebPubSub(pnconf *Config) *WebPubSub {
 	pn.jobQueue = make(chan *JobQItem)
 	pn.requestWorkers = pn.newNonSubQueueProcessor(pnconf.MaxWorkers, ctx)
 	pn.tokenManager = newTokenManager(pn, ctx)
+	pn.publishQueueManager = newPublishQueueManager(pn, ctx)
+	pn.publishQueueManager.start()
 
 	return pn
 }
@@ -775,7 +854,7 @@ func NewWebPubSub(pnconf *Config) *WebPubSub {
 func (pn *WebPubSub) newNonSubQueueProcessor(maxWorkers int, ctx Context) *RequestWorkers {
 	workers := make(chan chan *JobQItem, maxWorkers)
 
-	pn.Config.Log.Printf("Init RequestWorkers: workers %d", maxWorkers)
+	pn.Config.logger(WPSGeneralSubsystem).Debug("Init RequestWorkers", "workers", maxWorkers)
 
 	p := &RequestWorkers{
 		WorkersChannel: workers,
@@ -785,6 +864,12 @@ func (pn *WebPubSub) newNonSubQueueProcessor(maxWorkers int, ctx Context) *Reque
 	return p
 }
 
+// PendingPublishes returns the items of the offline publish queue which are
+// not delivered yet.
+func (pn *WebPubSub) PendingPublishes() []QueuedPublish {
+	return pn.publishQueueManager.pending()
+}
+
 // NewWebPubSubDemo returns an instance with demo keys
 func NewWebPubSubDemo() *WebPubSub {
 	return NewWebPubSub(NewDemoConfig())
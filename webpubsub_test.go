@@ -1,11 +1,20 @@
 package webpubsub
 
 import (
+	"net/http"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
+// newTestWebPubSub returns an instance with the demo keys whose non-subscribe
+// requests go through transport.
+func newTestWebPubSub(transport http.RoundTripper) *WebPubSub {
+	pn := NewWebPubSub(NewDemoConfig())
+	pn.SetClient(&http.Client{Transport: transport})
+	return pn
+}
+
 func TestInitializer(t *testing.T) {
 	assert := assert.New(t)
 
@@ -149,6 +149,10 @@ func (o *whereNowOpts) telemetryManager() *TelemetryManager {
 	return o.webpubsub.telemetryManager
 }
 
+func (o *whereNowOpts) middlewares() []Middleware {
+	return o.webpubsub.copyMiddlewares()
+}
+
 func (o *whereNowOpts) tokenManager() *TokenManager {
 	return o.webpubsub.tokenManager
 }